@@ -0,0 +1,165 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// maxActivity is the number of events retained in an activity feed.
+const maxActivity = 1000
+
+// ActivityFeed merges the events of many mutexes into a single stream.
+// Typically, the feed is stored in the parent directory of the mutexes,
+// allowing a single dashboard to follow hundreds of locks with a single
+// watch. Mutexes publish to the feed when constructed with the
+// [[WithActivityFeed]] option.
+type ActivityFeed struct{ subspace.Subspace }
+
+// NewActivityFeed constructs an activity feed. 'root' is
+// the directory where the feed's events are stored.
+func NewActivityFeed(root subspace.Subspace) ActivityFeed {
+	return ActivityFeed{root}
+}
+
+// WithActivityFeed causes the mutex to publish its events to the given
+// feed, in addition to its own event log. [[Event.Mutex]] identifies
+// which mutex each event belongs to.
+func WithActivityFeed(feed ActivityFeed) Option {
+	return func(x *Mutex) {
+		x.feed = &feed
+	}
+}
+
+// Events returns the retained events of the feed, oldest first.
+// Only the latest 1000 events are retained.
+func (x *ActivityFeed) Events(db fdb.Transactor) (_ []Event, err error) {
+	defer wrapErr(&err)
+	return x.getEvents(db, nil)
+}
+
+// Stream calls 'fn' for each event published to the feed after this method
+// is called, in order. This method blocks until the context is cancelled or
+// an error occurs. See [[Mutex.StreamEvents]].
+func (x *ActivityFeed) Stream(ctx context.Context, db fdb.Transactor, fn func(Event)) (err error) {
+	defer wrapErr(&err)
+	return streamLog(ctx, db, x, fn)
+}
+
+// publish appends an event of the mutex with the given ID to the feed.
+func (x *ActivityFeed) publish(tr fdb.Transaction, kind EventKind, mutex string, name string) error {
+	rngActivity, err := x.packActivityRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack activity range: %w", err)
+	}
+
+	key, err := x.packActivityKey(kind, mutex)
+	if err != nil {
+		return fmt.Errorf("failed to pack activity key: %w", err)
+	}
+	tr.SetVersionstampedKey(key, packEventValue(kind, name, time.Now()))
+	tr.Add(x.packVersionKey(), packIncrement())
+
+	trimLog(tr, rngActivity, maxActivity)
+	return nil
+}
+
+// getEvents returns the published events which come after the
+// event with the key 'after'. If 'after' is nil, all the
+// retained events are returned.
+func (x *ActivityFeed) getEvents(db fdb.Transactor, after fdb.Key) ([]Event, error) {
+	rngActivity, err := x.packActivityRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack activity range: %w", err)
+	}
+	if after != nil {
+		rngActivity.Begin = fdb.Key(append(after, 0x00))
+	}
+
+	events, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var events []Event
+		iter := tr.GetRange(rngActivity, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			event, err := x.unpackActivity(kv.Key, kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack activity: %w", err)
+			}
+			events = append(events, event)
+		}
+		return events, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events.([]Event), nil
+}
+
+// getLastEventKey returns the key of the latest published
+// event. If no events are published, nil is returned.
+func (x *ActivityFeed) getLastEventKey(db fdb.Transactor) (fdb.Key, error) {
+	rngActivity, err := x.packActivityRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack activity range: %w", err)
+	}
+	return getLastKey(db, rngActivity)
+}
+
+// watchEvents returns a channel which signals when an event is published.
+// When an event is published, the channel returns nil. If the watch setup
+// fails or the provided context is canceled, the channel returns an error.
+func (x *ActivityFeed) watchEvents(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packVersionKey(), nil
+	})
+}
+
+// eventKey returns the key of the given event.
+func (x *ActivityFeed) eventKey(event Event) fdb.Key {
+	return x.Pack(tuple.Tuple{"activity", event.Version, event.Mutex})
+}
+
+func (x *ActivityFeed) packActivityRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"activity"}))
+}
+
+func (x *ActivityFeed) packActivityKey(kind EventKind, mutex string) (fdb.Key, error) {
+	// Include the mutex ID so events of different mutexes
+	// published by the same transaction don't collide.
+	tup := tuple.Tuple{"activity", tuple.IncompleteVersionstamp(eventOrder(kind)), mutex}
+	return tup.PackWithVersionstamp(x.Bytes())
+}
+
+func (x *ActivityFeed) unpackActivity(key fdb.Key, val []byte) (Event, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to unpack key tuple: %w", err)
+	}
+	if len(tup) != 3 {
+		return Event{}, fmt.Errorf("key tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[1].(tuple.Versionstamp)
+	if !ok {
+		return Event{}, fmt.Errorf("key tuple element 1 is not a versionstamp")
+	}
+	mutex, ok := tup[2].(string)
+	if !ok {
+		return Event{}, fmt.Errorf("key tuple element 2 is not a string")
+	}
+
+	event, err := unpackEventValue(val)
+	if err != nil {
+		return Event{}, err
+	}
+	event.Mutex = mutex
+	event.Version = vstamp
+	return event, nil
+}
+
+func (x *ActivityFeed) packVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"version"})
+}
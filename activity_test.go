@@ -0,0 +1,77 @@
+package mutex
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityFeed(t *testing.T) {
+	tests := map[string]testFn{
+		"merged": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+			feed := NewActivityFeed(parent)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+
+			xA, err := NewMutex(db, dirA, "clientA", WithActivityFeed(feed))
+			require.NoError(t, err)
+
+			xB, err := NewMutex(db, dirB, "clientB", WithActivityFeed(feed))
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ch := make(chan Event, 3)
+			go func() {
+				_ = feed.Stream(ctx, db, func(event Event) { ch <- event })
+			}()
+
+			// Give the stream time to start.
+			time.Sleep(100 * time.Millisecond)
+
+			acquired, err := xA.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = xB.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = xA.Release(db)
+			require.NoError(t, err)
+
+			pathA := strings.Join(dirA.GetPath(), "/")
+			pathB := strings.Join(dirB.GetPath(), "/")
+
+			event := <-ch
+			require.Equal(t, pathA, event.Mutex)
+			require.Equal(t, EventAcquired, event.Kind)
+
+			event = <-ch
+			require.Equal(t, pathB, event.Mutex)
+			require.Equal(t, EventAcquired, event.Kind)
+
+			event = <-ch
+			require.Equal(t, pathA, event.Mutex)
+			require.Equal(t, EventReleased, event.Kind)
+
+			events, err := feed.Events(db)
+			require.NoError(t, err)
+			require.Len(t, events, 3)
+		},
+	}
+
+	runTests(t, tests)
+}
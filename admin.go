@@ -0,0 +1,651 @@
+package mutex
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// maxAuditEntries is the number of entries retained in the audit log.
+const maxAuditEntries = 1000
+
+// AdminClient bundles the operational APIs for the mutexes stored in the
+// immediate subdirectories of a parent directory. Every action which
+// changes a mutex is recorded in an audit log, stored in the parent
+// directory, along with the operator who performed it. Mutexes are
+// referred to by the name of their subdirectory. The operations which
+// modify mutexes can be previewed using [[AdminClient.DryRun]].
+type AdminClient struct {
+	parent   directory.DirectorySubspace
+	operator string
+
+	// dryRun, if true, causes transactions to be aborted
+	// after their changes are recorded. See [[AdminClient.DryRun]].
+	dryRun  bool
+	mu      sync.Mutex
+	changes []Change
+}
+
+// NewAdminClient constructs an admin client for the mutexes stored in the
+// immediate subdirectories of 'parent'. 'operator' identifies the person
+// or system performing the actions and is recorded in the audit log.
+func NewAdminClient(parent directory.DirectorySubspace, operator string) (*AdminClient, error) {
+	if operator == "" {
+		return nil, fmt.Errorf("operator is empty")
+	}
+	return &AdminClient{parent: parent, operator: operator}, nil
+}
+
+// AuditEntry describes an action performed through an [[AdminClient]].
+type AuditEntry struct {
+	// Operator identifies who performed the action.
+	Operator string
+
+	// Action names the [[AdminClient]] method which was called.
+	Action string
+
+	// Path is the directory path of the affected mutex.
+	Path []string
+
+	// Detail describes the outcome of the action.
+	Detail string
+
+	// Time is when the action was performed,
+	// according to the operator's clock.
+	Time time.Time
+
+	// Version orders the entries.
+	Version tuple.Versionstamp
+}
+
+// Problem describes a kind of inconsistency found by [[AdminClient.Check]].
+type Problem int
+
+const (
+	// ProblemMultipleOwners means more than one owner key is set.
+	ProblemMultipleOwners Problem = iota
+
+	// ProblemOwnerQueued means the owner is also in the queue.
+	ProblemOwnerQueued
+
+	// ProblemDuplicateQueueEntry means a client is in the queue more than once.
+	ProblemDuplicateQueueEntry
+
+	// ProblemOrphanPriority means a priority is set for a client which isn't queued.
+	ProblemOrphanPriority
+
+	// ProblemStaleReservation means a reservation is set while the mutex is held.
+	ProblemStaleReservation
+)
+
+func (p Problem) String() string {
+	switch p {
+	case ProblemMultipleOwners:
+		return "multiple owners"
+	case ProblemOwnerQueued:
+		return "owner queued"
+	case ProblemDuplicateQueueEntry:
+		return "duplicate queue entry"
+	case ProblemOrphanPriority:
+		return "orphan priority"
+	case ProblemStaleReservation:
+		return "stale reservation"
+	default:
+		return "unknown"
+	}
+}
+
+// Inconsistency describes a problem found in a mutex's state.
+type Inconsistency struct {
+	// Path is the directory path of the mutex.
+	Path []string
+
+	// Problem is the kind of inconsistency.
+	Problem Problem
+
+	// Client is the name of the client involved,
+	// if the problem concerns a single client.
+	Client string
+}
+
+// ForceRelease takes the mutex away from its owner, as [[ReleaseAllOwnedBy]]
+// does for a single mutex. The mutex is handed to the next client in the
+// queue. If the mutex is free, this method is a noop. The name of the
+// evicted owner is returned. If the mutex doesn't exist, [[ErrNotFound]]
+// is returned.
+func (x *AdminClient) ForceRelease(db fdb.Transactor, name string) (_ string, err error) {
+	defer wrapErr(&err)
+
+	owner, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+		m, err := x.open(tr, name, p)
+		if err != nil {
+			return nil, err
+		}
+		owner, err := m.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name == "" {
+			return "", nil
+		}
+		if _, err := m.evict(tr, owner.name); err != nil {
+			return nil, fmt.Errorf("failed to evict owner: %w", err)
+		}
+		detail := fmt.Sprintf("evicted %s", owner.name)
+		if err := x.audit(tr, "ForceRelease", m, detail); err != nil {
+			return nil, err
+		}
+		return owner.name, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return owner.(string), nil
+}
+
+// PurgeQueue removes every client from the mutex's queue and returns the
+// number of clients removed. Clients blocked in [[Mutex.Acquire]] keep
+// waiting until their context ends, so this is meant for queues left
+// behind by dead clients. If the mutex doesn't exist, [[ErrNotFound]]
+// is returned.
+func (x *AdminClient) PurgeQueue(db fdb.Transactor, name string) (_ int, err error) {
+	defer wrapErr(&err)
+
+	count, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+		m, err := x.open(tr, name, p)
+		if err != nil {
+			return nil, err
+		}
+		count, err := m.purgeQueue(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge queue: %w", err)
+		}
+		detail := fmt.Sprintf("removed %d clients", count)
+		if err := x.audit(tr, "PurgeQueue", m, detail); err != nil {
+			return nil, err
+		}
+		return count, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.(int), nil
+}
+
+// SetMaintenance puts the mutex into or takes it out of maintenance mode.
+// During maintenance, new acquisitions wait in the queue while the current
+// owner may finish its work. When it releases the mutex, the mutex is left
+// vacant. This allows operators to drain activity before maintenance. When
+// maintenance ends, a vacant mutex is handed to the queue. If the mutex
+// doesn't exist, [[ErrNotFound]] is returned.
+func (x *AdminClient) SetMaintenance(db fdb.Transactor, name string, on bool) (err error) {
+	defer wrapErr(&err)
+
+	_, err = x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+		m, err := x.open(tr, name, p)
+		if err != nil {
+			return nil, err
+		}
+		return nil, x.setMaintenance(tr, m, on)
+	})
+	return err
+}
+
+// SetMaintenanceAll is like [[AdminClient.SetMaintenance]] but applies to
+// every mutex. Each mutex is updated in its own transaction. The paths of
+// the updated mutexes are returned.
+func (x *AdminClient) SetMaintenanceAll(db fdb.Transactor, on bool) (_ [][]string, err error) {
+	defer wrapErr(&err)
+
+	names, err := x.parent.List(db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subdirectories: %w", err)
+	}
+
+	var updated [][]string
+	for _, name := range names {
+		path, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+			m, err := x.open(tr, name, p)
+			if errors.Is(err, ErrNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return lockPath(m), x.setMaintenance(tr, m, on)
+		})
+		if err != nil {
+			return updated, fmt.Errorf("failed to update %s: %w", name, err)
+		}
+		if path != nil {
+			updated = append(updated, path.([]string))
+		}
+	}
+	return updated, nil
+}
+
+// Maintenance returns true if the mutex is in maintenance mode. See
+// [[AdminClient.SetMaintenance]]. If the mutex doesn't exist,
+// [[ErrNotFound]] is returned.
+func (x *AdminClient) Maintenance(db fdb.Transactor, name string) (_ bool, err error) {
+	defer wrapErr(&err)
+
+	on, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		m, err := x.open(tr, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		return m.getMaintenance(tr)
+	})
+	if err != nil {
+		return false, err
+	}
+	return on.(bool), nil
+}
+
+// setMaintenance updates the maintenance mode of the mutex and audits it.
+func (x *AdminClient) setMaintenance(tr fdb.Transaction, m kv, on bool) error {
+	if err := m.setMaintenance(tr, on); err != nil {
+		return fmt.Errorf("failed to set maintenance: %w", err)
+	}
+	detail := "ended maintenance"
+	if on {
+		detail = "started maintenance"
+	}
+	return x.audit(tr, "SetMaintenance", m, detail)
+}
+
+// List is like [[List]]. Listing isn't audited.
+func (x *AdminClient) List(db fdb.Transactor, filter map[string]string) ([]MutexInfo, error) {
+	return List(db, x.parent, filter)
+}
+
+// Check inspects every mutex and returns the inconsistencies found. Each
+// mutex is read in its own transaction. Checking isn't audited.
+func (x *AdminClient) Check(db fdb.Transactor) ([]Inconsistency, error) {
+	return x.inspectAll(db, "Check", false)
+}
+
+// Repair is like [[AdminClient.Check]] but also fixes the inconsistencies
+// found. Extra queue entries and priorities are removed, reservations of
+// held mutexes are cleared, and a mutex with multiple owners is handed to
+// the next client in its queue. Each repaired mutex is audited.
+func (x *AdminClient) Repair(db fdb.Transactor) ([]Inconsistency, error) {
+	return x.inspectAll(db, "Repair", true)
+}
+
+// GC is like [[ExpireIdle]]. Each deleted mutex is audited.
+func (x *AdminClient) GC(db fdb.Transactor, policy IdlePolicy) (_ [][]string, err error) {
+	defer wrapErr(&err)
+
+	names, err := x.parent.List(db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subdirectories: %w", err)
+	}
+
+	var deleted [][]string
+	for _, name := range names {
+		path, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+			if err := p.watchChild(tr, x.parent, name); err != nil {
+				return nil, err
+			}
+			path, err := expireIdle(tr, x.parent, name, policy)
+			if err != nil || path == nil {
+				return path, err
+			}
+			if err := x.logAudit(tr, "GC", path.([]string), "deleted idle mutex"); err != nil {
+				return nil, err
+			}
+			return path, nil
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to expire %s: %w", name, err)
+		}
+		if path != nil {
+			deleted = append(deleted, path.([]string))
+		}
+	}
+	return deleted, nil
+}
+
+// AuditLog returns the retained audit entries, oldest first.
+// Only the latest [[maxAuditEntries]] are retained.
+func (x *AdminClient) AuditLog(db fdb.Transactor) (_ []AuditEntry, err error) {
+	defer wrapErr(&err)
+
+	rngAudit, err := x.packAuditRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack audit range: %w", err)
+	}
+
+	entries, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var entries []AuditEntry
+		iter := tr.GetRange(rngAudit, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			entry, err := x.unpackAuditEntry(kv)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries.([]AuditEntry), nil
+}
+
+// inspectAll runs [[inspect]] on each mutex. If 'fix' is
+// true, the inconsistencies are repaired and audited.
+func (x *AdminClient) inspectAll(db fdb.Transactor, action string, fix bool) (_ []Inconsistency, err error) {
+	defer wrapErr(&err)
+
+	names, err := x.parent.List(db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subdirectories: %w", err)
+	}
+
+	var found []Inconsistency
+	for _, name := range names {
+		list, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+			m, err := x.open(tr, name, p)
+			if errors.Is(err, ErrNotFound) {
+				return []Inconsistency(nil), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			list, err := inspect(tr, m, fix)
+			if err != nil {
+				return nil, err
+			}
+			if fix && len(list) > 0 {
+				if err := x.audit(tr, action, m, describeProblems(list)); err != nil {
+					return nil, err
+				}
+			}
+			return list, nil
+		})
+		if err != nil {
+			return found, fmt.Errorf("failed to inspect %s: %w", name, err)
+		}
+		found = append(found, list.([]Inconsistency)...)
+	}
+	return found, nil
+}
+
+// inspect looks for inconsistencies in the given mutex.
+// If 'fix' is true, the inconsistencies are repaired.
+func inspect(tr fdb.Transaction, x kv, fix bool) ([]Inconsistency, error) {
+	rngOwner, err := x.packOwnerRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack owner range: %w", err)
+	}
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack queue range: %w", err)
+	}
+	rngPriority, err := x.packPriorityRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack priority range: %w", err)
+	}
+
+	path := lockPath(x)
+	var found []Inconsistency
+	report := func(p Problem, client string) {
+		found = append(found, Inconsistency{Path: path, Problem: p, Client: client})
+	}
+
+	var owners []string
+	iter := tr.GetRange(rngOwner, fdb.RangeOptions{}).Iterator()
+	for iter.Advance() {
+		name, err := x.unpackOwnerKey(iter.MustGet().Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack owner key: %w", err)
+		}
+		owners = append(owners, name)
+	}
+	var owner string
+	if len(owners) == 1 {
+		owner = owners[0]
+	}
+	if len(owners) > 1 {
+		report(ProblemMultipleOwners, "")
+	}
+
+	queued := make(map[string]bool)
+	var queueFixed bool
+	iter = tr.GetRange(rngQueue, fdb.RangeOptions{}).Iterator()
+	for iter.Advance() {
+		kv := iter.MustGet()
+		name, _ := x.unpackQueueValue(kv.Value)
+		switch {
+		case owner != "" && name == owner:
+			report(ProblemOwnerQueued, name)
+		case queued[name]:
+			report(ProblemDuplicateQueueEntry, name)
+		default:
+			queued[name] = true
+			continue
+		}
+		if fix {
+			tr.Clear(kv.Key)
+			queueFixed = true
+		}
+	}
+	if queueFixed {
+		x.bumpQueueVersion(tr)
+		if err := x.indexQueue(tr); err != nil {
+			return nil, fmt.Errorf("failed to index queue: %w", err)
+		}
+	}
+
+	iter = tr.GetRange(rngPriority, fdb.RangeOptions{}).Iterator()
+	for iter.Advance() {
+		kv := iter.MustGet()
+		name, err := x.unpackPriorityKey(kv.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack priority key: %w", err)
+		}
+		if queued[name] {
+			continue
+		}
+		report(ProblemOrphanPriority, name)
+		if fix {
+			tr.Clear(kv.Key)
+		}
+	}
+
+	if owner != "" {
+		sticky, ok, err := x.getSticky(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sticky: %w", err)
+		}
+		if ok {
+			report(ProblemStaleReservation, sticky.name)
+			if fix {
+				if err := x.clearSticky(tr); err != nil {
+					return nil, fmt.Errorf("failed to clear sticky: %w", err)
+				}
+			}
+		}
+	}
+
+	// None of the owners can be trusted, so the mutex is handed
+	// to the queue and a new fencing epoch is started.
+	if fix && len(owners) > 1 {
+		next, err := x.dequeue(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dequeue: %w", err)
+		}
+		if err := x.setOwner(tr, next); err != nil {
+			return nil, fmt.Errorf("failed to set owner: %w", err)
+		}
+		if next == "" {
+			tr.Add(x.packEpochKey(), packIncrement())
+		}
+	}
+	return found, nil
+}
+
+// describeProblems summarizes the inconsistencies for the audit log.
+func describeProblems(list []Inconsistency) string {
+	parts := make([]string, len(list))
+	for i, inc := range list {
+		parts[i] = inc.Problem.String()
+		if inc.Client != "" {
+			parts[i] += " (" + inc.Client + ")"
+		}
+	}
+	return "repaired " + strings.Join(parts, ", ")
+}
+
+// open returns the mutex stored in the subdirectory with the provided
+// name. If the subdirectory doesn't contain a mutex, [[ErrNotFound]]
+// is returned. The mutex is added to the plan, if any.
+func (x *AdminClient) open(tr fdb.Transaction, name string, p *plan) (kv, error) {
+	return x.openPath(tr, []string{name}, p)
+}
+
+// openPath is like [[AdminClient.open]] but accepts the path
+// of a subdirectory at any depth beneath the parent.
+func (x *AdminClient) openPath(tr fdb.Transaction, path []string, p *plan) (kv, error) {
+	ok, err := x.parent.Exists(tr, path)
+	if err != nil {
+		return kv{}, fmt.Errorf("failed to check for subdirectory: %w", err)
+	}
+	if !ok {
+		return kv{}, ErrNotFound
+	}
+	dir, err := x.parent.Open(tr, path, nil)
+	if err != nil {
+		return kv{}, fmt.Errorf("failed to open subdirectory: %w", err)
+	}
+	if err := p.watch(tr, dir); err != nil {
+		return kv{}, err
+	}
+
+	m := kv{Subspace: dir}
+	exists, err := m.exists(tr)
+	if err != nil {
+		return kv{}, fmt.Errorf("failed to check for mutex: %w", err)
+	}
+	if !exists {
+		return kv{}, ErrNotFound
+	}
+	if err := m.checkSchema(tr); err != nil {
+		return kv{}, err
+	}
+	return m, nil
+}
+
+// audit records an action performed on the given mutex.
+func (x *AdminClient) audit(tr fdb.Transaction, action string, m kv, detail string) error {
+	return x.logAudit(tr, action, lockPath(m), detail)
+}
+
+// logAudit appends an entry to the audit log. Only
+// the latest [[maxAuditEntries]] are retained.
+func (x *AdminClient) logAudit(tr fdb.Transaction, action string, path []string, detail string) error {
+	rngAudit, err := x.packAuditRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack audit range: %w", err)
+	}
+	key, err := x.packAuditKey()
+	if err != nil {
+		return fmt.Errorf("failed to pack audit key: %w", err)
+	}
+	tr.SetVersionstampedKey(key, x.packAuditValue(action, path, detail, time.Now()))
+	trimLog(tr, rngAudit, maxAuditEntries)
+	return nil
+}
+
+// lockPath returns the directory path of the mutex,
+// or nil if it isn't stored in a directory.
+func lockPath(x kv) []string {
+	if dir, ok := x.Subspace.(directory.DirectorySubspace); ok {
+		return dir.GetPath()
+	}
+	return nil
+}
+
+func (x *AdminClient) packAuditRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.parent.Pack(tuple.Tuple{"audit"}))
+}
+
+func (x *AdminClient) packAuditKey() (fdb.Key, error) {
+	return x.parent.PackWithVersionstamp(tuple.Tuple{"audit", tuple.IncompleteVersionstamp(0)})
+}
+
+func (x *AdminClient) packAuditValue(action string, path []string, detail string, t time.Time) []byte {
+	pathTup := make(tuple.Tuple, len(path))
+	for i, p := range path {
+		pathTup[i] = p
+	}
+	return tuple.Tuple{x.operator, action, pathTup, detail, t.UnixNano()}.Pack()
+}
+
+func (x *AdminClient) unpackAuditEntry(kv fdb.KeyValue) (AuditEntry, error) {
+	keyTup, err := x.parent.Unpack(kv.Key)
+	if err != nil {
+		return AuditEntry{}, fmt.Errorf("failed to unpack audit key: %w", err)
+	}
+	if len(keyTup) != 2 {
+		return AuditEntry{}, fmt.Errorf("audit key tuple is incorrect length %d", len(keyTup))
+	}
+	vstamp, ok := keyTup[1].(tuple.Versionstamp)
+	if !ok {
+		return AuditEntry{}, fmt.Errorf("tuple element 1 is not a versionstamp")
+	}
+
+	tup, err := tuple.Unpack(kv.Value)
+	if err != nil {
+		return AuditEntry{}, fmt.Errorf("failed to unpack audit value: %w", err)
+	}
+	if len(tup) < 5 {
+		return AuditEntry{}, fmt.Errorf("audit value tuple is incorrect length %d", len(tup))
+	}
+	operator, ok := tup[0].(string)
+	if !ok {
+		return AuditEntry{}, fmt.Errorf("tuple element 0 is not a string")
+	}
+	action, ok := tup[1].(string)
+	if !ok {
+		return AuditEntry{}, fmt.Errorf("tuple element 1 is not a string")
+	}
+	pathTup, ok := tup[2].(tuple.Tuple)
+	if !ok {
+		return AuditEntry{}, fmt.Errorf("tuple element 2 is not a tuple")
+	}
+	path := make([]string, len(pathTup))
+	for i, p := range pathTup {
+		if path[i], ok = p.(string); !ok {
+			return AuditEntry{}, fmt.Errorf("path element %d is not a string", i)
+		}
+	}
+	detail, ok := tup[3].(string)
+	if !ok {
+		return AuditEntry{}, fmt.Errorf("tuple element 3 is not a string")
+	}
+	nanos, ok := tup[4].(int64)
+	if !ok {
+		return AuditEntry{}, fmt.Errorf("tuple element 4 is not an int")
+	}
+
+	return AuditEntry{
+		Operator: operator,
+		Action:   action,
+		Path:     path,
+		Detail:   detail,
+		Time:     time.Unix(0, nanos),
+		Version:  vstamp,
+	}, nil
+}
@@ -0,0 +1,240 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminClient(t *testing.T) {
+	tests := map[string]testFn{
+		"force release": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			x1, err := NewMutex(db, dir, "client1")
+			require.NoError(t, err)
+			require.NoError(t, x1.Acquire(context.Background(), db))
+			require.NoError(t, x1.enqueue(db, "client2", 0))
+			require.NoError(t, x1.enqueue(db, "client3", 0))
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+
+			evicted, err := admin.ForceRelease(db, "a")
+			require.NoError(t, err)
+			require.Equal(t, "client1", evicted)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner.name)
+
+			count, err := admin.PurgeQueue(db, "a")
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+
+			queue, err := x1.getQueue(db)
+			require.NoError(t, err)
+			require.Empty(t, queue)
+
+			_, err = admin.ForceRelease(db, "missing")
+			require.ErrorIs(t, err, ErrNotFound)
+
+			log, err := admin.AuditLog(db)
+			require.NoError(t, err)
+			require.Len(t, log, 2)
+			require.Equal(t, "oncall", log[0].Operator)
+			require.Equal(t, "ForceRelease", log[0].Action)
+			require.Equal(t, dir.GetPath(), log[0].Path)
+			require.Equal(t, "evicted client1", log[0].Detail)
+			require.Equal(t, "PurgeQueue", log[1].Action)
+		},
+		"maintenance": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+
+			x1, err := NewMutex(db, dirA, "client1")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, dirA, "client2")
+			require.NoError(t, err)
+			y, err := NewMutex(db, dirB, "client3")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+
+			updated, err := admin.SetMaintenanceAll(db, true)
+			require.NoError(t, err)
+			require.Len(t, updated, 2)
+
+			on, err := admin.Maintenance(db, "a")
+			require.NoError(t, err)
+			require.True(t, on)
+
+			// New acquisitions wait in the queue.
+			acquired, err = y.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// The owner may finish, leaving the mutex vacant.
+			require.NoError(t, x1.Release(db))
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- x2.Acquire(ctx, db) }()
+
+			err = admin.SetMaintenance(db, "a", false)
+			require.NoError(t, err)
+			require.NoError(t, <-done)
+
+			on, err = admin.Maintenance(db, "b")
+			require.NoError(t, err)
+			require.True(t, on)
+			err = admin.SetMaintenance(db, "b", false)
+			require.NoError(t, err)
+
+			owner, err = y.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client3", owner.name)
+
+			log, err := admin.AuditLog(db)
+			require.NoError(t, err)
+			require.Len(t, log, 4)
+			require.Equal(t, "started maintenance", log[0].Detail)
+			require.Equal(t, "ended maintenance", log[3].Detail)
+		},
+		"dry run": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			x1, err := NewMutex(db, dir, "client1")
+			require.NoError(t, err)
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+			dry := admin.DryRun()
+
+			evicted, err := dry.ForceRelease(db, "a")
+			require.NoError(t, err)
+			require.Equal(t, "client1", evicted)
+
+			// The owner key is replaced with a vacant one.
+			var cleared, vacated bool
+			for _, c := range dry.Changes() {
+				switch c.Key.String() {
+				case x1.packOwnerKey("client1").String():
+					cleared = c.After == nil
+				case x1.packOwnerKey("").String():
+					vacated = c.Before == nil
+				}
+			}
+			require.True(t, cleared)
+			require.True(t, vacated)
+
+			// Nothing was committed.
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+
+			log, err := admin.AuditLog(db)
+			require.NoError(t, err)
+			require.Empty(t, log)
+			require.Empty(t, admin.Changes())
+		},
+		"repair": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			x1, err := NewMutex(db, dir, "client1")
+			require.NoError(t, err)
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+
+			found, err := admin.Check(db)
+			require.NoError(t, err)
+			require.Empty(t, found)
+
+			// Corrupt the mutex's state. Each queue entry
+			// is written in its own transaction so the
+			// versionstamps differ.
+			for _, name := range []string{"client1", "client2", "client2"} {
+				_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+					key, err := x1.packQueueKey()
+					if err != nil {
+						return nil, err
+					}
+					tr.SetVersionstampedKey(key, x1.packQueueValue(name, time.Now()))
+					return nil, nil
+				})
+				require.NoError(t, err)
+			}
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Set(x1.packPriorityKey("client3"), x1.packPriorityValue(1))
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			found, err = admin.Check(db)
+			require.NoError(t, err)
+			require.Equal(t, []Inconsistency{
+				{Path: dir.GetPath(), Problem: ProblemOwnerQueued, Client: "client1"},
+				{Path: dir.GetPath(), Problem: ProblemDuplicateQueueEntry, Client: "client2"},
+				{Path: dir.GetPath(), Problem: ProblemOrphanPriority, Client: "client3"},
+			}, found)
+
+			repaired, err := admin.Repair(db)
+			require.NoError(t, err)
+			require.Equal(t, found, repaired)
+
+			found, err = admin.Check(db)
+			require.NoError(t, err)
+			require.Empty(t, found)
+
+			queue, err := x1.getQueue(db)
+			require.NoError(t, err)
+			require.Len(t, queue, 1)
+			require.Equal(t, "client2", queue[0].name)
+
+			log, err := admin.AuditLog(db)
+			require.NoError(t, err)
+			require.Len(t, log, 1)
+			require.Equal(t, "Repair", log[0].Action)
+		},
+	}
+
+	runTests(t, tests)
+}
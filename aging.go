@@ -0,0 +1,46 @@
+package mutex
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// SetPriorityAging boosts the priority of queued clients by one for each
+// 'interval' they've waited, so a low priority client waits at most
+// 'interval' times the priority gap before it's favored over new, urgent
+// work. Urgent work is still dequeued first while the gap lasts. Aging is
+// stored with the mutex and applied by every client, so clients needn't
+// be configured. A zero interval disables aging. See [[WithPriority]].
+// Waits are measured against the clocks of the releasing clients.
+func (x *Mutex) SetPriorityAging(db fdb.Transactor, interval time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	if interval < 0 {
+		return fmt.Errorf("aging interval must not be negative")
+	}
+	return x.setAging(x.withBreaker(db), interval)
+}
+
+// PriorityAging returns the interval set by [[Mutex.SetPriorityAging]].
+// If aging is disabled, zero is returned.
+func (x *Mutex) PriorityAging(db fdb.Transactor) (_ time.Duration, err error) {
+	defer wrapErr(&err)
+	return x.getAging(x.withBreaker(db))
+}
+
+// boostPriority returns the effective priority of a client queued at
+// 'enqueued'. Clients queued by versions of this package which didn't
+// record the enqueue time aren't boosted.
+func boostPriority(priority int64, enqueued time.Time, aging time.Duration) int64 {
+	if aging <= 0 || enqueued.IsZero() {
+		return priority
+	}
+	boost := int64(max(time.Since(enqueued), 0) / aging)
+	if priority > math.MaxInt64-boost {
+		return math.MaxInt64
+	}
+	return priority + boost
+}
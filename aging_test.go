@@ -0,0 +1,83 @@
+package mutex
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityAging(t *testing.T) {
+	setup := func(t *testing.T, db fdb.Database, root subspace.Subspace) *Mutex {
+		owner, err := NewMutex(db, root, "owner")
+		require.NoError(t, err)
+		low, err := NewMutex(db, root, "low")
+		require.NoError(t, err)
+		high, err := NewMutex(db, root, "high", WithPriority(1))
+		require.NoError(t, err)
+
+		acquired, err := owner.TryAcquire(db)
+		require.NoError(t, err)
+		require.True(t, acquired)
+
+		acquired, err = low.TryAcquire(db)
+		require.NoError(t, err)
+		require.False(t, acquired)
+
+		time.Sleep(150 * time.Millisecond)
+
+		acquired, err = high.TryAcquire(db)
+		require.NoError(t, err)
+		require.False(t, acquired)
+		return owner
+	}
+
+	tests := map[string]testFn{
+		"boosted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner := setup(t, db, root)
+
+			err := owner.SetPriorityAging(db, 50*time.Millisecond)
+			require.NoError(t, err)
+
+			aging, err := owner.PriorityAging(db)
+			require.NoError(t, err)
+			require.Equal(t, 50*time.Millisecond, aging)
+
+			err = owner.Release(db)
+			require.NoError(t, err)
+
+			next, err := owner.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "low", next.name)
+		},
+		"disabled": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner := setup(t, db, root)
+
+			err := owner.SetPriorityAging(db, time.Minute)
+			require.NoError(t, err)
+			err = owner.SetPriorityAging(db, 0)
+			require.NoError(t, err)
+
+			err = owner.Release(db)
+			require.NoError(t, err)
+
+			next, err := owner.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "high", next.name)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestBoostPriority(t *testing.T) {
+	now := time.Now()
+	require.Equal(t, int64(3), boostPriority(3, now.Add(-time.Hour), 0))
+	require.Equal(t, int64(3), boostPriority(3, time.Time{}, time.Minute))
+	require.Equal(t, int64(5), boostPriority(3, now.Add(-150*time.Second), time.Minute))
+	require.Equal(t, int64(3), boostPriority(3, now.Add(time.Hour), time.Minute))
+	require.Equal(t, int64(math.MaxInt64), boostPriority(math.MaxInt64-1, now.Add(-time.Hour), time.Second))
+}
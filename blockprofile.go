@@ -0,0 +1,315 @@
+package mutex
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// maxStackDepth limits the number of frames
+// recorded for each wait. See [[callers]].
+const maxStackDepth = 32
+
+// WriteBlockProfile writes the waits recorded by every client reporting to
+// the profiler as a gzipped pprof profile, like the block profile of the Go
+// runtime, so distributed lock contention can be analyzed with 'go tool
+// pprof'. Each sample counts the waits of a client on a mutex at a single
+// call stack, and the total time spent waiting. Samples are labeled with the
+// mutex & the client. Stacks are captured by the process which waited, so
+// the profile spans the whole fleet, but each stack only contains the frames
+// of one process.
+func (p *ContentionProfiler) WriteBlockProfile(w io.Writer, db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	blocks, err := p.getBlocks(db)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(encodeBlockProfile(blocks, time.Now())); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+// frame is a symbolized call site. Frames are
+// stored symbolized so any process may read them.
+type frame struct {
+	function string
+	file     string
+	line     int64
+}
+
+// callers returns the stack of the calling goroutine,
+// skipping the caller & the 'skip' frames above it.
+func callers(skip int) []frame {
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(skip+2, pcs)]
+
+	var stack []frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		stack = append(stack, frame{function: f.Function, file: f.File, line: int64(f.Line)})
+		if !more {
+			return stack
+		}
+	}
+}
+
+// stackID identifies a stack within the waits of a client on a mutex.
+func stackID(stack []frame) int64 {
+	h := fnv.New64a()
+	for _, f := range stack {
+		_, _ = fmt.Fprintf(h, "%s\x00%s\x00%d\x00", f.function, f.file, f.line)
+	}
+	return int64(h.Sum64())
+}
+
+func packStack(stack []frame) []byte {
+	tup := make(tuple.Tuple, len(stack))
+	for i, f := range stack {
+		tup[i] = tuple.Tuple{f.function, f.file, f.line}
+	}
+	return tup.Pack()
+}
+
+func unpackStack(val []byte) ([]frame, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack: %w", err)
+	}
+
+	stack := make([]frame, len(tup))
+	for i, elem := range tup {
+		f, ok := elem.(tuple.Tuple)
+		if !ok || len(f) != 3 {
+			return nil, fmt.Errorf("tuple element %d is not a frame", i)
+		}
+		if stack[i].function, ok = f[0].(string); !ok {
+			return nil, fmt.Errorf("frame %d function is not a string", i)
+		}
+		if stack[i].file, ok = f[1].(string); !ok {
+			return nil, fmt.Errorf("frame %d file is not a string", i)
+		}
+		if stack[i].line, ok = f[2].(int64); !ok {
+			return nil, fmt.Errorf("frame %d line is not an int", i)
+		}
+	}
+	return stack, nil
+}
+
+// blockKV is the aggregate of the waits of
+// a client on a mutex at a single stack.
+type blockKV struct {
+	mutex  string
+	client string
+	stack  []frame
+	count  int64
+	delay  time.Duration
+}
+
+// getBlocks reads the waits recorded by [[ContentionProfiler.wait]].
+func (p *ContentionProfiler) getBlocks(db fdb.Transactor) ([]blockKV, error) {
+	rngProfile, err := p.packProfileRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack profile range: %w", err)
+	}
+
+	blocks, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var blocks []blockKV
+		iter := tr.GetRange(rngProfile, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			tup, err := p.Unpack(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack profile key: %w", err)
+			}
+			if len(tup) < 5 || tup[3] != "block" {
+				continue
+			}
+			mutex, ok := tup[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("tuple element 1 is not a string")
+			}
+			client, ok := tup[2].(string)
+			if !ok {
+				return nil, fmt.Errorf("tuple element 2 is not a string")
+			}
+
+			// The stack key sorts before its stats,
+			// so it always begins a new block.
+			if len(tup) == 5 {
+				stack, err := unpackStack(kv.Value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to unpack stack: %w", err)
+				}
+				blocks = append(blocks, blockKV{mutex: mutex, client: client, stack: stack})
+				continue
+			}
+			if len(blocks) == 0 {
+				continue
+			}
+			b := &blocks[len(blocks)-1]
+
+			switch tup[5] {
+			case "count":
+				b.count = unpackCounter(kv.Value)
+			case "delay":
+				b.delay = time.Duration(unpackCounter(kv.Value))
+			}
+		}
+		return blocks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks.([]blockKV), nil
+}
+
+// encodeBlockProfile encodes the blocks as an uncompressed pprof profile.
+// See https://github.com/google/pprof/blob/main/proto/profile.proto.
+func encodeBlockProfile(blocks []blockKV, now time.Time) []byte {
+	var (
+		b         protoBuffer
+		strs      = map[string]int64{"": 0}
+		strTable  = []string{""}
+		functions = map[[2]string]uint64{}
+		locations = map[frame]uint64{}
+	)
+	str := func(s string) int64 {
+		i, ok := strs[s]
+		if !ok {
+			i = int64(len(strTable))
+			strs[s] = i
+			strTable = append(strTable, s)
+		}
+		return i
+	}
+	valueType := func(typ, unit string) []byte {
+		var vt protoBuffer
+		vt.int64(1, str(typ))
+		vt.int64(2, str(unit))
+		return vt.data
+	}
+
+	// Field 1: sample_type.
+	b.bytes(1, valueType("contentions", "count"))
+	b.bytes(1, valueType("delay", "nanoseconds"))
+
+	// Field 2: sample.
+	for _, blk := range blocks {
+		var ids []uint64
+		for _, f := range blk.stack {
+			id, ok := locations[f]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locations[f] = id
+			}
+			ids = append(ids, id)
+		}
+
+		var s protoBuffer
+		s.packed(1, ids)
+		s.packed(2, []uint64{uint64(blk.count), uint64(blk.delay)})
+		for _, label := range [][2]string{{"mutex", blk.mutex}, {"client", blk.client}} {
+			var l protoBuffer
+			l.int64(1, str(label[0]))
+			l.int64(2, str(label[1]))
+			s.bytes(3, l.data)
+		}
+		b.bytes(2, s.data)
+	}
+
+	// Field 4: location. Each frame is its own location
+	// because addresses aren't comparable between processes.
+	locs := make([]frame, len(locations))
+	for f, id := range locations {
+		locs[id-1] = f
+	}
+	for i, f := range locs {
+		key := [2]string{f.function, f.file}
+		fnID, ok := functions[key]
+		if !ok {
+			fnID = uint64(len(functions) + 1)
+			functions[key] = fnID
+		}
+
+		var line protoBuffer
+		line.uint64(1, fnID)
+		line.int64(2, f.line)
+
+		var loc protoBuffer
+		loc.uint64(1, uint64(i+1))
+		loc.bytes(4, line.data)
+		b.bytes(4, loc.data)
+	}
+
+	// Field 5: function.
+	fns := make([][2]string, len(functions))
+	for key, id := range functions {
+		fns[id-1] = key
+	}
+	for i, key := range fns {
+		var fn protoBuffer
+		fn.uint64(1, uint64(i+1))
+		fn.int64(2, str(key[0]))
+		fn.int64(3, str(key[0]))
+		fn.int64(4, str(key[1]))
+		b.bytes(5, fn.data)
+	}
+
+	// Fields 9, 11 & 12: time_nanos, period_type & period.
+	// The string table is written last as it's built above.
+	b.int64(9, now.UnixNano())
+	b.bytes(11, valueType("contentions", "count"))
+	b.int64(12, 1)
+
+	// Field 6: string_table.
+	for _, s := range strTable {
+		b.bytes(6, []byte(s))
+	}
+	return b.data
+}
+
+// protoBuffer encodes the protobuf wire format, which
+// is just enough to write a pprof profile.
+type protoBuffer struct{ data []byte }
+
+func (b *protoBuffer) key(field int, wireType uint64) {
+	b.data = binary.AppendUvarint(b.data, uint64(field)<<3|wireType)
+}
+
+func (b *protoBuffer) uint64(field int, v uint64) {
+	b.key(field, 0)
+	b.data = binary.AppendUvarint(b.data, v)
+}
+
+func (b *protoBuffer) int64(field int, v int64) {
+	b.uint64(field, uint64(v))
+}
+
+func (b *protoBuffer) bytes(field int, v []byte) {
+	b.key(field, 2)
+	b.data = binary.AppendUvarint(b.data, uint64(len(v)))
+	b.data = append(b.data, v...)
+}
+
+func (b *protoBuffer) packed(field int, vs []uint64) {
+	var p []byte
+	for _, v := range vs {
+		p = binary.AppendUvarint(p, v)
+	}
+	b.bytes(field, p)
+}
@@ -0,0 +1,86 @@
+package mutex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStack(t *testing.T) {
+	stack := callers(0)
+	require.NotEmpty(t, stack)
+	require.True(t, strings.HasSuffix(stack[0].function, "TestStack"))
+
+	unpacked, err := unpackStack(packStack(stack))
+	require.NoError(t, err)
+	require.Equal(t, stack, unpacked)
+	require.Equal(t, stackID(stack), stackID(unpacked))
+}
+
+func TestBlockProfile(t *testing.T) {
+	tests := map[string]testFn{
+		"write": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			dirProfile, err := parent.CreateOrOpen(db, []string{"profile"}, nil)
+			require.NoError(t, err)
+
+			profiler := NewContentionProfiler(dirProfile)
+			opt := WithContentionProfiler(profiler)
+
+			x1, err := NewMutex(db, dirA, "client1", opt)
+			require.NoError(t, err)
+			x2, err := NewMutex(db, dirA, "client2", opt)
+			require.NoError(t, err)
+
+			require.NoError(t, x1.Acquire(context.Background(), db))
+
+			done := make(chan error, 1)
+			go func() { done <- x2.Acquire(context.Background(), db) }()
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, x1.Release(db))
+			require.NoError(t, <-done)
+
+			blocks, err := profiler.getBlocks(db)
+			require.NoError(t, err)
+			require.Len(t, blocks, 2)
+
+			var b blockKV
+			for _, blk := range blocks {
+				if blk.client == "client2" {
+					b = blk
+				}
+			}
+			require.Equal(t, lockID(dirA), b.mutex)
+			require.Equal(t, int64(1), b.count)
+			require.GreaterOrEqual(t, b.delay, 100*time.Millisecond)
+			require.NotEmpty(t, b.stack)
+			require.Contains(t, b.stack[0].function, "(*Mutex).Acquire")
+
+			var buf bytes.Buffer
+			require.NoError(t, profiler.WriteBlockProfile(&buf, db))
+
+			zr, err := gzip.NewReader(&buf)
+			require.NoError(t, err)
+			raw, err := io.ReadAll(zr)
+			require.NoError(t, err)
+			require.Contains(t, string(raw), "client2")
+			require.Contains(t, string(raw), "contentions")
+			require.Contains(t, string(raw), b.stack[0].function)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,192 @@
+package mutex
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrCircuitOpen is returned by mutex operations while the circuit
+// breaker is open. See [[WithCircuitBreaker]].
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// WithCircuitBreaker protects the mutex from FDB outages. Every transaction
+// performed by the mutex is given the provided timeout. After 'threshold'
+// consecutive transactions fail, the breaker opens and mutex operations
+// return [[ErrCircuitOpen]] without contacting FDB. After 'cooldown' has
+// passed, a single trial transaction is allowed through while the others
+// keep failing fast. If the trial fails, the breaker reopens. Otherwise,
+// it closes.
+//
+// While the breaker is open, the heartbeat is degraded: heartbeats fail
+// fast until the cooldown passes. See [[Mutex.Degraded]].
+func WithCircuitBreaker(threshold int, cooldown, timeout time.Duration) Option {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return func(x *Mutex) {
+		x.breaker = &breaker{
+			threshold: threshold,
+			cooldown:  cooldown,
+			timeout:   timeout,
+		}
+	}
+}
+
+// breaker counts consecutive transaction failures and
+// decides when transactions should fail fast.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	timeout   time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+
+	// trial is true while the transaction admitted
+	// by the half-open breaker hasn't finished.
+	trial bool
+}
+
+// allow returns [[ErrCircuitOpen]] if the breaker is open. Once the
+// cooldown passes, a single transaction is admitted as a trial, for
+// which true is returned. Its result must be given to [[breaker.record]].
+func (b *breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+	if time.Since(b.openedAt) >= b.cooldown && !b.trial {
+		// Half-open: let a single transaction through.
+		// Its result decides if the breaker closes.
+		b.trial = true
+		return true, nil
+	}
+	return false, ErrCircuitOpen
+}
+
+// record updates the breaker with the result of a transaction. 'trial'
+// is true if the transaction was admitted as the trial of a half-open
+// breaker. Only FDB errors are counted as failures. Errors caused by
+// cancellation or by the mutex's own logic are ignored, though they end
+// the trial so another transaction may be admitted.
+func (b *breaker) record(trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+
+	var ferr fdb.Error
+	switch {
+	case err == nil:
+		b.failures = 0
+
+	case errors.As(err, &ferr) && classify(err) != ClassCancelled:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openedAt = time.Now()
+		}
+	}
+}
+
+// isOpen returns true if transactions are currently failing fast.
+// Unlike [[breaker.allow]], it never admits a trial.
+func (b *breaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false
+	}
+	return time.Since(b.openedAt) < b.cooldown || b.trial
+}
+
+// breakerTransactor wraps a transactor so every transaction
+// is subject to the breaker and the breaker's timeout.
+type breakerTransactor struct {
+	fdb.Transactor
+	b *breaker
+}
+
+func (t breakerTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	trial, err := t.b.allow()
+	if err != nil {
+		return nil, err
+	}
+	ret, err := t.Transactor.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := t.setTimeout(tr); err != nil {
+			return nil, err
+		}
+		return f(tr)
+	})
+	t.b.record(trial, err)
+	return ret, err
+}
+
+func (t breakerTransactor) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	trial, err := t.b.allow()
+	if err != nil {
+		return nil, err
+	}
+	ret, err := t.Transactor.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		if err := t.setTimeout(tr); err != nil {
+			return nil, err
+		}
+		return f(tr)
+	})
+	t.b.record(trial, err)
+	return ret, err
+}
+
+func (t breakerTransactor) setTimeout(tr fdb.ReadTransaction) error {
+	if t.b.timeout <= 0 {
+		return nil
+	}
+	return tr.Options().SetTimeout(t.b.timeout.Milliseconds())
+}
+
+// withBreaker wraps the transactor with the mutex's circuit breaker.
+// If the mutex doesn't have a breaker or the transactor is already
+// wrapped, the transactor is returned as is.
+func (x *Mutex) withBreaker(db fdb.Transactor) fdb.Transactor {
+	if x.breaker == nil {
+		return db
+	}
+	if _, ok := withoutDeadline(db).(breakerTransactor); ok {
+		return db
+	}
+	return breakerTransactor{Transactor: db, b: x.breaker}
+}
+
+// withoutBreaker removes the circuit breaker from the transactor. Watches
+// are cancelled when their transaction times out, so transactions which
+// create long-lived watches must not be subject to the breaker's timeout.
+func withoutBreaker(db fdb.Transactor) fdb.Transactor {
+	switch t := db.(type) {
+	case breakerTransactor:
+		return t.Transactor
+	case deadlineTransactor:
+		// The deadline still applies as the caller
+		// stops waiting on the watch by then anyway.
+		t.Transactor = withoutBreaker(t.Transactor)
+		return t
+	}
+	return db
+}
+
+// Degraded returns true if the mutex's circuit breaker is open, meaning
+// operations and heartbeats are currently failing fast. If the mutex
+// doesn't have a circuit breaker then this method always returns false.
+func (x *Mutex) Degraded() bool {
+	if x.breaker == nil {
+		return false
+	}
+	return x.breaker.isOpen()
+}
@@ -0,0 +1,99 @@
+package mutex
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	timeout := fdb.Error{Code: 1031}
+
+	allowed := func(b *breaker) error {
+		_, err := b.allow()
+		return err
+	}
+
+	t.Run("opens", func(t *testing.T) {
+		b := breaker{threshold: 2, cooldown: time.Hour}
+
+		b.record(false, timeout)
+		require.NoError(t, allowed(&b))
+
+		b.record(false, timeout)
+		require.ErrorIs(t, allowed(&b), ErrCircuitOpen)
+		require.True(t, b.isOpen())
+	})
+
+	t.Run("ignores non-fdb errors", func(t *testing.T) {
+		b := breaker{threshold: 1, cooldown: time.Hour}
+
+		b.record(false, errors.New("oops"))
+		require.NoError(t, allowed(&b))
+
+		b.record(false, fdb.Error{Code: 1101})
+		require.NoError(t, allowed(&b))
+	})
+
+	t.Run("success resets", func(t *testing.T) {
+		b := breaker{threshold: 2, cooldown: time.Hour}
+
+		b.record(false, timeout)
+		b.record(false, nil)
+		b.record(false, timeout)
+		require.NoError(t, allowed(&b))
+	})
+
+	t.Run("half-open", func(t *testing.T) {
+		b := breaker{threshold: 1, cooldown: 10 * time.Millisecond}
+
+		b.record(false, timeout)
+		require.ErrorIs(t, allowed(&b), ErrCircuitOpen)
+
+		time.Sleep(20 * time.Millisecond)
+		trial, err := b.allow()
+		require.NoError(t, err)
+		require.True(t, trial)
+
+		// A failed trial reopens the breaker.
+		b.record(trial, timeout)
+		require.ErrorIs(t, allowed(&b), ErrCircuitOpen)
+
+		time.Sleep(20 * time.Millisecond)
+		trial, err = b.allow()
+		require.NoError(t, err)
+		require.True(t, trial)
+
+		b.record(trial, nil)
+		trial, err = b.allow()
+		require.NoError(t, err)
+		require.False(t, trial)
+		require.False(t, b.isOpen())
+	})
+
+	t.Run("single trial", func(t *testing.T) {
+		b := breaker{threshold: 1, cooldown: 10 * time.Millisecond}
+
+		b.record(false, timeout)
+		time.Sleep(20 * time.Millisecond)
+		require.False(t, b.isOpen())
+
+		trial, err := b.allow()
+		require.NoError(t, err)
+		require.True(t, trial)
+
+		// Other transactions fail fast until the trial resolves.
+		require.ErrorIs(t, allowed(&b), ErrCircuitOpen)
+		require.True(t, b.isOpen())
+
+		// A trial ended by a non-fdb error
+		// lets another trial through.
+		b.record(trial, errors.New("oops"))
+		trial, err = b.allow()
+		require.NoError(t, err)
+		require.True(t, trial)
+	})
+}
@@ -0,0 +1,192 @@
+package mutex
+
+import "time"
+
+// Hold is a period during which a client held a mutex, as
+// reconstructed from records by [[CheckExclusion]].
+type Hold struct {
+	Mutex  string
+	Client string
+
+	// Epoch is the fencing epoch of the hold. It's
+	// zero if the epoch wasn't recorded. See [[Record]].
+	Epoch int64
+
+	// StartVersion & EndVersion bound the hold by database
+	// version. EndVersion is zero if the hold doesn't end
+	// within the records.
+	StartVersion int64
+	EndVersion   int64
+
+	// Start & End bound the hold by the clock of its client:
+	// from when the acquiring operation returned until the
+	// client called Release. This is when the client believed
+	// it held the mutex. End is zero if the client didn't call
+	// Release within the records.
+	Start time.Time
+	End   time.Time
+}
+
+// ViolationKind describes how mutual exclusion was violated.
+type ViolationKind int
+
+const (
+	// ViolationOverlap means a hold began, according to the
+	// database, before the previous hold ended.
+	ViolationOverlap ViolationKind = iota
+
+	// ViolationEpoch means two holds were granted
+	// the same fencing epoch.
+	ViolationEpoch
+
+	// ViolationClock means two clients believed they held
+	// the mutex at the same time, according to their clocks.
+	// This happens when a client keeps working after its hold
+	// expires, which fencing is meant to guard against.
+	ViolationClock
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case ViolationOverlap:
+		return "overlap"
+	case ViolationEpoch:
+		return "epoch"
+	case ViolationClock:
+		return "clock"
+	default:
+		return "unknown"
+	}
+}
+
+// Violation is a pair of holds which breach mutual exclusion.
+// The first hold began before the second.
+type Violation struct {
+	Kind   ViolationKind
+	First  Hold
+	Second Hold
+}
+
+// CheckExclusion verifies that the given records, which may come from many
+// clients and mutexes, describe an execution where every mutex was held by
+// at most one client at a time. It's meant to be run after incidents or
+// configuration changes. The holds of each mutex are reconstructed in the
+// order of [[Replay]] and each pair of holds is checked three ways: by the
+// database versions bounding the holds, by their fencing epochs, and by the
+// clocks of the clients. Clock spans are only compared if both are known and
+// overlaps shorter than 'skew' are ignored, as the clocks of the clients may
+// differ by that much.
+//
+// Records are only as complete as the clients which wrote them. If a hold is
+// ended by an operation which isn't recorded, such as an eviction, the hold
+// appears to overlap its successor unless both of their epochs are known.
+func CheckExclusion(records []Record, skew time.Duration) []Violation {
+	var (
+		order []string
+		holds = make(map[string][]*Hold)
+
+		// open holds haven't ended according to the database.
+		// believed holds haven't ended according to the client.
+		open     = make(map[[2]string]*Hold)
+		believed = make(map[[2]string]*Hold)
+	)
+	begin := func(mutex, client string, rec Record) {
+		h := &Hold{Mutex: mutex, Client: client, StartVersion: rec.Version, Start: rec.End}
+		if client == rec.Client {
+			h.Epoch = rec.Epoch
+		}
+		if _, ok := holds[mutex]; !ok {
+			order = append(order, mutex)
+		}
+		holds[mutex] = append(holds[mutex], h)
+		open[[2]string{mutex, client}] = h
+		believed[[2]string{mutex, client}] = h
+	}
+	end := func(mutex, client string, rec Record, released bool) {
+		key := [2]string{mutex, client}
+		if h, ok := open[key]; ok {
+			h.EndVersion = rec.Version
+			delete(open, key)
+		}
+		if h, ok := believed[key]; ok && released {
+			h.End = rec.Start
+			delete(believed, key)
+		}
+	}
+
+	for _, rec := range sortRecords(records) {
+		if rec.Err != "" {
+			continue
+		}
+		switch {
+		case rec.Acquired:
+			// Acquiring a held mutex continues the hold.
+			if _, ok := open[[2]string{rec.Mutex, rec.Client}]; !ok {
+				begin(rec.Mutex, rec.Client, rec)
+			}
+
+		case rec.Op == "Release":
+			end(rec.Mutex, rec.Client, rec, true)
+
+		case rec.Op == "TransferTo" || rec.Op == "CommitRelease":
+			if _, ok := open[[2]string{rec.Mutex, rec.Client}]; ok && rec.Target != "" {
+				end(rec.Mutex, rec.Client, rec, true)
+				begin(rec.Mutex, rec.Target, rec)
+			}
+
+		case rec.Op == "Expire":
+			// The expired client isn't told, so it
+			// believes it holds the mutex until it
+			// calls Release.
+			end(rec.Mutex, rec.Target, rec, false)
+		}
+	}
+
+	var violations []Violation
+	for _, mutex := range order {
+		violations = append(violations, checkHolds(holds[mutex], skew)...)
+	}
+	return violations
+}
+
+// checkHolds compares each pair of the holds of a
+// single mutex, which are ordered by start version.
+func checkHolds(holds []*Hold, skew time.Duration) []Violation {
+	var violations []Violation
+	for i, first := range holds {
+		for _, second := range holds[i+1:] {
+			known := first.Epoch != 0 && second.Epoch != 0
+			if known && first.Epoch == second.Epoch {
+				violations = append(violations, Violation{Kind: ViolationEpoch, First: *first, Second: *second})
+			}
+
+			// Distinct epochs imply the first hold ended, even
+			// if the operation which ended it wasn't recorded.
+			overlap := first.EndVersion == 0 || second.StartVersion < first.EndVersion
+			if overlap && !(known && first.Epoch != second.Epoch) {
+				violations = append(violations, Violation{Kind: ViolationOverlap, First: *first, Second: *second})
+			}
+
+			if first.Client != second.Client && clockOverlap(*first, *second, skew) {
+				violations = append(violations, Violation{Kind: ViolationClock, First: *first, Second: *second})
+			}
+		}
+	}
+	return violations
+}
+
+// clockOverlap returns true if the client clock spans of
+// the holds are known & overlap by more than 'skew'.
+func clockOverlap(a, b Hold, skew time.Duration) bool {
+	if a.Start.IsZero() || a.End.IsZero() || b.Start.IsZero() || b.End.IsZero() {
+		return false
+	}
+	start, end := a.Start, a.End
+	if b.Start.After(start) {
+		start = b.Start
+	}
+	if b.End.Before(end) {
+		end = b.End
+	}
+	return end.Sub(start) > skew
+}
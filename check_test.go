@@ -0,0 +1,119 @@
+package mutex
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckExclusion(t *testing.T) {
+	tests := map[string]testFn{
+		"recorded": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			var buf bytes.Buffer
+			rec := NewJSONRecorder(&buf)
+
+			x1, err := NewMutex(db, root, "client1", WithRecorder(rec))
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2", WithRecorder(rec))
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{x1, x2, x1} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+				require.NoError(t, x.Release(db))
+			}
+			require.NoError(t, rec.Err())
+
+			records, err := ReadRecords(&buf)
+			require.NoError(t, err)
+			require.Len(t, records, 6)
+
+			// Each hold records its own epoch.
+			var epochs []int64
+			for _, r := range records {
+				if r.Acquired {
+					epochs = append(epochs, r.Epoch)
+				}
+			}
+			require.Len(t, epochs, 3)
+			require.Less(t, epochs[0], epochs[1])
+			require.Less(t, epochs[1], epochs[2])
+
+			require.Empty(t, CheckExclusion(records, 0))
+		},
+	}
+	runTests(t, tests)
+}
+
+func TestCheckExclusionViolations(t *testing.T) {
+	at := func(s int) time.Time {
+		return time.Unix(int64(s), 0)
+	}
+
+	t.Run("overlap", func(t *testing.T) {
+		violations := CheckExclusion([]Record{
+			{Mutex: "m", Client: "a", Op: "Acquire", Version: 1, End: at(1), Acquired: true},
+			{Mutex: "m", Client: "b", Op: "Acquire", Version: 3, End: at(3), Acquired: true},
+			{Mutex: "m", Client: "a", Op: "Release", Version: 4, Start: at(2)},
+			{Mutex: "m", Client: "b", Op: "Release", Version: 5, Start: at(5)},
+		}, 0)
+		require.Len(t, violations, 1)
+		require.Equal(t, ViolationOverlap, violations[0].Kind)
+		require.Equal(t, "a", violations[0].First.Client)
+		require.Equal(t, "b", violations[0].Second.Client)
+	})
+
+	t.Run("epoch", func(t *testing.T) {
+		violations := CheckExclusion([]Record{
+			{Mutex: "m", Client: "a", Op: "Acquire", Version: 1, End: at(1), Acquired: true, Epoch: 7},
+			{Mutex: "m", Client: "a", Op: "Release", Version: 2, Start: at(2)},
+			{Mutex: "m", Client: "b", Op: "Acquire", Version: 3, End: at(3), Acquired: true, Epoch: 7},
+		}, 0)
+		require.Len(t, violations, 1)
+		require.Equal(t, ViolationEpoch, violations[0].Kind)
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		records := []Record{
+			{Mutex: "m", Client: "a", Op: "Acquire", Version: 1, End: at(1), Acquired: true, Epoch: 1},
+			{Mutex: "m", Client: "b", Op: "Expire", Target: "a", Version: 2, Start: at(4), End: at(4)},
+			{Mutex: "m", Client: "b", Op: "Acquire", Version: 3, End: at(5), Acquired: true, Epoch: 2},
+			{Mutex: "m", Client: "b", Op: "Release", Version: 4, Start: at(8)},
+
+			// Client a kept working after its hold expired.
+			{Mutex: "m", Client: "a", Op: "Release", Version: 5, Start: at(7)},
+		}
+
+		violations := CheckExclusion(records, time.Second)
+		require.Len(t, violations, 1)
+		require.Equal(t, ViolationClock, violations[0].Kind)
+		require.Equal(t, int64(2), violations[0].First.EndVersion)
+
+		// Within the skew, the overlap is tolerated.
+		require.Empty(t, CheckExclusion(records, 2*time.Second))
+	})
+
+	t.Run("unrecorded end", func(t *testing.T) {
+		// Client a was evicted, which isn't recorded, but
+		// the epochs show its hold ended.
+		violations := CheckExclusion([]Record{
+			{Mutex: "m", Client: "a", Op: "Acquire", Version: 1, End: at(1), Acquired: true, Epoch: 1},
+			{Mutex: "m", Client: "b", Op: "Acquire", Version: 3, End: at(3), Acquired: true, Epoch: 2},
+		}, 0)
+		require.Empty(t, violations)
+	})
+
+	t.Run("mutexes", func(t *testing.T) {
+		violations := CheckExclusion([]Record{
+			{Mutex: "m1", Client: "a", Op: "Acquire", Version: 1, Acquired: true},
+			{Mutex: "m2", Client: "b", Op: "Acquire", Version: 2, Acquired: true},
+		}, 0)
+		require.Empty(t, violations)
+	})
+}
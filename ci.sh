@@ -12,4 +12,8 @@ cd "${0%/*}"
   go build ./...
   golangci-lint run ./...
   go test ./... -timeout 5s
+  cd grpcmutex
+  go build ./...
+  golangci-lint run ./...
+  go test ./... -timeout 5s
 '
@@ -0,0 +1,223 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// maxRequeue is the number of expired claims returned
+// to a [[Deque]] by a single transaction.
+const maxRequeue = 100
+
+// ErrClaimExpired is returned when a [[Claim]] is completed or extended
+// after its visibility timeout passed. The item may have been returned
+// to the deque and claimed by another consumer.
+var ErrClaimExpired = errors.New("claim expired")
+
+// Claim is an item taken from a [[Deque]] by [[Deque.Claim]]. Until the claim
+// is completed or its deadline passes, the item is invisible to the other
+// consumers. A claim isn't safe for concurrent use.
+type Claim struct {
+	// Item is the claimed item.
+	Item []byte
+
+	// Deadline is when the claim expires and the item is
+	// returned to the deque, unless it's extended first.
+	Deadline time.Time
+
+	// key is where the claim is stored, and
+	// vstamp is the item's key in the deque.
+	key    fdb.Key
+	vstamp tuple.Versionstamp
+}
+
+// Claim takes the item at the front of the deque with a visibility timeout,
+// like a message queue. The item becomes invisible to [[Deque.Pop]],
+// [[Deque.Steal]], & other claims until [[Deque.Complete]] is called or
+// 'timeout' passes, at which point it's returned to its original position in
+// the deque. Long-running work should keep the claim alive with
+// [[Deque.KeepClaim]], just as a mutex holder heartbeats. Expired claims are
+// returned lazily by the operations which take items, so the timeout is
+// measured by the consumers' clocks. If the deque is empty, false is returned.
+func (x *Deque) Claim(db fdb.Transactor, timeout time.Duration) (_ *Claim, _ bool, err error) {
+	defer wrapErr(&err)
+
+	rngItems, err := x.packItemRange()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to pack item range: %w", err)
+	}
+
+	c, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		now := time.Now()
+		if err := x.requeue(tr, now); err != nil {
+			return nil, fmt.Errorf("failed to requeue expired claims: %w", err)
+		}
+
+		kvs, err := tr.Snapshot().GetRange(rngItems, fdb.RangeOptions{Limit: 1}).GetSliceWithError()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read items: %w", err)
+		}
+		if len(kvs) == 0 {
+			return nil, nil
+		}
+
+		item := kvs[0]
+		if err := tr.AddReadConflictKey(item.Key); err != nil {
+			return nil, fmt.Errorf("failed to add read conflict: %w", err)
+		}
+		vstamp, err := x.unpackItemKey(item.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack item key: %w", err)
+		}
+
+		c := &Claim{Item: item.Value, Deadline: now.Add(timeout), vstamp: vstamp}
+		c.key = x.packClaimKey(c.Deadline, vstamp)
+		tr.Clear(item.Key)
+		tr.Set(c.key, item.Value)
+		return c, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if c == nil {
+		return nil, false, nil
+	}
+	return c.(*Claim), true, nil
+}
+
+// Complete removes the claimed item from the deque for good. If
+// the claim expired, [[ErrClaimExpired]] is returned.
+func (x *Deque) Complete(db fdb.Transactor, c *Claim) (err error) {
+	defer wrapErr(&err)
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.checkClaim(tr, c); err != nil {
+			return nil, err
+		}
+		tr.Clear(c.key)
+		return nil, nil
+	})
+	return err
+}
+
+// Extend pushes the claim's deadline to 'timeout' from now. If
+// the claim expired, [[ErrClaimExpired]] is returned.
+func (x *Deque) Extend(db fdb.Transactor, c *Claim, timeout time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	deadline := time.Now().Add(timeout)
+	key := x.packClaimKey(deadline, c.vstamp)
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.checkClaim(tr, c); err != nil {
+			return nil, err
+		}
+		tr.Clear(c.key)
+		tr.Set(key, c.Item)
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	c.key, c.Deadline = key, deadline
+	return nil
+}
+
+// KeepClaim extends the claim every quarter of 'timeout' until the context
+// ends, keeping the item invisible while the claim's holder works on it.
+// Failed extensions are retried until the claim expires, at which point
+// [[ErrClaimExpired]] is returned. When the context ends, nil is returned.
+func (x *Deque) KeepClaim(ctx context.Context, db fdb.Transactor, c *Claim, timeout time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	interval := timeout / 4
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		err := x.Extend(db, c, timeout)
+		switch {
+		case err == nil:
+			interval = timeout / 4
+		case errors.Is(err, ErrClaimExpired) || !IsRetryable(err):
+			return err
+		default:
+			interval = minBackoff
+		}
+	}
+}
+
+// checkClaim returns [[ErrClaimExpired]] if the claim is no longer
+// stored. Reading the claim adds it to the transaction's read conflict
+// range, so the transaction conflicts with a concurrent requeue.
+func (x *Deque) checkClaim(tr fdb.Transaction, c *Claim) error {
+	val, err := tr.Get(c.key).Get()
+	if err != nil {
+		return fmt.Errorf("failed to get claim: %w", err)
+	}
+	if val == nil {
+		return ErrClaimExpired
+	}
+	return nil
+}
+
+// requeue returns the items of claims which expired before 'now' to their
+// original position in the deque. Claims are read at snapshot isolation and
+// only the ones being requeued are added to the read conflict range.
+func (x *Deque) requeue(tr fdb.Transaction, now time.Time) error {
+	rngClaims, err := x.packClaimRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack claim range: %w", err)
+	}
+	rngClaims.End = x.Pack(tuple.Tuple{"claims", now.UnixNano()})
+
+	kvs, err := tr.Snapshot().GetRange(rngClaims, fdb.RangeOptions{Limit: maxRequeue}).GetSliceWithError()
+	if err != nil {
+		return fmt.Errorf("failed to read claims: %w", err)
+	}
+	for _, kv := range kvs {
+		if err := tr.AddReadConflictKey(kv.Key); err != nil {
+			return fmt.Errorf("failed to add read conflict: %w", err)
+		}
+		vstamp, err := x.unpackClaimKey(kv.Key)
+		if err != nil {
+			return fmt.Errorf("failed to unpack claim key: %w", err)
+		}
+		tr.Clear(kv.Key)
+		tr.Set(x.packRequeuedItemKey(vstamp), kv.Value)
+	}
+	if len(kvs) > 0 {
+		tr.Add(x.packVersionKey(), packIncrement())
+	}
+	return nil
+}
+
+func (x *Deque) packClaimRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"claims"}))
+}
+
+func (x *Deque) packClaimKey(deadline time.Time, vstamp tuple.Versionstamp) fdb.Key {
+	return x.Pack(tuple.Tuple{"claims", deadline.UnixNano(), vstamp})
+}
+
+func (x *Deque) unpackClaimKey(key fdb.Key) (tuple.Versionstamp, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 3 {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[2].(tuple.Versionstamp)
+	if !ok {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple element 2 is not a versionstamp")
+	}
+	return vstamp, nil
+}
@@ -0,0 +1,90 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaim(t *testing.T) {
+	tests := map[string]testFn{
+		"complete": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewDeque(db, root)
+			require.NoError(t, err)
+			require.NoError(t, x.Push(db, []byte("a"), []byte("b")))
+
+			c, ok, err := x.Claim(db, time.Minute)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("a"), c.Item)
+
+			// The claimed item is invisible.
+			n, err := x.Len(db)
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+
+			item, ok, err := x.Steal(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("b"), item)
+
+			require.NoError(t, x.Complete(db, c))
+			_, ok, err = x.Claim(db, time.Minute)
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+
+		"expired": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewDeque(db, root)
+			require.NoError(t, err)
+			require.NoError(t, x.Push(db, []byte("a")))
+
+			c1, ok, err := x.Claim(db, 100*time.Millisecond)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			// Once the timeout passes, the item
+			// is returned and claimed by another.
+			time.Sleep(200 * time.Millisecond)
+			c2, ok, err := x.Claim(db, time.Minute)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("a"), c2.Item)
+
+			require.ErrorIs(t, x.Complete(db, c1), ErrClaimExpired)
+			require.ErrorIs(t, x.Extend(db, c1, time.Minute), ErrClaimExpired)
+			require.NoError(t, x.Complete(db, c2))
+		},
+
+		"keep": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewDeque(db, root)
+			require.NoError(t, err)
+			require.NoError(t, x.Push(db, []byte("a")))
+
+			const timeout = 200 * time.Millisecond
+			c, ok, err := x.Claim(db, timeout)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- x.KeepClaim(ctx, db, c, timeout) }()
+
+			// The claim outlives its original timeout.
+			time.Sleep(3 * timeout)
+			_, ok, err = x.Claim(db, time.Minute)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			cancel()
+			require.NoError(t, <-done)
+			require.NoError(t, x.Complete(db, c))
+		},
+	}
+
+	runTests(t, tests)
+}
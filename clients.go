@@ -0,0 +1,386 @@
+package mutex
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// ClientSession describes a client found in a [[ClientRegistry]].
+type ClientSession struct {
+	// Name uniquely identifies the client.
+	Name string
+
+	// Host is the hostname of the machine running the client.
+	Host string
+
+	// Locks identifies the mutexes currently held by the
+	// client. For mutexes stored in a directory, the ID
+	// is the directory path joined with slashes. Otherwise,
+	// the ID is the hex encoded subspace prefix.
+	Locks []string
+
+	// Heartbeat is the time of the client's latest heartbeat.
+	Heartbeat time.Time
+}
+
+// ClientRegistry tracks the clients interacting with mutexes. A single
+// registry may be shared by any number of mutexes, allowing operators to
+// see what a client is doing across all of them. Mutexes are attached to
+// the registry using the [[WithClientRegistry]] option.
+type ClientRegistry struct{ subspace.Subspace }
+
+// NewClientRegistry constructs a client registry. 'root' is the directory
+// where the registry state is stored.
+func NewClientRegistry(root subspace.Subspace) ClientRegistry {
+	return ClientRegistry{root}
+}
+
+// List returns all the client sessions in the registry.
+func (x *ClientRegistry) List(db fdb.Transactor) (_ []ClientSession, err error) {
+	defer wrapErr(&err)
+
+	rngClients, err := x.packClientRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack client range: %w", err)
+	}
+	return x.readSessions(db, rngClients)
+}
+
+// Get returns the session for the client with the provided name.
+// If the client isn't in the registry then false is returned.
+func (x *ClientRegistry) Get(db fdb.Transactor, name string) (_ ClientSession, _ bool, err error) {
+	defer wrapErr(&err)
+
+	rngSession, err := x.packSessionRange(name)
+	if err != nil {
+		return ClientSession{}, false, fmt.Errorf("failed to pack session range: %w", err)
+	}
+
+	sessions, err := x.readSessions(db, rngSession)
+	if err != nil {
+		return ClientSession{}, false, err
+	}
+	if len(sessions) == 0 {
+		return ClientSession{}, false, nil
+	}
+	return sessions[0], true, nil
+}
+
+// Remove deletes the session of the client with the provided name.
+// This should be called when a client shuts down. If the session is
+// heartbeating in this process, the heartbeat is stopped first.
+func (x *ClientRegistry) Remove(db fdb.Transactor, name string) (err error) {
+	defer wrapErr(&err)
+
+	x.stopSession(name)
+
+	rngClient, err := x.packSessionRange(name)
+	if err != nil {
+		return fmt.Errorf("failed to pack session range: %w", err)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.ClearRange(rngClient)
+		x.bumpVersion(tr)
+		return nil, nil
+	})
+	return err
+}
+
+// Expire removes the sessions whose latest heartbeat is older than 'maxAge'
+// and returns the names of their clients. This cleans up after clients which
+// died without calling [[ClientRegistry.Remove]]. Heartbeats are timed by
+// the clocks of the clients, so 'maxAge' should leave room for clock skew.
+// Live clients heartbeat their session every second, whether or not they
+// hold or wait for a mutex, so 'maxAge' should span several heartbeats.
+func (x *ClientRegistry) Expire(db fdb.Transactor, maxAge time.Duration) (_ []string, err error) {
+	defer wrapErr(&err)
+
+	rngClients, err := x.packClientRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack client range: %w", err)
+	}
+
+	expired, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		sessions, err := x.readSessions(tr, rngClients)
+		if err != nil {
+			return nil, err
+		}
+
+		var expired []string
+		for _, session := range sessions {
+			if time.Since(session.Heartbeat) <= maxAge {
+				continue
+			}
+			rngSession, err := x.packSessionRange(session.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to pack session range: %w", err)
+			}
+			tr.ClearRange(rngSession)
+			expired = append(expired, session.Name)
+		}
+		if len(expired) > 0 {
+			x.bumpVersion(tr)
+		}
+		return expired, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired.([]string), nil
+}
+
+// Watch returns a channel which signals a change to the membership of the
+// registry or to the locks held by its clients. When a change occurs, the
+// channel returns nil. Heartbeats aren't signaled, so clients holding
+// mutexes don't wake the watchers every second. If the watch setup fails
+// or the provided context is canceled, the channel returns an error.
+func (x *ClientRegistry) Watch(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packVersionKey(), nil
+	})
+}
+
+// readSessions reads the sessions stored in the given range.
+func (x *ClientRegistry) readSessions(db fdb.Transactor, rng fdb.KeyRange) ([]ClientSession, error) {
+	sessions, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var sessions []ClientSession
+
+		iter := tr.GetRange(rng, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			tup, err := x.Unpack(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack client key: %w", err)
+			}
+			if len(tup) < 3 {
+				return nil, fmt.Errorf("client key tuple is incorrect length %d", len(tup))
+			}
+			name, ok := tup[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("tuple element 1 is not a string")
+			}
+
+			// Keys are grouped by client name, so we only
+			// need to check the latest session.
+			if len(sessions) == 0 || sessions[len(sessions)-1].Name != name {
+				sessions = append(sessions, ClientSession{Name: name})
+			}
+			session := &sessions[len(sessions)-1]
+
+			switch tup[2] {
+			case "host":
+				session.Host = string(kv.Value)
+
+			case "hbeat":
+				session.Heartbeat = x.unpackHeartbeatValue(kv.Value)
+
+			case "lock":
+				if len(tup) != 4 {
+					return nil, fmt.Errorf("lock key tuple is incorrect length %d", len(tup))
+				}
+				id, ok := tup[3].(string)
+				if !ok {
+					return nil, fmt.Errorf("tuple element 3 is not a string")
+				}
+				session.Locks = append(session.Locks, id)
+			}
+		}
+		return sessions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions.([]ClientSession), nil
+}
+
+// register creates or updates the session for the client with the provided name.
+func (x *ClientRegistry) register(db fdb.Transactor, name string) error {
+	host, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		// Only a new session changes the membership
+		// of the registry. See [[ClientRegistry.Watch]].
+		prev, err := tr.Get(x.packHostKey(name)).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get host: %w", err)
+		}
+		tr.Set(x.packHostKey(name), []byte(host))
+		tr.Set(x.packHeartbeatKey(name), x.packHeartbeatValue(time.Now()))
+		if prev == nil {
+			x.bumpVersion(tr)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// sessionBeats holds the session heartbeats running in this process.
+// See [[ClientRegistry.beatSession]].
+var sessionBeats = struct {
+	sync.Mutex
+	m map[sessionID]sessionBeat
+}{m: make(map[sessionID]sessionBeat)}
+
+// sessionID identifies a client session across registries.
+type sessionID struct{ registry, name string }
+
+// sessionBeat controls a goroutine started by [[ClientRegistry.beatSession]].
+// Closing 'stop' ends the goroutine, which then closes 'done'.
+type sessionBeat struct{ stop, done chan struct{} }
+
+// beatSession heartbeats the session of the client with the provided name
+// every [[defaultBeatInterval]] until [[ClientRegistry.Remove]] is called.
+// Each beat registers the client again, so a session which was expired while
+// the client was still alive is restored along with its host. If the session
+// is already heartbeating in this process, this method is a noop.
+func (x *ClientRegistry) beatSession(db fdb.Transactor, name string) {
+	id := sessionID{registry: string(x.Bytes()), name: name}
+
+	sessionBeats.Lock()
+	defer sessionBeats.Unlock()
+	if _, ok := sessionBeats.m[id]; ok {
+		return
+	}
+	beat := sessionBeat{stop: make(chan struct{}), done: make(chan struct{})}
+	sessionBeats.m[id] = beat
+
+	go func() {
+		defer close(beat.done)
+		ticker := time.NewTicker(defaultBeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-beat.stop:
+				return
+			case <-ticker.C:
+				// Failed beats are retried on the next tick.
+				_ = x.register(db, name)
+			}
+		}
+	}()
+}
+
+// stopSession stops the heartbeat started by [[ClientRegistry.beatSession]]
+// and waits for any in-flight beat, so the session isn't registered again
+// after it's removed.
+func (x *ClientRegistry) stopSession(name string) {
+	id := sessionID{registry: string(x.Bytes()), name: name}
+
+	sessionBeats.Lock()
+	beat, ok := sessionBeats.m[id]
+	delete(sessionBeats.m, id)
+	sessionBeats.Unlock()
+
+	if ok {
+		close(beat.stop)
+		<-beat.done
+	}
+}
+
+// heartbeat updates the latest heartbeat for the client with the provided
+// name. The version isn't bumped, so watchers aren't woken by heartbeats.
+func (x *ClientRegistry) heartbeat(db fdb.Transactor, name string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packHeartbeatKey(name), x.packHeartbeatValue(time.Now()))
+		return nil, nil
+	})
+	return err
+}
+
+// addLock records that the client with the provided name holds the given lock.
+func (x *ClientRegistry) addLock(db fdb.Transactor, name string, lockID string) error {
+	if name == "" {
+		return nil
+	}
+
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packLockKey(name, lockID), nil)
+		tr.Set(x.packHeartbeatKey(name), x.packHeartbeatValue(time.Now()))
+		x.bumpVersion(tr)
+		return nil, nil
+	})
+	return err
+}
+
+// removeLock records that the client with the provided name no longer holds the given lock.
+func (x *ClientRegistry) removeLock(db fdb.Transactor, name string, lockID string) error {
+	if name == "" {
+		return nil
+	}
+
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packLockKey(name, lockID))
+		x.bumpVersion(tr)
+		return nil, nil
+	})
+	return err
+}
+
+// bumpVersion increments the version key, triggering any watches
+// created by [[ClientRegistry.Watch]].
+func (x *ClientRegistry) bumpVersion(tr fdb.Transaction) {
+	tr.Add(x.packVersionKey(), packIncrement())
+}
+
+func (x *ClientRegistry) packClientRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"client"}))
+}
+
+func (x *ClientRegistry) packSessionRange(name string) (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"client", name}))
+}
+
+func (x *ClientRegistry) packHostKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"client", name, "host"})
+}
+
+func (x *ClientRegistry) packHeartbeatKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"client", name, "hbeat"})
+}
+
+func (x *ClientRegistry) packHeartbeatValue(t time.Time) []byte {
+	return tuple.Tuple{t.UnixNano()}.Pack()
+}
+
+func (x *ClientRegistry) unpackHeartbeatValue(val []byte) time.Time {
+	tup, err := tuple.Unpack(val)
+	if err != nil || len(tup) != 1 {
+		return time.Time{}
+	}
+	nanos, ok := tup[0].(int64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func (x *ClientRegistry) packLockKey(name string, lockID string) fdb.Key {
+	return x.Pack(tuple.Tuple{"client", name, "lock", lockID})
+}
+
+func (x *ClientRegistry) packVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"version"})
+}
+
+// lockID returns a human readable identifier for the mutex
+// stored in the given subspace. See [[ClientSession.Locks]].
+func lockID(root subspace.Subspace) string {
+	if dir, ok := root.(directory.DirectorySubspace); ok {
+		return strings.Join(dir.GetPath(), "/")
+	}
+	return hex.EncodeToString(root.Bytes())
+}
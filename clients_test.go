@@ -0,0 +1,166 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRegistry(t *testing.T) {
+	tests := map[string]testFn{
+		"empty": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			reg := NewClientRegistry(root)
+
+			sessions, err := reg.List(db)
+			require.NoError(t, err)
+			require.Empty(t, sessions)
+
+			_, ok, err := reg.Get(db, "client")
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+		"register": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			reg := NewClientRegistry(root.Sub("clients"))
+
+			_, err := NewMutex(db, root.Sub("mutex"), "client", WithClientRegistry(reg))
+			require.NoError(t, err)
+
+			session, ok, err := reg.Get(db, "client")
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "client", session.Name)
+			require.NotEmpty(t, session.Host)
+			require.False(t, session.Heartbeat.IsZero())
+			require.Empty(t, session.Locks)
+
+			err = reg.Remove(db, "client")
+			require.NoError(t, err)
+
+			sessions, err := reg.List(db)
+			require.NoError(t, err)
+			require.Empty(t, sessions)
+		},
+		"locks": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			reg := NewClientRegistry(root.Sub("clients"))
+			id := lockID(root.Sub("mutex"))
+
+			x1, err := NewMutex(db, root.Sub("mutex"), "client1", WithClientRegistry(reg))
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root.Sub("mutex"), "client2", WithClientRegistry(reg))
+			require.NoError(t, err)
+
+			defer func() {
+				require.NoError(t, reg.Remove(db, "client1"))
+				require.NoError(t, reg.Remove(db, "client2"))
+			}()
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			session, _, err := reg.Get(db, "client1")
+			require.NoError(t, err)
+			require.Equal(t, []string{id}, session.Locks)
+
+			// Releasing hands the lock to the
+			// queued client.
+			err = x1.Release(db)
+			require.NoError(t, err)
+
+			session, _, err = reg.Get(db, "client1")
+			require.NoError(t, err)
+			require.Empty(t, session.Locks)
+
+			session, _, err = reg.Get(db, "client2")
+			require.NoError(t, err)
+			require.Equal(t, []string{id}, session.Locks)
+		},
+		"watch": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			reg := NewClientRegistry(root)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			watch := reg.Watch(ctx, db)
+
+			err := reg.register(db, "client")
+			require.NoError(t, err)
+
+			require.NoError(t, <-watch)
+
+			// Heartbeats & repeated registrations
+			// don't change the membership.
+			watch = reg.Watch(ctx, db)
+			require.NoError(t, reg.heartbeat(db, "client"))
+			require.NoError(t, reg.register(db, "client"))
+			select {
+			case err := <-watch:
+				t.Fatalf("watch signaled without a membership change: %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			require.NoError(t, reg.Remove(db, "client"))
+			require.NoError(t, <-watch)
+		},
+		"expire": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			reg := NewClientRegistry(root)
+
+			require.NoError(t, reg.register(db, "dead"))
+			_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Set(reg.packHeartbeatKey("dead"), reg.packHeartbeatValue(time.Now().Add(-time.Hour)))
+				return nil, nil
+			})
+			require.NoError(t, err)
+			require.NoError(t, reg.register(db, "alive"))
+
+			expired, err := reg.Expire(db, time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, []string{"dead"}, expired)
+
+			sessions, err := reg.List(db)
+			require.NoError(t, err)
+			require.Len(t, sessions, 1)
+			require.Equal(t, "alive", sessions[0].Name)
+
+			expired, err = reg.Expire(db, time.Minute)
+			require.NoError(t, err)
+			require.Empty(t, expired)
+		},
+		"idle": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			reg := NewClientRegistry(root.Sub("clients"))
+
+			_, err := NewMutex(db, root.Sub("mutex"), "idle", WithClientRegistry(reg))
+			require.NoError(t, err)
+			defer func() { require.NoError(t, reg.Remove(db, "idle")) }()
+
+			// The idle client keeps its session
+			// fresh without holding the mutex.
+			time.Sleep(2 * defaultBeatInterval)
+			expired, err := reg.Expire(db, defaultBeatInterval+time.Second)
+			require.NoError(t, err)
+			require.Empty(t, expired)
+
+			// If its session is expired anyways, the
+			// next beat restores it with the host.
+			expired, err = reg.Expire(db, 0)
+			require.NoError(t, err)
+			require.Equal(t, []string{"idle"}, expired)
+
+			require.Eventually(t, func() bool {
+				session, ok, err := reg.Get(db, "idle")
+				return err == nil && ok && session.Host != ""
+			}, 5*defaultBeatInterval, 50*time.Millisecond)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,177 @@
+package mutex
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// versionsPerSecond is the approximate rate at which FDB advances its
+// commit version. It's used to convert versions into durations.
+const versionsPerSecond = 1_000_000
+
+// minDriftSpan is how much database time must separate
+// two clock samples before drift is estimated from them.
+const minDriftSpan = 10 * time.Second
+
+// maxDrift bounds the drift used to shrink safety margins,
+// so a wild estimate can't disable the fail-safe.
+const maxDrift = 0.5
+
+// ClockSkew estimates how far a client's clock is from the clocks of
+// the other clients of a mutex. Skew is measured against the version
+// timeline of the database: each client's samples pair its local time
+// with a read version, and the median offset is taken as the reference.
+// A positive skew means the client's clock is ahead.
+type ClockSkew struct {
+	// Client is the name of the client.
+	Client string
+
+	// Skew is the estimated offset of the client's clock.
+	Skew time.Duration
+
+	// Sampled is when the client recorded its latest
+	// sample, according to the client's own clock.
+	Sampled time.Time
+}
+
+// MeasureClock records a clock sample for this client. Holders record a
+// sample with every heartbeat. Other clients should call this periodically
+// so they're included in [[Mutex.ClockSkews]].
+func (x *Mutex) MeasureClock(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	sample, err := x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		return x.sampleClock(tr, x.name)
+	})
+	if err != nil {
+		return err
+	}
+	x.clock.observe(sample.(clockKV))
+	return nil
+}
+
+// ClockSkews returns the estimated skew of every client which has recorded
+// a clock sample, sorted by client name. Versions advance at roughly one
+// million per second but jump during cluster recoveries, so samples taken
+// on either side of a recovery aren't comparable until they're refreshed.
+// Operators can alert on clients whose skew is too large.
+func (x *Mutex) ClockSkews(db fdb.Transactor) (_ []ClockSkew, err error) {
+	defer wrapErr(&err)
+
+	clocks, err := x.getClocks(x.withBreaker(db))
+	if err != nil {
+		return nil, err
+	}
+	return toClockSkews(clocks), nil
+}
+
+// ClockDrift returns the estimated rate at which this client's clock gains
+// on the database's version timeline, as a fraction of elapsed time. For
+// instance, 0.01 means the local clock runs 1% fast. The estimate is built
+// from the samples recorded by this handle. Until enough time has passed
+// between samples, zero is returned. While holding the mutex, the fail-safe
+// deadline is shortened by the absolute drift. See [[WithFailSafe]].
+func (x *Mutex) ClockDrift() float64 {
+	return x.clock.drift()
+}
+
+// ClockSkews is like [[Mutex.ClockSkews]].
+func (x *Observer) ClockSkews(db fdb.Transactor) (_ []ClockSkew, err error) {
+	defer wrapErr(&err)
+
+	clocks, err := x.getClocks(db)
+	if err != nil {
+		return nil, err
+	}
+	return toClockSkews(clocks), nil
+}
+
+// toClockSkews measures each client's offset from the version timeline
+// and reports it relative to the median offset.
+func toClockSkews(clocks map[string]clockKV) []ClockSkew {
+	offsets := make(map[string]time.Duration, len(clocks))
+	sorted := make([]time.Duration, 0, len(clocks))
+	for name, sample := range clocks {
+		offset := clockOffset(sample)
+		offsets[name] = offset
+		sorted = append(sorted, offset)
+	}
+	slices.Sort(sorted)
+
+	var median time.Duration
+	if n := len(sorted); n > 0 {
+		median = sorted[n/2]
+		if n%2 == 0 {
+			median = (sorted[n/2-1] + sorted[n/2]) / 2
+		}
+	}
+
+	skews := make([]ClockSkew, 0, len(clocks))
+	for name, sample := range clocks {
+		skews = append(skews, ClockSkew{
+			Client:  name,
+			Skew:    offsets[name] - median,
+			Sampled: sample.local,
+		})
+	}
+	sort.Slice(skews, func(i, j int) bool {
+		return skews[i].Client < skews[j].Client
+	})
+	return skews
+}
+
+// clockOffset returns the difference between the sample's local
+// time and the time implied by its version. Only differences
+// between offsets are meaningful.
+func clockOffset(sample clockKV) time.Duration {
+	return time.Duration(sample.local.UnixNano()) - versionDuration(sample.version)
+}
+
+// versionDuration converts a number of versions to a duration.
+func versionDuration(versions int64) time.Duration {
+	return time.Duration(versions) * (time.Second / versionsPerSecond)
+}
+
+// clockTracker estimates the drift of the local clock
+// from the samples recorded by a single handle.
+type clockTracker struct {
+	mu          sync.Mutex
+	first, last clockKV
+}
+
+// observe adds a sample. If versions went backwards, as
+// when the handle is used with a different cluster, the
+// estimate starts over.
+func (c *clockTracker) observe(sample clockKV) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.first.local.IsZero() || sample.version < c.last.version {
+		c.first = sample
+	}
+	c.last = sample
+}
+
+func (c *clockTracker) drift() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	span := versionDuration(c.last.version - c.first.version)
+	if span < minDriftSpan {
+		return 0
+	}
+	elapsed := c.last.local.Sub(c.first.local)
+	return float64(elapsed-span) / float64(span)
+}
+
+// margin shortens the duration 'd' by the absolute drift, so a
+// deadline measured with the local clock errs on the safe side.
+func (c *clockTracker) margin(d time.Duration) time.Duration {
+	drift := min(math.Abs(c.drift()), maxDrift)
+	return time.Duration(float64(d) * (1 - drift))
+}
@@ -0,0 +1,79 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockSkews(t *testing.T) {
+	t.Run("median", func(t *testing.T) {
+		base := time.Unix(1000, 0)
+		skews := toClockSkews(map[string]clockKV{
+			"a": {version: 0, local: base},
+			"b": {version: 1_000_000, local: base.Add(time.Second)},
+			"c": {version: 2_000_000, local: base.Add(5 * time.Second)},
+		})
+		require.Len(t, skews, 3)
+		require.Equal(t, "a", skews[0].Client)
+		require.Equal(t, time.Duration(0), skews[0].Skew)
+		require.Equal(t, time.Duration(0), skews[1].Skew)
+		require.Equal(t, 3*time.Second, skews[2].Skew)
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		var c clockTracker
+		base := time.Unix(1000, 0)
+
+		c.observe(clockKV{version: 0, local: base})
+		c.observe(clockKV{version: 1_000_000, local: base.Add(2 * time.Second)})
+		require.Zero(t, c.drift())
+		require.Equal(t, time.Minute, c.margin(time.Minute))
+
+		// The local clock gained 1s over 20s of versions.
+		c.observe(clockKV{version: 20_000_000, local: base.Add(21 * time.Second)})
+		require.InDelta(t, 0.05, c.drift(), 1e-9)
+		require.InDelta(t, 57*time.Second, c.margin(time.Minute), float64(time.Millisecond))
+
+		// Versions going backwards restart the estimate.
+		c.observe(clockKV{version: 5, local: base})
+		require.Zero(t, c.drift())
+	})
+
+	tests := map[string]testFn{
+		"measure": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			require.NoError(t, x1.MeasureClock(db))
+			require.NoError(t, x2.MeasureClock(db))
+
+			skews, err := x1.ClockSkews(db)
+			require.NoError(t, err)
+			require.Len(t, skews, 2)
+			require.Equal(t, "client1", skews[0].Client)
+			require.Equal(t, "client2", skews[1].Client)
+
+			// Both clients share a clock, so
+			// the skew should be negligible.
+			for _, skew := range skews {
+				require.Less(t, skew.Skew.Abs(), time.Second)
+			}
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			obsSkews, err := obs.ClockSkews(db)
+			require.NoError(t, err)
+			require.Equal(t, skews, obsSkews)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,129 @@
+package mutex
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// maxLocalHandoffs is the number of times in a row a coalesced or locally
+// arbitrated hold is passed between goroutines before the mutex is released
+// to the queue, preventing a busy process from starving other clients.
+const maxLocalHandoffs = 16
+
+// WithCoalescing causes goroutines which call [[Mutex.Acquire]] on this handle
+// to share a single place in the queue & a single watch. One goroutine waits
+// for the mutex on behalf of the others. When the holder calls
+// [[Mutex.Release]], the hold is passed to the next waiting goroutine without
+// touching FDB. After 16 such handoffs, the mutex is released to the queue
+// and the remaining goroutines wait for it again. Without this option, the
+// goroutines of a handle share its hold, so at most one should acquire at a
+// time. Each call to Acquire must be paired with exactly one call to Release.
+func WithCoalescing() Option {
+	return func(x *Mutex) {
+		x.coalesce = &coalescer{}
+	}
+}
+
+// coalescer hands a handle's hold between its goroutines.
+type coalescer struct {
+	mu sync.Mutex
+
+	// busy is true while a goroutine is
+	// acquiring or holding the mutex.
+	busy bool
+
+	// waiters are signaled in order. They receive true
+	// if they're handed the hold, or false if they
+	// should acquire the mutex from FDB.
+	waiters []chan bool
+
+	// handoffs counts the holds passed between
+	// goroutines since the mutex was acquired.
+	handoffs int
+}
+
+// enter blocks until the calling goroutine may use the handle. If the
+// hold was passed from another goroutine, true is returned & the mutex
+// needn't be acquired. If the context ends, its error is returned. If
+// the hold was passed while the context ended, true is returned with
+// the error & the caller must release the mutex.
+func (c *coalescer) enter(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	if !c.busy {
+		c.busy = true
+		c.mu.Unlock()
+		return false, nil
+	}
+	ch := make(chan bool, 1)
+	c.waiters = append(c.waiters, ch)
+	c.mu.Unlock()
+
+	select {
+	case held := <-ch:
+		return held, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		i := slices.Index(c.waiters, ch)
+		if i >= 0 {
+			c.waiters = slices.Delete(c.waiters, i, i+1)
+		}
+		c.mu.Unlock()
+		if i >= 0 {
+			return false, ctx.Err()
+		}
+
+		// We were signaled while giving up. If we
+		// weren't handed the hold, our turn to
+		// acquire is passed along.
+		held := <-ch
+		if !held {
+			c.leave()
+		}
+		return held, ctx.Err()
+	}
+}
+
+// tryEnter returns true if the calling goroutine may use the handle.
+func (c *coalescer) tryEnter() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.busy {
+		return false
+	}
+	c.busy = true
+	return true
+}
+
+// handOff passes the hold to the next waiting goroutine. If none are
+// waiting or the hold has been passed too many times, false is returned
+// & the mutex should be released.
+func (c *coalescer) handOff() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.waiters) == 0 || c.handoffs >= maxLocalHandoffs {
+		return false
+	}
+	c.handoffs++
+	c.pop() <- true
+	return true
+}
+
+// leave is called once the mutex is released or couldn't be acquired.
+// The next waiting goroutine is told to acquire the mutex from FDB.
+func (c *coalescer) leave() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handoffs = 0
+	if len(c.waiters) == 0 {
+		c.busy = false
+		return
+	}
+	c.pop() <- false
+}
+
+func (c *coalescer) pop() chan bool {
+	ch := c.waiters[0]
+	c.waiters = c.waiters[1:]
+	return ch
+}
@@ -0,0 +1,108 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescing(t *testing.T) {
+	tests := map[string]testFn{
+		"exclusive": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "x", WithCoalescing())
+			require.NoError(t, err)
+
+			const n = 20
+			errOverlap := errors.New("holds overlapped")
+			var holders atomic.Int32
+			errs := make(chan error, n)
+			for range n {
+				go func() {
+					if err := x.Acquire(context.Background(), db); err != nil {
+						errs <- err
+						return
+					}
+					if holders.Add(1) != 1 {
+						errs <- errOverlap
+						return
+					}
+					time.Sleep(time.Millisecond)
+					holders.Add(-1)
+					errs <- x.Release(db)
+				}()
+			}
+			for range n {
+				require.NoError(t, <-errs)
+			}
+
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+		},
+		"single entry": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			other, err := NewMutex(db, root, "other")
+			require.NoError(t, err)
+
+			x, err := NewMutex(db, root, "x", WithCoalescing())
+			require.NoError(t, err)
+
+			acquired, err := other.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			const n = 5
+			errs := make(chan error, n)
+			for range n {
+				go func() {
+					if err := x.Acquire(context.Background(), db); err != nil {
+						errs <- err
+						return
+					}
+					errs <- x.Release(db)
+				}()
+			}
+
+			require.Eventually(t, func() bool {
+				queue, err := x.getQueue(db)
+				return err == nil && len(queue) == 1
+			}, time.Second, 10*time.Millisecond)
+
+			// Only one goroutine may try to acquire at a time.
+			acquired, err = x.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			err = other.Release(db)
+			require.NoError(t, err)
+			for range n {
+				require.NoError(t, <-errs)
+			}
+		},
+		"cancel": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "x", WithCoalescing())
+			require.NoError(t, err)
+
+			err = x.Acquire(context.Background(), db)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err = x.Acquire(ctx, db)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			err = x.Release(db)
+			require.NoError(t, err)
+
+			err = x.Acquire(context.Background(), db)
+			require.NoError(t, err)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,71 @@
+package mutex
+
+import (
+	"context"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ConfigStore is a key-value store where only the leader can write keys
+// and every client can read or watch them. The leader is the current
+// owner of the underlying mutex, so leadership is won by acquiring the
+// mutex. Writes are fenced: they fail with [[ErrNotOwner]] if the
+// writer isn't the leader when the transaction commits.
+type ConfigStore struct{ x *Mutex }
+
+// NewConfigStore constructs a config store. The store's state is kept in
+// the mutex's subspace and 'x' identifies the client using the store.
+func NewConfigStore(x *Mutex) *ConfigStore {
+	return &ConfigStore{x: x}
+}
+
+// Set writes the value of the given key. Only the leader may write.
+func (c *ConfigStore) Set(db fdb.Transactor, key string, val []byte) (err error) {
+	defer wrapErr(&err)
+
+	_, err = c.x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		if err := c.x.fence(tr); err != nil {
+			return nil, err
+		}
+		tr.Set(c.x.packStoreKey(key), val)
+		return nil, nil
+	})
+	return err
+}
+
+// Clear deletes the given key. Only the leader may clear keys.
+func (c *ConfigStore) Clear(db fdb.Transactor, key string) (err error) {
+	defer wrapErr(&err)
+
+	_, err = c.x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		if err := c.x.fence(tr); err != nil {
+			return nil, err
+		}
+		tr.Clear(c.x.packStoreKey(key))
+		return nil, nil
+	})
+	return err
+}
+
+// Get reads the value of the given key. If the key
+// doesn't exist, nil is returned. Any client may read.
+func (c *ConfigStore) Get(db fdb.Transactor, key string) (_ []byte, err error) {
+	defer wrapErr(&err)
+
+	val, err := c.x.withBreaker(db).ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(c.x.packStoreKey(key)).Get()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]byte), nil
+}
+
+// Watch returns a channel which signals a change to the given key. When
+// the key changes, the channel returns nil. If the watch setup fails or
+// the provided context is canceled, the channel returns an error.
+func (c *ConfigStore) Watch(ctx context.Context, db fdb.Transactor, key string) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return c.x.packStoreKey(key), nil
+	})
+}
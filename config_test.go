@@ -0,0 +1,71 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigStore(t *testing.T) {
+	tests := map[string]testFn{
+		"leader writes": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			leader, err := NewMutex(db, root, "leader")
+			require.NoError(t, err)
+
+			follower, err := NewMutex(db, root, "follower")
+			require.NoError(t, err)
+
+			err = leader.Acquire(context.Background(), db)
+			require.NoError(t, err)
+
+			err = NewConfigStore(leader).Set(db, "key", []byte("val"))
+			require.NoError(t, err)
+
+			val, err := NewConfigStore(follower).Get(db, "key")
+			require.NoError(t, err)
+			require.Equal(t, []byte("val"), val)
+
+			err = NewConfigStore(leader).Clear(db, "key")
+			require.NoError(t, err)
+
+			val, err = NewConfigStore(follower).Get(db, "key")
+			require.NoError(t, err)
+			require.Nil(t, val)
+		},
+		"follower writes": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			follower, err := NewMutex(db, root, "follower")
+			require.NoError(t, err)
+
+			err = NewConfigStore(follower).Set(db, "key", []byte("val"))
+			require.ErrorIs(t, err, ErrNotOwner)
+
+			err = NewConfigStore(follower).Clear(db, "key")
+			require.ErrorIs(t, err, ErrNotOwner)
+		},
+		"watch": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			leader, err := NewMutex(db, root, "leader")
+			require.NoError(t, err)
+
+			follower, err := NewMutex(db, root, "follower")
+			require.NoError(t, err)
+
+			err = leader.Acquire(context.Background(), db)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			watch := NewConfigStore(follower).Watch(ctx, db, "key")
+
+			err = NewConfigStore(leader).Set(db, "key", []byte("val"))
+			require.NoError(t, err)
+
+			require.NoError(t, <-watch)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,49 @@
+package mutex
+
+import (
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ConflictKey returns a key which is written whenever the ownership of the
+// mutex changes, including when it's released, but not by heartbeats. Adding
+// the key as a read conflict to a transaction causes the transaction to
+// fail if the mutex changes hands before it commits. See
+// [[Mutex.AddOwnershipConflict]].
+func (x *Mutex) ConflictKey() fdb.Key {
+	return x.packOwnershipKey()
+}
+
+// AddOwnershipConflict ties the commit of any FDB transaction to this
+// client's ownership of the mutex, without the full [[Guard.Transact]]
+// wrapper. If the client doesn't own the mutex, [[ErrNotOwner]] is
+// returned. Otherwise, [[Mutex.ConflictKey]] is added as a read conflict
+// so the transaction fails to commit, and is retried by [[fdb.Database.Transact]],
+// if ownership changes in the meantime. The owner is read at snapshot
+// isolation, so heartbeats don't cause conflicts.
+func (x *Mutex) AddOwnershipConflict(tr fdb.Transaction) error {
+	rngOwner, err := x.packOwnerRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack owner range: %w", err)
+	}
+	kvs, err := tr.Snapshot().GetRange(rngOwner, fdb.RangeOptions{Limit: 1}).GetSliceWithError()
+	if err != nil {
+		return fmt.Errorf("failed to get owner: %w", err)
+	}
+	if len(kvs) == 0 {
+		return ErrNotOwner
+	}
+	name, err := x.unpackOwnerKey(kvs[0].Key)
+	if err != nil {
+		return fmt.Errorf("failed to unpack owner key: %w", err)
+	}
+	if name != x.name {
+		return ErrNotOwner
+	}
+
+	if err := tr.AddReadConflictKey(x.ConflictKey()); err != nil {
+		return fmt.Errorf("failed to add read conflict: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package mutex
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConflictKey(t *testing.T) {
+	tests := map[string]testFn{
+		"not owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "x")
+			require.NoError(t, err)
+
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				return nil, x.AddOwnershipConflict(tr)
+			})
+			require.ErrorIs(t, err, ErrNotOwner)
+		},
+		"ownership changed": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "x1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "x2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			tr, err := db.CreateTransaction()
+			require.NoError(t, err)
+			require.NoError(t, x1.AddOwnershipConflict(tr))
+			tr.Set(root.Sub("app"), []byte("data"))
+
+			// A heartbeat doesn't conflict.
+			require.NoError(t, x1.heartbeat(db, "x1"))
+			require.NoError(t, tr.Commit().Get())
+
+			tr, err = db.CreateTransaction()
+			require.NoError(t, err)
+			require.NoError(t, x1.AddOwnershipConflict(tr))
+			tr.Set(root.Sub("app"), []byte("data"))
+
+			// The mutex changes hands before the commit.
+			require.NoError(t, x1.Release(db))
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = tr.Commit().Get()
+			var ferr fdb.Error
+			require.ErrorAs(t, err, &ferr)
+			require.Equal(t, 1020, ferr.Code)
+		},
+	}
+
+	runTests(t, tests)
+}
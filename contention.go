@@ -0,0 +1,159 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ContentionLevel buckets the depth of a mutex's queue, allowing services
+// to shed or defer work before attempting to acquire a busy mutex.
+type ContentionLevel int
+
+const (
+	// ContentionLow means no clients are waiting.
+	ContentionLow ContentionLevel = iota
+
+	// ContentionMedium means fewer than 5 clients are waiting.
+	ContentionMedium
+
+	// ContentionHigh means 5 or more clients are waiting.
+	ContentionHigh
+)
+
+// highContention is the queue depth at which contention is high.
+const highContention = 5
+
+func (l ContentionLevel) String() string {
+	switch l {
+	case ContentionLow:
+		return "low"
+	case ContentionMedium:
+		return "medium"
+	case ContentionHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("ContentionLevel(%d)", int(l))
+	}
+}
+
+// ContentionLevel returns the contention level of the mutex, based on the
+// depth of its queue. Only the first few queue entries are read. To avoid
+// the read altogether, see [[Observer.CacheContention]].
+func (x *Observer) ContentionLevel(db fdb.Transactor) (_ ContentionLevel, err error) {
+	defer wrapErr(&err)
+	return x.getContentionLevel(db)
+}
+
+// ContentionCache answers [[Observer.ContentionLevel]] from memory. The level
+// is read once along with a watch which invalidates it when the queue changes,
+// so services may consult it on every request. ContentionCaches are created
+// by [[Observer.CacheContention]].
+type ContentionCache struct {
+	x   *Observer
+	db  fdb.Transactor
+	ctx context.Context
+
+	mu    sync.Mutex
+	level ContentionLevel
+	valid bool
+
+	// gen is incremented whenever the cache is filled
+	// so an old watch can't invalidate a newer entry.
+	gen int
+}
+
+// CacheContention returns a cache of the mutex's contention level. The
+// cache's watches are cancelled when the context ends, after which every
+// call to [[ContentionCache.Level]] reads the queue from the database.
+func (x *Observer) CacheContention(ctx context.Context, db fdb.Transactor) *ContentionCache {
+	return &ContentionCache{x: x, db: db, ctx: ctx}
+}
+
+// Level returns the contention level of the mutex.
+// See [[Observer.ContentionLevel]].
+func (c *ContentionCache) Level() (_ ContentionLevel, err error) {
+	defer wrapErr(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.valid {
+		return c.level, nil
+	}
+	if c.ctx.Err() != nil {
+		return c.x.getContentionLevel(c.db)
+	}
+
+	// Read the queue & watch for a change in the
+	// same transaction, so no change can be missed.
+	var level ContentionLevel
+	ctx, cancel := context.WithCancel(c.ctx)
+	ch := watch(ctx, c.db, func(tr fdb.Transaction) (fdb.Key, error) {
+		var err error
+		if level, err = c.x.getContentionLevel(tr); err != nil {
+			return nil, fmt.Errorf("failed to get contention level: %w", err)
+		}
+		return c.x.packQueueVersionKey(), nil
+	})
+
+	// If the watch couldn't be set up, the
+	// level is read again on the next call.
+	select {
+	case err := <-ch:
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+		return level, nil
+	default:
+	}
+
+	c.gen++
+	c.level, c.valid = level, true
+	go c.invalidate(ch, cancel, c.gen)
+	return level, nil
+}
+
+// invalidate clears the cache once the watch fires or fails.
+func (c *ContentionCache) invalidate(ch <-chan error, cancel context.CancelFunc, gen int) {
+	<-ch
+	cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		c.valid = false
+	}
+}
+
+// getContentionLevel buckets the depth of the queue. At most
+// [[highContention]] entries of the queue are read.
+func (x *kv) getContentionLevel(db fdb.Transactor) (ContentionLevel, error) {
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack queue range: %w", err)
+	}
+
+	depth, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		kvs, err := tr.GetRange(rngQueue, fdb.RangeOptions{Limit: highContention}).GetSliceWithError()
+		if err != nil {
+			return nil, err
+		}
+		return len(kvs), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	switch d := depth.(int); {
+	case d == 0:
+		return ContentionLow, nil
+	case d < highContention:
+		return ContentionMedium, nil
+	default:
+		return ContentionHigh, nil
+	}
+}
@@ -0,0 +1,91 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentionLevel(t *testing.T) {
+	tests := map[string]testFn{
+		"levels": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner, err := NewMutex(db, root, "owner")
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			level, err := obs.ContentionLevel(db)
+			require.NoError(t, err)
+			require.Equal(t, ContentionLow, level)
+
+			for i := range highContention {
+				x, err := NewMutex(db, root, fmt.Sprintf("waiter%d", i))
+				require.NoError(t, err)
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.False(t, acquired)
+
+				level, err := obs.ContentionLevel(db)
+				require.NoError(t, err)
+				if i+1 < highContention {
+					require.Equal(t, ContentionMedium, level)
+				} else {
+					require.Equal(t, ContentionHigh, level)
+				}
+			}
+		},
+		"cached": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner, err := NewMutex(db, root, "owner", WithLeaseTTL(100*time.Millisecond))
+			require.NoError(t, err)
+
+			waiter, err := NewMutex(db, root, "waiter")
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			counter := &attemptCounter{Transactor: db}
+			cache := obs.CacheContention(ctx, counter)
+
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			level, err := cache.Level()
+			require.NoError(t, err)
+			require.Equal(t, ContentionLow, level)
+
+			acquired, err = waiter.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			require.Eventually(t, func() bool {
+				level, err := cache.Level()
+				return err == nil && level == ContentionMedium
+			}, time.Second, 10*time.Millisecond)
+
+			// Heartbeats don't invalidate the cache.
+			reads := counter.attempts.Load()
+			time.Sleep(200 * time.Millisecond)
+			level, err = cache.Level()
+			require.NoError(t, err)
+			require.Equal(t, ContentionMedium, level)
+			require.Equal(t, reads, counter.attempts.Load())
+		},
+	}
+
+	runTests(t, tests)
+}
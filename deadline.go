@@ -0,0 +1,77 @@
+package mutex
+
+import (
+	"context"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// deadlineTransactor wraps a transactor so every transaction times out
+// by the deadline of the context. FDB's timeout spans the retries of a
+// transaction, so an operation returns by the caller's deadline instead of
+// overrunning inside the retry loop. [[Mutex.Acquire]] wraps its transactor
+// when given a context with a deadline.
+type deadlineTransactor struct {
+	fdb.Transactor
+	ctx context.Context
+}
+
+func (t deadlineTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.Transactor.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := t.setTimeout(tr); err != nil {
+			return nil, err
+		}
+		return f(tr)
+	})
+}
+
+func (t deadlineTransactor) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.Transactor.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		if err := t.setTimeout(tr); err != nil {
+			return nil, err
+		}
+		return f(tr)
+	})
+}
+
+// setTimeout sets the transaction's timeout to the time remaining until
+// the deadline. A timeout of zero disables the timeout, so the timeout is
+// at least a millisecond. If the wrapped transactor is subject to a
+// circuit breaker with a shorter timeout, the breaker's timeout is kept.
+func (t deadlineTransactor) setTimeout(tr fdb.ReadTransaction) error {
+	deadline, _ := t.ctx.Deadline()
+	timeout := max(time.Until(deadline), time.Millisecond)
+	if b, ok := t.Transactor.(breakerTransactor); ok && b.b.timeout > 0 && b.b.timeout < timeout {
+		return nil
+	}
+	return tr.Options().SetTimeout(timeout.Milliseconds())
+}
+
+// withDeadline wraps the transactor so its transactions time out by the
+// context's deadline. If the context has no deadline or the transactor
+// is already wrapped, the transactor is returned as is.
+func withDeadline(ctx context.Context, db fdb.Transactor) fdb.Transactor {
+	if _, ok := ctx.Deadline(); !ok {
+		return db
+	}
+	if _, ok := db.(deadlineTransactor); ok {
+		return db
+	}
+	return deadlineTransactor{Transactor: db, ctx: ctx}
+}
+
+// withoutDeadline removes the deadline from the transactor. Work which
+// outlives the call, such as the heartbeat, mustn't be bound by it.
+func withoutDeadline(db fdb.Transactor) fdb.Transactor {
+	if t, ok := db.(deadlineTransactor); ok {
+		return t.Transactor
+	}
+	return db
+}
@@ -0,0 +1,73 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadline(t *testing.T) {
+	tests := map[string]testFn{
+		"wrapping": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "x", WithCircuitBreaker(3, time.Second, time.Second))
+			require.NoError(t, err)
+
+			require.Equal(t, fdb.Transactor(db), withDeadline(context.Background(), db))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			wrapped := withDeadline(ctx, x.withBreaker(db))
+			require.IsType(t, deadlineTransactor{}, wrapped)
+			require.Equal(t, wrapped, withDeadline(ctx, wrapped))
+			require.Equal(t, wrapped, x.withBreaker(wrapped))
+			require.Equal(t, x.withBreaker(db), withoutDeadline(wrapped))
+			require.Equal(t, deadlineTransactor{Transactor: db, ctx: ctx}, withoutBreaker(wrapped))
+		},
+		"expired": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			<-ctx.Done()
+
+			_, err := withDeadline(ctx, db).Transact(func(tr fdb.Transaction) (any, error) {
+				t.Fatal("transaction ran after the deadline")
+				return nil, nil
+			})
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		},
+		"acquire": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "x1", WithLeaseTTL(100*time.Millisecond))
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "x2")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			require.NoError(t, x1.Acquire(ctx, db))
+
+			// The heartbeat isn't bound by the deadline.
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			<-ctx.Done()
+			require.Eventually(t, func() bool {
+				latest, err := x1.getOwner(db)
+				return err == nil && string(latest.hbeat) != string(owner.hbeat)
+			}, time.Second, 10*time.Millisecond)
+
+			// A waiter returns by its deadline.
+			ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err = x2.Acquire(ctx, db)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Less(t, time.Since(start), time.Second)
+		},
+	}
+
+	runTests(t, tests)
+}
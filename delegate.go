@@ -0,0 +1,16 @@
+package mutex
+
+// WithDelegator causes the handle to acquire on behalf of another principal,
+// such as when a gateway or sidecar manages locks for the workloads behind
+// it. The handle's client name identifies the principal, which becomes the
+// effective owner of the mutex, while 'delegator' names the client acting
+// for it. Both are recorded with each acquisition and can be read with
+// [[Observer.OwnerIdentity]]. Either may release the mutex: the principal
+// by its own name, or the delegator through any handle with the delegator's
+// name. The delegation is cleared when the principal loses the mutex. If
+// 'delegator' is blank, the option has no effect.
+func WithDelegator(delegator string) Option {
+	return func(x *Mutex) {
+		x.delegator = delegator
+	}
+}
@@ -0,0 +1,88 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelegator(t *testing.T) {
+	tests := map[string]testFn{
+		"identity": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "workload", WithDelegator("sidecar"))
+			require.NoError(t, err)
+			require.Equal(t, "sidecar", x.Identity().Delegator)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			id, err := obs.OwnerIdentity(db)
+			require.NoError(t, err)
+			require.Equal(t, "workload", id.Name)
+			require.Equal(t, "sidecar", id.Delegator)
+		},
+		"release by delegator": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "workload", WithDelegator("sidecar"))
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			defer func() { _ = x.Release(db) }()
+
+			// Other clients can't release the mutex.
+			other, err := NewMutex(db, root, "other")
+			require.NoError(t, err)
+			require.NoError(t, other.Release(db))
+
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "workload", owner.name)
+
+			sidecar, err := NewMutex(db, root, "sidecar")
+			require.NoError(t, err)
+			require.NoError(t, sidecar.Release(db))
+
+			owner, err = x.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+
+			// The delegation is cleared with the hold.
+			delegator, err := x.getDelegator(db, "workload")
+			require.NoError(t, err)
+			require.Empty(t, delegator)
+		},
+		"release by principal": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "workload", WithDelegator("sidecar"))
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			principal, err := NewMutex(db, root, "workload")
+			require.NoError(t, err)
+			require.NoError(t, principal.Release(db))
+			x.relinquish()
+
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+		},
+		"context": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := ContextWithIdentity(context.Background(), Identity{Name: "workload", Delegator: "sidecar"})
+
+			x, err := NewMutexFromContext(ctx, db, root)
+			require.NoError(t, err)
+			require.Equal(t, "sidecar", x.Identity().Delegator)
+		},
+	}
+	runTests(t, tests)
+}
@@ -0,0 +1,102 @@
+package mutex
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrDenied is returned when a client whose name matches the mutex's
+// denylist tries to acquire it. See [[AdminClient.Deny]].
+var ErrDenied = errors.New("client is denied from acquiring the mutex")
+
+// Deny forbids the clients whose names match 'pattern' from acquiring the
+// mutex, such as the clients of a known-bad build. Patterns use the syntax
+// of [[path.Match]]. The denylist is enforced by the acquire transaction,
+// so denied clients fail with [[ErrDenied]]. Matching clients are removed
+// from the queue so they aren't handed the mutex. Those blocked in
+// [[Mutex.Acquire]] keep waiting until their context ends. A matching owner
+// isn't evicted, see [[AdminClient.ForceRelease]]. The number of clients
+// removed from the queue is returned. If the mutex doesn't exist,
+// [[ErrNotFound]] is returned.
+func (x *AdminClient) Deny(db fdb.Transactor, name, pattern string) (_ int, err error) {
+	defer wrapErr(&err)
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	count, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+		m, err := x.open(tr, name, p)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.setDenied(tr, pattern, true); err != nil {
+			return nil, fmt.Errorf("failed to set denied: %w", err)
+		}
+
+		queue, err := m.getQueue(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get queue: %w", err)
+		}
+		var count int
+		for _, q := range queue {
+			if ok, _ := path.Match(pattern, q.name); !ok {
+				continue
+			}
+			if err := m.removeFromQueue(tr, q.name); err != nil {
+				return nil, fmt.Errorf("failed to remove %s from queue: %w", q.name, err)
+			}
+			count++
+		}
+
+		detail := fmt.Sprintf("denied %s, removed %d clients", pattern, count)
+		if err := x.audit(tr, "Deny", m, detail); err != nil {
+			return nil, err
+		}
+		return count, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.(int), nil
+}
+
+// Allow removes 'pattern' from the mutex's denylist. See [[AdminClient.Deny]].
+// If the mutex doesn't exist, [[ErrNotFound]] is returned.
+func (x *AdminClient) Allow(db fdb.Transactor, name, pattern string) (err error) {
+	defer wrapErr(&err)
+
+	_, err = x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+		m, err := x.open(tr, name, p)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.setDenied(tr, pattern, false); err != nil {
+			return nil, fmt.Errorf("failed to clear denied: %w", err)
+		}
+		return nil, x.audit(tr, "Allow", m, "allowed "+pattern)
+	})
+	return err
+}
+
+// Denylist returns the client-name patterns which are forbidden from
+// acquiring the mutex. See [[AdminClient.Deny]]. If the mutex doesn't
+// exist, [[ErrNotFound]] is returned.
+func (x *AdminClient) Denylist(db fdb.Transactor, name string) (_ []string, err error) {
+	defer wrapErr(&err)
+
+	patterns, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		m, err := x.open(tr, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		return m.getDenylist(tr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patterns.([]string), nil
+}
@@ -0,0 +1,79 @@
+package mutex
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeny(t *testing.T) {
+	tests := map[string]testFn{
+		"enforced": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			owner, err := NewMutex(db, dir, "owner")
+			require.NoError(t, err)
+			bad1, err := NewMutex(db, dir, "build-42-host1")
+			require.NoError(t, err)
+			bad2, err := NewMutex(db, dir, "build-42-host2")
+			require.NoError(t, err)
+			good, err := NewMutex(db, dir, "build-43-host1")
+			require.NoError(t, err)
+
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = bad1.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+
+			_, err = admin.Deny(db, "a", "[")
+			require.Error(t, err)
+
+			removed, err := admin.Deny(db, "a", "build-42-*")
+			require.NoError(t, err)
+			require.Equal(t, 1, removed)
+
+			patterns, err := admin.Denylist(db, "a")
+			require.NoError(t, err)
+			require.Equal(t, []string{"build-42-*"}, patterns)
+
+			_, err = bad2.TryAcquire(db)
+			require.ErrorIs(t, err, ErrDenied)
+
+			acquired, err = good.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			queue, err := owner.getQueue(db)
+			require.NoError(t, err)
+			require.Len(t, queue, 1)
+			require.Equal(t, "build-43-host1", queue[0].name)
+
+			err = admin.Allow(db, "a", "build-42-*")
+			require.NoError(t, err)
+
+			acquired, err = bad2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			log, err := admin.AuditLog(db)
+			require.NoError(t, err)
+			require.Len(t, log, 2)
+			require.Equal(t, "denied build-42-*, removed 1 clients", log[0].Detail)
+			require.Equal(t, "allowed build-42-*", log[1].Detail)
+		},
+	}
+
+	runTests(t, tests)
+}
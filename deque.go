@@ -0,0 +1,185 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// typeDeque marks a subspace as holding a [[Deque]].
+const typeDeque = "deque"
+
+// Deque is a distributed work-stealing deque. Its owner pushes items onto
+// the back and pops them from the back, working on its newest items first,
+// while idle workers steal the oldest items from the front. Items are keyed
+// by versionstamps, so pushes never conflict with each other. Pops & steals
+// read the deque at snapshot isolation and only conflict on the item they
+// take, so the owner & thieves only contend when they race for the same
+// item. A deque is usually paired with a [[Mutex]] electing its owner, but
+// the deque itself doesn't enforce who pushes or pops.
+type Deque struct{ subspace.Subspace }
+
+// NewDeque constructs a deque stored in 'root'. If 'root' holds
+// another kind of primitive, [[ErrWrongType]] is returned.
+func NewDeque(db fdb.Transactor, root subspace.Subspace) (_ *Deque, err error) {
+	defer wrapErr(&err)
+
+	x := kv{Subspace: root}
+	if err := x.claimType(db, typeDeque); err != nil {
+		return nil, err
+	}
+	return &Deque{root}, nil
+}
+
+// Push appends the items to the back of the deque in the given order. Items
+// pushed by a single transaction share a versionstamp, so a transaction may
+// only call this method once.
+func (x *Deque) Push(db fdb.Transactor, items ...[]byte) (err error) {
+	defer wrapErr(&err)
+
+	if len(items) > math.MaxUint16+1 {
+		return fmt.Errorf("can't push more than %d items at once", math.MaxUint16+1)
+	}
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		for i, item := range items {
+			key, err := x.packItemKey(uint16(i))
+			if err != nil {
+				return nil, fmt.Errorf("failed to pack item key: %w", err)
+			}
+			tr.SetVersionstampedKey(key, item)
+		}
+		if len(items) > 0 {
+			tr.Add(x.packVersionKey(), packIncrement())
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// Pop removes & returns the item at the back of the deque, which is
+// the newest item. If the deque is empty, false is returned.
+func (x *Deque) Pop(db fdb.Transactor) (_ []byte, _ bool, err error) {
+	defer wrapErr(&err)
+	return x.take(db, true)
+}
+
+// Steal removes & returns the item at the front of the deque, which
+// is the oldest item. If the deque is empty, false is returned.
+func (x *Deque) Steal(db fdb.Transactor) (_ []byte, _ bool, err error) {
+	defer wrapErr(&err)
+	return x.take(db, false)
+}
+
+// Len returns the number of items in the deque.
+// Claimed items aren't counted. See [[Deque.Claim]].
+func (x *Deque) Len(db fdb.Transactor) (_ int, err error) {
+	defer wrapErr(&err)
+
+	rngItems, err := x.packItemRange()
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack item range: %w", err)
+	}
+	n, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var n int
+		iter := tr.GetRange(rngItems, fdb.RangeOptions{Mode: fdb.StreamingModeWantAll}).Iterator()
+		for iter.Advance() {
+			if _, err := iter.Get(); err != nil {
+				return nil, err
+			}
+			n++
+		}
+		return n, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n.(int), nil
+}
+
+// Watch returns a channel which signals when items are pushed onto the
+// deque, allowing idle workers to wait for work rather than poll. When
+// items are pushed, the channel returns nil. If the watch setup fails
+// or the provided context is canceled, the channel returns an error.
+func (x *Deque) Watch(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packVersionKey(), nil
+	})
+}
+
+// take removes & returns the item at the back of the deque if 'back'
+// is true, otherwise the item at the front. The end of the deque is
+// read at snapshot isolation, so the transaction only conflicts with
+// others which take the same item. Expired claims are returned to the
+// deque first. See [[Deque.Claim]].
+func (x *Deque) take(db fdb.Transactor, back bool) ([]byte, bool, error) {
+	rngItems, err := x.packItemRange()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to pack item range: %w", err)
+	}
+
+	item, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.requeue(tr, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to requeue expired claims: %w", err)
+		}
+
+		opts := fdb.RangeOptions{Limit: 1, Reverse: back}
+		kvs, err := tr.Snapshot().GetRange(rngItems, opts).GetSliceWithError()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read items: %w", err)
+		}
+		if len(kvs) == 0 {
+			return nil, nil
+		}
+
+		key := kvs[0].Key
+		if err := tr.AddReadConflictKey(key); err != nil {
+			return nil, fmt.Errorf("failed to add read conflict: %w", err)
+		}
+		tr.Clear(key)
+		return kvs[0].Value, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if item == nil {
+		return nil, false, nil
+	}
+	return item.([]byte), true, nil
+}
+
+func (x *Deque) packItemRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"items"}))
+}
+
+func (x *Deque) packItemKey(order uint16) (fdb.Key, error) {
+	tup := tuple.Tuple{"items", tuple.IncompleteVersionstamp(order)}
+	return tup.PackWithVersionstamp(x.Bytes())
+}
+
+func (x *Deque) packRequeuedItemKey(vstamp tuple.Versionstamp) fdb.Key {
+	return x.Pack(tuple.Tuple{"items", vstamp})
+}
+
+func (x *Deque) unpackItemKey(key fdb.Key) (tuple.Versionstamp, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[1].(tuple.Versionstamp)
+	if !ok {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple element 1 is not a versionstamp")
+	}
+	return vstamp, nil
+}
+
+func (x *Deque) packVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"version"})
+}
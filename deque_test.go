@@ -0,0 +1,70 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeque(t *testing.T) {
+	tests := map[string]testFn{
+		"pop & steal": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewDeque(db, root)
+			require.NoError(t, err)
+
+			require.NoError(t, x.Push(db, []byte("a"), []byte("b")))
+			require.NoError(t, x.Push(db, []byte("c")))
+
+			n, err := x.Len(db)
+			require.NoError(t, err)
+			require.Equal(t, 3, n)
+
+			// The owner takes the newest item
+			// while thieves take the oldest.
+			item, ok, err := x.Pop(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("c"), item)
+
+			item, ok, err = x.Steal(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("a"), item)
+
+			item, ok, err = x.Pop(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("b"), item)
+
+			_, ok, err = x.Steal(db)
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+
+		"watch": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewDeque(db, root)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			watch := x.Watch(ctx, db)
+			require.NoError(t, x.Push(db, []byte("a")))
+			require.NoError(t, <-watch)
+		},
+
+		"wrong type": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			_, err = NewDeque(db, root)
+			require.ErrorIs(t, err, ErrWrongType)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,158 @@
+package mutex
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrHeld is returned by [[AdminClient.Destroy]] when a mutex it
+// would delete is held or waited for and deletion wasn't forced.
+var ErrHeld = errors.New("mutex is held")
+
+// Destroy releases and deletes every mutex stored beneath the subdirectory
+// at the given path, at any depth, along with the subdirectory itself, for
+// decommissioning an application's entire lock namespace. An empty path
+// deletes every mutex beneath the parent, but not the parent.
+//
+// Unless 'force' is true, nothing is deleted if any of the mutexes are held
+// or have clients waiting in their queues, and the error wraps [[ErrHeld]] &
+// names the mutexes in use. Holders of mutexes deleted by force aren't
+// notified. They discover the loss with
+// their next heartbeat. Combine with [[AdminClient.DryRun]] to see what
+// would be deleted. Each mutex is deleted in its own transaction, deepest
+// first, and audited. The paths of the deleted mutexes are returned. If
+// the subdirectory doesn't exist, [[ErrNotFound]] is returned.
+func (x *AdminClient) Destroy(db fdb.Transactor, force bool, path ...string) (_ [][]string, err error) {
+	defer wrapErr(&err)
+
+	ok, err := x.parent.Exists(db, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for subdirectory: %w", err)
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	paths, err := x.listPaths(db, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > 0 {
+		paths = append(paths, path)
+	}
+	slices.SortStableFunc(paths, func(a, b []string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+
+	if !force {
+		held, err := x.heldPaths(db, paths)
+		if err != nil {
+			return nil, err
+		}
+		if len(held) > 0 {
+			return nil, fmt.Errorf("%w: %v", ErrHeld, held)
+		}
+	}
+
+	var deleted [][]string
+	for _, path := range paths {
+		lock, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+			m, err := x.openPath(tr, path, p)
+			if errors.Is(err, ErrNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			owner, err := m.getOwner(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get owner: %w", err)
+			}
+			if !force {
+				used, err := m.inUse(tr, owner)
+				if err != nil {
+					return nil, err
+				}
+				if used {
+					return nil, ErrHeld
+				}
+			}
+			detail := "destroyed mutex"
+			if owner.name != "" {
+				detail = fmt.Sprintf("destroyed mutex held by %s", owner.name)
+			}
+			if err := x.audit(tr, "Destroy", m, detail); err != nil {
+				return nil, err
+			}
+			if _, err := x.parent.Remove(tr, path); err != nil {
+				return nil, fmt.Errorf("failed to remove subdirectory: %w", err)
+			}
+			return lockPath(m), nil
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to destroy %v: %w", path, err)
+		}
+		if lock != nil {
+			deleted = append(deleted, lock.([]string))
+		}
+	}
+
+	// Remove the subdirectory even if it
+	// doesn't hold a mutex of its own.
+	if len(path) > 0 && !x.dryRun {
+		_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+			return x.parent.Remove(tr, path)
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to remove %v: %w", path, err)
+		}
+	}
+	return deleted, nil
+}
+
+// heldPaths returns the paths of the mutexes which are held or have
+// clients waiting in their queues. Paths which don't hold a mutex are
+// ignored.
+func (x *AdminClient) heldPaths(db fdb.Transactor, paths [][]string) ([][]string, error) {
+	var held [][]string
+	for _, path := range paths {
+		used, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+			m, err := x.openPath(tr, path, nil)
+			if errors.Is(err, ErrNotFound) {
+				return false, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			owner, err := m.getOwner(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get owner: %w", err)
+			}
+			return m.inUse(tr, owner)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %v: %w", path, err)
+		}
+		if used.(bool) {
+			held = append(held, path)
+		}
+	}
+	return held, nil
+}
+
+// inUse returns true if the mutex has the given owner
+// or any client is waiting in its queue.
+func (x *kv) inUse(tr fdb.Transaction, owner ownerKV) (bool, error) {
+	if owner.name != "" {
+		return true, nil
+	}
+	queued, err := x.hasCandidates(tr)
+	if err != nil {
+		return false, fmt.Errorf("failed to check queue: %w", err)
+	}
+	return queued, nil
+}
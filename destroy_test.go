@@ -0,0 +1,105 @@
+package mutex
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestroy(t *testing.T) {
+	tests := map[string]testFn{
+		"prefix": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"app", "a"}, nil)
+			require.NoError(t, err)
+			dirB, err := parent.CreateOrOpen(db, []string{"app", "team", "b"}, nil)
+			require.NoError(t, err)
+			dirC, err := parent.CreateOrOpen(db, []string{"other", "c"}, nil)
+			require.NoError(t, err)
+
+			a, err := NewMutex(db, dirA, "client1")
+			require.NoError(t, err)
+			_, err = NewMutex(db, dirB, "client1")
+			require.NoError(t, err)
+			_, err = NewMutex(db, dirC, "client1")
+			require.NoError(t, err)
+
+			acquired, err := a.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+
+			// Held mutexes must be confirmed.
+			_, err = admin.Destroy(db, false, "app")
+			require.ErrorIs(t, err, ErrHeld)
+			ok, err := parent.Exists(db, []string{"app", "team", "b"})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			dry := admin.DryRun()
+			deleted, err := dry.Destroy(db, true, "app")
+			require.NoError(t, err)
+			require.Equal(t, [][]string{dirB.GetPath(), dirA.GetPath()}, deleted)
+			require.NotEmpty(t, dry.Changes())
+			ok, err = parent.Exists(db, []string{"app"})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			deleted, err = admin.Destroy(db, true, "app")
+			require.NoError(t, err)
+			require.Equal(t, [][]string{dirB.GetPath(), dirA.GetPath()}, deleted)
+
+			ok, err = parent.Exists(db, []string{"app"})
+			require.NoError(t, err)
+			require.False(t, ok)
+			ok, err = parent.Exists(db, []string{"other", "c"})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			log, err := admin.AuditLog(db)
+			require.NoError(t, err)
+			require.Len(t, log, 2)
+			require.Equal(t, "destroyed mutex held by client1", log[1].Detail)
+
+			_, err = admin.Destroy(db, true, "app")
+			require.ErrorIs(t, err, ErrNotFound)
+		},
+		"queued": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dir, err := parent.CreateOrOpen(db, []string{"app"}, nil)
+			require.NoError(t, err)
+			x, err := NewMutex(db, dir, "client1")
+			require.NoError(t, err)
+
+			// A vacant mutex with a waiting
+			// client is still in use.
+			err = x.enqueue(db, "client2", 0)
+			require.NoError(t, err)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+
+			_, err = admin.Destroy(db, false, "app")
+			require.ErrorIs(t, err, ErrHeld)
+			ok, err := parent.Exists(db, []string{"app"})
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			_, err = x.dequeue(db)
+			require.NoError(t, err)
+
+			deleted, err := admin.Destroy(db, false, "app")
+			require.NoError(t, err)
+			require.Equal(t, [][]string{dir.GetPath()}, deleted)
+		},
+	}
+
+	runTests(t, tests)
+}
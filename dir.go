@@ -0,0 +1,97 @@
+package mutex
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+)
+
+// partitionLayer is the layer which marks a directory as a partition.
+var partitionLayer = []byte("partition")
+
+// DirOptions configures the directory created by [[NewMutexAt]].
+type DirOptions struct {
+	// Layer is the layer of the mutex's directory. If the
+	// directory exists, its layer must match.
+	Layer []byte
+
+	// Prefix, if not nil, is the key prefix used when creating the
+	// mutex's directory. The directory layer must allow manual
+	// prefixes, which the default root & partitions don't.
+	Prefix []byte
+
+	// PartitionAt, if positive, is the number of leading path
+	// elements which name a directory partition. For instance,
+	// with the path ["app", "locks", "job"] and a PartitionAt of
+	// 2, the directory ["app", "locks"] is created as a partition
+	// and the mutex is stored within it. The mutex itself can't
+	// be stored at the root of a partition.
+	PartitionAt int
+}
+
+// NewMutexAt is like [[NewMutex]] but stores the mutex in the directory at
+// 'path' beneath 'parent', creating the directory and any missing parent
+// directories using the given options. This allows the mutex to be placed
+// inside the directory partitions of an application.
+func NewMutexAt(db fdb.Transactor, parent directory.Directory, path []string, name string, dirOpts DirOptions, opts ...Option) (_ *Mutex, err error) {
+	defer wrapErr(&err)
+
+	dir, err := openDir(db, parent, path, dirOpts)
+	if err != nil {
+		return nil, err
+	}
+	return NewMutex(db, dir, name, opts...)
+}
+
+// openDir creates or opens the directory at 'path' beneath 'parent'.
+func openDir(db fdb.Transactor, parent directory.Directory, path []string, o DirOptions) (directory.DirectorySubspace, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("directory path is empty")
+	}
+	if o.PartitionAt < 0 || o.PartitionAt >= len(path) {
+		return nil, fmt.Errorf("partition depth %d is out of range for path of length %d", o.PartitionAt, len(path))
+	}
+	if bytes.Equal(o.Layer, partitionLayer) {
+		return nil, fmt.Errorf("a mutex can't be stored at the root of a partition")
+	}
+
+	dir, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if o.PartitionAt > 0 {
+			_, err := parent.CreateOrOpen(tr, path[:o.PartitionAt], partitionLayer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create partition: %w", err)
+			}
+		}
+
+		if o.Prefix == nil {
+			dir, err := parent.CreateOrOpen(tr, path, o.Layer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create directory: %w", err)
+			}
+			return dir, nil
+		}
+
+		exists, err := parent.Exists(tr, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for directory: %w", err)
+		}
+		if exists {
+			dir, err := parent.Open(tr, path, o.Layer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open directory: %w", err)
+			}
+			return dir, nil
+		}
+		dir, err := parent.CreatePrefix(tr, path, o.Layer, o.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create directory with prefix: %w", err)
+		}
+		return dir, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dir.(directory.DirectorySubspace), nil
+}
@@ -0,0 +1,57 @@
+package mutex
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMutexAt(t *testing.T) {
+	tests := map[string]testFn{
+		"nested": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+			path := []string{"app", "locks", "job"}
+
+			x, err := NewMutexAt(db, parent, path, "client", DirOptions{Layer: []byte("mutex")})
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			dir, err := parent.Open(db, path, []byte("mutex"))
+			require.NoError(t, err)
+			require.Equal(t, dir.Bytes(), x.Bytes())
+
+			// The existing directory is reused, but
+			// its layer must match.
+			_, err = NewMutexAt(db, parent, path, "client2", DirOptions{Layer: []byte("mutex")})
+			require.NoError(t, err)
+			_, err = NewMutexAt(db, parent, path, "client2", DirOptions{})
+			require.Error(t, err)
+		},
+		"partition": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+			path := []string{"app", "locks", "job"}
+
+			x, err := NewMutexAt(db, parent, path, "client", DirOptions{PartitionAt: 2})
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			part, err := parent.Open(db, path[:2], nil)
+			require.NoError(t, err)
+			require.Equal(t, []byte("partition"), part.GetLayer())
+
+			_, err = NewMutexAt(db, parent, path, "client", DirOptions{PartitionAt: 3})
+			require.Error(t, err)
+		},
+	}
+
+	runTests(t, tests)
+}
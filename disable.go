@@ -0,0 +1,35 @@
+package mutex
+
+import (
+	"errors"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrDisabled is returned when acquiring a mutex which was
+// disabled by [[Mutex.Disable]].
+var ErrDisabled = errors.New("mutex is disabled")
+
+// Disable takes the pathway protected by the mutex out of service without
+// deleting the mutex. While disabled, [[Mutex.Acquire]] & [[Mutex.TryAcquire]]
+// fail with [[ErrDisabled]]. The current owner isn't affected, but when it
+// releases the mutex, the mutex is left vacant and the clients waiting in
+// the queue fail with ErrDisabled. The flag is stored with the mutex, so
+// every client honors it.
+func (x *Mutex) Disable(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	return x.setDisabled(x.withBreaker(db), true)
+}
+
+// Enable undoes [[Mutex.Disable]]. If the mutex is vacant,
+// it's handed to the next client in the queue.
+func (x *Mutex) Enable(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	return x.setDisabled(x.withBreaker(db), false)
+}
+
+// Disabled returns true if the mutex was disabled by [[Mutex.Disable]].
+func (x *Mutex) Disabled(db fdb.Transactor) (_ bool, err error) {
+	defer wrapErr(&err)
+	return x.getDisabled(x.withBreaker(db))
+}
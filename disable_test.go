@@ -0,0 +1,76 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisable(t *testing.T) {
+	tests := map[string]testFn{
+		"rejected": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner, err := NewMutex(db, root, "owner")
+			require.NoError(t, err)
+
+			waiter, err := NewMutex(db, root, "waiter")
+			require.NoError(t, err)
+
+			other, err := NewMutex(db, root, "other")
+			require.NoError(t, err)
+
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- waiter.Acquire(ctx, db) }()
+
+			require.Eventually(t, func() bool {
+				queued, err := owner.isQueued(db, "waiter")
+				return err == nil && queued
+			}, time.Second, 10*time.Millisecond)
+
+			err = owner.Disable(db)
+			require.NoError(t, err)
+
+			disabled, err := other.Disabled(db)
+			require.NoError(t, err)
+			require.True(t, disabled)
+
+			_, err = other.TryAcquire(db)
+			require.ErrorIs(t, err, ErrDisabled)
+
+			// The holder isn't affected.
+			err = owner.Validate(db, 0)
+			require.NoError(t, err)
+
+			// Once the holder releases, the waiter gives up.
+			err = owner.Release(db)
+			require.NoError(t, err)
+			require.ErrorIs(t, <-done, ErrDisabled)
+
+			queue, err := owner.getQueue(db)
+			require.NoError(t, err)
+			require.Empty(t, queue)
+
+			current, err := owner.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, current.name)
+
+			err = owner.Enable(db)
+			require.NoError(t, err)
+
+			acquired, err = other.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,137 @@
+package mutex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+)
+
+// WriteDOT renders the lock topology of the mutexes stored in the immediate
+// subdirectories of 'parent' as a Graphviz DOT graph, for visualizing lock
+// dependencies during design reviews & incidents. Mutexes are drawn as boxes
+// and clients as ellipses. Each mutex points to its owner, each waiting
+// client points to the mutexes it waits for, labeled with its position in
+// the queue, and bold red wait-for edges point from each waiting client to
+// the owner it waits on. A cycle of wait-for edges is a deadlock. Like
+// [[List]], the mutexes are read in a single transaction.
+func WriteDOT(w io.Writer, db fdb.Transactor, parent directory.Directory) (err error) {
+	defer wrapErr(&err)
+
+	type lockDOT struct {
+		id    string
+		owner string
+		queue []string
+	}
+
+	locks, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		names, err := parent.List(tr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subdirectories: %w", err)
+		}
+
+		var locks []lockDOT
+		for _, name := range names {
+			dir, err := parent.Open(tr, []string{name}, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open subdirectory %s: %w", name, err)
+			}
+
+			x := kv{Subspace: dir}
+			isMutex, err := x.isMutex(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check for mutex in %s: %w", name, err)
+			}
+			if !isMutex {
+				continue
+			}
+
+			owner, err := x.getOwner(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get owner of %s: %w", name, err)
+			}
+			queue, err := x.getQueue(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get queue of %s: %w", name, err)
+			}
+
+			l := lockDOT{id: lockID(dir), owner: owner.name}
+			for _, q := range queue {
+				l.queue = append(l.queue, q.name)
+			}
+			locks = append(locks, l)
+		}
+		return locks, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		clients []string
+		waits   = make(map[[2]string]bool)
+	)
+	for _, l := range locks.([]lockDOT) {
+		if l.owner != "" {
+			clients = append(clients, l.owner)
+		}
+		clients = append(clients, l.queue...)
+		for _, q := range l.queue {
+			if l.owner != "" && q != l.owner {
+				waits[[2]string{q, l.owner}] = true
+			}
+		}
+	}
+	slices.Sort(clients)
+	clients = slices.Compact(clients)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph locks {")
+	for _, l := range locks.([]lockDOT) {
+		fmt.Fprintf(bw, "\t%s [shape=box, label=%s];\n", dotID("mutex", l.id), dotQuote(l.id))
+	}
+	for _, c := range clients {
+		fmt.Fprintf(bw, "\t%s [shape=ellipse, label=%s];\n", dotID("client", c), dotQuote(c))
+	}
+	for _, l := range locks.([]lockDOT) {
+		if l.owner != "" {
+			fmt.Fprintf(bw, "\t%s -> %s [label=\"held by\"];\n", dotID("mutex", l.id), dotID("client", l.owner))
+		}
+		for i, q := range l.queue {
+			fmt.Fprintf(bw, "\t%s -> %s [style=dashed, label=\"waiting #%d\"];\n", dotID("client", q), dotID("mutex", l.id), i+1)
+		}
+	}
+
+	edges := make([][2]string, 0, len(waits))
+	for e := range waits {
+		edges = append(edges, e)
+	}
+	slices.SortFunc(edges, func(a, b [2]string) int {
+		return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1])
+	})
+	for _, e := range edges {
+		fmt.Fprintf(bw, "\t%s -> %s [color=red, style=bold, label=\"waits for\"];\n", dotID("client", e[0]), dotID("client", e[1]))
+	}
+	fmt.Fprintln(bw, "}")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	return nil
+}
+
+// dotID returns a quoted node ID which is unique across
+// kinds of nodes, as a client & mutex may share a name.
+func dotID(kind, name string) string {
+	return dotQuote(kind + ":" + name)
+}
+
+// dotQuote returns a DOT string literal holding 's'.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
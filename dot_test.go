@@ -0,0 +1,65 @@
+package mutex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDotQuote(t *testing.T) {
+	require.Equal(t, `"a"`, dotQuote("a"))
+	require.Equal(t, `"a\"b\\c"`, dotQuote(`a"b\c`))
+}
+
+func TestWriteDOT(t *testing.T) {
+	tests := map[string]testFn{
+		"deadlock": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+
+			// client1 holds 'a' & waits for 'b' while
+			// client2 holds 'b' & waits for 'a'.
+			for _, step := range []struct {
+				dir      directory.DirectorySubspace
+				name     string
+				acquired bool
+			}{
+				{dirA, "client1", true},
+				{dirB, "client2", true},
+				{dirA, "client2", false},
+				{dirB, "client1", false},
+			} {
+				x, err := NewMutex(db, step.dir, step.name)
+				require.NoError(t, err)
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.Equal(t, step.acquired, acquired)
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, WriteDOT(&buf, db, parent))
+
+			a, b := dotID("mutex", lockID(dirA)), dotID("mutex", lockID(dirB))
+			c1, c2 := dotID("client", "client1"), dotID("client", "client2")
+
+			out := buf.String()
+			require.Contains(t, out, a+" [shape=box")
+			require.Contains(t, out, c1+" [shape=ellipse")
+			require.Contains(t, out, a+" -> "+c1+` [label="held by"]`)
+			require.Contains(t, out, c2+" -> "+a+` [style=dashed, label="waiting #1"]`)
+			require.Contains(t, out, b+" -> "+c2+` [label="held by"]`)
+			require.Contains(t, out, c1+" -> "+c2+" [color=red")
+			require.Contains(t, out, c2+" -> "+c1+" [color=red")
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,107 @@
+package mutex
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// Operation is a unit of work performed on behalf of a hold of the mutex,
+// tagged with the hold's fencing epoch. Operations are tracked so a cutover
+// can wait for the work of earlier holds to finish. See
+// [[Mutex.WaitForEpochDrain]].
+type Operation struct {
+	// Epoch is the fencing epoch of the hold
+	// which began the operation.
+	Epoch int64
+
+	// ID distinguishes the operations of an epoch.
+	ID string
+}
+
+// BeginOperation records the start of an operation tagged with the current
+// fencing epoch. Each operation must be finished by calling
+// [[Mutex.CompleteOperation]], even if the mutex was lost in the meantime.
+// If this client doesn't own the mutex, [[ErrNotOwner]] is returned.
+func (x *Mutex) BeginOperation(db fdb.Transactor) (_ Operation, err error) {
+	defer wrapErr(&err)
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return Operation{}, fmt.Errorf("failed to generate an operation ID: %w", err)
+	}
+
+	op, err := x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+		epoch, err := x.getEpoch(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get epoch: %w", err)
+		}
+		op := Operation{Epoch: epoch, ID: hex.EncodeToString(id[:])}
+		return op, x.beginOperation(tr, op, x.name)
+	})
+	if err != nil {
+		return Operation{}, err
+	}
+	return op.(Operation), nil
+}
+
+// CompleteOperation marks an operation started by [[Mutex.BeginOperation]]
+// as complete. Any client may complete an operation, so work handed to
+// another process may be completed there. Completing an operation twice
+// has no effect.
+func (x *Mutex) CompleteOperation(db fdb.Transactor, op Operation) (err error) {
+	defer wrapErr(&err)
+	return x.completeOperation(x.withBreaker(db), op)
+}
+
+// WaitForEpochDrain blocks until every operation tagged with a fencing
+// epoch older than 'epoch' has been completed, or the context ends. After
+// a forced change of ownership, such as [[ReleaseAllOwnedBy]], passing the
+// new epoch waits out the work still in flight on behalf of earlier holds.
+// Operations which are never completed block this method indefinitely.
+func (x *Mutex) WaitForEpochDrain(ctx context.Context, db fdb.Transactor, epoch int64) (err error) {
+	defer wrapErr(&err)
+	return x.waitForEpochDrain(ctx, x.withBreaker(db), epoch)
+}
+
+// WaitForEpochDrain blocks until every operation tagged with a fencing epoch
+// older than 'epoch' has been completed. See [[Mutex.WaitForEpochDrain]].
+func (x *Observer) WaitForEpochDrain(ctx context.Context, db fdb.Transactor, epoch int64) (err error) {
+	defer wrapErr(&err)
+	return x.waitForEpochDrain(ctx, db, epoch)
+}
+
+func (x *kv) waitForEpochDrain(ctx context.Context, db fdb.Transactor, epoch int64) error {
+	for {
+		// Check for operations & watch for a completion
+		// atomically so the last one isn't missed.
+		var drained bool
+		watch := watch(ctx, db, func(tr fdb.Transaction) (fdb.Key, error) {
+			pending, err := x.hasOperationsBefore(tr, epoch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get operations: %w", err)
+			}
+			drained = !pending
+			if drained {
+				return nil, nil
+			}
+			return x.packOperationVersionKey(), nil
+		})
+
+		if err := <-watch; err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to watch operations: %w", err)
+		}
+		if drained {
+			return nil
+		}
+	}
+}
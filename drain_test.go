@@ -0,0 +1,81 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEpochDrain(t *testing.T) {
+	tests := map[string]testFn{
+		"drained": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "x1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "x2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			op, err := x1.BeginOperation(db)
+			require.NoError(t, err)
+
+			// Take the mutex away from x1 while
+			// its operation is in flight.
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				return x1.evict(tr, "x1")
+			})
+			require.NoError(t, err)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			epoch, err := x2.getEpoch(db)
+			require.NoError(t, err)
+			require.Greater(t, epoch, op.Epoch)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() { done <- x2.WaitForEpochDrain(ctx, db, epoch) }()
+
+			select {
+			case err := <-done:
+				t.Fatalf("returned before the operation completed: %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			err = x1.CompleteOperation(db, op)
+			require.NoError(t, err)
+			require.NoError(t, <-done)
+		},
+		"current epoch": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "x")
+			require.NoError(t, err)
+
+			_, err = x.BeginOperation(db)
+			require.ErrorIs(t, err, ErrNotOwner)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			op, err := x.BeginOperation(db)
+			require.NoError(t, err)
+
+			// Operations of the given epoch aren't waited on.
+			err = x.WaitForEpochDrain(context.Background(), db, op.Epoch)
+			require.NoError(t, err)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,175 @@
+package mutex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// errDryRun aborts the transactions of a dry run.
+var errDryRun = errors.New("dry run")
+
+// Change describes a key which an admin operation modified,
+// or would modify during a dry run. See [[AdminClient.DryRun]].
+type Change struct {
+	// Key is the modified key.
+	Key fdb.Key
+
+	// Before is the value prior to the operation.
+	// It's nil if the key didn't exist.
+	Before []byte
+
+	// After is the value following the operation.
+	// It's nil if the key is cleared.
+	After []byte
+}
+
+// DryRun returns a copy of the admin client whose operations are never
+// committed. Each operation returns what it would have returned and the
+// keys it would have modified are recorded for [[AdminClient.Changes]].
+// Entries appended to logs, such as the event log and the audit log, are
+// keyed by versionstamps which aren't assigned until commit, so they're
+// not reported. The transactor must not be an [[fdb.Transaction]],
+// since its writes can't be discarded.
+func (x *AdminClient) DryRun() *AdminClient {
+	return &AdminClient{parent: x.parent, operator: x.operator, dryRun: true}
+}
+
+// Changes returns the changes recorded by the operations of a dry run,
+// in the order they were performed. See [[AdminClient.DryRun]].
+func (x *AdminClient) Changes() []Change {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return slices.Clone(x.changes)
+}
+
+// transact runs the function in a transaction. During a dry
+// run, the function is given a plan for recording the mutexes
+// it modifies and the transaction is aborted once the changes
+// have been recorded. Otherwise, the plan is nil.
+func (x *AdminClient) transact(db fdb.Transactor, fn func(fdb.Transaction, *plan) (any, error)) (any, error) {
+	if !x.dryRun {
+		return db.Transact(func(tr fdb.Transaction) (any, error) {
+			return fn(tr, nil)
+		})
+	}
+	if _, ok := db.(fdb.Transaction); ok {
+		return nil, fmt.Errorf("dry run requires a database")
+	}
+
+	var (
+		ret     any
+		changes []Change
+	)
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		p := &plan{}
+		r, err := fn(tr, p)
+		if err != nil {
+			return nil, err
+		}
+		if changes, err = p.diff(tr); err != nil {
+			return nil, err
+		}
+		ret = r
+		return nil, errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.changes = append(x.changes, changes...)
+	return ret, nil
+}
+
+// plan records the state of the subspaces a dry
+// run may modify so the changes can be determined.
+type plan struct {
+	rngs   []fdb.KeyRange
+	before []map[string][]byte
+}
+
+// watch records the current state of the subspace. If
+// the plan is nil, as when not dry running, it's a noop.
+func (p *plan) watch(tr fdb.Transaction, ss subspace.Subspace) error {
+	if p == nil {
+		return nil
+	}
+	rng, err := fdb.PrefixRange(ss.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to pack subspace range: %w", err)
+	}
+	state, err := readState(tr, rng)
+	if err != nil {
+		return err
+	}
+	p.rngs = append(p.rngs, rng)
+	p.before = append(p.before, state)
+	return nil
+}
+
+// watchChild is like [[plan.watch]] for the subdirectory
+// of 'parent' with the provided name, if it exists.
+func (p *plan) watchChild(tr fdb.Transaction, parent directory.Directory, name string) error {
+	if p == nil {
+		return nil
+	}
+	dir, err := parent.Open(tr, []string{name}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open subdirectory: %w", err)
+	}
+	return p.watch(tr, dir)
+}
+
+// diff compares the recorded state with the state
+// visible to the transaction, which includes its
+// own writes. The changes are sorted by key.
+func (p *plan) diff(tr fdb.Transaction) ([]Change, error) {
+	var changes []Change
+	for i, rng := range p.rngs {
+		before := p.before[i]
+		after, err := readState(tr, rng)
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range after {
+			if prev, ok := before[key]; !ok || !bytes.Equal(prev, val) {
+				changes = append(changes, Change{Key: fdb.Key(key), Before: prev, After: val})
+			}
+		}
+		for key, prev := range before {
+			if _, ok := after[key]; !ok {
+				changes = append(changes, Change{Key: fdb.Key(key), Before: prev})
+			}
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int {
+		return bytes.Compare(a.Key, b.Key)
+	})
+	return changes, nil
+}
+
+// readState reads every key in the range.
+func readState(tr fdb.Transaction, rng fdb.KeyRange) (map[string][]byte, error) {
+	kvs, err := tr.GetRange(rng, fdb.RangeOptions{}).GetSliceWithError()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read range: %w", err)
+	}
+	state := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		// Empty values are kept non-nil so they're
+		// distinguished from cleared keys.
+		val := kv.Value
+		if val == nil {
+			val = []byte{}
+		}
+		state[string(kv.Key)] = val
+	}
+	return state, nil
+}
@@ -0,0 +1,164 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrorClass categorizes an error so callers can decide whether to
+// retry, ignore, or alarm without inspecting FDB error codes.
+type ErrorClass int
+
+const (
+	// ClassFatal errors won't succeed if retried.
+	ClassFatal ErrorClass = iota
+
+	// ClassRetryable errors are transient and the
+	// operation may succeed if retried.
+	ClassRetryable
+
+	// ClassConflict errors are caused by a concurrent
+	// transaction. The operation may succeed if retried.
+	ClassConflict
+
+	// ClassCancelled errors are caused by the caller
+	// cancelling the operation, usually via a context.
+	ClassCancelled
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassRetryable:
+		return "retryable"
+	case ClassConflict:
+		return "conflict"
+	case ClassCancelled:
+		return "cancelled"
+	default:
+		return "fatal"
+	}
+}
+
+// Error is returned by the exported methods of this package.
+// It wraps the underlying error with its classification.
+type Error struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the classification of the given error. Errors
+// returned by this package are already classified. Other errors
+// are classified by inspecting their chain for FDB errors.
+func Classify(err error) ErrorClass {
+	var merr *Error
+	if errors.As(err, &merr) {
+		return merr.Class
+	}
+	return classify(err)
+}
+
+// IsRetryable returns true if the given error is transient, meaning
+// it's classified as either [[ClassRetryable]] or [[ClassConflict]].
+func IsRetryable(err error) bool {
+	switch Classify(err) {
+	case ClassRetryable, ClassConflict:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsCancelled returns true if the given error is classified as [[ClassCancelled]].
+func IsCancelled(err error) bool {
+	return Classify(err) == ClassCancelled
+}
+
+// wrapErr classifies the error pointed to by 'err' and wraps it in an [[Error]].
+// It's meant to be deferred by exported methods. Nil errors are left untouched.
+func wrapErr(err *error) {
+	if *err == nil {
+		return
+	}
+	if _, ok := (*err).(*Error); ok {
+		return
+	}
+	*err = &Error{Class: Classify(*err), Err: *err}
+}
+
+func classify(err error) ErrorClass {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ClassCancelled
+	}
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrThrottled) || errors.Is(err, ErrRateLimited) {
+		return ClassRetryable
+	}
+
+	var ferr fdb.Error
+	if !errors.As(err, &ferr) {
+		return ClassFatal
+	}
+
+	// See https://apple.github.io/foundationdb/api-error-codes.html
+	switch ferr.Code {
+	case 1020: // not_committed
+		return ClassConflict
+
+	case 1025, // transaction_cancelled
+		1101: // operation_cancelled
+		return ClassCancelled
+
+	case 1004, // timed_out
+		1007, // transaction_too_old
+		1009, // future_version
+		1021, // commit_unknown_result
+		1031, // transaction_timed_out
+		1037, // process_behind
+		1039, // cluster_version_changed
+		1042, // proxy_memory_limit_exceeded
+		1078, // grv_proxy_memory_limit_exceeded
+		1213: // tag_throttled
+		return ClassRetryable
+
+	default:
+		return ClassFatal
+	}
+}
+
+// AcquireError is returned by [[Mutex.Acquire]] when it fails or the
+// context ends before the mutex is acquired. It describes the state
+// of the mutex when the client stopped waiting.
+type AcquireError struct {
+	// Owner is the last known owner of the mutex.
+	Owner string
+
+	// Position is the client's last known position in the
+	// queue, starting at 0. It's -1 if the position is unknown.
+	Position int
+
+	// Waited is how long the client waited.
+	Waited time.Duration
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *AcquireError) Error() string {
+	return fmt.Sprintf("failed to acquire after %v (owner %q, queue position %d): %v",
+		e.Waited.Round(time.Millisecond), e.Owner, e.Position, e.Err)
+}
+
+func (e *AcquireError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,40 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]struct {
+		err   error
+		class ErrorClass
+	}{
+		"unknown":   {errors.New("oops"), ClassFatal},
+		"conflict":  {fdb.Error{Code: 1020}, ClassConflict},
+		"cancelled": {fdb.Error{Code: 1101}, ClassCancelled},
+		"context":   {context.Canceled, ClassCancelled},
+		"retryable": {fdb.Error{Code: 1007}, ClassRetryable},
+		"fatal":     {fdb.Error{Code: 2000}, ClassFatal},
+		"wrapped":   {fmt.Errorf("failed: %w", fdb.Error{Code: 1020}), ClassConflict},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.class, Classify(test.err))
+
+			err := test.err
+			wrapErr(&err)
+
+			var merr *Error
+			require.ErrorAs(t, err, &merr)
+			require.Equal(t, test.class, merr.Class)
+			require.ErrorIs(t, err, test.err)
+		})
+	}
+}
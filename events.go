@@ -0,0 +1,104 @@
+package mutex
+
+import (
+	"context"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// maxEvents is the number of events retained in a mutex's event log.
+const maxEvents = 100
+
+// EventKind describes what happened to a mutex.
+type EventKind int
+
+const (
+	// EventAcquired means a client became the owner.
+	EventAcquired EventKind = iota
+
+	// EventReleased means the owner released the mutex.
+	EventReleased
+
+	// EventExpired means the owner stopped heartbeating and
+	// lost the mutex. See [[Mutex.AutoRelease]].
+	EventExpired
+
+	// EventIdle means the mutex has been idle for too long
+	// and will be deleted once its grace period ends unless
+	// it's used in the meantime. See [[ExpireIdle]].
+	EventIdle
+
+	// EventEvicted means an operator removed the owner from
+	// the mutex. See [[ReleaseAllOwnedBy]].
+	EventEvicted
+
+	// EventDegraded means the owner's heartbeats started failing
+	// or became slow, putting the hold at risk. The owner still
+	// holds the mutex. See [[WithHeartbeatQoS]].
+	EventDegraded
+
+	// EventHot means the mutex's contention exceeded the thresholds
+	// of a scanner. The client is the owner at the time, if any. See
+	// [[WithHotLockDetection]].
+	EventHot
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventAcquired:
+		return "acquired"
+	case EventReleased:
+		return "released"
+	case EventExpired:
+		return "expired"
+	case EventIdle:
+		return "idle"
+	case EventEvicted:
+		return "evicted"
+	case EventDegraded:
+		return "degraded"
+	case EventHot:
+		return "hot"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change of ownership. Events are stored in the
+// mutex's subspace and are available to any client. Only the latest
+// 100 events are retained.
+type Event struct {
+	// Mutex identifies the mutex. See [[ClientSession.Locks]].
+	Mutex string
+
+	// Kind describes what happened.
+	Kind EventKind
+
+	// Client is the name of the client which acquired,
+	// released, or lost the mutex.
+	Client string
+
+	// Time is when the event occurred, according
+	// to the clock of the client which logged it.
+	Time time.Time
+
+	// Version orders the events of the mutex.
+	Version tuple.Versionstamp
+}
+
+// Events returns the retained events of the mutex, oldest first.
+func (x *Mutex) Events(db fdb.Transactor) (_ []Event, err error) {
+	defer wrapErr(&err)
+	return x.getEvents(x.withBreaker(db), nil)
+}
+
+// StreamEvents calls 'fn' for each event which occurs after this method is
+// called, in order. This method blocks until the context is cancelled or an
+// error occurs. If 'fn' blocks, the stream falls behind and events which are
+// no longer retained are skipped.
+func (x *Mutex) StreamEvents(ctx context.Context, db fdb.Transactor, fn func(Event)) (err error) {
+	defer wrapErr(&err)
+	return x.streamEvents(ctx, x.withBreaker(db), fn)
+}
@@ -0,0 +1,102 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	tests := map[string]testFn{
+		"history": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// Releasing hands the mutex to client2
+			// in the same transaction.
+			err = x1.Release(db)
+			require.NoError(t, err)
+
+			events, err := x1.Events(db)
+			require.NoError(t, err)
+			require.Len(t, events, 3)
+
+			require.Equal(t, EventAcquired, events[0].Kind)
+			require.Equal(t, "client1", events[0].Client)
+			require.Equal(t, EventReleased, events[1].Kind)
+			require.Equal(t, "client1", events[1].Client)
+			require.Equal(t, EventAcquired, events[2].Kind)
+			require.Equal(t, "client2", events[2].Client)
+		},
+		"expired": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Stop heartbeating so auto release is triggered.
+			x1.stopBeating()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			goAutoRelease(t, x2, ctx, db, 500*time.Millisecond)
+
+			err = x2.Acquire(context.Background(), db)
+			require.NoError(t, err)
+
+			events, err := x2.Events(db)
+			require.NoError(t, err)
+			require.Len(t, events, 3)
+			require.Equal(t, EventExpired, events[1].Kind)
+			require.Equal(t, "client1", events[1].Client)
+		},
+		"stream": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ch := make(chan Event, 2)
+			go func() {
+				_ = x.StreamEvents(ctx, db, func(event Event) { ch <- event })
+			}()
+
+			// Give the stream time to start.
+			time.Sleep(100 * time.Millisecond)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = x.Release(db)
+			require.NoError(t, err)
+
+			require.Equal(t, EventAcquired, (<-ch).Kind)
+			require.Equal(t, EventReleased, (<-ch).Kind)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,139 @@
+package mutex
+
+import (
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+)
+
+// ReleaseAllOwnedBy evicts the client with the provided name from the
+// mutexes stored in the immediate subdirectories of 'parent'. It's meant
+// for operator cleanup after a host is known to be dead, so its locks are
+// freed without waiting for [[Mutex.AutoRelease]]. Each mutex held by the
+// client is handed to the next client in its queue, an [[EventEvicted]]
+// event is logged, and a new fencing epoch is started even if nobody was
+// waiting. The client is also removed from every queue and vacant mutexes
+// reserved for it are released. The paths of the mutexes which were held
+// by the client are returned.
+//
+// The evicted client isn't notified. If it's still alive, it will discover
+// the loss with its next heartbeat.
+func ReleaseAllOwnedBy(db fdb.Transactor, parent directory.Directory, name string) (_ [][]string, err error) {
+	defer wrapErr(&err)
+
+	if name == "" {
+		return nil, fmt.Errorf("client name is empty")
+	}
+
+	names, err := parent.List(db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subdirectories: %w", err)
+	}
+
+	var evicted [][]string
+	for _, child := range names {
+		// Each mutex is handled in its own transaction so a
+		// large directory doesn't exceed transaction limits.
+		path, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+			return evict(tr, parent, child, name)
+		})
+		if err != nil {
+			return evicted, fmt.Errorf("failed to evict from %s: %w", child, err)
+		}
+		if path != nil {
+			evicted = append(evicted, path.([]string))
+		}
+	}
+	return evicted, nil
+}
+
+// evict removes the client from a single mutex. If
+// the client held the mutex, its path is returned.
+func evict(tr fdb.Transaction, parent directory.Directory, child, name string) (any, error) {
+	dir, err := parent.Open(tr, []string{child}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open subdirectory: %w", err)
+	}
+
+	x := kv{Subspace: dir}
+	exists, err := x.exists(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for mutex: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	held, err := x.evict(tr, name)
+	if err != nil {
+		return nil, err
+	}
+	if !held {
+		return nil, nil
+	}
+	return dir.GetPath(), nil
+}
+
+// evict removes the client with the provided name from the mutex. If the
+// client holds the mutex or the vacant mutex is reserved for it, the mutex
+// is handed to the next client in the queue and a new fencing epoch is
+// started. True is returned if the client held the mutex.
+func (x *kv) evict(tr fdb.Transaction, name string) (bool, error) {
+	// Remove the client from the queue first
+	// so the mutex isn't handed back to it.
+	if err := x.removeFromQueue(tr, name); err != nil {
+		return false, fmt.Errorf("failed to remove from queue: %w", err)
+	}
+
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return false, fmt.Errorf("failed to get owner: %w", err)
+	}
+	held := owner.name == name
+
+	if !held {
+		if owner.name != "" {
+			return false, nil
+		}
+		sticky, ok, err := x.getSticky(tr)
+		if err != nil {
+			return false, fmt.Errorf("failed to get sticky: %w", err)
+		}
+		if !ok || sticky.name != name {
+			return false, nil
+		}
+	}
+
+	if held {
+		if err := x.recordPreemption(tr, name); err != nil {
+			return false, fmt.Errorf("failed to record preemption: %w", err)
+		}
+		if err := x.logEvent(tr, EventEvicted, name); err != nil {
+			return false, fmt.Errorf("failed to log event: %w", err)
+		}
+	}
+	if err := x.clearAttrs(tr, name); err != nil {
+		return false, fmt.Errorf("failed to clear attributes: %w", err)
+	}
+
+	next, err := x.dequeue(tr)
+	if err != nil {
+		return false, fmt.Errorf("failed to dequeue: %w", err)
+	}
+	if err := x.setOwner(tr, next); err != nil {
+		return false, fmt.Errorf("failed to set owner: %w", err)
+	}
+	if err := x.clearSticky(tr); err != nil {
+		return false, fmt.Errorf("failed to clear sticky: %w", err)
+	}
+
+	// A new owner starts a new epoch. If the mutex is
+	// left vacant, start one anyway so the evicted
+	// client's fencing token is rejected.
+	if next == "" {
+		tr.Add(x.packEpochKey(), packIncrement())
+	}
+
+	return held, nil
+}
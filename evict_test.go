@@ -0,0 +1,77 @@
+package mutex
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseAllOwnedBy(t *testing.T) {
+	tests := map[string]testFn{
+		"evicted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			var dirs []directory.DirectorySubspace
+			for _, name := range []string{"a", "b", "c"} {
+				dir, err := parent.CreateOrOpen(db, []string{name}, nil)
+				require.NoError(t, err)
+				dirs = append(dirs, dir)
+			}
+
+			xA, err := NewMutex(db, dirs[0], "dead")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, dirs[1], "dead")
+			require.NoError(t, err)
+			xC, err := NewMutex(db, dirs[2], "alive")
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{xA, xB, xC} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+
+			// Mutex 'a' has a client waiting, and the
+			// dead client is waiting for mutex 'c'.
+			require.NoError(t, xA.enqueue(db, "waiting", 0))
+			require.NoError(t, xC.enqueue(db, "dead", 0))
+
+			epochB, err := xB.getEpoch(db)
+			require.NoError(t, err)
+
+			evicted, err := ReleaseAllOwnedBy(db, parent, "dead")
+			require.NoError(t, err)
+			require.Equal(t, [][]string{dirs[0].GetPath(), dirs[1].GetPath()}, evicted)
+
+			owner, err := xA.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "waiting", owner.name)
+
+			owner, err = xB.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+
+			newEpochB, err := xB.getEpoch(db)
+			require.NoError(t, err)
+			require.Equal(t, epochB+1, newEpochB)
+
+			owner, err = xC.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "alive", owner.name)
+
+			queue, err := xC.getQueue(db)
+			require.NoError(t, err)
+			require.Empty(t, queue)
+
+			events, err := xB.Events(db)
+			require.NoError(t, err)
+			require.Equal(t, EventEvicted, events[len(events)-1].Kind)
+			require.Equal(t, "dead", events[len(events)-1].Client)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,76 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// AcquireToken is like [[Mutex.Acquire]] but also returns the fencing token
+// of the hold. The token is the commit version of the transaction which
+// granted the mutex, so each hold receives a larger token than the holds
+// before it, even if the mutex was destroyed & recreated in the meantime.
+// Pass the token along with writes to downstream systems, which can reject
+// writes carrying a token smaller than the largest they've seen. This
+// protects against a holder which paused, lost the mutex to
+// [[Mutex.AutoRelease]], and resumed believing it still holds the mutex.
+// Tokens only increase within a single cluster. If the token couldn't be
+// read, it's zero.
+func (x *Mutex) AcquireToken(ctx context.Context, db fdb.Transactor) (int64, error) {
+	return x.acquireToken(ctx, db)
+}
+
+// TryAcquireToken is like [[Mutex.TryAcquire]] but also returns the
+// fencing token of the hold. See [[Mutex.AcquireToken]].
+func (x *Mutex) TryAcquireToken(db fdb.Transactor) (int64, bool, error) {
+	return x.tryAcquireToken(db)
+}
+
+// FencingToken returns the fencing token of the hold.
+// See [[Mutex.AcquireToken]]. It's zero if unknown.
+func (g *Guard) FencingToken() int64 {
+	return g.token
+}
+
+// FencingToken returns the fencing token of the mutex's current hold, which
+// downstream systems may compare against the tokens presented by clients.
+// See [[Mutex.AcquireToken]]. If the mutex is free, false is returned.
+func (x *Observer) FencingToken(db fdb.Transactor) (_ int64, _ bool, err error) {
+	defer wrapErr(&err)
+
+	type result struct {
+		token int64
+		ok    bool
+	}
+
+	res, err := x.inspect(db, true).Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name == "" {
+			return result{}, nil
+		}
+		token, err := x.getToken(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		return result{token: token, ok: true}, nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	r := res.(result)
+	return r.token, r.ok, nil
+}
+
+// loadToken reads the fencing token of a hold which was granted by another
+// client's transaction, such as when this client is promoted from the queue.
+// The token is remembered for the goroutines which share the hold. If the
+// token can't be read or the client no longer owns the mutex, it's zero.
+func (x *Mutex) loadToken(db fdb.Transactor) int64 {
+	token, _, _ := x.getHoldToken(db, x.name)
+	x.token.Store(token)
+	return token
+}
@@ -0,0 +1,105 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFencingToken(t *testing.T) {
+	tests := map[string]testFn{
+		"increasing": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			_, ok, err := obs.FencingToken(db)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			token1, acquired, err := x1.TryAcquireToken(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NotZero(t, token1)
+
+			current, ok, err := obs.FencingToken(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, token1, current)
+
+			// The token is stored alongside the mutex.
+			stored, err := x1.getToken(db)
+			require.NoError(t, err)
+			require.Equal(t, stored, token1)
+
+			// Acquiring a held mutex keeps the token.
+			token, acquired, err := x1.TryAcquireToken(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.Equal(t, token1, token)
+
+			// The queued client is granted a larger token.
+			_, acquired, err = x2.TryAcquireToken(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			require.NoError(t, x1.Release(db))
+
+			token2, err := x2.AcquireToken(context.Background(), db)
+			require.NoError(t, err)
+			defer func() { _ = x2.Release(db) }()
+			require.Greater(t, token2, token1)
+
+			current, ok, err = obs.FencingToken(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, token2, current)
+		},
+		"guard": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			g, err := x.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+			defer func() { _ = g.Release(db) }()
+
+			token, err := x.getToken(db)
+			require.NoError(t, err)
+			require.Equal(t, token, g.FencingToken())
+		},
+		"recreated": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			token1, acquired, err := x.TryAcquireToken(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x.Release(db))
+
+			// Destroy the mutex & create it again.
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.ClearRange(root)
+				return nil, nil
+			})
+			require.NoError(t, err)
+			x, err = NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			// The new hold still receives a larger token.
+			token2, acquired, err := x.TryAcquireToken(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			defer func() { _ = x.Release(db) }()
+			require.Greater(t, token2, token1)
+		},
+	}
+	runTests(t, tests)
+}
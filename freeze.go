@@ -0,0 +1,113 @@
+package mutex
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrFrozen is returned when acquiring a mutex which was frozen
+// by [[AdminClient.Freeze]].
+var ErrFrozen = errors.New("mutex is frozen")
+
+// Freeze blocks new acquisitions of every mutex beneath the parent directory,
+// at any depth, for cluster-wide change windows. Attempts to acquire a frozen
+// mutex fail with [[ErrFrozen]]. Clients which were already waiting stay
+// parked in the queue, and current owners may finish their work. When an
+// owner releases a frozen mutex, the mutex is left vacant. Each mutex is
+// frozen in its own transaction. The paths of the frozen mutexes are
+// returned. Freezing is independent of [[AdminClient.SetMaintenance]].
+func (x *AdminClient) Freeze(db fdb.Transactor) ([][]string, error) {
+	return x.freezeAll(db, "Freeze", true)
+}
+
+// Unfreeze undoes [[AdminClient.Freeze]]. Each vacant mutex is handed to the
+// next client in its queue. The paths of the unfrozen mutexes are returned.
+func (x *AdminClient) Unfreeze(db fdb.Transactor) ([][]string, error) {
+	return x.freezeAll(db, "Unfreeze", false)
+}
+
+// Frozen returns true if the mutex stored in the subdirectory at the given
+// path beneath the parent is frozen. If the mutex doesn't exist,
+// [[ErrNotFound]] is returned.
+func (x *AdminClient) Frozen(db fdb.Transactor, path ...string) (_ bool, err error) {
+	defer wrapErr(&err)
+
+	frozen, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		m, err := x.openPath(tr, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		return m.getFrozen(tr)
+	})
+	if err != nil {
+		return false, err
+	}
+	return frozen.(bool), nil
+}
+
+// freezeAll freezes or unfreezes every mutex beneath the
+// parent directory, auditing each one as 'action'.
+func (x *AdminClient) freezeAll(db fdb.Transactor, action string, on bool) (_ [][]string, err error) {
+	defer wrapErr(&err)
+
+	paths, err := x.listPaths(db, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated [][]string
+	for _, path := range paths {
+		lock, err := x.transact(db, func(tr fdb.Transaction, p *plan) (any, error) {
+			m, err := x.openPath(tr, path, p)
+			if errors.Is(err, ErrNotFound) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := m.setFrozen(tr, on); err != nil {
+				return nil, fmt.Errorf("failed to set frozen: %w", err)
+			}
+			detail := "unfroze mutex"
+			if on {
+				detail = "froze mutex"
+			}
+			if err := x.audit(tr, action, m, detail); err != nil {
+				return nil, err
+			}
+			return lockPath(m), nil
+		})
+		if err != nil {
+			return updated, fmt.Errorf("failed to update %v: %w", path, err)
+		}
+		if lock != nil {
+			updated = append(updated, lock.([]string))
+		}
+	}
+	return updated, nil
+}
+
+// listPaths returns the paths, relative to the parent, of every
+// subdirectory beneath the subdirectory at 'path', at any depth.
+func (x *AdminClient) listPaths(db fdb.Transactor, path []string) ([][]string, error) {
+	names, err := x.parent.List(db, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subdirectories of %v: %w", path, err)
+	}
+
+	var paths [][]string
+	for _, name := range names {
+		child := append(slices.Clone(path), name)
+		paths = append(paths, child)
+
+		nested, err := x.listPaths(db, child)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, nested...)
+	}
+	return paths, nil
+}
@@ -0,0 +1,85 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze(t *testing.T) {
+	tests := map[string]testFn{
+		"nested": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			dirB, err := parent.CreateOrOpen(db, []string{"team", "b"}, nil)
+			require.NoError(t, err)
+
+			owner, err := NewMutex(db, dirA, "owner")
+			require.NoError(t, err)
+			waiter, err := NewMutex(db, dirA, "waiter")
+			require.NoError(t, err)
+			late, err := NewMutex(db, dirA, "late")
+			require.NoError(t, err)
+			other, err := NewMutex(db, dirB, "other")
+			require.NoError(t, err)
+
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = waiter.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+
+			frozen, err := admin.Freeze(db)
+			require.NoError(t, err)
+			require.ElementsMatch(t, [][]string{dirA.GetPath(), dirB.GetPath()}, frozen)
+
+			ok, err := admin.Frozen(db, "team", "b")
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			// New acquisitions are rejected.
+			_, err = other.TryAcquire(db)
+			require.ErrorIs(t, err, ErrFrozen)
+			err = late.Acquire(context.Background(), db)
+			require.ErrorIs(t, err, ErrFrozen)
+
+			// Parked waiters keep waiting.
+			acquired, err = waiter.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			require.NoError(t, owner.Release(db))
+			current, err := owner.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, current.name)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- waiter.Acquire(ctx, db) }()
+
+			unfrozen, err := admin.Unfreeze(db)
+			require.NoError(t, err)
+			require.Len(t, unfrozen, 2)
+			require.NoError(t, <-done)
+
+			acquired, err = other.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+		},
+	}
+
+	runTests(t, tests)
+}
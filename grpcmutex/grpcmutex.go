@@ -0,0 +1,127 @@
+// Package grpcmutex provides gRPC server interceptors which serialize RPCs
+// through distributed mutexes. It's the gRPC equivalent of [[mutex.Middleware]]
+// and lives in its own module so users of the mutex package needn't depend
+// on gRPC.
+package grpcmutex
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	mutex "github.com/janderland/fdb-mutex"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// KeyFunc chooses the mutex for an RPC. 'method' is the full name of the
+// method, such as "/pkg.Service/Method". For unary RPCs, 'req' is the
+// request message. For streaming RPCs, it's nil. If false is returned, the
+// RPC isn't serialized.
+type KeyFunc func(ctx context.Context, method string, req any) (string, bool)
+
+// UnaryServerInterceptor returns an interceptor which serializes unary RPCs
+// through distributed mutexes. 'key' chooses the mutex for each RPC. RPCs
+// with the same key are handled one at a time across every server using the
+// interceptor, while RPCs for which 'key' returns false are passed through.
+//
+// RPCs are serialized by a [[mutex.Section]] over 'root'. If the mutex isn't
+// acquired within 'timeout', or the mutex is disabled or frozen, or the
+// circuit breaker is open, the RPC fails with codes.Unavailable. If the mutex
+// is rate limited, it fails with codes.ResourceExhausted. Either way, the
+// "retry-after" header holds the number of seconds to wait before retrying.
+// Other failures result in codes.Internal. While the handler runs, its
+// context is that of the hold's [[mutex.Guard]], so it's cancelled if the
+// mutex is lost. The mutex is released once the handler returns.
+func UnaryServerInterceptor(db fdb.Transactor, root subspace.Subspace, key KeyFunc, timeout time.Duration, opts ...mutex.Option) grpc.UnaryServerInterceptor {
+	s := mutex.NewSection(db, root, timeout, opts...)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		k, ok := key(ctx, info.FullMethod, req)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		var resp any
+		err := s.Run(ctx, k, func(ctx context.Context) error {
+			var err error
+			resp, err = handler(ctx, req)
+			return err
+		})
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor is like [[UnaryServerInterceptor]] but for
+// streaming RPCs. The mutex is held for the life of the stream.
+func StreamServerInterceptor(db fdb.Transactor, root subspace.Subspace, key KeyFunc, timeout time.Duration, opts ...mutex.Option) grpc.StreamServerInterceptor {
+	s := mutex.NewSection(db, root, timeout, opts...)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		k, ok := key(ctx, info.FullMethod, nil)
+		if !ok {
+			return handler(srv, ss)
+		}
+
+		err := s.Run(ctx, k, func(ctx context.Context) error {
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		})
+		if err != nil {
+			return toStatus(ctx, err)
+		}
+		return nil
+	}
+}
+
+// serverStream replaces the context of a stream
+// with the context of the mutex's hold.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// toStatus converts a failure to acquire the mutex to a gRPC status, setting
+// the "retry-after" header if retrying may help. Errors returned by the
+// handler are passed through as they are.
+func toStatus(ctx context.Context, err error) error {
+	var serr *mutex.SectionError
+	if !errors.As(err, &serr) {
+		return err
+	}
+
+	// The client went away, so report why.
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	code := codes.Internal
+	switch {
+	case errors.Is(err, mutex.ErrRateLimited), errors.Is(err, mutex.ErrThrottled):
+		code = codes.ResourceExhausted
+	case serr.RetryAfter > 0:
+		code = codes.Unavailable
+	}
+	if serr.RetryAfter > 0 {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds(serr.RetryAfter))))
+	}
+	return status.Error(code, serr.Error())
+}
+
+// seconds rounds the duration up to a whole
+// number of seconds. It's at least 1.
+func seconds(d time.Duration) int {
+	return max(int((d+time.Second-1)/time.Second), 1)
+}
@@ -0,0 +1,60 @@
+package grpcmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mutex "github.com/janderland/fdb-mutex"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		code codes.Code
+	}{
+		"contended": {
+			err:  &mutex.SectionError{RetryAfter: time.Second, Err: context.DeadlineExceeded},
+			code: codes.Unavailable,
+		},
+		"rate limited": {
+			err:  &mutex.SectionError{RetryAfter: time.Second, Err: &mutex.RateLimitError{RetryAfter: time.Second}},
+			code: codes.ResourceExhausted,
+		},
+		"failed": {
+			err:  &mutex.SectionError{Err: errors.New("oops")},
+			code: codes.Internal,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := toStatus(context.Background(), test.err)
+			require.Equal(t, test.code, status.Code(err))
+		})
+	}
+
+	t.Run("handler error", func(t *testing.T) {
+		err := status.Error(codes.NotFound, "missing")
+		require.Equal(t, err, toStatus(context.Background(), err))
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := toStatus(ctx, &mutex.SectionError{Err: context.Canceled})
+		require.Equal(t, codes.Canceled, status.Code(err))
+	})
+}
+
+func TestSeconds(t *testing.T) {
+	require.Equal(t, 1, seconds(0))
+	require.Equal(t, 1, seconds(100*time.Millisecond))
+	require.Equal(t, 2, seconds(1500*time.Millisecond))
+	require.Equal(t, 3, seconds(3*time.Second))
+}
@@ -0,0 +1,274 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// ErrNotOwner is returned by fenced operations when
+// the client no longer holds the mutex.
+var ErrNotOwner = errors.New("not the owner of the mutex")
+
+// ErrLockLost is the cause given when a [[Guard]]'s context is cancelled
+// because the mutex is assumed lost, rather than because it was released.
+var ErrLockLost = errors.New("mutex lost")
+
+// minWatchdogPeriod bounds how often [[Mutex.watchdog]] checks
+// the heartbeat, so a tiny fail-safe doesn't spin the ticker.
+const minWatchdogPeriod = time.Millisecond
+
+// WithFailSafe causes the holder to assume it has lost the mutex if it cannot
+// heartbeat for longer than 'fraction' of 'ttl'. When this happens, the context
+// of the active [[Guard]] is cancelled with [[ErrLockLost]] as the cause. This
+// happens locally, before any [[Mutex.AutoRelease]] instance has released the
+// mutex, avoiding a split-brain during network partitions. 'ttl' should match
+// the 'maxAge' given to AutoRelease and 'fraction' must be between 0 & 1,
+// exclusive. Otherwise, constructing the mutex fails. The holder heartbeats at
+// least 4 times per fail-safe period, so a short fail-safe shortens the
+// heartbeat interval. See [[WithLeaseTTL]].
+func WithFailSafe(ttl time.Duration, fraction float64) Option {
+	return func(x *Mutex) {
+		if ttl <= 0 || fraction <= 0 || fraction >= 1 {
+			x.optErr = errors.Join(x.optErr, fmt.Errorf("fail-safe needs a positive ttl & a fraction between 0 & 1 but got %v & %v", ttl, fraction))
+			return
+		}
+		x.failSafe = time.Duration(float64(ttl) * fraction)
+	}
+}
+
+// Guard represents a single hold of a mutex. Its context is cancelled
+// when the mutex is released or assumed lost. Guards are created by
+// [[Mutex.AcquireGuard]] and [[Mutex.TryAcquireGuard]].
+type Guard struct {
+	x      *Mutex
+	db     fdb.Transactor
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	// token is the fencing token of the hold.
+	// See [[Guard.FencingToken]].
+	token int64
+
+	// requested is closed when another client asks
+	// for the mutex to be released. It's watched
+	// lazily. See [[Guard.ReleaseRequested]].
+	requested     chan struct{}
+	requestedOnce sync.Once
+}
+
+// AcquireGuard is like [[Mutex.Acquire]] but returns a [[Guard]]
+// representing the hold. The guard's context derives from 'ctx'.
+func (x *Mutex) AcquireGuard(ctx context.Context, db fdb.Transactor) (*Guard, error) {
+	token, err := x.AcquireToken(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return x.newGuard(ctx, db, token), nil
+}
+
+// TryAcquireGuard is like [[Mutex.TryAcquire]] but returns a [[Guard]]
+// representing the hold. If the mutex wasn't acquired then the guard
+// is nil. The guard's context derives from 'ctx'.
+func (x *Mutex) TryAcquireGuard(ctx context.Context, db fdb.Transactor) (*Guard, bool, error) {
+	token, acquired, err := x.TryAcquireToken(db)
+	if err != nil || !acquired {
+		return nil, false, err
+	}
+	return x.newGuard(ctx, db, token), true, nil
+}
+
+// Context returns a context which is cancelled when the mutex is released
+// or assumed lost. If lost, [[context.Cause]] returns [[ErrLockLost]].
+func (g *Guard) Context() context.Context {
+	return g.ctx
+}
+
+// ReleaseRequested returns a channel which is closed when another client
+// calls [[Mutex.RequestRelease]] while this guard is active. This allows
+// the holder to implement polite preemption: finish its current work and
+// release the mutex early. Honoring the request is up to the holder.
+func (g *Guard) ReleaseRequested() <-chan struct{} {
+	g.requestedOnce.Do(func() {
+		go g.watchReleaseRequest()
+	})
+	return g.requested
+}
+
+// watchReleaseRequest closes the requested channel once a
+// release request is made or exits when the guard is cancelled.
+func (g *Guard) watchReleaseRequest() {
+	for {
+		err := <-g.x.watchReleaseRequest(g.ctx, g.db)
+		if g.ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			close(g.requested)
+			return
+		}
+
+		// The watch failed. Try again after a pause
+		// unless the guard is cancelled in the meantime.
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Release releases the mutex and cancels the guard's context.
+func (g *Guard) Release(db fdb.Transactor) error {
+	return g.x.Release(db)
+}
+
+// Transact runs 'fn' in a fenced transaction. Before 'fn' is called, the
+// transaction checks that the client still owns the mutex. If not, the
+// transaction fails with [[ErrNotOwner]]. Because the ownership check is
+// part of the transaction, any change of ownership before commit causes
+// the transaction to conflict and retry. 'data' is the subspace reserved
+// for application data. See [[Guard.Get]], [[Guard.Set]], & [[Guard.Clear]].
+func (g *Guard) Transact(db fdb.Transactor, fn func(tr fdb.Transaction, data subspace.Subspace) (any, error)) (_ any, err error) {
+	defer wrapErr(&err)
+
+	return g.x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		if err := g.fence(tr); err != nil {
+			return nil, err
+		}
+		return fn(tr, g.x.packDataSubspace())
+	})
+}
+
+// Get reads the application data stored at 'key'. If the key
+// doesn't exist, nil is returned. See [[Guard.Transact]].
+func (g *Guard) Get(db fdb.Transactor, key tuple.Tuple) ([]byte, error) {
+	val, err := g.Transact(db, func(tr fdb.Transaction, data subspace.Subspace) (any, error) {
+		return tr.Get(data.Pack(key)).Get()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return val.([]byte), nil
+}
+
+// Set writes application data to 'key'. See [[Guard.Transact]].
+func (g *Guard) Set(db fdb.Transactor, key tuple.Tuple, val []byte) error {
+	_, err := g.Transact(db, func(tr fdb.Transaction, data subspace.Subspace) (any, error) {
+		tr.Set(data.Pack(key), val)
+		return nil, nil
+	})
+	return err
+}
+
+// Clear deletes the application data at 'key'. See [[Guard.Transact]].
+func (g *Guard) Clear(db fdb.Transactor, key tuple.Tuple) error {
+	_, err := g.Transact(db, func(tr fdb.Transaction, data subspace.Subspace) (any, error) {
+		tr.Clear(data.Pack(key))
+		return nil, nil
+	})
+	return err
+}
+
+// fence returns an error if the guard is no longer
+// active or the client no longer owns the mutex.
+func (g *Guard) fence(tr fdb.Transaction) error {
+	if err := context.Cause(g.ctx); err != nil {
+		return fmt.Errorf("guard is inactive: %w", err)
+	}
+	return g.x.fence(tr)
+}
+
+// fence returns [[ErrNotOwner]] if the client doesn't own the mutex.
+// Reading the owner adds it to the transaction's read conflict range,
+// so the transaction conflicts with any concurrent ownership change.
+func (x *Mutex) fence(tr fdb.Transaction) error {
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return fmt.Errorf("failed to get owner: %w", err)
+	}
+	if owner.name != x.name {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+func (x *Mutex) newGuard(ctx context.Context, db fdb.Transactor, token int64) *Guard {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &Guard{
+		x:         x,
+		db:        db,
+		ctx:       ctx,
+		cancel:    cancel,
+		token:     token,
+		requested: make(chan struct{}),
+	}
+	x.guards.set(g)
+	return g
+}
+
+// watchdog calls 'lose' if the heartbeat hasn't succeeded within the
+// fail-safe duration. For a hold of the mutex, 'lose' cancels the active
+// guard with [[ErrLockLost]]. It runs until the 'done' channel is closed
+// or the hold is lost.
+func (x *Mutex) watchdog(done <-chan struct{}, lastBeat *atomic.Int64, lose func()) {
+	ticker := time.NewTicker(max(x.failSafe/4, minWatchdogPeriod))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+			// Shorten the deadline if the local
+			// clock is known to drift.
+			if time.Since(time.Unix(0, lastBeat.Load())) > x.clock.margin(x.failSafe) {
+				lose()
+				return
+			}
+		}
+	}
+}
+
+// guards tracks the active [[Guard]] of a mutex
+// so the heartbeat watchdog can cancel it.
+type guards struct {
+	mu     sync.Mutex
+	active *Guard
+}
+
+// set replaces the active guard. The
+// previous guard's context is cancelled.
+func (x *guards) set(g *Guard) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.active != nil {
+		x.active.cancel(nil)
+	}
+	x.active = g
+}
+
+// reset cancels and removes the active guard.
+func (x *guards) reset() {
+	x.set(nil)
+}
+
+// lose cancels the active guard with [[ErrLockLost]].
+func (x *guards) lose() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.active != nil {
+		x.active.cancel(ErrLockLost)
+		x.active = nil
+	}
+}
@@ -0,0 +1,194 @@
+package mutex
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard(t *testing.T) {
+	tests := map[string]testFn{
+		"release": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			g, err := x.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+			require.NoError(t, g.Context().Err())
+
+			err = g.Release(db)
+			require.NoError(t, err)
+
+			<-g.Context().Done()
+			require.ErrorIs(t, context.Cause(g.Context()), context.Canceled)
+		},
+		"not acquired": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			_, acquired, err := x1.TryAcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			g, acquired, err := x2.TryAcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+			require.Nil(t, g)
+		},
+		"data": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			g, err := x.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+
+			key := tuple.Tuple{"state"}
+
+			val, err := g.Get(db, key)
+			require.NoError(t, err)
+			require.Nil(t, val)
+
+			err = g.Set(db, key, []byte("hello"))
+			require.NoError(t, err)
+
+			val, err = g.Get(db, key)
+			require.NoError(t, err)
+			require.Equal(t, []byte("hello"), val)
+
+			err = g.Clear(db, key)
+			require.NoError(t, err)
+
+			val, err = g.Get(db, key)
+			require.NoError(t, err)
+			require.Nil(t, val)
+
+			err = g.Release(db)
+			require.NoError(t, err)
+
+			_, err = g.Get(db, key)
+			require.ErrorIs(t, err, context.Canceled)
+		},
+		"fenced": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			g, err := x.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+
+			// Simulate the mutex being auto-released
+			// and acquired by another client.
+			err = x.setOwner(db, "other")
+			require.NoError(t, err)
+
+			err = g.Set(db, tuple.Tuple{"state"}, []byte("hello"))
+			require.ErrorIs(t, err, ErrNotOwner)
+		},
+		"release requested": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			g, err := x1.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+
+			requested := g.ReleaseRequested()
+
+			err = x2.RequestRelease(db)
+			require.NoError(t, err)
+
+			select {
+			case <-requested:
+			case <-time.After(5 * time.Second):
+				t.Fatal("release request wasn't signaled")
+			}
+
+			// The request is cleared when ownership changes,
+			// so the next owner isn't asked to release.
+			err = g.Release(db)
+			require.NoError(t, err)
+
+			g, err = x2.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+
+			select {
+			case <-g.ReleaseRequested():
+				t.Fatal("stale release request was signaled")
+			case <-time.After(100 * time.Millisecond):
+			}
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestWatchdog(t *testing.T) {
+	x := &Mutex{failSafe: 10 * time.Millisecond}
+	g := x.newGuard(context.Background(), nil, 0)
+
+	var lastBeat atomic.Int64
+	lastBeat.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	x.watchdog(make(chan struct{}), &lastBeat, x.guards.lose)
+
+	<-g.Context().Done()
+	require.ErrorIs(t, context.Cause(g.Context()), ErrLockLost)
+}
+
+func TestWatchdogTinyFailSafe(t *testing.T) {
+	// The period is clamped instead of panicking.
+	x := &Mutex{failSafe: 3}
+	g := x.newGuard(context.Background(), nil, 0)
+
+	var lastBeat atomic.Int64
+	lastBeat.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	x.watchdog(make(chan struct{}), &lastBeat, x.guards.lose)
+
+	<-g.Context().Done()
+	require.ErrorIs(t, context.Cause(g.Context()), ErrLockLost)
+}
+
+func TestWithFailSafe(t *testing.T) {
+	tests := []struct {
+		ttl      time.Duration
+		fraction float64
+		expected time.Duration
+	}{
+		{ttl: time.Second, fraction: 0.5, expected: 500 * time.Millisecond},
+		{ttl: time.Second, fraction: 0},
+		{ttl: time.Second, fraction: 1},
+		{ttl: time.Second, fraction: -0.5},
+		{ttl: 0, fraction: 0.5},
+		{ttl: -time.Second, fraction: 0.5},
+	}
+	for _, test := range tests {
+		var x Mutex
+		WithFailSafe(test.ttl, test.fraction)(&x)
+		require.Equal(t, test.expected, x.failSafe, "ttl=%v fraction=%v", test.ttl, test.fraction)
+		require.Equal(t, test.expected == 0, x.optErr != nil, "ttl=%v fraction=%v", test.ttl, test.fraction)
+	}
+}
+
+func TestFailSafeBeatInterval(t *testing.T) {
+	// The default interval is longer than the fail-safe.
+	var x Mutex
+	WithFailSafe(2*time.Second, 0.25)(&x)
+	require.Equal(t, 125*time.Millisecond, x.beatInterval())
+
+	// The lease TTL is shorter than the fail-safe.
+	x = Mutex{}
+	WithLeaseTTL(200 * time.Millisecond)(&x)
+	WithFailSafe(2*time.Second, 0.5)(&x)
+	require.Equal(t, 50*time.Millisecond, x.beatInterval())
+}
@@ -0,0 +1,288 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrNoHandoff is returned when no release has been prepared
+// for the client. See [[Mutex.PrepareRelease]].
+var ErrNoHandoff = errors.New("no release is prepared for this client")
+
+// ErrHandoffNotAcked is returned by [[Mutex.CommitRelease]] when the
+// successor hasn't called [[Mutex.AckRelease]].
+var ErrHandoffNotAcked = errors.New("successor hasn't acknowledged the release")
+
+// PrepareRelease is the first phase of a two-phase release. The owner
+// announces its intent to release the mutex to the client with the provided
+// name. If the name is blank, the client which would acquire the mutex next
+// is chosen. The successor acknowledges with [[Mutex.AckRelease]], after
+// which the owner flips ownership with [[Mutex.CommitRelease]]. Until then,
+// the owner keeps the mutex, so there's no moment when neither client
+// believes it's the owner. The name of the successor is returned. If no
+// client is waiting, nothing is prepared and a blank name is returned.
+// If this client doesn't own the mutex, [[ErrNotOwner]] is returned.
+func (x *Mutex) PrepareRelease(db fdb.Transactor, name string) (_ string, err error) {
+	defer wrapErr(&err)
+	db = x.withBreaker(db)
+
+	successor, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+		successor := name
+		if successor == "" {
+			var err error
+			if successor, err = x.peekQueue(tr); err != nil {
+				return nil, fmt.Errorf("failed to peek queue: %w", err)
+			}
+		}
+		if successor == "" || successor == x.name {
+			return "", nil
+		}
+		handoff := handoffKV{successor: successor, prepared: time.Now()}
+		if err := x.setHandoff(tr, handoff); err != nil {
+			return nil, fmt.Errorf("failed to set handoff: %w", err)
+		}
+		return successor, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return successor.(string), nil
+}
+
+// HandoffPending returns true if the owner has prepared to release the
+// mutex to this client and is waiting for [[Mutex.AckRelease]].
+func (x *Mutex) HandoffPending(db fdb.Transactor) (_ bool, err error) {
+	defer wrapErr(&err)
+
+	handoff, ok, err := x.getHandoff(x.withBreaker(db))
+	if err != nil {
+		return false, err
+	}
+	return ok && handoff.successor == x.name && !handoff.acked, nil
+}
+
+// WatchHandoff returns a channel which signals when a release is prepared,
+// acknowledged, or cancelled. When a change occurs, the channel returns
+// nil. If the watch setup fails or the provided context is canceled, the
+// channel returns an error. See [[Mutex.HandoffPending]].
+func (x *Mutex) WatchHandoff(ctx context.Context, db fdb.Transactor) <-chan error {
+	return x.watchHandoff(ctx, x.withBreaker(db))
+}
+
+// AckRelease is the second phase of a two-phase release. The successor
+// signals it's ready to take over the mutex. To start heartbeating after
+// the ownership flips, the successor should be waiting in [[Mutex.Acquire]]
+// or call [[Mutex.TryAcquire]] after the flip. If no release has been
+// prepared for this client, [[ErrNoHandoff]] is returned.
+func (x *Mutex) AckRelease(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	_, err = x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		handoff, ok, err := x.getHandoff(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get handoff: %w", err)
+		}
+		if !ok || handoff.successor != x.name {
+			return nil, ErrNoHandoff
+		}
+		handoff.acked = true
+		if err := x.setHandoff(tr, handoff); err != nil {
+			return nil, fmt.Errorf("failed to set handoff: %w", err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// CommitRelease is the final phase of a two-phase release. Ownership is
+// atomically handed to the successor, bypassing the queue, and a new
+// fencing epoch starts. If no release is prepared, [[ErrNoHandoff]] is
+// returned. If the successor hasn't acknowledged, [[ErrHandoffNotAcked]]
+// is returned and the owner keeps the mutex. If this client doesn't own
+// the mutex, [[ErrNotOwner]] is returned.
+func (x *Mutex) CommitRelease(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	rec, db := x.startRecording("CommitRelease", db)
+	var successor string
+	defer func() { rec.finish(successor, false, err) }()
+	db = x.withBreaker(db)
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+		handoff, ok, err := x.getHandoff(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get handoff: %w", err)
+		}
+		if !ok {
+			return nil, ErrNoHandoff
+		}
+		if !handoff.acked {
+			return nil, ErrHandoffNotAcked
+		}
+		successor = handoff.successor
+
+		if err := x.clearAttrs(tr, x.name); err != nil {
+			return nil, fmt.Errorf("failed to clear attributes: %w", err)
+		}
+		return nil, x.handOver(tr, handoff.successor)
+	})
+	if err != nil {
+		return err
+	}
+
+	x.relinquish()
+	return nil
+}
+
+// AbortRelease cancels a release prepared by [[Mutex.PrepareRelease]].
+// The owner keeps the mutex. If this client doesn't own the mutex,
+// [[ErrNotOwner]] is returned.
+func (x *Mutex) AbortRelease(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	_, err = x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+		return nil, x.clearHandoff(tr)
+	})
+	return err
+}
+
+// HandoffTo performs a two-phase release which can't strand the mutex. A
+// release is prepared for the client with the provided name, or the next
+// client in the queue if the name is blank, which has 'window' to call
+// [[Mutex.AckRelease]]. If it acknowledges, ownership flips to it. If it
+// doesn't, the successor is removed from the queue and the mutex is
+// released as by [[Mutex.Release]], promoting the client at the front of
+// the queue or leaving the mutex free. The name of the new owner is
+// returned. If the context ends while waiting, the prepared release is
+// aborted and this client keeps the mutex.
+func (x *Mutex) HandoffTo(ctx context.Context, db fdb.Transactor, name string, window time.Duration) (_ string, err error) {
+	defer wrapErr(&err)
+
+	successor, err := x.PrepareRelease(db, name)
+	if err != nil {
+		return "", err
+	}
+	if successor == "" {
+		return "", x.Release(db)
+	}
+
+	acked, err := x.waitForAck(ctx, x.withBreaker(db), successor, window)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return "", errors.Join(err, x.AbortRelease(db))
+	}
+	if acked {
+		err := x.CommitRelease(db)
+		if err == nil {
+			return successor, nil
+		}
+		if !errors.Is(err, ErrNoHandoff) {
+			return "", err
+		}
+	}
+	return x.fallBack(db, successor)
+}
+
+// waitForAck waits up to 'window' for the successor to acknowledge the
+// prepared release. False is returned if the window ends first or the
+// release is no longer prepared for the successor.
+func (x *Mutex) waitForAck(ctx context.Context, db fdb.Transactor, successor string, window time.Duration) (bool, error) {
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+
+	for {
+		// Watch the handoff key before checking it
+		// so a change made after the check isn't missed.
+		watchCtx, cancel := context.WithCancel(ctx)
+		watch := x.watchHandoff(watchCtx, db)
+
+		handoff, ok, err := x.getHandoff(db)
+		if err != nil {
+			cancel()
+			return false, fmt.Errorf("failed to get handoff: %w", err)
+		}
+		if !ok || handoff.successor != successor {
+			cancel()
+			return false, nil
+		}
+		if handoff.acked {
+			cancel()
+			return true, nil
+		}
+
+		select {
+		case err := <-watch:
+			cancel()
+			if err != nil {
+				return false, fmt.Errorf("failed to watch handoff: %w", err)
+			}
+		case <-timer.C:
+			cancel()
+			return false, nil
+		}
+	}
+}
+
+// fallBack abandons the prepared release after the successor failed to
+// acknowledge it. If the successor acknowledged after the window ended but
+// before the release was abandoned, ownership flips to it as by
+// [[Mutex.CommitRelease]]. Otherwise, the successor is removed from the
+// queue so the mutex isn't handed to an unresponsive client, then the mutex
+// is released. A successor which is still waiting, whether in
+// [[Mutex.Acquire]], [[AcquireAny]], or [[Mutex.AcquireOrResult]], notices
+// it left the queue & joins it again. See [[Mutex.checkWait]]. The name of
+// the new owner is returned.
+func (x *Mutex) fallBack(db fdb.Transactor, successor string) (_ string, err error) {
+	rec, db := x.startRecording("Release", db)
+	defer func() { rec.finish("", false, err) }()
+	db = x.withBreaker(db)
+
+	next, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+		handoff, ok, err := x.getHandoff(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get handoff: %w", err)
+		}
+		if ok && handoff.successor == successor && handoff.acked {
+			if err := x.clearAttrs(tr, x.name); err != nil {
+				return nil, fmt.Errorf("failed to clear attributes: %w", err)
+			}
+			return successor, x.handOver(tr, successor)
+		}
+		if err := x.clearHandoff(tr); err != nil {
+			return nil, fmt.Errorf("failed to clear handoff: %w", err)
+		}
+		if err := x.removeFromQueue(tr, successor); err != nil {
+			return nil, fmt.Errorf("failed to remove successor from queue: %w", err)
+		}
+		if err := x.logEvent(tr, EventReleased, x.name); err != nil {
+			return nil, fmt.Errorf("failed to log event: %w", err)
+		}
+		if err := x.clearAttrs(tr, x.name); err != nil {
+			return nil, fmt.Errorf("failed to clear attributes: %w", err)
+		}
+		return x.release(tr)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	x.relinquish()
+	return next.(string), nil
+}
@@ -0,0 +1,297 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoPhaseRelease(t *testing.T) {
+	tests := map[string]testFn{
+		"committed": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			successor, err := x1.PrepareRelease(db, "")
+			require.NoError(t, err)
+			require.Equal(t, "client2", successor)
+
+			pending, err := x2.HandoffPending(db)
+			require.NoError(t, err)
+			require.True(t, pending)
+
+			// Until the successor acknowledges,
+			// the owner keeps the mutex.
+			err = x1.CommitRelease(db)
+			require.ErrorIs(t, err, ErrHandoffNotAcked)
+
+			require.NoError(t, x2.AckRelease(db))
+
+			pending, err = x2.HandoffPending(db)
+			require.NoError(t, err)
+			require.False(t, pending)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+
+			require.NoError(t, x1.CommitRelease(db))
+
+			owner, err = x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner.name)
+
+			candidates, err := x1.Candidates(db)
+			require.NoError(t, err)
+			require.Empty(t, candidates)
+
+			// The prepared release was consumed.
+			_, ok, err := x1.getHandoff(db)
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+		"aborted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Nobody is waiting.
+			successor, err := x1.PrepareRelease(db, "")
+			require.NoError(t, err)
+			require.Empty(t, successor)
+
+			err = x2.AckRelease(db)
+			require.ErrorIs(t, err, ErrNoHandoff)
+
+			successor, err = x1.PrepareRelease(db, "client2")
+			require.NoError(t, err)
+			require.Equal(t, "client2", successor)
+
+			require.NoError(t, x1.AbortRelease(db))
+
+			err = x2.AckRelease(db)
+			require.ErrorIs(t, err, ErrNoHandoff)
+
+			err = x1.CommitRelease(db)
+			require.ErrorIs(t, err, ErrNoHandoff)
+		},
+		"not owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			_, err = x1.PrepareRelease(db, "client2")
+			require.ErrorIs(t, err, ErrNotOwner)
+
+			err = x1.CommitRelease(db)
+			require.ErrorIs(t, err, ErrNotOwner)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestHandoffTo(t *testing.T) {
+	tests := map[string]testFn{
+		"acknowledged": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			// The successor acknowledges once
+			// the release is prepared for it.
+			acked := make(chan error, 1)
+			go func() {
+				for {
+					watch := x2.WatchHandoff(ctx, db)
+					pending, err := x2.HandoffPending(db)
+					if err != nil || pending {
+						if err == nil {
+							err = x2.AckRelease(db)
+						}
+						acked <- err
+						return
+					}
+					if err := <-watch; err != nil {
+						acked <- err
+						return
+					}
+				}
+			}()
+
+			next, err := x1.HandoffTo(ctx, db, "client2", time.Second)
+			require.NoError(t, err)
+			require.Equal(t, "client2", next)
+			require.NoError(t, <-acked)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner.name)
+		},
+		"fallback": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			x3, err := NewMutex(db, root, "client3")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			for _, x := range []*Mutex{x2, x3} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.False(t, acquired)
+			}
+
+			// The successor never acknowledges, so it's skipped
+			// and the next client in the queue is promoted.
+			next, err := x1.HandoffTo(context.Background(), db, "", 100*time.Millisecond)
+			require.NoError(t, err)
+			require.Equal(t, "client3", next)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client3", owner.name)
+
+			candidates, err := x1.Candidates(db)
+			require.NoError(t, err)
+			require.Empty(t, candidates)
+		},
+		"slow successor": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			// The successor may wait in any of the
+			// methods which block for the mutex.
+			waits := map[string]func(context.Context, *Mutex) error{
+				"acquire": func(ctx context.Context, x *Mutex) error {
+					return x.Acquire(ctx, db)
+				},
+				"acquire any": func(ctx context.Context, x *Mutex) error {
+					_, err := AcquireAny(ctx, db, x)
+					return err
+				},
+				"acquire or result": func(ctx context.Context, x *Mutex) error {
+					_, _, err := x.AcquireOrResult(ctx, db)
+					return err
+				},
+			}
+
+			for name, wait := range waits {
+				t.Run(name, func(t *testing.T) {
+					x1, err := NewMutex(db, root.Sub(name), "client1")
+					require.NoError(t, err)
+
+					x2, err := NewMutex(db, root.Sub(name), "client2")
+					require.NoError(t, err)
+
+					acquired, err := x1.TryAcquire(db)
+					require.NoError(t, err)
+					require.True(t, acquired)
+
+					ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+					defer cancel()
+
+					done := make(chan error, 1)
+					go func() { done <- wait(ctx, x2) }()
+
+					require.Eventually(t, func() bool {
+						queued, err := x1.isQueued(db, "client2")
+						return err == nil && queued
+					}, time.Second, 10*time.Millisecond)
+
+					// The successor is skipped, leaving the mutex free,
+					// but it isn't stranded outside of the queue.
+					next, err := x1.HandoffTo(context.Background(), db, "", 100*time.Millisecond)
+					require.NoError(t, err)
+					require.Empty(t, next)
+
+					require.NoError(t, <-done)
+					owner, err := x1.getOwner(db)
+					require.NoError(t, err)
+					require.Equal(t, "client2", owner.name)
+				})
+			}
+		},
+		"late ack": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			successor, err := x1.PrepareRelease(db, "")
+			require.NoError(t, err)
+			require.Equal(t, "client2", successor)
+
+			// The acknowledgement arrives after the window
+			// ended, but before the release is abandoned.
+			require.NoError(t, x2.AckRelease(db))
+
+			next, err := x1.fallBack(db, successor)
+			require.NoError(t, err)
+			require.Equal(t, "client2", next)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner.name)
+		},
+		"nobody waiting": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			next, err := x1.HandoffTo(context.Background(), db, "", time.Second)
+			require.NoError(t, err)
+			require.Empty(t, next)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+		},
+	}
+
+	runTests(t, tests)
+}
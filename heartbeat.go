@@ -0,0 +1,280 @@
+package mutex
+
+import (
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+const (
+	// defaultBeatInterval is the heartbeat interval
+	// used when no lease TTL is configured.
+	defaultBeatInterval = time.Second
+
+	// minBackoff is the delay before the first
+	// retry of a failed heartbeat. The delay
+	// doubles with each consecutive failure.
+	minBackoff = 50 * time.Millisecond
+
+	// defaultSlowRetries is how many times a heartbeat
+	// may be retried before it's considered slow.
+	defaultSlowRetries = 3
+)
+
+// HeartbeatState describes the health of a mutex's heartbeat.
+type HeartbeatState int
+
+const (
+	// HeartbeatStopped means the client isn't holding the mutex.
+	HeartbeatStopped HeartbeatState = iota
+
+	// HeartbeatHealthy means the latest heartbeat succeeded.
+	HeartbeatHealthy
+
+	// HeartbeatDegraded means recent heartbeats have failed
+	// and are being retried, or they succeeded but were slow
+	// or needed several attempts. The hold is at risk. See
+	// [[WithHeartbeatQoS]].
+	HeartbeatDegraded
+
+	// HeartbeatLost means the client no longer holds the mutex,
+	// either because another client owns it or because the
+	// error budget was exhausted. The heartbeat has stopped and
+	// the active [[Guard]] is cancelled with [[ErrLockLost]].
+	HeartbeatLost
+)
+
+func (s HeartbeatState) String() string {
+	switch s {
+	case HeartbeatStopped:
+		return "stopped"
+	case HeartbeatHealthy:
+		return "healthy"
+	case HeartbeatDegraded:
+		return "degraded"
+	case HeartbeatLost:
+		return "lost"
+	default:
+		return "unknown"
+	}
+}
+
+// WithLeaseTTL adapts the heartbeat interval to the given lease TTL, which
+// should match the 'maxAge' given to [[Mutex.AutoRelease]]. The holder
+// heartbeats 4 times per TTL, leaving room for retries. By default, the
+// holder heartbeats every second.
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(x *Mutex) {
+		x.ttl = ttl
+	}
+}
+
+// WithHeartbeatErrorBudget sets how many consecutive heartbeats may fail
+// before the holder assumes it has lost the mutex and stops heartbeating.
+// Failed heartbeats are retried with exponential backoff. By default, the
+// budget is unlimited and the holder never gives up.
+func WithHeartbeatErrorBudget(failures int) Option {
+	return func(x *Mutex) {
+		x.budget = failures
+	}
+}
+
+// WithHeartbeatPanicHandler sets a function which is called when the
+// heartbeat loop panics. The panic is recovered and the loop is restarted,
+// so the hold isn't silently lost. 'fn' receives the recovered value.
+func WithHeartbeatPanicHandler(fn func(recovered any)) Option {
+	return func(x *Mutex) {
+		x.onBeatPanic = fn
+	}
+}
+
+// WithHeartbeatQoS sets when a successful heartbeat is considered slow. A
+// heartbeat which takes longer than 'latency' or needs more than 'retries'
+// retries of its transaction marks the heartbeat as [[HeartbeatDegraded]],
+// warning operators that the hold is at risk before heartbeats fail. By
+// default, a heartbeat is slow if it takes more than half the heartbeat
+// interval or needs more than 3 retries.
+func WithHeartbeatQoS(latency time.Duration, retries int) Option {
+	return func(x *Mutex) {
+		x.slowLatency = latency
+		x.slowRetries = retries
+	}
+}
+
+// HeartbeatLatency returns how long the latest heartbeat transaction
+// took, including retries. It's zero if no heartbeat has been sent.
+func (x *Mutex) HeartbeatLatency() time.Duration {
+	return time.Duration(x.beatLatency.Load())
+}
+
+// SlowHeartbeats returns the number of successful heartbeats which were
+// slow over the lifetime of the handle. See [[WithHeartbeatQoS]].
+func (x *Mutex) SlowHeartbeats() int64 {
+	return x.slowBeats.Load()
+}
+
+// HeartbeatRestarts returns the number of times the heartbeat loop
+// panicked and was restarted over the lifetime of the handle.
+func (x *Mutex) HeartbeatRestarts() int64 {
+	return x.beatRestarts.Load()
+}
+
+// HeartbeatState returns the health of the heartbeat of the current hold.
+func (x *Mutex) HeartbeatState() HeartbeatState {
+	return HeartbeatState(x.state.Load())
+}
+
+// beatInterval returns the time between successful heartbeats. The
+// holder heartbeats 4 times per lease TTL or fail-safe period, whichever
+// is shorter, so a healthy holder never trips the fail-safe.
+func (x *Mutex) beatInterval() time.Duration {
+	interval := defaultBeatInterval
+	if x.ttl > 0 {
+		interval = x.ttl / 4
+	}
+	if x.failSafe > 0 {
+		interval = min(interval, x.failSafe/4)
+	}
+	return max(interval, minWatchdogPeriod)
+}
+
+// superviseBeat runs the heartbeat loop, restarting it if it panics.
+func (x *Mutex) superviseBeat(db fdb.Transactor, stop chan struct{}) {
+	for {
+		recovered := x.runBeatLoop(db, stop)
+		if recovered == nil {
+			return
+		}
+
+		x.beatRestarts.Add(1)
+		x.state.Store(int32(HeartbeatDegraded))
+		if x.onBeatPanic != nil {
+			x.onBeatPanic(recovered)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(minBackoff):
+		}
+	}
+}
+
+// runBeatLoop runs the heartbeat loop and returns the
+// recovered value if it panics. Otherwise, nil is returned.
+func (x *Mutex) runBeatLoop(db fdb.Transactor, stop chan struct{}) (recovered any) {
+	defer func() {
+		recovered = recover()
+	}()
+	x.beatLoop(db, stop)
+	return nil
+}
+
+// beatLoop heartbeats until the stop channel is closed or the hold is
+// lost. Failed heartbeats are retried with exponential backoff, capped
+// at the heartbeat interval.
+func (x *Mutex) beatLoop(db fdb.Transactor, stop chan struct{}) {
+	interval := x.beatInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	var failures int
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+
+		// Skip the heartbeat if one was piggybacked
+		// on an application transaction since the
+		// last tick. See [[Mutex.AddHeartbeat]].
+		if since := time.Since(time.Unix(0, x.lastBeat.Load())); since < interval {
+			timer.Reset(interval - since)
+			continue
+		}
+
+		counter := &attemptCounter{Transactor: db}
+		start := time.Now()
+		owned, sample, err := x.beat(counter, x.name)
+		latency := time.Since(start)
+		x.beatLatency.Store(int64(latency))
+		x.reportTxns("Heartbeat", counter)
+
+		switch {
+		case err == nil && owned:
+			failures = 0
+			x.clock.observe(sample)
+			x.lastBeat.Store(time.Now().UnixNano())
+			if x.isSlowBeat(latency, int(counter.attempts.Load())) {
+				x.slowBeats.Add(1)
+				x.degrade(db)
+			} else {
+				x.state.Store(int32(HeartbeatHealthy))
+			}
+			if x.clients != nil {
+				_ = x.clients.heartbeat(db, x.name)
+			}
+			timer.Reset(interval)
+
+		case err == nil:
+			// Another client owns the mutex.
+			x.lose(stop)
+			return
+
+		default:
+			failures++
+			if x.budget > 0 && failures >= x.budget {
+				x.lose(stop)
+				return
+			}
+			x.degrade(db)
+			timer.Reset(min(minBackoff<<min(failures-1, 16), interval))
+		}
+	}
+}
+
+// isSlowBeat returns true if a heartbeat which took 'latency' and
+// made 'attempts' attempts is slow. See [[WithHeartbeatQoS]].
+func (x *Mutex) isSlowBeat(latency time.Duration, attempts int) bool {
+	slowLatency, slowRetries := x.slowLatency, x.slowRetries
+	if slowLatency <= 0 {
+		slowLatency = x.beatInterval() / 2
+	}
+	if slowRetries <= 0 {
+		slowRetries = defaultSlowRetries
+	}
+	return latency > slowLatency || attempts-1 > slowRetries
+}
+
+// degrade marks the heartbeat as degraded. When the heartbeat becomes
+// degraded, an [[EventDegraded]] event is logged on a best effort basis,
+// as the database may be the cause of the degradation.
+func (x *Mutex) degrade(db fdb.Transactor) {
+	if HeartbeatState(x.state.Swap(int32(HeartbeatDegraded))) == HeartbeatDegraded {
+		return
+	}
+	_, _ = db.Transact(func(tr fdb.Transaction) (any, error) {
+		return nil, x.logEvent(tr, EventDegraded, x.name)
+	})
+}
+
+// lose marks the hold as lost and cancels the active guard. The
+// heartbeat's stop channel is cleared so a later acquisition
+// starts a new heartbeat loop.
+func (x *Mutex) lose(stop chan struct{}) {
+	x.mu.Lock()
+
+	// If the heartbeat was stopped in the
+	// meantime, the hold already ended.
+	if x.stop != stop {
+		x.mu.Unlock()
+		return
+	}
+	x.stop = nil
+	x.state.Store(int32(HeartbeatLost))
+	x.guards.lose()
+	x.mu.Unlock()
+
+	x.untrackHold(stop)
+}
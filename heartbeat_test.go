@@ -0,0 +1,143 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeat(t *testing.T) {
+	tests := map[string]testFn{
+		"lost to another owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client", WithLeaseTTL(100*time.Millisecond))
+			require.NoError(t, err)
+
+			g, acquired, err := x.TryAcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.Equal(t, HeartbeatHealthy, x.HeartbeatState())
+
+			// Steal the mutex.
+			err = x.setOwner(db, "thief")
+			require.NoError(t, err)
+
+			<-g.Context().Done()
+			require.ErrorIs(t, context.Cause(g.Context()), ErrLockLost)
+			require.Equal(t, HeartbeatLost, x.HeartbeatState())
+		},
+		"error budget": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client",
+				WithLeaseTTL(100*time.Millisecond),
+				WithHeartbeatErrorBudget(3))
+			require.NoError(t, err)
+
+			flaky := &flakyTransactor{Transactor: db}
+			g, acquired, err := x.TryAcquireGuard(context.Background(), flaky)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			flaky.failing.Store(true)
+
+			<-g.Context().Done()
+			require.ErrorIs(t, context.Cause(g.Context()), ErrLockLost)
+			require.Equal(t, HeartbeatLost, x.HeartbeatState())
+
+			// The mutex can be acquired again.
+			flaky.failing.Store(false)
+			acquired, err = x.TryAcquire(flaky)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.Equal(t, HeartbeatHealthy, x.HeartbeatState())
+
+			err = x.Release(flaky)
+			require.NoError(t, err)
+			require.Equal(t, HeartbeatStopped, x.HeartbeatState())
+		},
+		"slow": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			// Every heartbeat takes longer than a nanosecond.
+			x, err := NewMutex(db, root, "client",
+				WithLeaseTTL(100*time.Millisecond),
+				WithHeartbeatQoS(time.Nanosecond, 3))
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			require.Eventually(t, func() bool {
+				return x.SlowHeartbeats() >= 2
+			}, time.Second, 10*time.Millisecond)
+			require.Equal(t, HeartbeatDegraded, x.HeartbeatState())
+			require.Positive(t, x.HeartbeatLatency())
+
+			// The event is only logged when the
+			// heartbeat first becomes degraded.
+			events, err := x.Events(db)
+			require.NoError(t, err)
+			var degraded int
+			for _, e := range events {
+				if e.Kind == EventDegraded {
+					degraded++
+				}
+			}
+			require.Equal(t, 1, degraded)
+
+			require.NoError(t, x.Release(db))
+		},
+		"panic recovery": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			panics := make(chan any, 1)
+			x, err := NewMutex(db, root, "client",
+				WithLeaseTTL(100*time.Millisecond),
+				WithHeartbeatPanicHandler(func(recovered any) { panics <- recovered }))
+			require.NoError(t, err)
+
+			flaky := &flakyTransactor{Transactor: db}
+			acquired, err := x.TryAcquire(flaky)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			flaky.panicking.Store(true)
+			require.Equal(t, "flaky transactor", <-panics)
+			require.Equal(t, int64(1), x.HeartbeatRestarts())
+
+			// The restarted loop keeps heartbeating.
+			<-x.watchOwner(context.Background(), db)
+			require.Eventually(t, func() bool {
+				return x.HeartbeatState() == HeartbeatHealthy
+			}, time.Second, 10*time.Millisecond)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+// flakyTransactor fails every transaction while 'failing' is
+// set. If 'panicking' is set, the next transaction panics.
+type flakyTransactor struct {
+	fdb.Transactor
+	failing   atomic.Bool
+	panicking atomic.Bool
+}
+
+func (t *flakyTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	if t.panicking.CompareAndSwap(true, false) {
+		panic("flaky transactor")
+	}
+	if t.failing.Load() {
+		return nil, errors.New("flaky transactor")
+	}
+	return t.Transactor.Transact(f)
+}
+
+func (t *flakyTransactor) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	if t.failing.Load() {
+		return nil, errors.New("flaky transactor")
+	}
+	return t.Transactor.ReadTransact(f)
+}
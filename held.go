@@ -0,0 +1,145 @@
+package mutex
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// HeldLock describes a mutex held by this process. See [[HeldLocks]].
+type HeldLock struct {
+	// ID identifies the mutex. See [[ClientSession.Locks]].
+	ID string
+
+	// Client is the name of the handle holding the mutex.
+	Client string
+
+	// Since is when the mutex was acquired.
+	Since time.Time
+}
+
+// HoldTime returns how long the mutex has been held.
+func (h HeldLock) HoldTime() time.Duration {
+	return time.Since(h.Since)
+}
+
+// MetricsHook receives metrics from every mutex in the process.
+// Nil fields are ignored. See [[SetMetricsHook]].
+type MetricsHook struct {
+	// HeldLocks is called whenever this process acquires or
+	// releases a mutex. It's given every mutex currently held,
+	// so the count is len(locks). See [[HeldLocks]].
+	HeldLocks func(locks []HeldLock)
+
+	// Transactions is called after each mutex operation with
+	// counts of the operation's transaction attempts, retries
+	// & conflicts. Rising counts mean the mutex has become a
+	// database hotspot. See [[TxnMetrics]].
+	Transactions func(metrics TxnMetrics)
+}
+
+// SetMetricsHook replaces the process's metrics hook. Hooks are called
+// synchronously, so they should return quickly and mustn't acquire or
+// release mutexes themselves.
+func SetMetricsHook(hook MetricsHook) {
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	held.hook = hook
+}
+
+// HeldLocks returns the mutexes currently held by this process, sorted
+// by ID. A mutex is considered held from the moment it's acquired until
+// it's released, transferred, or its heartbeat is lost.
+func HeldLocks() []HeldLock {
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	return held.snapshot()
+}
+
+// held tracks the holds of every mutex handle in the process.
+var held = heldLocks{locks: make(map[*Mutex]heldLock)}
+
+type heldLocks struct {
+	// notify serializes calls to the hook so
+	// it never observes an out-of-date state.
+	notify sync.Mutex
+
+	mu    sync.Mutex
+	locks map[*Mutex]heldLock
+	hook  MetricsHook
+}
+
+// heldLock is a hold identified by the
+// stop channel of its heartbeat loop.
+type heldLock struct {
+	HeldLock
+	stop chan struct{}
+}
+
+// snapshot must be called with the mu locked.
+func (h *heldLocks) snapshot() []HeldLock {
+	locks := make([]HeldLock, 0, len(h.locks))
+	for _, l := range h.locks {
+		locks = append(locks, l.HeldLock)
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].ID != locks[j].ID {
+			return locks[i].ID < locks[j].ID
+		}
+		return locks[i].Client < locks[j].Client
+	})
+	return locks
+}
+
+// update applies 'fn' to the set of holds and reports
+// the result to the hook if 'fn' returns true.
+func (h *heldLocks) update(fn func(map[*Mutex]heldLock) bool) {
+	h.notify.Lock()
+	defer h.notify.Unlock()
+
+	h.mu.Lock()
+	changed := fn(h.locks)
+	hook := h.hook.HeldLocks
+	var locks []HeldLock
+	if changed && hook != nil {
+		locks = h.snapshot()
+	}
+	h.mu.Unlock()
+
+	if changed && hook != nil {
+		hook(locks)
+	}
+}
+
+// trackHold records the hold started with the given heartbeat. It must
+// be called without x.mu locked. If the heartbeat has already stopped,
+// the hold has already ended and isn't recorded.
+func (x *Mutex) trackHold(stop chan struct{}) {
+	id := lockID(x.Subspace)
+	held.update(func(locks map[*Mutex]heldLock) bool {
+		x.mu.Lock()
+		active := x.stop == stop
+		x.mu.Unlock()
+		if !active {
+			return false
+		}
+		locks[x] = heldLock{
+			HeldLock: HeldLock{ID: id, Client: x.name, Since: time.Now()},
+			stop:     stop,
+		}
+		return true
+	})
+}
+
+// untrackHold removes the hold started with the given heartbeat. It must
+// be called without x.mu locked. If a newer hold has been tracked in the
+// meantime, it's kept.
+func (x *Mutex) untrackHold(stop chan struct{}) {
+	held.update(func(locks map[*Mutex]heldLock) bool {
+		if l, ok := locks[x]; !ok || l.stop != stop {
+			return false
+		}
+		delete(locks, x)
+		return true
+	})
+}
@@ -0,0 +1,73 @@
+package mutex
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeldLocks(t *testing.T) {
+	// Tests run in parallel, so only
+	// consider the locks of this test.
+	heldBy := func(locks []HeldLock, root subspace.Subspace) []HeldLock {
+		var filtered []HeldLock
+		for _, l := range locks {
+			if l.ID == lockID(root) {
+				filtered = append(filtered, l)
+			}
+		}
+		return filtered
+	}
+
+	tests := map[string]testFn{
+		"acquire & release": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+			require.Empty(t, heldBy(HeldLocks(), root))
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			locks := heldBy(HeldLocks(), root)
+			require.Len(t, locks, 1)
+			require.Equal(t, "client", locks[0].Client)
+			require.False(t, locks[0].Since.IsZero())
+
+			require.NoError(t, x.Release(db))
+			require.Empty(t, heldBy(HeldLocks(), root))
+		},
+		"hook": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			var (
+				mu     sync.Mutex
+				counts []int
+			)
+			SetMetricsHook(MetricsHook{
+				HeldLocks: func(locks []HeldLock) {
+					mu.Lock()
+					defer mu.Unlock()
+					counts = append(counts, len(heldBy(locks, root)))
+				},
+			})
+			defer SetMetricsHook(MetricsHook{})
+
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x.Release(db))
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Contains(t, counts, 1)
+			require.Equal(t, 0, counts[len(counts)-1])
+		},
+	}
+
+	runTests(t, tests)
+}
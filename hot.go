@@ -0,0 +1,99 @@
+package mutex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// HotLockThresholds configure [[WithHotLockDetection]]. A zero
+// threshold is ignored.
+type HotLockThresholds struct {
+	// QueueDepth is the number of waiting clients.
+	QueueDepth int
+
+	// AcquireRate is the number of acquisitions per second.
+	AcquireRate float64
+
+	// ContentionRate is the number of acquisition attempts per
+	// second which found the mutex held by another client.
+	ContentionRate float64
+}
+
+// HotLock describes a mutex found to be hot by [[WithHotLockDetection]].
+type HotLock struct {
+	// Path is the directory path of the mutex.
+	Path []string
+
+	// QueueDepth is the number of waiting clients.
+	QueueDepth int
+
+	// AcquireRate is the number of acquisitions per
+	// second since the mutex was last polled.
+	AcquireRate float64
+
+	// ContentionRate is the number of contended acquisition
+	// attempts per second since the mutex was last polled.
+	ContentionRate float64
+}
+
+// WithHotLockDetection causes [[AutoReleaseAll]] to sample the contention of
+// each mutex whenever it's polled. When a metric first exceeds its threshold,
+// an [[EventHot]] event is logged to the mutex and 'fn' is called, if it's not
+// nil, so operators discover hotspots before they become outages. The mutex
+// isn't reported again until its metrics fall back below the thresholds. Rates
+// are measured between polls, so they're averaged over longer periods for
+// vacant mutexes, which are only polled once per idle poll.
+func WithHotLockDetection(t HotLockThresholds, fn func(HotLock)) ScanOption {
+	return func(s *scanner) {
+		s.hot = &t
+		s.onHot = fn
+	}
+}
+
+// exceeds returns true if any of the metrics exceed their thresholds.
+func (t HotLockThresholds) exceeds(h HotLock) bool {
+	return t.QueueDepth > 0 && h.QueueDepth > t.QueueDepth ||
+		t.AcquireRate > 0 && h.AcquireRate > t.AcquireRate ||
+		t.ContentionRate > 0 && h.ContentionRate > t.ContentionRate
+}
+
+// sampleHeat samples the contention of the mutex and reports it if it
+// became hot. Rates aren't known until the second sample.
+func (s *scanner) sampleHeat(db fdb.Transactor, sc *scanned) error {
+	now := time.Now()
+	heat, err := sc.x.getHeat(db)
+	if err != nil {
+		return fmt.Errorf("failed to sample heat: %w", err)
+	}
+	prev, sampled := sc.sample, sc.sampled
+	sc.sample, sc.sampled = heat, now
+
+	h := HotLock{
+		Path:       lockPath(sc.x.kv),
+		QueueDepth: heat.depth,
+	}
+	if elapsed := now.Sub(sampled).Seconds(); !sampled.IsZero() && elapsed > 0 {
+		h.AcquireRate = float64(heat.epoch-prev.epoch) / elapsed
+		h.ContentionRate = float64(heat.contended-prev.contended) / elapsed
+	}
+
+	hot := s.hot.exceeds(h)
+	if !hot || sc.hot {
+		sc.hot = hot
+		return nil
+	}
+	sc.hot = true
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		return nil, sc.x.logEvent(tr, EventHot, sc.owner.name)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+	if s.onHot != nil {
+		s.onHot(h)
+	}
+	return nil
+}
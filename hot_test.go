@@ -0,0 +1,78 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotLockDetection(t *testing.T) {
+	tests := map[string]testFn{
+		"queue depth": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+			const maxAge = 300 * time.Millisecond
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+
+			// 'a' is held with two clients waiting.
+			var xA *Mutex
+			for i, name := range []string{"client1", "client2", "client3"} {
+				x, err := NewMutex(db, dirA, name)
+				require.NoError(t, err)
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.Equal(t, i == 0, acquired)
+				xA = x
+			}
+
+			// 'b' is held without contention.
+			xB, err := NewMutex(db, dirB, "client1")
+			require.NoError(t, err)
+			acquired, err := xB.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			hot := make(chan HotLock, 10)
+			opt := WithHotLockDetection(HotLockThresholds{QueueDepth: 1}, func(h HotLock) { hot <- h })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*maxAge)
+			defer cancel()
+			err = AutoReleaseAll(ctx, db, parent, maxAge, opt, WithIdlePoll(50*time.Millisecond))
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			// 'a' is only reported once, even
+			// though it was polled many times.
+			require.Len(t, hot, 1)
+			h := <-hot
+			require.Equal(t, dirA.GetPath(), h.Path)
+			require.Equal(t, 2, h.QueueDepth)
+
+			events, err := xA.Events(db)
+			require.NoError(t, err)
+			require.Equal(t, EventHot, events[len(events)-1].Kind)
+			require.Equal(t, "client1", events[len(events)-1].Client)
+
+			events, err = xB.Events(db)
+			require.NoError(t, err)
+			for _, e := range events {
+				require.NotEqual(t, EventHot, e.Kind)
+			}
+		},
+		"thresholds": func(t *testing.T, _ fdb.Database, _ subspace.Subspace) {
+			th := HotLockThresholds{QueueDepth: 5, AcquireRate: 10}
+			require.False(t, th.exceeds(HotLock{QueueDepth: 5, AcquireRate: 10, ContentionRate: 1000}))
+			require.True(t, th.exceeds(HotLock{QueueDepth: 6}))
+			require.True(t, th.exceeds(HotLock{AcquireRate: 11}))
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,68 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// Middleware returns HTTP middleware which serializes handlers through
+// distributed mutexes, an easy way to protect non-idempotent endpoints.
+// 'key' chooses the mutex for each request. Requests with the same key
+// are handled one at a time across every process using the middleware,
+// while requests for which 'key' returns false are passed through.
+//
+// Requests are serialized by a [[Section]] over 'root', which describes
+// where the mutexes are stored. If the mutex isn't acquired within
+// 'timeout', the response is 503 Service Unavailable with a Retry-After
+// header, as it is when the mutex is rate limited, disabled, or frozen, or
+// when the circuit breaker is open. Other failures result in 500 Internal
+// Server Error. While the handler runs, the request's context is that of
+// the hold's [[Guard]], so it's cancelled if the mutex is lost. The mutex
+// is released once the handler returns.
+func Middleware(db fdb.Transactor, root subspace.Subspace, key func(*http.Request) (string, bool), timeout time.Duration, opts ...Option) func(http.Handler) http.Handler {
+	s := NewSection(db, root, timeout, opts...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k, ok := key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			err := s.Run(r.Context(), k, func(ctx context.Context) error {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return nil
+			})
+			if err == nil {
+				return
+			}
+
+			// The client went away, so
+			// there's no one to respond to.
+			if r.Context().Err() != nil {
+				return
+			}
+			var serr *SectionError
+			if errors.As(err, &serr) && serr.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(serr.RetryAfter)))
+				http.Error(w, "mutex unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, "failed to acquire mutex", http.StatusInternalServerError)
+		})
+	}
+}
+
+// seconds rounds the duration up to a whole number
+// of seconds for the Retry-After header. It's at least 1.
+func seconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
@@ -0,0 +1,82 @@
+package mutex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := map[string]testFn{
+		"serialized": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			var active, overlaps atomic.Int32
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if active.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(20 * time.Millisecond)
+				active.Add(-1)
+			})
+
+			key := func(r *http.Request) (string, bool) {
+				return r.URL.Path, r.Method == http.MethodPost
+			}
+			srv := httptest.NewServer(Middleware(db, root, key, 5*time.Second)(handler))
+			defer srv.Close()
+
+			done := make(chan int, 5)
+			for range 5 {
+				go func() {
+					resp, err := http.Post(srv.URL+"/orders", "", nil)
+					if err != nil {
+						done <- 0
+						return
+					}
+					_ = resp.Body.Close()
+					done <- resp.StatusCode
+				}()
+			}
+			for range 5 {
+				require.Equal(t, http.StatusOK, <-done)
+			}
+			require.Zero(t, overlaps.Load())
+
+			// Unmatched requests aren't serialized.
+			resp, err := http.Get(srv.URL + "/orders")
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		},
+		"timeout": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			holder, err := NewMutex(db, root.Sub("orders"), "holder")
+			require.NoError(t, err)
+			acquired, err := holder.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			key := func(r *http.Request) (string, bool) { return "orders", true }
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler ran without the mutex")
+			})
+			mw := Middleware(db, root, key, 100*time.Millisecond)(handler)
+
+			rec := httptest.NewRecorder()
+			mw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+			require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			require.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+			// The request left the queue.
+			queue, err := holder.getQueue(db)
+			require.NoError(t, err)
+			require.Empty(t, queue)
+		},
+	}
+
+	runTests(t, tests)
+}
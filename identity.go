@@ -0,0 +1,125 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// Identity describes who is acting on a mutex, such as the user,
+// tenant, or trace ID of a request. Identities are carried by
+// contexts, allowing middleware to attribute acquisitions to
+// requests without threading names through the application.
+type Identity struct {
+	// Name is used as the client name. See [[NewMutex]].
+	Name string
+
+	// Attrs are attached to the client's acquisitions and can
+	// be read by other clients. See [[Observer.OwnerIdentity]].
+	Attrs map[string]string
+
+	// Delegator, if not blank, names the client which acquires
+	// on behalf of this one. See [[WithDelegator]].
+	Delegator string
+}
+
+// IdentityResolver derives an identity from a context. Resolvers allow
+// identities to be derived from context values set by other libraries,
+// such as authentication middleware. If the context doesn't carry the
+// information needed, the resolver returns false.
+type IdentityResolver func(ctx context.Context) (Identity, bool)
+
+type identityKey struct{}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   []IdentityResolver
+)
+
+// ContextWithIdentity returns a copy of 'ctx' carrying the given identity.
+func ContextWithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// RegisterIdentityResolver adds a resolver used by [[IdentityFromContext]].
+// Resolvers are tried in the order they are registered. This is typically
+// called during program initialization.
+func RegisterIdentityResolver(fn IdentityResolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers = append(resolvers, fn)
+}
+
+// IdentityFromContext returns the identity carried by the context. An
+// identity set with [[ContextWithIdentity]] takes precedence. Otherwise,
+// the registered resolvers are tried. If no identity is found, false
+// is returned.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	if id, ok := ctx.Value(identityKey{}).(Identity); ok {
+		return id, true
+	}
+
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	for _, fn := range resolvers {
+		if id, ok := fn(ctx); ok {
+			return id, true
+		}
+	}
+	return Identity{}, false
+}
+
+// NewMutexFromContext is like [[NewMutex]] but the client name is taken
+// from the identity carried by the context. The identity's attributes
+// are attached to each of the client's acquisitions. If the identity
+// names a delegator, the handle acquires on its behalf. If the context
+// doesn't carry an identity, a random name is chosen.
+func NewMutexFromContext(ctx context.Context, db fdb.Transactor, root subspace.Subspace, opts ...Option) (*Mutex, error) {
+	id, _ := IdentityFromContext(ctx)
+	opts = append([]Option{withAttrs(id.Attrs), WithDelegator(id.Delegator)}, opts...)
+	return NewMutex(db, root, id.Name, opts...)
+}
+
+// withAttrs sets the identity attributes of the client.
+func withAttrs(attrs map[string]string) Option {
+	return func(x *Mutex) {
+		x.attrs = attrs
+	}
+}
+
+// Identity returns the identity of this client.
+func (x *Mutex) Identity() Identity {
+	return Identity{Name: x.name, Attrs: x.attrs, Delegator: x.delegator}
+}
+
+// OwnerIdentity returns the identity of the client holding the mutex. If
+// the mutex is free, a zero identity is returned.
+func (x *Observer) OwnerIdentity(db fdb.Transactor) (_ Identity, err error) {
+	defer wrapErr(&err)
+
+	id, err := x.inspect(db, true).Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name == "" {
+			return Identity{}, nil
+		}
+		attrs, err := x.getAttrs(tr, owner.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get attributes: %w", err)
+		}
+		delegator, err := x.getDelegator(tr, owner.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get delegator: %w", err)
+		}
+		return Identity{Name: owner.name, Attrs: attrs, Delegator: delegator}, nil
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	return id.(Identity), nil
+}
@@ -0,0 +1,75 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantKey struct{}
+
+func TestIdentity(t *testing.T) {
+	RegisterIdentityResolver(func(ctx context.Context) (Identity, bool) {
+		tenant, ok := ctx.Value(tenantKey{}).(string)
+		if !ok {
+			return Identity{}, false
+		}
+		return Identity{Name: "tenant-" + tenant, Attrs: map[string]string{"tenant": tenant}}, true
+	})
+
+	tests := map[string]testFn{
+		"explicit": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := ContextWithIdentity(context.Background(), Identity{
+				Name:  "alice",
+				Attrs: map[string]string{"trace": "abc123"},
+			})
+
+			x, err := NewMutexFromContext(ctx, db, root)
+			require.NoError(t, err)
+			require.Equal(t, "alice", x.Identity().Name)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			id, err := obs.OwnerIdentity(db)
+			require.NoError(t, err)
+			require.Equal(t, "alice", id.Name)
+			require.Equal(t, map[string]string{"trace": "abc123"}, id.Attrs)
+
+			// Attributes are removed on release.
+			err = x.Release(db)
+			require.NoError(t, err)
+
+			attrs, err := x.getAttrs(db, "alice")
+			require.NoError(t, err)
+			require.Empty(t, attrs)
+		},
+		"resolver": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+			x, err := NewMutexFromContext(ctx, db, root)
+			require.NoError(t, err)
+
+			id := x.Identity()
+			require.Equal(t, "tenant-acme", id.Name)
+			require.Equal(t, "acme", id.Attrs["tenant"])
+		},
+		"none": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, ok := IdentityFromContext(context.Background())
+			require.False(t, ok)
+
+			x, err := NewMutexFromContext(context.Background(), db, root)
+			require.NoError(t, err)
+			require.NotEmpty(t, x.Identity().Name)
+		},
+	}
+
+	runTests(t, tests)
+}
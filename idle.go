@@ -0,0 +1,130 @@
+package mutex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+)
+
+// IdlePolicy determines when [[ExpireIdle]] deletes a mutex.
+type IdlePolicy struct {
+	// MaxIdle is how long a mutex may go unused before
+	// it's marked for deletion. A mutex is used when it's
+	// created or changes owners, such as when it's acquired,
+	// released, or expires. A mutex created by an older
+	// version of this package which hasn't been used since
+	// is treated as used on the first pass.
+	MaxIdle time.Duration
+
+	// Grace is how long a mutex stays marked before it's
+	// deleted. If the mutex is used during this time, the
+	// mark is removed.
+	Grace time.Duration
+}
+
+// ExpireIdle enforces the given policy on the mutexes stored in the
+// immediate subdirectories of 'parent', keeping the directory layer from
+// accumulating one-off locks. Mutexes unused for longer than MaxIdle are
+// marked and an [[EventIdle]] event is logged. Marked mutexes which are
+// still unused once the grace period ends are deleted along with their
+// directory. Mutexes which are held or have clients waiting are never
+// deleted. The paths of the deleted mutexes are returned. This function
+// performs a single pass and is meant to be run periodically.
+func ExpireIdle(db fdb.Transactor, parent directory.Directory, policy IdlePolicy) (_ [][]string, err error) {
+	defer wrapErr(&err)
+
+	names, err := parent.List(db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subdirectories: %w", err)
+	}
+
+	var deleted [][]string
+	for _, name := range names {
+		// Each mutex is handled in its own transaction so a
+		// large directory doesn't exceed transaction limits.
+		path, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+			return expireIdle(tr, parent, name, policy)
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to expire %s: %w", name, err)
+		}
+		if path != nil {
+			deleted = append(deleted, path.([]string))
+		}
+	}
+	return deleted, nil
+}
+
+// expireIdle enforces the idle policy on a single mutex.
+// If the mutex is deleted, its path is returned.
+func expireIdle(tr fdb.Transaction, parent directory.Directory, name string, policy IdlePolicy) (any, error) {
+	dir, err := parent.Open(tr, []string{name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open subdirectory: %w", err)
+	}
+
+	x := kv{Subspace: dir}
+	exists, err := x.exists(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for mutex: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get owner: %w", err)
+	}
+	queue, err := x.getQueue(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue: %w", err)
+	}
+	last, used, err := x.getLastUsed(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last use: %w", err)
+	}
+
+	// Without a recorded use, the mutex is assumed
+	// to be in use & the idle period starts now.
+	if !used {
+		last = time.Now()
+		if err := x.setLastUsed(tr, last); err != nil {
+			return nil, fmt.Errorf("failed to set last use: %w", err)
+		}
+	}
+	deadline, marked, err := x.getIdleMarker(tr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle marker: %w", err)
+	}
+
+	// If the mutex is in use, remove any mark.
+	if owner.name != "" || len(queue) > 0 || time.Since(last) < policy.MaxIdle {
+		if marked {
+			if err := x.clearIdleMarker(tr); err != nil {
+				return nil, fmt.Errorf("failed to clear idle marker: %w", err)
+			}
+		}
+		return nil, nil
+	}
+
+	if !marked {
+		if err := x.setIdleMarker(tr, time.Now().Add(policy.Grace)); err != nil {
+			return nil, fmt.Errorf("failed to set idle marker: %w", err)
+		}
+		if err := x.logEvent(tr, EventIdle, ""); err != nil {
+			return nil, fmt.Errorf("failed to log event: %w", err)
+		}
+		return nil, nil
+	}
+
+	if time.Now().Before(deadline) {
+		return nil, nil
+	}
+	if _, err := parent.Remove(tr, []string{name}); err != nil {
+		return nil, fmt.Errorf("failed to remove subdirectory: %w", err)
+	}
+	return dir.GetPath(), nil
+}
@@ -0,0 +1,138 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireIdle(t *testing.T) {
+	tests := map[string]testFn{
+		"deleted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+
+			xA, err := NewMutex(db, dirA, "clientA")
+			require.NoError(t, err)
+
+			xB, err := NewMutex(db, dirB, "clientB")
+			require.NoError(t, err)
+
+			// Held mutexes are never deleted.
+			acquired, err := xB.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			policy := IdlePolicy{MaxIdle: 0, Grace: 100 * time.Millisecond}
+
+			// The first pass marks the idle mutex.
+			deleted, err := ExpireIdle(db, parent, policy)
+			require.NoError(t, err)
+			require.Empty(t, deleted)
+
+			events, err := xA.Events(db)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			require.Equal(t, EventIdle, events[0].Kind)
+
+			time.Sleep(200 * time.Millisecond)
+
+			// Once the grace period ends, it's deleted.
+			deleted, err = ExpireIdle(db, parent, policy)
+			require.NoError(t, err)
+			require.Equal(t, [][]string{dirA.GetPath()}, deleted)
+
+			list, err := List(db, parent, nil)
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			require.Equal(t, dirB.GetPath(), list[0].Path)
+		},
+		"used during grace": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			x, err := NewMutex(db, dir, "client")
+			require.NoError(t, err)
+
+			policy := IdlePolicy{MaxIdle: time.Hour, Grace: 0}
+
+			// Pretend the mutex was created long ago.
+			err = x.setLastUsed(db, time.Now().Add(-2*time.Hour))
+			require.NoError(t, err)
+
+			deleted, err := ExpireIdle(db, parent, policy)
+			require.NoError(t, err)
+			require.Empty(t, deleted)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = x.Release(db)
+			require.NoError(t, err)
+
+			// The mutex was used, so the mark is removed.
+			deleted, err = ExpireIdle(db, parent, policy)
+			require.NoError(t, err)
+			require.Empty(t, deleted)
+
+			_, marked, err := x.getIdleMarker(db)
+			require.NoError(t, err)
+			require.False(t, marked)
+		},
+		"never used": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			x, err := NewMutex(db, dir, "client")
+			require.NoError(t, err)
+
+			// Pretend the mutex was created by a version of this
+			// package which didn't record its last use & its
+			// events have been trimmed from the log.
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Clear(x.packLastUsedKey())
+				rngEvents, err := x.packEventRange()
+				if err != nil {
+					return nil, err
+				}
+				tr.ClearRange(rngEvents)
+				return nil, nil
+			})
+			require.NoError(t, err)
+			err = x.setMetadata(db, metadataKV{})
+			require.NoError(t, err)
+
+			policy := IdlePolicy{MaxIdle: time.Hour, Grace: 0}
+
+			// The mutex is treated as used on the first pass.
+			deleted, err := ExpireIdle(db, parent, policy)
+			require.NoError(t, err)
+			require.Empty(t, deleted)
+
+			_, marked, err := x.getIdleMarker(db)
+			require.NoError(t, err)
+			require.False(t, marked)
+
+			last, used, err := x.getLastUsed(db)
+			require.NoError(t, err)
+			require.True(t, used)
+			require.WithinDuration(t, time.Now(), last, time.Minute)
+		},
+	}
+
+	runTests(t, tests)
+}
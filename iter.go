@@ -0,0 +1,173 @@
+package mutex
+
+import (
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+)
+
+// seqPageSize is the number of items read per
+// transaction by the iterators in this file.
+const seqPageSize = 100
+
+// ListSeq is like [[List]] but streams the mutexes instead of returning a
+// slice. The mutexes are read in pages, each in its own transaction, so
+// large directories don't exceed transaction limits. Unlike List, the
+// result isn't a consistent snapshot. If an error occurs, it's yielded
+// and the iteration ends.
+func ListSeq(db fdb.Transactor, parent directory.Directory, filter map[string]string) iter.Seq2[MutexInfo, error] {
+	return func(yield func(MutexInfo, error) bool) {
+		names, err := parent.List(db, nil)
+		if err != nil {
+			yieldErr(yield, fmt.Errorf("failed to list subdirectories: %w", err))
+			return
+		}
+
+		for len(names) > 0 {
+			page := names[:min(len(names), seqPageSize)]
+			names = names[len(page):]
+
+			list, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+				var list []MutexInfo
+				for _, name := range page {
+					ok, err := parent.Exists(tr, []string{name})
+					if err != nil {
+						return nil, fmt.Errorf("failed to check for subdirectory %s: %w", name, err)
+					}
+					// Subdirectories removed since they
+					// were listed are skipped.
+					if !ok {
+						continue
+					}
+					info, ok, err := readInfo(tr, parent, name, filter)
+					if err != nil {
+						return nil, err
+					}
+					if ok {
+						list = append(list, info)
+					}
+				}
+				return list, nil
+			})
+			if err != nil {
+				yieldErr(yield, err)
+				return
+			}
+			for _, info := range list.([]MutexInfo) {
+				if !yield(info, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// CandidateSeq is like [[Mutex.Candidates]] but streams the candidates
+// instead of returning a slice. The queue is read in pages, each in its
+// own transaction, so the result isn't a consistent snapshot. Streaming
+// can't sort the candidates, so they're yielded in the order they were
+// queued rather than by priority. If an error occurs, it's yielded and
+// the iteration ends.
+func (x *Mutex) CandidateSeq(db fdb.Transactor) iter.Seq2[Candidate, error] {
+	return x.candidateSeq(x.withBreaker(db))
+}
+
+// CandidateSeq is like [[Mutex.CandidateSeq]].
+func (x *Observer) CandidateSeq(db fdb.Transactor) iter.Seq2[Candidate, error] {
+	return x.candidateSeq(x.inspect(db, true))
+}
+
+func (x *kv) candidateSeq(db fdb.Transactor) iter.Seq2[Candidate, error] {
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return errSeq[Candidate](fmt.Errorf("failed to pack queue range: %w", err))
+	}
+
+	return func(yield func(Candidate, error) bool) {
+		// Aging is read along with the first
+		// candidate of each iteration.
+		var (
+			aging time.Duration
+			read  bool
+		)
+		seq := rangeSeq(db, rngQueue, func(kv fdb.KeyValue) (Candidate, error) {
+			if !read {
+				var err error
+				if aging, err = x.getAging(db); err != nil {
+					return Candidate{}, fmt.Errorf("failed to get aging: %w", err)
+				}
+				read = true
+			}
+			vstamp, err := x.unpackQueueKey(kv.Key)
+			if err != nil {
+				return Candidate{}, fmt.Errorf("failed to unpack queue key: %w", err)
+			}
+			name, enqueued := x.unpackQueueValue(kv.Value)
+			return x.describeCandidate(db, queueKV{name: name, vstamp: vstamp, enqueued: enqueued}, aging)
+		})
+		seq(yield)
+	}
+}
+
+// AuditLogSeq is like [[AdminClient.AuditLog]] but streams the entries
+// instead of returning a slice. The log is read in pages, each in its own
+// transaction. If an error occurs, it's yielded and the iteration ends.
+func (x *AdminClient) AuditLogSeq(db fdb.Transactor) iter.Seq2[AuditEntry, error] {
+	rngAudit, err := x.packAuditRange()
+	if err != nil {
+		return errSeq[AuditEntry](fmt.Errorf("failed to pack audit range: %w", err))
+	}
+	return rangeSeq(db, rngAudit, x.unpackAuditEntry)
+}
+
+// rangeSeq yields the key-values of the range, decoded by 'fn', reading
+// a page at a time. Each page begins after the last key of the previous.
+func rangeSeq[T any](db fdb.Transactor, rng fdb.KeyRange, fn func(fdb.KeyValue) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		begin := fdb.FirstGreaterOrEqual(rng.Begin)
+		for {
+			kvs, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+				sel := fdb.SelectorRange{Begin: begin, End: fdb.FirstGreaterOrEqual(rng.End)}
+				return tr.GetRange(sel, fdb.RangeOptions{Limit: seqPageSize}).GetSliceWithError()
+			})
+			if err != nil {
+				yieldErr(yield, err)
+				return
+			}
+
+			page := kvs.([]fdb.KeyValue)
+			for _, kv := range page {
+				item, err := fn(kv)
+				if err != nil {
+					yieldErr(yield, err)
+					return
+				}
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(page) < seqPageSize {
+				return
+			}
+			begin = fdb.FirstGreaterThan(page[len(page)-1].Key)
+		}
+	}
+}
+
+// errSeq returns an iterator which only yields the error.
+func errSeq[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		yieldErr(yield, err)
+	}
+}
+
+// yieldErr yields the error, wrapped like the errors
+// returned by the exported methods of this package.
+func yieldErr[T any](yield func(T, error) bool, err error) {
+	wrapErr(&err)
+	var zero T
+	yield(zero, err)
+}
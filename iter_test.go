@@ -0,0 +1,104 @@
+package mutex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeq(t *testing.T) {
+	tests := map[string]testFn{
+		"list": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+
+			for _, name := range []string{"a", "b", "c"} {
+				dir, err := parent.CreateOrOpen(db, []string{name}, nil)
+				require.NoError(t, err)
+				_, err = NewMutex(db, dir, "client")
+				require.NoError(t, err)
+			}
+
+			// This directory doesn't contain a mutex.
+			_, err := parent.CreateOrOpen(db, []string{"d"}, nil)
+			require.NoError(t, err)
+
+			list, err := List(db, parent, nil)
+			require.NoError(t, err)
+
+			var streamed []MutexInfo
+			for info, err := range ListSeq(db, parent, nil) {
+				require.NoError(t, err)
+				streamed = append(streamed, info)
+			}
+			require.Len(t, streamed, 3)
+			require.Equal(t, list, streamed)
+
+			// Breaking ends the iteration.
+			var n int
+			for range ListSeq(db, parent, nil) {
+				n++
+				break
+			}
+			require.Equal(t, 1, n)
+		},
+		"candidates": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner, err := NewMutex(db, root, "owner")
+			require.NoError(t, err)
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Enough waiters to span multiple pages.
+			for i := range seqPageSize + 5 {
+				x, err := NewMutex(db, root, fmt.Sprintf("waiter%03d", i))
+				require.NoError(t, err)
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.False(t, acquired)
+			}
+
+			candidates, err := owner.Candidates(db)
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			var streamed []Candidate
+			for c, err := range obs.CandidateSeq(db) {
+				require.NoError(t, err)
+				streamed = append(streamed, c)
+			}
+			require.Len(t, streamed, seqPageSize+5)
+			require.Equal(t, candidates, streamed)
+		},
+		"audit": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.DirectorySubspace)
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			_, err = NewMutex(db, dir, "client")
+			require.NoError(t, err)
+
+			admin, err := NewAdminClient(parent, "oncall")
+			require.NoError(t, err)
+			require.NoError(t, admin.SetMaintenance(db, "a", true))
+			require.NoError(t, admin.SetMaintenance(db, "a", false))
+
+			log, err := admin.AuditLog(db)
+			require.NoError(t, err)
+
+			var streamed []AuditEntry
+			for entry, err := range admin.AuditLogSeq(db) {
+				require.NoError(t, err)
+				streamed = append(streamed, entry)
+			}
+			require.Len(t, streamed, 2)
+			require.Equal(t, log, streamed)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,36 @@
+package mutex
+
+import (
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// NewKeyedMutex is like [[NewMutex]] but stores the mutex within 'parent',
+// distinguished by 'key', such as a resource ID. Thousands of mutexes may
+// share a single directory this way instead of each requiring its own
+// directory-layer entry, cutting the metadata overhead and the latency of
+// creating them. The mutex is stored in [[KeyedSubspace]], so it can be
+// observed by passing the same subspace to [[NewObserver]]. A hot key may
+// be spread across several mutexes instead. See [[NewShardedMutex]].
+//
+// Keyed mutexes aren't found by the functions which scan directories, such
+// as [[List]] & [[AutoReleaseAll]], and are identified by their subspace
+// prefix rather than a path. See [[ClientSession.Locks]].
+func NewKeyedMutex(db fdb.Transactor, parent subspace.Subspace, key tuple.Tuple, name string, opts ...Option) (*Mutex, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key must not be empty")
+	}
+	return NewMutex(db, KeyedSubspace(parent, key), name, opts...)
+}
+
+// KeyedSubspace returns the subspace storing the mutex identified by 'key'
+// within 'parent'. It's the subspace ("keyed", key) of 'parent', so the keys
+// of a mutex stored directly in 'parent' don't collide with it. The key is
+// nested as a single element, so a key which is a prefix of another, such
+// as ("a") & ("a", "b"), doesn't share any data with it.
+func KeyedSubspace(parent subspace.Subspace, key tuple.Tuple) subspace.Subspace {
+	return parent.Sub("keyed", key)
+}
@@ -0,0 +1,78 @@
+package mutex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	tests := map[string]testFn{
+		"independent": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewKeyedMutex(db, root, nil, "client1")
+			require.Error(t, err)
+
+			// A mutex stored directly in the parent
+			// doesn't collide with the keyed mutexes.
+			plain, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x1, err := NewKeyedMutex(db, root, tuple.Tuple{"order", int64(1)}, "client1")
+			require.NoError(t, err)
+			x2, err := NewKeyedMutex(db, root, tuple.Tuple{"order", int64(2)}, "client1")
+			require.NoError(t, err)
+			other, err := NewKeyedMutex(db, root, tuple.Tuple{"order", int64(1)}, "client2")
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{plain, x1, x2} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+
+			acquired, err := other.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			obs, err := NewObserver(db, KeyedSubspace(root, tuple.Tuple{"order", int64(1)}))
+			require.NoError(t, err)
+			owner, err := obs.Owner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner)
+
+			require.NoError(t, x1.Release(db))
+			owner, err = obs.Owner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner)
+		},
+		"prefix": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			short := KeyedSubspace(root, tuple.Tuple{"a"})
+			long := KeyedSubspace(root, tuple.Tuple{"a", "owner"})
+			require.False(t, bytes.HasPrefix(long.Bytes(), short.Bytes()))
+
+			x1, err := NewKeyedMutex(db, root, tuple.Tuple{"a"}, "client1")
+			require.NoError(t, err)
+			x2, err := NewKeyedMutex(db, root, tuple.Tuple{"a", "owner"}, "client2")
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{x1, x2} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+			owner, err = x2.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner.name)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -1,12 +1,18 @@
 package mutex
 
 import (
+	"cmp"
 	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"path"
+	"slices"
+	"time"
 
-	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 )
 
 type ownerKV struct {
@@ -14,10 +20,79 @@ type ownerKV struct {
 	hbeat []byte
 }
 
+type heartbeatKV struct {
+	vstamp tuple.Versionstamp
+	time   time.Time
+}
+
+type clockKV struct {
+	version int64
+	local   time.Time
+}
+
+type stickyKV struct {
+	name     string
+	deadline time.Time
+}
+
+type preemptKV struct {
+	name  string
+	grace time.Duration
+
+	// deadline is zero until a waiter asks
+	// for the mutex. See [[Mutex.SetPreemptible]].
+	deadline time.Time
+}
+
+type heatKV struct {
+	depth     int
+	epoch     int64
+	contended int64
+}
+
+type handoffKV struct {
+	successor string
+	acked     bool
+	prepared  time.Time
+}
+
+type statsKV struct {
+	acquisitions int64
+	holdTime     time.Duration
+	preemptions  int64
+}
+
+type metadataKV struct {
+	created     time.Time
+	creator     string
+	description string
+}
+
+type queueKV struct {
+	name     string
+	vstamp   tuple.Versionstamp
+	enqueued time.Time
+}
+
 // kv implements the various queries performed by [[Mutex]]. Some
 // of the methods of kv don't include much logic but explicitly
 // define the DB schema.
-type kv struct{ subspace.Subspace }
+type kv struct {
+	subspace.Subspace
+
+	// feed, if not nil, receives a copy of every
+	// event. See [[WithActivityFeed]].
+	feed *ActivityFeed
+
+	// dualRead causes heartbeats & queue entries to be
+	// written using version 1 of the schema, which every
+	// version of this package can read. See [[WithDualRead]].
+	dualRead bool
+
+	// serializer, if not nil, encodes the metadata
+	// of the mutex. See [[WithSerializer]].
+	serializer Serializer
+}
 
 // setOwner sets the owner key for the client with the provided name.
 func (x *kv) setOwner(db fdb.Transactor, name string) error {
@@ -27,12 +102,53 @@ func (x *kv) setOwner(db fdb.Transactor, name string) error {
 	}
 
 	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		// Record per-client statistics before the
+		// previous owner is cleared. See [[kv.getStats]].
+		prev, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if prev.name != name {
+			if err := x.recordHandoff(tr, prev.name, name); err != nil {
+				return nil, fmt.Errorf("failed to record handoff: %w", err)
+			}
+			if name != "" {
+				if err := x.logEvent(tr, EventAcquired, name); err != nil {
+					return nil, fmt.Errorf("failed to log event: %w", err)
+				}
+			}
+		}
+
 		// Clear any existing owner keys.
 		tr.ClearRange(rngOwner)
 
 		// Set the owner key. The heartbeat (value) is left
 		// empty. It's set by the [[kv.heartbeat]] method.
 		tr.Set(x.packOwnerKey(name), nil)
+
+		// Release requests, prepared releases, &
+		// preemptible holds are meant for the
+		// previous owner.
+		tr.Clear(x.packReleaseRequestKey())
+		tr.Clear(x.packHandoffKey())
+		tr.Clear(x.packPreemptKey())
+
+		// A new owner invalidates any reservation held for
+		// the previous owner and starts a new fencing epoch.
+		// Its fencing token is the version of this commit.
+		// See [[Mutex.AcquireToken]].
+		if name != "" {
+			tr.Clear(x.packStickyKey())
+			tr.Add(x.packEpochKey(), packIncrement())
+			if err := x.setToken(tr); err != nil {
+				return nil, fmt.Errorf("failed to set token: %w", err)
+			}
+		}
+
+		// Unlike the epoch, this counts releases too, so
+		// its key conflicts with every change of ownership.
+		// See [[Mutex.ConflictKey]].
+		tr.Add(x.packOwnershipKey(), packIncrement())
 		return nil, nil
 	})
 	return err
@@ -73,29 +189,53 @@ func (x *kv) getOwner(db fdb.Transactor) (ownerKV, error) {
 // changes, the channel returns nil. If the watch setup fails or the provided context
 // is canceled, the channel retuns an error.
 func (x *kv) watchOwner(ctx context.Context, db fdb.Transactor) <-chan error {
-	ch := make(chan error, 1)
-
-	ret, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+	return watch(ctx, db, func(tr fdb.Transaction) (fdb.Key, error) {
 		owner, err := x.getOwner(tr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get owner: %w", err)
 		}
-		return tr.Watch(x.packOwnerKey(owner.name)), nil
+		return x.packOwnerKey(owner.name), nil
+	})
+}
+
+// watch returns a channel which signals a change to the key returned by 'key'.
+// When the key changes, the channel returns nil. If 'key' returns a nil key,
+// the channel returns nil immediately, allowing 'key' to check a condition
+// and set up the watch atomically. If the watch setup fails or the provided
+// context is canceled, the channel returns an error. Watches are cancelled
+// when their transaction times out, so the watch is created outside of any
+// circuit breaker. See [[withoutBreaker]].
+func watch(ctx context.Context, db fdb.Transactor, key func(tr fdb.Transaction) (fdb.Key, error)) <-chan error {
+	ch := make(chan error, 1)
+
+	ret, err := withoutBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		k, err := key(tr)
+		if err != nil {
+			return nil, err
+		}
+		if k == nil {
+			return nil, nil
+		}
+		return tr.Watch(k), nil
 	})
 	if err != nil {
 		ch <- err
 		return ch
 	}
+	if ret == nil {
+		ch <- nil
+		return ch
+	}
 
-	watch := ret.(fdb.FutureNil)
+	future := ret.(fdb.FutureNil)
 
 	go func() {
 		<-ctx.Done()
-		watch.Cancel()
+		future.Cancel()
 	}()
 
 	go func() {
-		ch <- watch.Get()
+		ch <- future.Get()
 	}()
 
 	return ch
@@ -105,74 +245,165 @@ func (x *kv) watchOwner(ctx context.Context, db fdb.Transactor) <-chan error {
 // If the provided name doesn't belong to the owner of the mutex then this
 // method is a noop.
 func (x *kv) heartbeat(db fdb.Transactor, name string) error {
+	_, _, err := x.beat(db, name)
+	return err
+}
+
+// beat is like [[kv.heartbeat]] but also reports whether the client owns
+// the mutex. Each heartbeat records a clock sample, which is returned.
+func (x *kv) beat(db fdb.Transactor, name string) (bool, clockKV, error) {
+	type result struct {
+		owned  bool
+		sample clockKV
+	}
+
 	if name == "" {
-		return nil
+		return false, clockKV{}, nil
 	}
 
-	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
-		owner, err := x.getOwner(db)
+	res, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get owner: %w", err)
 		}
 
 		// If we're not the owner, don't heartbeat.
 		if name != owner.name {
-			return nil, nil
+			return result{}, nil
 		}
 
 		// Update the heartbeat using the current versionstamp.
-		tr.SetVersionstampedValue(x.packOwnerKey(name), x.packOwnerValue())
-		return nil, nil
+		val, err := x.packOwnerValue(time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack owner value: %w", err)
+		}
+		tr.SetVersionstampedValue(x.packOwnerKey(name), val)
+
+		sample, err := x.sampleClock(tr, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample clock: %w", err)
+		}
+		return result{owned: true, sample: sample}, nil
 	})
-	return err
+	if err != nil {
+		return false, clockKV{}, err
+	}
+	r := res.(result)
+	return r.owned, r.sample, nil
 }
 
-// enqueue places the provided client in the queue for control of the mutex.
-// If the provided name is already in the queue then this method is a noop.
-func (x *kv) enqueue(db fdb.Transactor, name string) error {
-	rngQueue, err := x.packQueueRange()
+// sampleClock records the local time alongside the read version of the
+// transaction for the client with the provided name. See [[Mutex.ClockSkews]].
+func (x *kv) sampleClock(tr fdb.Transaction, name string) (clockKV, error) {
+	version, err := tr.GetReadVersion().Get()
 	if err != nil {
-		return fmt.Errorf("failed to pack queue range: %w", err)
+		return clockKV{}, fmt.Errorf("failed to get read version: %w", err)
 	}
+	sample := clockKV{version: version, local: time.Now()}
+	tr.Set(x.packClockKey(name), x.packClockValue(sample))
+	return sample, nil
+}
 
-	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
-		iter := tr.GetRange(rngQueue, fdb.RangeOptions{}).Iterator()
+// getClocks returns the latest clock sample of every
+// client which has recorded one, keyed by client name.
+func (x *kv) getClocks(db fdb.Transactor) (map[string]clockKV, error) {
+	rngClocks, err := x.packClockRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack clock range: %w", err)
+	}
 
-		// If we're already enqueued, skip this operation.
+	clocks, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		clocks := make(map[string]clockKV)
+		iter := tr.GetRange(rngClocks, fdb.RangeOptions{}).Iterator()
 		for iter.Advance() {
-			if name == x.unpackQueueValue(iter.MustGet().Value) {
-				return nil, nil
+			kv := iter.MustGet()
+			name, err := x.unpackClockKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack clock key: %w", err)
 			}
+			sample, err := x.unpackClockValue(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack clock value: %w", err)
+			}
+			clocks[name] = sample
+		}
+		return clocks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clocks.(map[string]clockKV), nil
+}
+
+// enqueue places the provided client in the queue for control of the mutex.
+// If the provided name is already in the queue then this method is a noop.
+// If the priority is non-zero, it's recorded for use by [[kv.dequeue]].
+func (x *kv) enqueue(db fdb.Transactor, name string, priority int64) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		// If we're already enqueued, skip this operation. The
+		// membership index makes this a point read rather than
+		// a scan of the queue.
+		_, queued, err := x.getMember(tr, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get membership: %w", err)
+		}
+		if queued {
+			return nil, nil
 		}
 
 		key, err := x.packQueueKey()
 		if err != nil {
 			return nil, fmt.Errorf("failed to pack the queue key: %w", err)
 		}
+		member, err := x.packMemberValue()
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack the member value: %w", err)
+		}
 
-		// Place ourselves at the end of the queue.
-		tr.SetVersionstampedKey(key, x.packQueueValue(name))
+		// Place ourselves at the end of the queue. Both keys
+		// are written with the same versionstamp, so the index
+		// points at the queue entry.
+		tr.SetVersionstampedKey(key, x.packQueueValue(name, time.Now()))
+		tr.SetVersionstampedValue(x.packMemberKey(name), member)
+		if priority != 0 {
+			tr.Set(x.packPriorityKey(name), x.packPriorityValue(priority))
+		}
+		x.bumpQueueVersion(tr)
 		return nil, nil
 	})
 	return err
 }
 
-// dequeue pops the name off the front of the queue and returns it.
+// dequeue pops the next client off the queue and returns its name. If none
+// of the queued clients have a priority, the client at the front of the queue
+// is chosen. Otherwise, the client with the highest priority is chosen, with
+// ties broken by queue order. Priorities are boosted by the time spent in the
+// queue. See [[Mutex.SetPriorityAging]]. Reserved entries are passed over, and expired
+// reservations are removed. See [[Mutex.ReserveSlot]].
 func (x *kv) dequeue(db fdb.Transactor) (string, error) {
-	rng, err := x.packQueueRange()
-	if err != nil {
-		return "", fmt.Errorf("failed to pack queue range: %w", err)
-	}
-
 	name, err := db.Transact(func(tr fdb.Transaction) (any, error) {
-		iter := tr.GetRange(rng, fdb.RangeOptions{Limit: 1}).Iterator()
-		if !iter.Advance() {
+		if err := x.dropExpiredReservations(tr); err != nil {
+			return nil, fmt.Errorf("failed to drop expired reservations: %w", err)
+		}
+		chosen, found, err := x.chooseNext(tr)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
 			return "", nil
 		}
 
-		kv := iter.MustGet()
-		tr.Clear(kv.Key)
-		return x.unpackQueueValue(kv.Value), nil
+		name, enqueued := x.unpackQueueValue(chosen.Value)
+		if !enqueued.IsZero() {
+			if err := x.recordQueueWait(tr, name, enqueued, time.Now()); err != nil {
+				return nil, fmt.Errorf("failed to record queue wait: %w", err)
+			}
+		}
+		tr.Clear(chosen.Key)
+		tr.Clear(x.packMemberKey(name))
+		tr.Clear(x.packPriorityKey(name))
+		x.bumpQueueVersion(tr)
+		return name, nil
 	})
 	if err != nil {
 		return "", err
@@ -180,53 +411,3058 @@ func (x *kv) dequeue(db fdb.Transactor) (string, error) {
 	return name.(string), nil
 }
 
-func (x *kv) packOwnerRange() (fdb.KeyRange, error) {
-	return fdb.PrefixRange(x.Pack(tuple.Tuple{"owner"}))
+// peekQueue returns the name of the client [[kv.dequeue]]
+// would choose without removing it from the queue.
+func (x *kv) peekQueue(db fdb.Transactor) (string, error) {
+	name, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		chosen, found, err := x.chooseNext(tr)
+		if err != nil || !found {
+			return "", err
+		}
+		name, _ := x.unpackQueueValue(chosen.Value)
+		return name, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return name.(string), nil
 }
 
-func (x *kv) packOwnerKey(name string) fdb.Key {
-	return x.Pack(tuple.Tuple{"owner", name})
+// chooseNext returns the queue entry of the next client to be dequeued.
+// If the queue is empty then false is returned. See [[kv.dequeue]].
+func (x *kv) chooseNext(tr fdb.ReadTransaction) (fdb.KeyValue, bool, error) {
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return fdb.KeyValue{}, false, fmt.Errorf("failed to pack queue range: %w", err)
+	}
+	rngPriority, err := x.packPriorityRange()
+	if err != nil {
+		return fdb.KeyValue{}, false, fmt.Errorf("failed to pack priority range: %w", err)
+	}
+
+	reserved, err := x.getReservations(tr)
+	if err != nil {
+		return fdb.KeyValue{}, false, fmt.Errorf("failed to get reservations: %w", err)
+	}
+	aging, err := x.getAging(tr)
+	if err != nil {
+		return fdb.KeyValue{}, false, fmt.Errorf("failed to get aging: %w", err)
+	}
+
+	priorities := make(map[string]int64)
+	iter := tr.GetRange(rngPriority, fdb.RangeOptions{}).Iterator()
+	for iter.Advance() {
+		kv := iter.MustGet()
+		name, err := x.unpackPriorityKey(kv.Key)
+		if err != nil {
+			return fdb.KeyValue{}, false, fmt.Errorf("failed to unpack priority key: %w", err)
+		}
+		priority, err := x.unpackPriorityValue(kv.Value)
+		if err != nil {
+			return fdb.KeyValue{}, false, fmt.Errorf("failed to unpack priority value: %w", err)
+		}
+		priorities[name] = priority
+	}
+
+	// Without priorities or reservations,
+	// only the front of the queue is needed.
+	opts := fdb.RangeOptions{}
+	if len(priorities) == 0 && len(reserved) == 0 {
+		opts.Limit = 1
+	}
+
+	var (
+		chosen   fdb.KeyValue
+		found    bool
+		priority int64
+	)
+	iter = tr.GetRange(rngQueue, opts).Iterator()
+	for iter.Advance() {
+		kv := iter.MustGet()
+		name, enqueued := x.unpackQueueValue(kv.Value)
+		if _, ok := reserved[name]; ok {
+			continue
+		}
+		p := boostPriority(priorities[name], enqueued, aging)
+		if !found || p > priority {
+			chosen, found, priority = kv, true, p
+		}
+	}
+	return chosen, found, nil
 }
 
-func (x *kv) unpackOwnerKey(key fdb.Key) (string, error) {
-	tup, err := x.Unpack(key)
+// hasCandidates returns true if any client is waiting in the queue.
+// Only the front of the queue is read.
+func (x *kv) hasCandidates(db fdb.Transactor) (bool, error) {
+	rngQueue, err := x.packQueueRange()
 	if err != nil {
-		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+		return false, fmt.Errorf("failed to pack queue range: %w", err)
 	}
-	if len(tup) != 2 {
-		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+
+	queued, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		iter := tr.GetRange(rngQueue, fdb.RangeOptions{Limit: 1}).Iterator()
+		return iter.Advance(), nil
+	})
+	if err != nil {
+		return false, err
 	}
-	// The 1st element should be the string "owner". We won't
-	// bother confirming that. The 2nd is the name of the owner.
-	name, ok := tup[1].(string)
-	if !ok {
-		return "", fmt.Errorf("tuple element 1 is not a string")
+	return queued.(bool), nil
+}
+
+// isQueued returns true if the client with the
+// provided name is waiting in the queue.
+func (x *kv) isQueued(db fdb.Transactor, name string) (bool, error) {
+	queued, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		_, queued, err := x.getMember(tr, name)
+		return queued, err
+	})
+	if err != nil {
+		return false, err
 	}
-	return name, nil
+	return queued.(bool), nil
+}
+
+// getMember returns the key of the queue entry of the client with the
+// provided name, found using the membership index. If the client isn't
+// queued, false is returned. The index is only trusted if it points
+// at an entry of the client, so a stale index is ignored.
+func (x *kv) getMember(tr fdb.ReadTransaction, name string) (fdb.Key, bool, error) {
+	val, err := tr.Get(x.packMemberKey(name)).Get()
+	if err != nil {
+		return nil, false, err
+	}
+	if val == nil {
+		return nil, false, nil
+	}
+	vstamp, err := x.unpackMemberValue(val)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unpack member value: %w", err)
+	}
+
+	key := x.packQueueKeyAt(vstamp)
+	entry, err := tr.Get(key).Get()
+	if err != nil {
+		return nil, false, err
+	}
+	if entry == nil {
+		return nil, false, nil
+	}
+	if queued, _ := x.unpackQueueValue(entry); queued != name {
+		return nil, false, nil
+	}
+	return key, true, nil
 }
 
-func (x *kv) packOwnerValue() []byte {
-	// Return a blank parameter for versionstamping
-	// the value. This will result in the value
-	// simply being the 12 byte versionstamp.
-	// See [[fdb.Transaction.SetVersionstampedValue]]
-	// for details.
-	return make([]byte, 16)
+// indexQueue writes the membership index of every queue entry which
+// isn't indexed, such as those written before the index existed.
+func (x *kv) indexQueue(tr fdb.Transaction) error {
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack queue range: %w", err)
+	}
+
+	iter := tr.GetRange(rngQueue, fdb.RangeOptions{}).Iterator()
+	for iter.Advance() {
+		kv := iter.MustGet()
+		vstamp, err := x.unpackQueueKey(kv.Key)
+		if err != nil {
+			return fmt.Errorf("failed to unpack queue key: %w", err)
+		}
+		name, _ := x.unpackQueueValue(kv.Value)
+		_, queued, err := x.getMember(tr, name)
+		if err != nil {
+			return fmt.Errorf("failed to get membership: %w", err)
+		}
+		if !queued {
+			tr.Set(x.packMemberKey(name), tuple.Tuple{vstamp}.Pack())
+		}
+	}
+	return nil
 }
 
-func (x *kv) packQueueRange() (fdb.KeyRange, error) {
-	return fdb.PrefixRange(x.Pack(tuple.Tuple{"queue"}))
+// getPriority returns the priority of the queued client with the
+// provided name. Clients without a priority have a priority of zero.
+func (x *kv) getPriority(db fdb.ReadTransactor, name string) (int64, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packPriorityKey(name)).Get()
+	})
+	if err != nil {
+		return 0, err
+	}
+	if val.([]byte) == nil {
+		return 0, nil
+	}
+	return x.unpackPriorityValue(val.([]byte))
 }
 
-func (x *kv) packQueueKey() (fdb.Key, error) {
-	tup := tuple.Tuple{"queue", tuple.IncompleteVersionstamp(0)}
-	return tup.PackWithVersionstamp(x.Bytes())
+// setReservation marks the queue entry of the client with the provided
+// name as reserved until the deadline. See [[Mutex.ReserveSlot]].
+func (x *kv) setReservation(db fdb.Transactor, name string, deadline time.Time) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packReservationKey(name), x.packTimeValue(deadline))
+		return nil, nil
+	})
+	return err
 }
 
-func (x *kv) packQueueValue(name string) []byte {
-	return []byte(name)
+// getReservations returns the deadlines of the reserved
+// queue entries, keyed by the names of their clients.
+func (x *kv) getReservations(db fdb.ReadTransactor) (map[string]time.Time, error) {
+	rngReservations, err := x.packReservationRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack reservation range: %w", err)
+	}
+
+	reserved, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		reserved := make(map[string]time.Time)
+		iter := tr.GetRange(rngReservations, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv, err := iter.Get()
+			if err != nil {
+				return nil, err
+			}
+			name, err := x.unpackReservationKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack reservation key: %w", err)
+			}
+			deadline, err := x.unpackTimeValue(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack reservation value: %w", err)
+			}
+			reserved[name] = deadline
+		}
+		return reserved, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reserved.(map[string]time.Time), nil
 }
 
-func (x *kv) unpackQueueValue(val []byte) string {
-	return string(val)
+// dropExpiredReservations removes the queue entries whose
+// reservations expired without being claimed.
+func (x *kv) dropExpiredReservations(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		reserved, err := x.getReservations(tr)
+		if err != nil {
+			return nil, err
+		}
+		for name, deadline := range reserved {
+			if time.Now().Before(deadline) {
+				continue
+			}
+			if err := x.removeFromQueue(tr, name); err != nil {
+				return nil, fmt.Errorf("failed to remove %s from queue: %w", name, err)
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// activateReservation clears the reservation of the client with the provided
+// name, making its queue entry eligible for dequeue. If the reservation
+// expired, the entry is removed instead, so the client joins the back of the
+// queue. Whether a valid reservation was activated is returned.
+func (x *kv) activateReservation(db fdb.Transactor, name string) (bool, error) {
+	activated, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		val, err := tr.Get(x.packReservationKey(name)).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reservation: %w", err)
+		}
+		if val == nil {
+			return false, nil
+		}
+		deadline, err := x.unpackTimeValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack reservation: %w", err)
+		}
+		if !time.Now().Before(deadline) {
+			return false, x.removeFromQueue(tr, name)
+		}
+		tr.Clear(x.packReservationKey(name))
+		x.bumpQueueVersion(tr)
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return activated.(bool), nil
+}
+
+// getQueue returns the clients in the queue, ordered from front to back.
+func (x *kv) getQueue(db fdb.ReadTransactor) ([]queueKV, error) {
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack queue range: %w", err)
+	}
+
+	queue, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var queue []queueKV
+		iter := tr.GetRange(rngQueue, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			vstamp, err := x.unpackQueueKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack queue key: %w", err)
+			}
+			name, enqueued := x.unpackQueueValue(kv.Value)
+			queue = append(queue, queueKV{
+				name:     name,
+				vstamp:   vstamp,
+				enqueued: enqueued,
+			})
+		}
+		return queue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return queue.([]queueKV), nil
+}
+
+// getCandidates returns the queued clients in the order [[kv.chooseNext]]
+// would dequeue them, ignoring reservations. See [[Mutex.Candidates]].
+func (x *kv) getCandidates(db fdb.ReadTransactor) ([]Candidate, error) {
+	candidates, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		aging, err := x.getAging(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get aging: %w", err)
+		}
+		queue, err := x.getQueue(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get queue: %w", err)
+		}
+
+		candidates := make([]Candidate, len(queue))
+		for i, q := range queue {
+			if candidates[i], err = x.describeCandidate(tr, q, aging); err != nil {
+				return nil, err
+			}
+		}
+
+		// The sort is stable, so equal priorities
+		// remain in the order they were queued.
+		slices.SortStableFunc(candidates, func(a, b Candidate) int {
+			return cmp.Compare(b.Priority, a.Priority)
+		})
+		return candidates, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candidates.([]Candidate), nil
+}
+
+// describeCandidate reads the priority & attributes of the given queue entry.
+// 'aging' is the interval returned by [[kv.getAging]].
+func (x *kv) describeCandidate(db fdb.ReadTransactor, q queueKV, aging time.Duration) (Candidate, error) {
+	priority, err := x.getPriority(db, q.name)
+	if err != nil {
+		return Candidate{}, fmt.Errorf("failed to get priority of %s: %w", q.name, err)
+	}
+	attrs, err := x.getAttrs(db, q.name)
+	if err != nil {
+		return Candidate{}, fmt.Errorf("failed to get attributes of %s: %w", q.name, err)
+	}
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+	return Candidate{
+		Name:     q.name,
+		Version:  q.vstamp,
+		Enqueued: q.enqueued,
+		Priority: boostPriority(priority, q.enqueued, aging),
+		Attrs:    attrs,
+	}, nil
+}
+
+// watchQueue returns a channel which signals a change to the queue. When a
+// client is enqueued or dequeued, the channel returns nil. If the watch setup
+// fails or the provided context is canceled, the channel returns an error.
+func (x *kv) watchQueue(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packQueueVersionKey(), nil
+	})
+}
+
+// bumpQueueVersion increments the queue version key,
+// triggering any watches created by [[kv.watchQueue]].
+func (x *kv) bumpQueueVersion(tr fdb.Transaction) {
+	tr.Add(x.packQueueVersionKey(), packIncrement())
+}
+
+// getEpoch returns the fencing epoch, which is incremented
+// every time the mutex is granted to a client.
+func (x *kv) getEpoch(db fdb.Transactor) (int64, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packEpochKey()).Get()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return x.unpackEpochValue(val.([]byte)), nil
+}
+
+// setToken records the commit version of the transaction as the fencing
+// token of the current hold. The token can't be read by the transaction
+// which sets it. See [[Mutex.AcquireToken]].
+func (x *kv) setToken(tr fdb.Transaction) error {
+	val, err := x.packTokenValue()
+	if err != nil {
+		return fmt.Errorf("failed to pack token value: %w", err)
+	}
+	tr.SetVersionstampedValue(x.packTokenKey(), val)
+	return nil
+}
+
+// getToken returns the fencing token of the current hold, which is the
+// commit version of the transaction which granted it. If the mutex has
+// never been granted, zero is returned.
+func (x *kv) getToken(db fdb.Transactor) (int64, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packTokenKey()).Get()
+	})
+	if err != nil {
+		return 0, err
+	}
+	if val.([]byte) == nil {
+		return 0, nil
+	}
+	vstamp, err := x.unpackTokenValue(val.([]byte))
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack token value: %w", err)
+	}
+	return commitVersion(vstamp), nil
+}
+
+// readerKV is a client holding the mutex for reading. The age is the time
+// since the reader's latest heartbeat, derived from database versions.
+// See [[RWMutex]].
+type readerKV struct {
+	name string
+	age  time.Duration
+}
+
+// addReader registers the client with the provided name as a reader
+// and records its first heartbeat.
+func (x *kv) addReader(tr fdb.Transaction, name string) error {
+	val, err := x.packReaderValue()
+	if err != nil {
+		return fmt.Errorf("failed to pack reader value: %w", err)
+	}
+	tr.SetVersionstampedValue(x.packReaderKey(name), val)
+	tr.Add(x.packReaderVersionKey(), packIncrement())
+	return nil
+}
+
+// beatReader updates the heartbeat of the reader with the provided name.
+// If the client isn't registered as a reader, false is returned.
+func (x *kv) beatReader(db fdb.Transactor, name string) (bool, error) {
+	val, err := x.packReaderValue()
+	if err != nil {
+		return false, fmt.Errorf("failed to pack reader value: %w", err)
+	}
+
+	ok, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		cur, err := tr.Get(x.packReaderKey(name)).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reader: %w", err)
+		}
+		if cur == nil {
+			return false, nil
+		}
+		tr.SetVersionstampedValue(x.packReaderKey(name), val)
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok.(bool), nil
+}
+
+// removeReader unregisters the reader with the provided name, triggering
+// any watches created by [[kv.watchReaders]]. If the client isn't
+// registered as a reader then this method is a noop.
+func (x *kv) removeReader(db fdb.Transactor, name string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packReaderKey(name))
+		tr.Add(x.packReaderVersionKey(), packIncrement())
+		return nil, nil
+	})
+	return err
+}
+
+// getReaders returns the registered readers & the ages of their heartbeats.
+// If 'maxAge' is positive, readers whose heartbeats are older are removed
+// and aren't returned.
+func (x *kv) getReaders(db fdb.Transactor, maxAge time.Duration) ([]readerKV, error) {
+	rngReaders, err := x.packReaderRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack reader range: %w", err)
+	}
+
+	readers, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		readVersion, err := tr.GetReadVersion().Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get read version: %w", err)
+		}
+
+		var readers []readerKV
+		iter := tr.GetRange(rngReaders, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			name, err := x.unpackReaderKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack reader key: %w", err)
+			}
+			vstamp, err := x.unpackReaderValue(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack reader value: %w", err)
+			}
+			// FDB advances roughly one million versions per second.
+			age := time.Duration(readVersion-commitVersion(vstamp)) * time.Microsecond
+			if maxAge > 0 && age > maxAge {
+				if err := x.removeReader(tr, name); err != nil {
+					return nil, fmt.Errorf("failed to remove reader: %w", err)
+				}
+				continue
+			}
+			readers = append(readers, readerKV{name: name, age: age})
+		}
+		return readers, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return readers.([]readerKV), nil
+}
+
+// watchReaders returns a channel which signals
+// when a reader is registered or unregistered.
+func (x *kv) watchReaders(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packReaderVersionKey(), nil
+	})
+}
+
+// commitVersion returns the commit version of the
+// transaction which completed the versionstamp.
+func commitVersion(vstamp tuple.Versionstamp) int64 {
+	return int64(binary.BigEndian.Uint64(vstamp.TransactionVersion[:8]))
+}
+
+// getHoldEpoch returns the fencing epoch of the hold of the client with
+// the provided name. If the client doesn't own the mutex, false is returned.
+func (x *kv) getHoldEpoch(db fdb.Transactor, name string) (int64, bool, error) {
+	type result struct {
+		epoch int64
+		ok    bool
+	}
+
+	res, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name != name {
+			return result{}, nil
+		}
+		epoch, err := x.getEpoch(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get epoch: %w", err)
+		}
+		return result{epoch: epoch, ok: true}, nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	r := res.(result)
+	return r.epoch, r.ok, nil
+}
+
+// getHoldToken returns the fencing token of the hold of the client with
+// the provided name. If the client doesn't own the mutex, false is returned.
+func (x *kv) getHoldToken(db fdb.Transactor, name string) (int64, bool, error) {
+	type result struct {
+		token int64
+		ok    bool
+	}
+
+	res, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name != name {
+			return result{}, nil
+		}
+		token, err := x.getToken(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		return result{token: token, ok: true}, nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	r := res.(result)
+	return r.token, r.ok, nil
+}
+
+// removeFromQueue removes the client with the provided name from the
+// queue. If the client isn't in the queue then this method is a noop.
+func (x *kv) removeFromQueue(db fdb.Transactor, name string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		key, queued, err := x.getMember(tr, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get membership: %w", err)
+		}
+		if queued {
+			tr.Clear(key)
+			tr.Clear(x.packPriorityKey(name))
+			x.bumpQueueVersion(tr)
+		}
+		tr.Clear(x.packMemberKey(name))
+		tr.Clear(x.packReservationKey(name))
+		return nil, nil
+	})
+	return err
+}
+
+// purgeQueue removes every client from the queue
+// and returns the number of clients removed.
+func (x *kv) purgeQueue(db fdb.Transactor) (int, error) {
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack queue range: %w", err)
+	}
+	rngPriority, err := x.packPriorityRange()
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack priority range: %w", err)
+	}
+	rngMember, err := x.packMemberRange()
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack member range: %w", err)
+	}
+
+	count, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		kvs, err := tr.GetRange(rngQueue, fdb.RangeOptions{}).GetSliceWithError()
+		if err != nil {
+			return nil, err
+		}
+		if len(kvs) > 0 {
+			tr.ClearRange(rngQueue)
+			x.bumpQueueVersion(tr)
+		}
+		tr.ClearRange(rngMember)
+		tr.ClearRange(rngPriority)
+		return len(kvs), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.(int), nil
+}
+
+// setTransfer records whether the client with the provided
+// name accepts the mutex being transferred to it.
+func (x *kv) setTransfer(db fdb.Transactor, name string, accept bool) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if accept {
+			tr.Set(x.packTransferKey(name), nil)
+		} else {
+			tr.Clear(x.packTransferKey(name))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// getTransfer returns true if the client with the provided
+// name accepts the mutex being transferred to it.
+func (x *kv) getTransfer(db fdb.Transactor, name string) (bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packTransferKey(name)).Get()
+	})
+	if err != nil {
+		return false, err
+	}
+	return val.([]byte) != nil, nil
+}
+
+// watchTransfer returns a channel which signals when the client with the
+// provided name accepts a transfer or its acceptance is withdrawn. If the
+// watch setup fails or the provided context is canceled, the channel
+// returns an error.
+func (x *kv) watchTransfer(ctx context.Context, db fdb.Transactor, name string) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packTransferKey(name), nil
+	})
+}
+
+// setRotation adds the client with the provided name to, or removes
+// it from, the set of clients the mutex rotates among.
+func (x *kv) setRotation(db fdb.Transactor, name string, member bool) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if member {
+			tr.Set(x.packRotationKey(name), nil)
+		} else {
+			tr.Clear(x.packRotationKey(name))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// getRotation returns the names of the clients the
+// mutex rotates among, sorted by name.
+func (x *kv) getRotation(db fdb.Transactor) ([]string, error) {
+	rngRotation, err := x.packRotationRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack rotation range: %w", err)
+	}
+
+	names, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var names []string
+		iter := tr.GetRange(rngRotation, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv, err := iter.Get()
+			if err != nil {
+				return nil, err
+			}
+			name, err := x.unpackRotationKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack rotation key: %w", err)
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names.([]string), nil
+}
+
+// setSchedule stores the acquisition schedule of the mutex.
+func (x *kv) setSchedule(db fdb.Transactor, s Schedule) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packScheduleKey(), x.packScheduleValue(s))
+		return nil, nil
+	})
+	return err
+}
+
+// getSchedule returns the acquisition schedule of the mutex.
+// If the mutex has no schedule, false is returned.
+func (x *kv) getSchedule(db fdb.Transactor) (Schedule, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packScheduleKey()).Get()
+	})
+	if err != nil {
+		return Schedule{}, false, err
+	}
+	if val.([]byte) == nil {
+		return Schedule{}, false, nil
+	}
+	s, err := x.unpackScheduleValue(val.([]byte))
+	if err != nil {
+		return Schedule{}, false, fmt.Errorf("failed to unpack schedule: %w", err)
+	}
+	return s, true, nil
+}
+
+// clearSchedule removes the acquisition schedule of the mutex.
+func (x *kv) clearSchedule(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packScheduleKey())
+		return nil, nil
+	})
+	return err
+}
+
+// setAging stores the interval after which the priority of a
+// queued client is boosted. A zero interval disables aging.
+func (x *kv) setAging(db fdb.Transactor, interval time.Duration) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if interval == 0 {
+			tr.Clear(x.packAgingKey())
+		} else {
+			tr.Set(x.packAgingKey(), x.packAgingValue(interval))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// getAging returns the interval after which the priority of a queued
+// client is boosted. If aging is disabled, zero is returned.
+func (x *kv) getAging(db fdb.ReadTransactor) (time.Duration, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packAgingKey()).Get()
+	})
+	if err != nil {
+		return 0, err
+	}
+	if val.([]byte) == nil {
+		return 0, nil
+	}
+	interval, err := x.unpackAgingValue(val.([]byte))
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack aging: %w", err)
+	}
+	return interval, nil
+}
+
+// setPreempt marks the current hold as preemptible. See [[Mutex.SetPreemptible]].
+func (x *kv) setPreempt(db fdb.Transactor, p preemptKV) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packPreemptKey(), x.packPreemptValue(p))
+		return nil, nil
+	})
+	return err
+}
+
+// getPreempt returns the preemptible hold. If the current
+// hold isn't preemptible, false is returned.
+func (x *kv) getPreempt(db fdb.Transactor) (preemptKV, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packPreemptKey()).Get()
+	})
+	if err != nil {
+		return preemptKV{}, false, err
+	}
+	if val.([]byte) == nil {
+		return preemptKV{}, false, nil
+	}
+	p, err := x.unpackPreemptValue(val.([]byte))
+	if err != nil {
+		return preemptKV{}, false, fmt.Errorf("failed to unpack preempt value: %w", err)
+	}
+	return p, true, nil
+}
+
+// clearPreempt makes the current hold non-preemptible.
+func (x *kv) clearPreempt(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packPreemptKey())
+		return nil, nil
+	})
+	return err
+}
+
+// beginOperation records an operation tagged with the given epoch.
+func (x *kv) beginOperation(db fdb.Transactor, op Operation, name string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packOperationKey(op), x.packOperationValue(name, time.Now()))
+		return nil, nil
+	})
+	return err
+}
+
+// completeOperation removes the record of an operation, triggering
+// any watches created by [[kv.waitForEpochDrain]].
+func (x *kv) completeOperation(db fdb.Transactor, op Operation) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packOperationKey(op))
+		tr.Add(x.packOperationVersionKey(), packIncrement())
+		return nil, nil
+	})
+	return err
+}
+
+// hasOperationsBefore returns true if any operation
+// tagged with an epoch older than 'epoch' is incomplete.
+func (x *kv) hasOperationsBefore(db fdb.Transactor, epoch int64) (bool, error) {
+	rng := fdb.KeyRange{
+		Begin: x.Pack(tuple.Tuple{"operation"}),
+		End:   x.Pack(tuple.Tuple{"operation", epoch}),
+	}
+	kvs, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.GetRange(rng, fdb.RangeOptions{Limit: 1}).GetSliceWithError()
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(kvs.([]fdb.KeyValue)) > 0, nil
+}
+
+// setMaintenance puts the mutex into or takes it out of maintenance mode.
+// When maintenance ends and the mutex is vacant, it's handed to the queue.
+// See [[AdminClient.SetMaintenance]].
+func (x *kv) setMaintenance(db fdb.Transactor, on bool) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if on {
+			tr.Set(x.packMaintenanceKey(), x.packTimeValue(time.Now()))
+			return nil, nil
+		}
+
+		val, err := tr.Get(x.packMaintenanceKey()).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get maintenance: %w", err)
+		}
+		if val == nil {
+			return nil, nil
+		}
+		tr.Clear(x.packMaintenanceKey())
+		return nil, x.resume(tr)
+	})
+	return err
+}
+
+// getMaintenance returns true if the mutex is in maintenance mode.
+func (x *kv) getMaintenance(db fdb.Transactor) (bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packMaintenanceKey()).Get()
+	})
+	if err != nil {
+		return false, err
+	}
+	return val.([]byte) != nil, nil
+}
+
+// setFrozen freezes or unfreezes the mutex. When the mutex is unfrozen
+// and vacant, it's handed to the queue. See [[AdminClient.Freeze]].
+func (x *kv) setFrozen(db fdb.Transactor, on bool) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if on {
+			tr.Set(x.packFrozenKey(), x.packTimeValue(time.Now()))
+			return nil, nil
+		}
+
+		val, err := tr.Get(x.packFrozenKey()).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get frozen: %w", err)
+		}
+		if val == nil {
+			return nil, nil
+		}
+		tr.Clear(x.packFrozenKey())
+		return nil, x.resume(tr)
+	})
+	return err
+}
+
+// getFrozen returns true if the mutex is frozen.
+func (x *kv) getFrozen(db fdb.Transactor) (bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packFrozenKey()).Get()
+	})
+	if err != nil {
+		return false, err
+	}
+	return val.([]byte) != nil, nil
+}
+
+// setDisabled disables or enables the mutex. When the mutex is enabled
+// and vacant, it's handed to the queue. See [[Mutex.Disable]].
+func (x *kv) setDisabled(db fdb.Transactor, on bool) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if on {
+			tr.Set(x.packDisabledKey(), x.packTimeValue(time.Now()))
+			return nil, nil
+		}
+
+		val, err := tr.Get(x.packDisabledKey()).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get disabled: %w", err)
+		}
+		if val == nil {
+			return nil, nil
+		}
+		tr.Clear(x.packDisabledKey())
+		return nil, x.resume(tr)
+	})
+	return err
+}
+
+// getDisabled returns true if the mutex is disabled.
+func (x *kv) getDisabled(db fdb.Transactor) (bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packDisabledKey()).Get()
+	})
+	if err != nil {
+		return false, err
+	}
+	return val.([]byte) != nil, nil
+}
+
+// setDenied adds the client-name pattern to or removes it from the
+// mutex's denylist. See [[AdminClient.Deny]].
+func (x *kv) setDenied(db fdb.Transactor, pattern string, deny bool) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if deny {
+			tr.Set(x.packDenyKey(pattern), nil)
+		} else {
+			tr.Clear(x.packDenyKey(pattern))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// getDenylist returns the client-name patterns which
+// are forbidden from acquiring the mutex.
+func (x *kv) getDenylist(db fdb.Transactor) ([]string, error) {
+	rngDeny, err := x.packDenyRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack deny range: %w", err)
+	}
+
+	patterns, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var patterns []string
+		iter := tr.GetRange(rngDeny, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv, err := iter.Get()
+			if err != nil {
+				return nil, err
+			}
+			pattern, err := x.unpackDenyKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack deny key: %w", err)
+			}
+			patterns = append(patterns, pattern)
+		}
+		return patterns, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patterns.([]string), nil
+}
+
+// isDenied returns true if the client with the provided
+// name matches a pattern of the mutex's denylist.
+func (x *kv) isDenied(db fdb.Transactor, name string) (bool, error) {
+	patterns, err := x.getDenylist(db)
+	if err != nil {
+		return false, err
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setRateLimit stores the acquisition rate limit of the mutex. A zero
+// limit removes it. See [[Mutex.SetRateLimit]].
+func (x *kv) setRateLimit(db fdb.Transactor, limit int64, interval time.Duration) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if limit == 0 {
+			tr.Clear(x.packRateLimitKey())
+		} else {
+			tr.Set(x.packRateLimitKey(), x.packRateLimitValue(limit, interval))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// getRateLimit returns the acquisition rate limit of the mutex.
+// If the mutex isn't rate limited, a zero limit is returned.
+func (x *kv) getRateLimit(db fdb.Transactor) (int64, time.Duration, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packRateLimitKey()).Get()
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if val.([]byte) == nil {
+		return 0, 0, nil
+	}
+	limit, interval, err := x.unpackRateLimitValue(val.([]byte))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to unpack rate limit: %w", err)
+	}
+	return limit, interval, nil
+}
+
+// countAttempt counts an acquisition attempt against the rate limit of the
+// mutex. If the limit was exceeded, the time until the current interval
+// ends is returned. Attempts are counted with atomic adds & read at snapshot
+// isolation, so concurrent attempts don't conflict but the limit may be
+// exceeded slightly.
+func (x *kv) countAttempt(tr fdb.Transaction) (time.Duration, error) {
+	limit, interval, err := x.getRateLimit(tr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+	if limit == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	window := now.UnixNano() / int64(interval)
+	key := x.packAttemptsKey(window)
+
+	val, err := tr.Snapshot().Get(key).Get()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get attempts: %w", err)
+	}
+	tr.Add(key, packIncrement())
+
+	// Only the current interval is kept.
+	tr.ClearRange(fdb.KeyRange{Begin: x.packAttemptsKey(0), End: key})
+
+	if unpackCounter(val) < limit {
+		return 0, nil
+	}
+	return time.Duration((window+1)*int64(interval) - now.UnixNano()), nil
+}
+
+// countContended counts an acquisition attempt which found the mutex held
+// by another client. It's an atomic add, so attempts don't conflict.
+func (x *kv) countContended(tr fdb.Transaction) {
+	tr.Add(x.packContendedKey(), packIncrement())
+}
+
+// getHeat samples the contention metrics of the mutex.
+func (x *kv) getHeat(db fdb.Transactor) (heatKV, error) {
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return heatKV{}, fmt.Errorf("failed to pack queue range: %w", err)
+	}
+
+	heat, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		queue := tr.GetRange(rngQueue, fdb.RangeOptions{}).GetSliceOrPanic()
+		return heatKV{
+			depth:     len(queue),
+			epoch:     x.unpackEpochValue(tr.Get(x.packEpochKey()).MustGet()),
+			contended: unpackCounter(tr.Get(x.packContendedKey()).MustGet()),
+		}, nil
+	})
+	if err != nil {
+		return heatKV{}, err
+	}
+	return heat.(heatKV), nil
+}
+
+// resume hands a vacant mutex to the queue after
+// acquisitions were blocked, unless they still are.
+func (x *kv) resume(tr fdb.Transaction) error {
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return fmt.Errorf("failed to get owner: %w", err)
+	}
+	if owner.name != "" {
+		return nil
+	}
+	blocked, err := x.blocked(tr)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return nil
+	}
+	next, err := x.dequeue(tr)
+	if err != nil {
+		return fmt.Errorf("failed to dequeue: %w", err)
+	}
+	if next == "" {
+		return nil
+	}
+	return x.setOwner(tr, next)
+}
+
+// blocked returns true if new acquisitions of the mutex are blocked, either
+// by maintenance mode, a freeze, the mutex being disabled, or a closed
+// acquisition window.
+func (x *kv) blocked(db fdb.Transactor) (bool, error) {
+	disabled, err := x.getDisabled(db)
+	if err != nil {
+		return false, fmt.Errorf("failed to get disabled: %w", err)
+	}
+	if disabled {
+		return true, nil
+	}
+	maintenance, err := x.getMaintenance(db)
+	if err != nil {
+		return false, fmt.Errorf("failed to get maintenance: %w", err)
+	}
+	if maintenance {
+		return true, nil
+	}
+	frozen, err := x.getFrozen(db)
+	if err != nil {
+		return false, fmt.Errorf("failed to get frozen: %w", err)
+	}
+	if frozen {
+		return true, nil
+	}
+	wait, _, err := x.untilWindow(db, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to check schedule: %w", err)
+	}
+	return wait != 0, nil
+}
+
+// requestRelease asks the current owner to release the mutex on
+// behalf of the client with the provided name. The request is
+// cleared when ownership changes.
+func (x *kv) requestRelease(db fdb.Transactor, name string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packReleaseRequestKey(), []byte(name))
+		return nil, nil
+	})
+	return err
+}
+
+// watchReleaseRequest returns a channel which signals a release request. If a
+// request is already pending, the channel returns nil immediately. Otherwise,
+// it returns nil once a request is made. If the watch setup fails or the
+// provided context is canceled, the channel returns an error.
+func (x *kv) watchReleaseRequest(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(tr fdb.Transaction) (fdb.Key, error) {
+		val, err := tr.Get(x.packReleaseRequestKey()).Get()
+		if err != nil {
+			return nil, err
+		}
+		if val != nil {
+			return nil, nil
+		}
+		return x.packReleaseRequestKey(), nil
+	})
+}
+
+// setHandoff records a release prepared by the owner.
+// See [[Mutex.PrepareRelease]].
+func (x *kv) setHandoff(db fdb.Transactor, handoff handoffKV) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packHandoffKey(), x.packHandoffValue(handoff))
+		return nil, nil
+	})
+	return err
+}
+
+// getHandoff returns the release prepared by the owner.
+// If no release is prepared then false is returned.
+func (x *kv) getHandoff(db fdb.Transactor) (handoffKV, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packHandoffKey()).Get()
+	})
+	if err != nil {
+		return handoffKV{}, false, err
+	}
+	if val.([]byte) == nil {
+		return handoffKV{}, false, nil
+	}
+	handoff, err := x.unpackHandoffValue(val.([]byte))
+	if err != nil {
+		return handoffKV{}, false, fmt.Errorf("failed to unpack handoff value: %w", err)
+	}
+	return handoff, true, nil
+}
+
+// clearHandoff removes the release prepared by the owner.
+func (x *kv) clearHandoff(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packHandoffKey())
+		return nil, nil
+	})
+	return err
+}
+
+// watchHandoff returns a channel which signals a change to the prepared
+// release. If the watch setup fails or the provided context is canceled,
+// the channel returns an error.
+func (x *kv) watchHandoff(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packHandoffKey(), nil
+	})
+}
+
+// recordHandoff updates the statistics of the clients involved in an
+// ownership change. The previous owner's hold time is accumulated and
+// the new owner's acquisition is counted. Either name may be blank.
+func (x *kv) recordHandoff(tr fdb.Transaction, prev, next string) error {
+	now := time.Now()
+
+	if prev != "" {
+		val, err := tr.Get(x.packOwnerSinceKey()).Get()
+		if err != nil {
+			return fmt.Errorf("failed to get owner since: %w", err)
+		}
+		if val != nil {
+			since, err := x.unpackTimeValue(val)
+			if err != nil {
+				return fmt.Errorf("failed to unpack owner since: %w", err)
+			}
+			if held := now.Sub(since); held > 0 {
+				tr.Add(x.packStatKey(prev, "holdNanos"), packCounter(int64(held)))
+			}
+		}
+	}
+
+	// Every change of ownership counts as
+	// a use. See [[kv.getLastUsed]].
+	tr.Set(x.packLastUsedKey(), x.packTimeValue(now))
+
+	if next != "" {
+		tr.Add(x.packStatKey(next, "acquisitions"), packIncrement())
+		tr.Set(x.packOwnerSinceKey(), x.packTimeValue(now))
+	} else {
+		tr.Clear(x.packOwnerSinceKey())
+	}
+	return nil
+}
+
+// recordPreemption counts a hold of the client with the provided
+// name being ended without the client releasing the mutex.
+func (x *kv) recordPreemption(db fdb.Transactor, name string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Add(x.packStatKey(name, "preemptions"), packIncrement())
+		return nil, nil
+	})
+	return err
+}
+
+// getStats returns the statistics of every client which has held the mutex,
+// keyed by client name. If the current owner is included, its in-progress
+// hold is included in its hold time.
+func (x *kv) getStats(db fdb.Transactor) (map[string]statsKV, error) {
+	rngStats, err := x.packStatsRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack stats range: %w", err)
+	}
+
+	stats, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		stats := make(map[string]statsKV)
+		iter := tr.GetRange(rngStats, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			name, field, err := x.unpackStatKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack stat key: %w", err)
+			}
+
+			stat := stats[name]
+			switch field {
+			case "acquisitions":
+				stat.acquisitions = unpackCounter(kv.Value)
+			case "holdNanos":
+				stat.holdTime = time.Duration(unpackCounter(kv.Value))
+			case "preemptions":
+				stat.preemptions = unpackCounter(kv.Value)
+			}
+			stats[name] = stat
+		}
+
+		// Include the in-progress hold of the current owner.
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		val, err := tr.Get(x.packOwnerSinceKey()).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner since: %w", err)
+		}
+		if owner.name != "" && val != nil {
+			since, err := x.unpackTimeValue(val)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack owner since: %w", err)
+			}
+			stat := stats[owner.name]
+			stat.holdTime += time.Since(since)
+			stats[owner.name] = stat
+		}
+		return stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats.(map[string]statsKV), nil
+}
+
+// logEvent appends an event to the mutex's event log, triggering any watches
+// created by [[kv.watchEvents]]. Only the latest [[maxEvents]] are retained.
+func (x *kv) logEvent(tr fdb.Transaction, kind EventKind, name string) error {
+	rngEvents, err := x.packEventRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack event range: %w", err)
+	}
+
+	key, err := x.packEventKey(kind)
+	if err != nil {
+		return fmt.Errorf("failed to pack event key: %w", err)
+	}
+	tr.SetVersionstampedKey(key, packEventValue(kind, name, time.Now()))
+	tr.Add(x.packEventVersionKey(), packIncrement())
+
+	trimLog(tr, rngEvents, maxEvents)
+	if x.feed != nil {
+		if err := x.feed.publish(tr, kind, lockID(x.Subspace), name); err != nil {
+			return fmt.Errorf("failed to publish to activity feed: %w", err)
+		}
+	}
+	return nil
+}
+
+// getEvents returns the logged events which come after the
+// event with the key 'after'. If 'after' is nil, all the
+// retained events are returned.
+func (x *kv) getEvents(db fdb.Transactor, after fdb.Key) ([]Event, error) {
+	rngEvents, err := x.packEventRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack event range: %w", err)
+	}
+	if after != nil {
+		rngEvents.Begin = fdb.Key(append(after, 0x00))
+	}
+
+	events, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var events []Event
+		iter := tr.GetRange(rngEvents, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			event, err := x.unpackEvent(kv.Key, kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack event: %w", err)
+			}
+			events = append(events, event)
+		}
+		return events, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events.([]Event), nil
+}
+
+// getLastEventKey returns the key of the latest logged
+// event. If no events are logged, nil is returned.
+func (x *kv) getLastEventKey(db fdb.Transactor) (fdb.Key, error) {
+	rngEvents, err := x.packEventRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack event range: %w", err)
+	}
+	return getLastKey(db, rngEvents)
+}
+
+// watchEvents returns a channel which signals when an event is logged. When an
+// event is logged, the channel returns nil. If the watch setup fails or the
+// provided context is canceled, the channel returns an error.
+func (x *kv) watchEvents(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return x.packEventVersionKey(), nil
+	})
+}
+
+// eventKey returns the key of the given event.
+func (x *kv) eventKey(event Event) fdb.Key {
+	return x.Pack(tuple.Tuple{"event", event.Version})
+}
+
+// streamEvents calls 'fn' for each event logged after this method is
+// called, in order, until the context is cancelled or an error occurs.
+func (x *kv) streamEvents(ctx context.Context, db fdb.Transactor, fn func(Event)) error {
+	return streamLog(ctx, db, x, fn)
+}
+
+// eventLog is a log of events such as the one stored
+// in a mutex's subspace or an [[ActivityFeed]].
+type eventLog interface {
+	getEvents(db fdb.Transactor, after fdb.Key) ([]Event, error)
+	getLastEventKey(db fdb.Transactor) (fdb.Key, error)
+	watchEvents(ctx context.Context, db fdb.Transactor) <-chan error
+	eventKey(event Event) fdb.Key
+}
+
+// streamLog calls 'fn' for each event appended to the log after this
+// function is called, in order, until the context is cancelled or an
+// error occurs.
+func streamLog(ctx context.Context, db fdb.Transactor, log eventLog, fn func(Event)) error {
+	cursor, err := log.getLastEventKey(db)
+	if err != nil {
+		return fmt.Errorf("failed to get last event: %w", err)
+	}
+
+	for {
+		// Watch the event log before reading it so
+		// an event logged after the read isn't missed.
+		watchCtx, cancel := context.WithCancel(ctx)
+		watch := log.watchEvents(watchCtx, db)
+
+		events, err := log.getEvents(db, cursor)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to get events: %w", err)
+		}
+		for _, event := range events {
+			fn(event)
+		}
+		if len(events) > 0 {
+			cursor = log.eventKey(events[len(events)-1])
+		}
+
+		err = <-watch
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to watch events: %w", err)
+		}
+	}
+}
+
+// trimLog clears all but the latest 'keep' entries of the log stored in
+// the given range. The read is a snapshot read so concurrent appends don't
+// conflict with each other. This is meant to be called when appending an
+// entry, which isn't visible until commit, so one less entry is kept.
+func trimLog(tr fdb.Transaction, rng fdb.KeyRange, keep int) {
+	iter := tr.Snapshot().GetRange(rng, fdb.RangeOptions{
+		Limit:   keep - 1,
+		Reverse: true,
+	}).Iterator()
+
+	var oldest fdb.Key
+	var count int
+	for iter.Advance() {
+		oldest = iter.MustGet().Key
+		count++
+	}
+	if count == keep-1 {
+		tr.ClearRange(fdb.KeyRange{Begin: rng.Begin, End: oldest})
+	}
+}
+
+// recordQueueWait logs the time a client spent in the queue before
+// being promoted to owner. Only the latest samples are retained.
+// See [[Mutex.QueueWaitStats]].
+func (x *kv) recordQueueWait(tr fdb.Transaction, name string, enqueued, promoted time.Time) error {
+	rngWaits, err := x.packQueueWaitRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack queue wait range: %w", err)
+	}
+	key, err := x.packQueueWaitKey()
+	if err != nil {
+		return fmt.Errorf("failed to pack queue wait key: %w", err)
+	}
+	tr.SetVersionstampedKey(key, x.packQueueWaitValue(name, enqueued, promoted))
+	trimLog(tr, rngWaits, maxQueueWaits)
+	return nil
+}
+
+// getQueueWaits returns the time spent in the queue by the
+// clients promoted to owner at or after 'since', in the
+// order they were promoted.
+func (x *kv) getQueueWaits(db fdb.Transactor, since time.Time) ([]time.Duration, error) {
+	rngWaits, err := x.packQueueWaitRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack queue wait range: %w", err)
+	}
+
+	waits, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var waits []time.Duration
+		iter := tr.GetRange(rngWaits, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			_, enqueued, promoted, err := x.unpackQueueWaitValue(iter.MustGet().Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack queue wait: %w", err)
+			}
+			if promoted.Before(since) {
+				continue
+			}
+			waits = append(waits, promoted.Sub(enqueued))
+		}
+		return waits, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return waits.([]time.Duration), nil
+}
+
+// getLastKey returns the last key in the given
+// range. If the range is empty, nil is returned.
+func getLastKey(db fdb.Transactor, rng fdb.KeyRange) (fdb.Key, error) {
+	key, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		kvs, err := tr.GetRange(rng, fdb.RangeOptions{Limit: 1, Reverse: true}).GetSliceWithError()
+		if err != nil {
+			return nil, err
+		}
+		if len(kvs) == 0 {
+			return fdb.Key(nil), nil
+		}
+		return kvs[0].Key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.(fdb.Key), nil
+}
+
+// getHeartbeatAge returns the approximate time since the owner's latest
+// heartbeat. The age is derived from the difference between the current
+// read version and the version at which the heartbeat was committed, so
+// it doesn't depend on the clocks of the clients. If the owner hasn't
+// heartbeat yet, the time since it acquired the mutex is returned. If
+// the mutex has no owner, false is returned.
+func (x *kv) getHeartbeatAge(db fdb.Transactor) (time.Duration, bool, error) {
+	type result struct {
+		age time.Duration
+		ok  bool
+	}
+
+	res, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name == "" {
+			return result{}, nil
+		}
+
+		if hbeat, ok := x.unpackOwnerValue(owner.hbeat); ok {
+			readVersion, err := tr.GetReadVersion().Get()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get read version: %w", err)
+			}
+			// The first 8 bytes of the versionstamp are the
+			// commit version. FDB advances roughly one million
+			// versions per second.
+			version := int64(binary.BigEndian.Uint64(hbeat.vstamp.TransactionVersion[:8]))
+			return result{age: time.Duration(readVersion-version) * time.Microsecond, ok: true}, nil
+		}
+
+		val, err := tr.Get(x.packOwnerSinceKey()).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner since: %w", err)
+		}
+		if val == nil {
+			return result{}, nil
+		}
+		since, err := x.unpackTimeValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unpack owner since: %w", err)
+		}
+		return result{age: time.Since(since), ok: true}, nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	r := res.(result)
+	return r.age, r.ok, nil
+}
+
+// getHeartbeatTime returns the wall-clock time of the owner's latest
+// heartbeat, according to the owner's clock. If the mutex has no owner
+// or the heartbeat doesn't include a time, false is returned.
+func (x *kv) getHeartbeatTime(db fdb.Transactor) (time.Time, bool, error) {
+	owner, err := x.getOwner(db)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get owner: %w", err)
+	}
+	if owner.name == "" {
+		return time.Time{}, false, nil
+	}
+	hbeat, ok := x.unpackOwnerValue(owner.hbeat)
+	if !ok || hbeat.time.IsZero() {
+		return time.Time{}, false, nil
+	}
+	return hbeat.time, true, nil
+}
+
+// setMetadata records the creation metadata of the mutex.
+func (x *kv) setMetadata(db fdb.Transactor, meta metadataKV) error {
+	val, err := x.encodeMetadata(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packMetadataKey(), val)
+		return nil, nil
+	})
+	return err
+}
+
+// getMetadata returns the creation metadata of the mutex. Mutexes
+// created before metadata was recorded return false, as do mutexes
+// whose metadata was encoded by a [[Serializer]] this handle lacks.
+func (x *kv) getMetadata(db fdb.Transactor) (metadataKV, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packMetadataKey()).Get()
+	})
+	if err != nil {
+		return metadataKV{}, false, err
+	}
+	if val.([]byte) == nil {
+		return metadataKV{}, false, nil
+	}
+	meta, err := x.decodeMetadata(val.([]byte))
+	if errors.Is(err, errCustomEncoding) {
+		return metadataKV{}, false, nil
+	}
+	if err != nil {
+		return metadataKV{}, false, fmt.Errorf("failed to unpack metadata: %w", err)
+	}
+	return meta, true, nil
+}
+
+// setResult records the result of the work guarded by the mutex.
+// See [[DoOnce]].
+func (x *kv) setResult(db fdb.Transactor, result []byte) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packResultKey(), result)
+		return nil, nil
+	})
+	return err
+}
+
+// getResult returns the result of the work guarded by the mutex.
+// If no result has been recorded, false is returned.
+func (x *kv) getResult(db fdb.Transactor) ([]byte, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packResultKey()).Get()
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if val.([]byte) == nil {
+		return nil, false, nil
+	}
+	return val.([]byte), true, nil
+}
+
+// setLastUsed records when the mutex was last used. See [[kv.getLastUsed]].
+func (x *kv) setLastUsed(db fdb.Transactor, t time.Time) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packLastUsedKey(), x.packTimeValue(t))
+		return nil, nil
+	})
+	return err
+}
+
+// getLastUsed returns when the mutex was created or last changed owners.
+// Mutexes created by older versions of this package may not have the time
+// recorded until they're next used, in which case false is returned.
+func (x *kv) getLastUsed(db fdb.Transactor) (time.Time, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packLastUsedKey()).Get()
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if val.([]byte) == nil {
+		return time.Time{}, false, nil
+	}
+	t, err := x.unpackTimeValue(val.([]byte))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to unpack last used: %w", err)
+	}
+	return t, true, nil
+}
+
+// setIdleMarker marks the mutex for deletion after the given deadline.
+func (x *kv) setIdleMarker(db fdb.Transactor, deadline time.Time) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packIdleKey(), x.packTimeValue(deadline))
+		return nil, nil
+	})
+	return err
+}
+
+// getIdleMarker returns the deletion deadline of the
+// mutex. If the mutex isn't marked, false is returned.
+func (x *kv) getIdleMarker(db fdb.Transactor) (time.Time, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packIdleKey()).Get()
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if val.([]byte) == nil {
+		return time.Time{}, false, nil
+	}
+	deadline, err := x.unpackTimeValue(val.([]byte))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to unpack idle marker: %w", err)
+	}
+	return deadline, true, nil
+}
+
+// clearIdleMarker removes the deletion deadline of the mutex.
+func (x *kv) clearIdleMarker(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packIdleKey())
+		return nil, nil
+	})
+	return err
+}
+
+// setAttrs replaces the identity attributes of the client with
+// the provided name. See [[Identity]].
+func (x *kv) setAttrs(db fdb.Transactor, name string, attrs map[string]string) error {
+	rngAttrs, err := x.packAttrRange(name)
+	if err != nil {
+		return fmt.Errorf("failed to pack attribute range: %w", err)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.ClearRange(rngAttrs)
+		for key, val := range attrs {
+			tr.Set(x.packAttrKey(name, key), []byte(val))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// getAttrs returns the identity attributes of the client with the provided name.
+func (x *kv) getAttrs(db fdb.ReadTransactor, name string) (map[string]string, error) {
+	rngAttrs, err := x.packAttrRange(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack attribute range: %w", err)
+	}
+
+	attrs, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		attrs := make(map[string]string)
+		iter := tr.GetRange(rngAttrs, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			_, key, err := x.unpackAttrKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack attribute key: %w", err)
+			}
+			attrs[key] = string(kv.Value)
+		}
+		return attrs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attrs.(map[string]string), nil
+}
+
+// clearAttrs removes the identity attributes & the
+// delegator of the client with the provided name.
+func (x *kv) clearAttrs(db fdb.Transactor, name string) error {
+	rngAttrs, err := x.packAttrRange(name)
+	if err != nil {
+		return fmt.Errorf("failed to pack attribute range: %w", err)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.ClearRange(rngAttrs)
+		tr.Clear(x.packDelegatorKey(name))
+		return nil, nil
+	})
+	return err
+}
+
+// setDelegator records that the client named 'delegator' acquires
+// on behalf of the client with the provided name. See [[WithDelegator]].
+func (x *kv) setDelegator(db fdb.Transactor, name string, delegator string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packDelegatorKey(name), []byte(delegator))
+		return nil, nil
+	})
+	return err
+}
+
+// getDelegator returns the name of the client acquiring on behalf
+// of the client with the provided name. If there is none, a blank
+// name is returned.
+func (x *kv) getDelegator(db fdb.Transactor, name string) (string, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packDelegatorKey(name)).Get()
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(val.([]byte)), nil
+}
+
+// setSticky reserves the vacant mutex for the client with
+// the provided name until the given deadline.
+func (x *kv) setSticky(db fdb.Transactor, name string, deadline time.Time) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packStickyKey(), x.packStickyValue(name, deadline))
+		return nil, nil
+	})
+	return err
+}
+
+// getSticky returns the reservation of the vacant mutex.
+// If there is no reservation then false is returned.
+func (x *kv) getSticky(db fdb.Transactor) (stickyKV, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packStickyKey()).Get()
+	})
+	if err != nil {
+		return stickyKV{}, false, err
+	}
+	if val.([]byte) == nil {
+		return stickyKV{}, false, nil
+	}
+	sticky, err := x.unpackStickyValue(val.([]byte))
+	if err != nil {
+		return stickyKV{}, false, fmt.Errorf("failed to unpack sticky value: %w", err)
+	}
+	return sticky, true, nil
+}
+
+// clearSticky removes the reservation of the vacant mutex.
+func (x *kv) clearSticky(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Clear(x.packStickyKey())
+		return nil, nil
+	})
+	return err
+}
+
+// exists returns true if the owner key has been initialized,
+// meaning this subspace contains a mutex.
+func (x *kv) exists(db fdb.Transactor) (bool, error) {
+	rngOwner, err := x.packOwnerRange()
+	if err != nil {
+		return false, fmt.Errorf("failed to pack owner range: %w", err)
+	}
+
+	exists, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		iter := tr.GetRange(rngOwner, fdb.RangeOptions{Limit: 1}).Iterator()
+		return iter.Advance(), nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return exists.(bool), nil
+}
+
+// getType returns the kind of primitive the subspace is marked
+// as holding. If the subspace isn't marked then false is returned.
+func (x *kv) getType(db fdb.Transactor) (string, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packTypeKey()).Get()
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if val.([]byte) == nil {
+		return "", false, nil
+	}
+	return string(val.([]byte)), true, nil
+}
+
+// getSchemaVersion returns the schema version the mutex is marked with.
+// If the mutex isn't marked then false is returned.
+func (x *kv) getSchemaVersion(db fdb.Transactor) (int, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packSchemaVersionKey()).Get()
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if val.([]byte) == nil {
+		return 0, false, nil
+	}
+	version, err := x.unpackSchemaVersionValue(val.([]byte))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to unpack schema version: %w", err)
+	}
+	return version, true, nil
+}
+
+// setSchemaVersion marks the mutex with the given schema version.
+func (x *kv) setSchemaVersion(db fdb.Transactor, version int) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Set(x.packSchemaVersionKey(), x.packSchemaVersionValue(version))
+		return nil, nil
+	})
+	return err
+}
+
+// migrateValues rewrites the heartbeat & queue values encoded by version 1
+// of the schema using the current encodings. Both encodings are readable,
+// so handles running older versions of this package may continue to
+// write the old encodings while they are phased out.
+func (x *kv) migrateValues(tr fdb.Transaction) error {
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return fmt.Errorf("failed to get owner: %w", err)
+	}
+	// Version 1 heartbeats are bare 12 byte
+	// versionstamps. Tuples are always longer.
+	if len(owner.hbeat) == 12 {
+		if hbeat, ok := x.unpackOwnerValue(owner.hbeat); ok {
+			tr.Set(x.packOwnerKey(owner.name), tuple.Tuple{hbeat.vstamp}.Pack())
+		}
+	}
+
+	rngQueue, err := x.packQueueRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack queue range: %w", err)
+	}
+	iter := tr.GetRange(rngQueue, fdb.RangeOptions{}).Iterator()
+	for iter.Advance() {
+		kv := iter.MustGet()
+
+		// Version 1 queue values are the bare name.
+		if name, _ := x.unpackQueueValue(kv.Value); name == string(kv.Value) {
+			tr.Set(kv.Key, x.packQueueValue(name, time.Time{}))
+		}
+	}
+	return x.indexQueue(tr)
+}
+
+// setLabels replaces the labels attached to the mutex.
+func (x *kv) setLabels(db fdb.Transactor, labels map[string]string) error {
+	rngLabels, err := x.packLabelRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack label range: %w", err)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.ClearRange(rngLabels)
+		for key, val := range labels {
+			tr.Set(x.packLabelKey(key), x.packLabelValue(val))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// getLabels returns the labels attached to the mutex.
+func (x *kv) getLabels(db fdb.Transactor) (map[string]string, error) {
+	rngLabels, err := x.packLabelRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack label range: %w", err)
+	}
+
+	labels, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		labels := make(map[string]string)
+		iter := tr.GetRange(rngLabels, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			key, err := x.unpackLabelKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack label key: %w", err)
+			}
+			labels[key] = x.unpackLabelValue(kv.Value)
+		}
+		return labels, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return labels.(map[string]string), nil
+}
+
+func (x *kv) packOwnerRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"owner"}))
+}
+
+func (x *kv) packOwnerKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"owner", name})
+}
+
+func (x *kv) unpackOwnerKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	// The 1st element should be the string "owner". We won't
+	// bother confirming that. The 2nd is the name of the owner.
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+func (x *kv) packOwnerValue(t time.Time) ([]byte, error) {
+	if x.dualRead {
+		// Version 1 stored the bare versionstamp. The
+		// trailing 4 bytes are the versionstamp's offset.
+		return make([]byte, 16), nil
+	}
+
+	// The value is a tuple containing the versionstamp of the heartbeat
+	// transaction & the writer's wall-clock time. The time lets operators
+	// read the age of raw state without converting versions to time.
+	// See [[fdb.Transaction.SetVersionstampedValue]].
+	return tuple.Tuple{tuple.IncompleteVersionstamp(0), t.UnixNano()}.PackWithVersionstamp(nil)
+}
+
+// unpackOwnerValue decodes the owner's heartbeat. Version 1 of the
+// schema stored the bare 12 byte versionstamp, which is also accepted.
+// Heartbeats without a time have a zero time. If the owner hasn't
+// heartbeat yet, false is returned.
+func (x *kv) unpackOwnerValue(val []byte) (heartbeatKV, bool) {
+	if len(val) == 0 {
+		return heartbeatKV{}, false
+	}
+	if tup, err := tuple.Unpack(val); err == nil && len(tup) >= 1 {
+		if vstamp, ok := tup[0].(tuple.Versionstamp); ok {
+			hbeat := heartbeatKV{vstamp: vstamp}
+			if len(tup) >= 2 {
+				if nanos, ok := tup[1].(int64); ok {
+					hbeat.time = time.Unix(0, nanos)
+				}
+			}
+			return hbeat, true
+		}
+	}
+	if len(val) == 12 {
+		var vstamp tuple.Versionstamp
+		copy(vstamp.TransactionVersion[:], val[:10])
+		vstamp.UserVersion = binary.BigEndian.Uint16(val[10:])
+		return heartbeatKV{vstamp: vstamp}, true
+	}
+	return heartbeatKV{}, false
+}
+
+func (x *kv) packQueueRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"queue"}))
+}
+
+func (x *kv) packQueueKey() (fdb.Key, error) {
+	tup := tuple.Tuple{"queue", tuple.IncompleteVersionstamp(0)}
+	return tup.PackWithVersionstamp(x.Bytes())
+}
+
+// packQueueKeyAt packs the key of the queue
+// entry with the given, complete, versionstamp.
+func (x *kv) packQueueKeyAt(vstamp tuple.Versionstamp) fdb.Key {
+	return x.Pack(tuple.Tuple{"queue", vstamp})
+}
+
+func (x *kv) unpackQueueKey(key fdb.Key) (tuple.Versionstamp, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[1].(tuple.Versionstamp)
+	if !ok {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple element 1 is not a versionstamp")
+	}
+	return vstamp, nil
+}
+
+func (x *kv) packQueueVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"queueVersion"})
+}
+
+// packIncrement returns a little-endian 1
+// for use with [[fdb.Transaction.Add]].
+func packIncrement() []byte {
+	return packCounter(1)
+}
+
+// packCounter encodes a counter value or delta
+// for use with [[fdb.Transaction.Add]].
+func packCounter(n int64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(n))
+	return buf[:]
+}
+
+// unpackCounter decodes a counter maintained by
+// [[fdb.Transaction.Add]]. A missing key is 0.
+func unpackCounter(val []byte) int64 {
+	var buf [8]byte
+	copy(buf[:], val)
+	return int64(binary.LittleEndian.Uint64(buf[:]))
+}
+
+func (x *kv) packQueueValue(name string, enqueued time.Time) []byte {
+	if x.dualRead {
+		return []byte(name)
+	}
+
+	// An unknown time is encoded as 0.
+	var nanos int64
+	if !enqueued.IsZero() {
+		nanos = enqueued.UnixNano()
+	}
+	return tuple.Tuple{name, nanos}.Pack()
+}
+
+// unpackQueueValue decodes the name of a queued client & when it was
+// enqueued. Version 1 of the schema stored the bare name, which is also
+// accepted, in which case the time is zero. Elements beyond those known
+// are ignored, allowing fields to be added.
+func (x *kv) unpackQueueValue(val []byte) (string, time.Time) {
+	if tup, err := tuple.Unpack(val); err == nil && len(tup) >= 2 {
+		name, okName := tup[0].(string)
+		nanos, okTime := tup[1].(int64)
+		if okName && okTime {
+			if nanos == 0 {
+				return name, time.Time{}
+			}
+			return name, time.Unix(0, nanos)
+		}
+	}
+	return string(val), time.Time{}
+}
+
+func (x *kv) packLabelRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"label"}))
+}
+
+func (x *kv) packLabelKey(key string) fdb.Key {
+	return x.Pack(tuple.Tuple{"label", key})
+}
+
+func (x *kv) unpackLabelKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	label, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return label, nil
+}
+
+func (x *kv) packLabelValue(val string) []byte {
+	return []byte(val)
+}
+
+func (x *kv) unpackLabelValue(val []byte) string {
+	return string(val)
+}
+
+func (x *kv) packDataSubspace() subspace.Subspace {
+	return x.Sub("data")
+}
+
+func (x *kv) packStoreKey(key string) fdb.Key {
+	return x.Pack(tuple.Tuple{"store", key})
+}
+
+func (x *kv) unpackStoreKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	k, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return k, nil
+}
+
+func (x *kv) packPriorityRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"priority"}))
+}
+
+func (x *kv) packPriorityKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"priority", name})
+}
+
+func (x *kv) unpackPriorityKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+func (x *kv) packPriorityValue(priority int64) []byte {
+	return tuple.Tuple{priority}.Pack()
+}
+
+func (x *kv) unpackPriorityValue(val []byte) (int64, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 1 {
+		return 0, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	priority, ok := tup[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	return priority, nil
+}
+
+func (x *kv) packStickyKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"sticky"})
+}
+
+func (x *kv) packStickyValue(name string, deadline time.Time) []byte {
+	return tuple.Tuple{name, deadline.UnixNano()}.Pack()
+}
+
+func (x *kv) unpackStickyValue(val []byte) (stickyKV, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return stickyKV{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return stickyKV{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[0].(string)
+	if !ok {
+		return stickyKV{}, fmt.Errorf("tuple element 0 is not a string")
+	}
+	nanos, ok := tup[1].(int64)
+	if !ok {
+		return stickyKV{}, fmt.Errorf("tuple element 1 is not an int64")
+	}
+	return stickyKV{name: name, deadline: time.Unix(0, nanos)}, nil
+}
+
+func (x *kv) packTypeKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"_meta", "type"})
+}
+
+func (x *kv) packSchemaVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"_meta", "schema"})
+}
+
+func (x *kv) packSchemaVersionValue(version int) []byte {
+	return tuple.Tuple{int64(version)}.Pack()
+}
+
+func (x *kv) unpackSchemaVersionValue(val []byte) (int, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) < 1 {
+		return 0, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	version, ok := tup[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	return int(version), nil
+}
+
+func (x *kv) packHandoffKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"handoff"})
+}
+
+func (x *kv) packHandoffValue(handoff handoffKV) []byte {
+	return tuple.Tuple{handoff.successor, handoff.acked, handoff.prepared.UnixNano()}.Pack()
+}
+
+func (x *kv) unpackHandoffValue(val []byte) (handoffKV, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return handoffKV{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) < 3 {
+		return handoffKV{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	successor, ok := tup[0].(string)
+	if !ok {
+		return handoffKV{}, fmt.Errorf("tuple element 0 is not a string")
+	}
+	acked, ok := tup[1].(bool)
+	if !ok {
+		return handoffKV{}, fmt.Errorf("tuple element 1 is not a bool")
+	}
+	nanos, ok := tup[2].(int64)
+	if !ok {
+		return handoffKV{}, fmt.Errorf("tuple element 2 is not an int64")
+	}
+	return handoffKV{successor: successor, acked: acked, prepared: time.Unix(0, nanos)}, nil
+}
+
+func (x *kv) packEpochKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"epoch"})
+}
+
+func (x *kv) unpackEpochValue(val []byte) int64 {
+	return unpackCounter(val)
+}
+
+func (x *kv) packTokenKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"token"})
+}
+
+// packTokenValue packs the versionstamp of the transaction.
+// See [[fdb.Transaction.SetVersionstampedValue]].
+func (x *kv) packTokenValue() ([]byte, error) {
+	return tuple.Tuple{tuple.IncompleteVersionstamp(0)}.PackWithVersionstamp(nil)
+}
+
+func (x *kv) unpackTokenValue(val []byte) (tuple.Versionstamp, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 1 {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[0].(tuple.Versionstamp)
+	if !ok {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple element 0 is not a versionstamp")
+	}
+	return vstamp, nil
+}
+
+func (x *kv) packOwnershipKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"ownership"})
+}
+
+func (x *kv) packTransferKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"transfer", name})
+}
+
+func (x *kv) unpackTransferKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+func (x *kv) packRotationRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"rotation"}))
+}
+
+func (x *kv) packRotationKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"rotation", name})
+}
+
+func (x *kv) unpackRotationKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+func (x *kv) packScheduleKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"schedule"})
+}
+
+func (x *kv) packScheduleValue(s Schedule) []byte {
+	tup := tuple.Tuple{int64(s.Period), s.Reject}
+	for _, w := range s.Windows {
+		tup = append(tup, int64(w.Start), int64(w.Length))
+	}
+	return tup.Pack()
+}
+
+func (x *kv) unpackScheduleValue(val []byte) (Schedule, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) < 2 || len(tup)%2 != 0 {
+		return Schedule{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	period, ok := tup[0].(int64)
+	if !ok {
+		return Schedule{}, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	reject, ok := tup[1].(bool)
+	if !ok {
+		return Schedule{}, fmt.Errorf("tuple element 1 is not a bool")
+	}
+
+	s := Schedule{Period: time.Duration(period), Reject: reject}
+	for i := 2; i < len(tup); i += 2 {
+		start, ok := tup[i].(int64)
+		if !ok {
+			return Schedule{}, fmt.Errorf("tuple element %d is not an int64", i)
+		}
+		length, ok := tup[i+1].(int64)
+		if !ok {
+			return Schedule{}, fmt.Errorf("tuple element %d is not an int64", i+1)
+		}
+		s.Windows = append(s.Windows, Window{
+			Start:  time.Duration(start),
+			Length: time.Duration(length),
+		})
+	}
+	return s, nil
+}
+
+func (x *kv) packReservationRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"reservation"}))
+}
+
+func (x *kv) packReservationKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"reservation", name})
+}
+
+func (x *kv) unpackReservationKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+func (x *kv) packAgingKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"aging"})
+}
+
+func (x *kv) packAgingValue(interval time.Duration) []byte {
+	return tuple.Tuple{int64(interval)}.Pack()
+}
+
+func (x *kv) unpackAgingValue(val []byte) (time.Duration, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 1 {
+		return 0, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	nanos, ok := tup[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	return time.Duration(nanos), nil
+}
+
+func (x *kv) packPreemptKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"preempt"})
+}
+
+func (x *kv) packPreemptValue(p preemptKV) []byte {
+	var deadline int64
+	if !p.deadline.IsZero() {
+		deadline = p.deadline.UnixNano()
+	}
+	return tuple.Tuple{p.name, int64(p.grace), deadline}.Pack()
+}
+
+func (x *kv) unpackPreemptValue(val []byte) (preemptKV, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return preemptKV{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 3 {
+		return preemptKV{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[0].(string)
+	if !ok {
+		return preemptKV{}, fmt.Errorf("tuple element 0 is not a string")
+	}
+	grace, ok := tup[1].(int64)
+	if !ok {
+		return preemptKV{}, fmt.Errorf("tuple element 1 is not an int64")
+	}
+	nanos, ok := tup[2].(int64)
+	if !ok {
+		return preemptKV{}, fmt.Errorf("tuple element 2 is not an int64")
+	}
+	p := preemptKV{name: name, grace: time.Duration(grace)}
+	if nanos != 0 {
+		p.deadline = time.Unix(0, nanos)
+	}
+	return p, nil
+}
+
+func (x *kv) packOperationKey(op Operation) fdb.Key {
+	return x.Pack(tuple.Tuple{"operation", op.Epoch, op.ID})
+}
+
+func (x *kv) packOperationValue(name string, started time.Time) []byte {
+	return tuple.Tuple{name, started.UnixNano()}.Pack()
+}
+
+func (x *kv) packOperationVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"operationVersion"})
+}
+
+func (x *kv) packMaintenanceKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"maintenance"})
+}
+
+func (x *kv) packFrozenKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"frozen"})
+}
+
+func (x *kv) packDisabledKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"disabled"})
+}
+
+func (x *kv) packDenyRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"deny"}))
+}
+
+func (x *kv) packDenyKey(pattern string) fdb.Key {
+	return x.Pack(tuple.Tuple{"deny", pattern})
+}
+
+func (x *kv) unpackDenyKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	pattern, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return pattern, nil
+}
+
+func (x *kv) packRateLimitKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"rateLimit"})
+}
+
+func (x *kv) packRateLimitValue(limit int64, interval time.Duration) []byte {
+	return tuple.Tuple{limit, int64(interval)}.Pack()
+}
+
+func (x *kv) unpackRateLimitValue(val []byte) (int64, time.Duration, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return 0, 0, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	limit, ok := tup[0].(int64)
+	if !ok {
+		return 0, 0, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	interval, ok := tup[1].(int64)
+	if !ok {
+		return 0, 0, fmt.Errorf("tuple element 1 is not an int64")
+	}
+	return limit, time.Duration(interval), nil
+}
+
+func (x *kv) packAttemptsKey(window int64) fdb.Key {
+	return x.Pack(tuple.Tuple{"attempts", window})
+}
+
+func (x *kv) packContendedKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"contended"})
+}
+
+func (x *kv) packMemberRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"member"}))
+}
+
+func (x *kv) packMemberKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"member", name})
+}
+
+// packMemberValue packs the versionstamp of the transaction, which is
+// also the versionstamp of the queue entry written by the transaction.
+// See [[fdb.Transaction.SetVersionstampedValue]].
+func (x *kv) packMemberValue() ([]byte, error) {
+	return tuple.Tuple{tuple.IncompleteVersionstamp(0)}.PackWithVersionstamp(nil)
+}
+
+func (x *kv) unpackMemberValue(val []byte) (tuple.Versionstamp, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 1 {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[0].(tuple.Versionstamp)
+	if !ok {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple element 0 is not a versionstamp")
+	}
+	return vstamp, nil
+}
+
+func (x *kv) packReaderRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"reader"}))
+}
+
+func (x *kv) packReaderKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"reader", name})
+}
+
+func (x *kv) unpackReaderKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+// packReaderValue packs the versionstamp of the
+// transaction as the reader's heartbeat.
+func (x *kv) packReaderValue() ([]byte, error) {
+	return tuple.Tuple{tuple.IncompleteVersionstamp(0)}.PackWithVersionstamp(nil)
+}
+
+func (x *kv) unpackReaderValue(val []byte) (tuple.Versionstamp, error) {
+	return x.unpackMemberValue(val)
+}
+
+func (x *kv) packReaderVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"readerVersion"})
+}
+
+func (x *kv) packReleaseRequestKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"releaseRequest"})
+}
+
+func (x *kv) packOwnerSinceKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"ownerSince"})
+}
+
+func (x *kv) packTimeValue(t time.Time) []byte {
+	return tuple.Tuple{t.UnixNano()}.Pack()
+}
+
+func (x *kv) unpackTimeValue(val []byte) (time.Time, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 1 {
+		return time.Time{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	nanos, ok := tup[0].(int64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	return time.Unix(0, nanos), nil
+}
+
+func (x *kv) packStatsRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"stats"}))
+}
+
+func (x *kv) packStatKey(name, field string) fdb.Key {
+	return x.Pack(tuple.Tuple{"stats", name, field})
+}
+
+func (x *kv) unpackStatKey(key fdb.Key) (string, string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 3 {
+		return "", "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	field, ok := tup[2].(string)
+	if !ok {
+		return "", "", fmt.Errorf("tuple element 2 is not a string")
+	}
+	return name, field, nil
+}
+
+func (x *kv) packEventRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"event"}))
+}
+
+func (x *kv) packEventKey(kind EventKind) (fdb.Key, error) {
+	tup := tuple.Tuple{"event", tuple.IncompleteVersionstamp(eventOrder(kind))}
+	return tup.PackWithVersionstamp(x.Bytes())
+}
+
+// eventOrder returns the user version of an event's key, which orders
+// the events logged by the same transaction. A hold always ends before
+// the next hold begins, so acquisitions are ordered last.
+func eventOrder(kind EventKind) uint16 {
+	if kind == EventAcquired {
+		return 1
+	}
+	return 0
+}
+
+// packEventValue encodes the kind, client, & time of an event.
+// The value encoding is shared with [[ActivityFeed]].
+func packEventValue(kind EventKind, name string, t time.Time) []byte {
+	return tuple.Tuple{int64(kind), name, t.UnixNano()}.Pack()
+}
+
+func (x *kv) unpackEvent(key fdb.Key, val []byte) (Event, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to unpack key tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return Event{}, fmt.Errorf("key tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[1].(tuple.Versionstamp)
+	if !ok {
+		return Event{}, fmt.Errorf("key tuple element 1 is not a versionstamp")
+	}
+
+	event, err := unpackEventValue(val)
+	if err != nil {
+		return Event{}, err
+	}
+	event.Mutex = lockID(x.Subspace)
+	event.Version = vstamp
+	return event, nil
+}
+
+// unpackEventValue decodes the value encoded by [[packEventValue]].
+func unpackEventValue(val []byte) (Event, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to unpack value tuple: %w", err)
+	}
+	if len(tup) != 3 {
+		return Event{}, fmt.Errorf("value tuple is incorrect length %d", len(tup))
+	}
+	kind, ok := tup[0].(int64)
+	if !ok {
+		return Event{}, fmt.Errorf("value tuple element 0 is not an int64")
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return Event{}, fmt.Errorf("value tuple element 1 is not a string")
+	}
+	nanos, ok := tup[2].(int64)
+	if !ok {
+		return Event{}, fmt.Errorf("value tuple element 2 is not an int64")
+	}
+
+	return Event{
+		Kind:   EventKind(kind),
+		Client: name,
+		Time:   time.Unix(0, nanos),
+	}, nil
+}
+
+func (x *kv) packEventVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"eventVersion"})
+}
+
+func (x *kv) packMetadataKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"metadata"})
+}
+
+func (x *kv) packMetadataValue(meta metadataKV) []byte {
+	return tuple.Tuple{meta.created.UnixNano(), meta.creator, meta.description}.Pack()
+}
+
+func (x *kv) unpackMetadataValue(val []byte) (metadataKV, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return metadataKV{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 3 {
+		return metadataKV{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	nanos, ok := tup[0].(int64)
+	if !ok {
+		return metadataKV{}, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	creator, ok := tup[1].(string)
+	if !ok {
+		return metadataKV{}, fmt.Errorf("tuple element 1 is not a string")
+	}
+	description, ok := tup[2].(string)
+	if !ok {
+		return metadataKV{}, fmt.Errorf("tuple element 2 is not a string")
+	}
+	return metadataKV{
+		created:     time.Unix(0, nanos),
+		creator:     creator,
+		description: description,
+	}, nil
+}
+
+func (x *kv) packResultKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"result"})
+}
+
+func (x *kv) packIdleKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"idle"})
+}
+
+func (x *kv) packLastUsedKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"lastUsed"})
+}
+
+func (x *kv) packAttrRange(name string) (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"attr", name}))
+}
+
+func (x *kv) packAttrKey(name string, key string) fdb.Key {
+	return x.Pack(tuple.Tuple{"attr", name, key})
+}
+
+func (x *kv) unpackAttrKey(key fdb.Key) (string, string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 3 {
+		return "", "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	attr, ok := tup[2].(string)
+	if !ok {
+		return "", "", fmt.Errorf("tuple element 2 is not a string")
+	}
+	return name, attr, nil
+}
+
+func (x *kv) packDelegatorKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"delegator", name})
+}
+
+func (x *kv) unpackDelegatorKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+func (x *kv) packClockRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"clock"}))
+}
+
+func (x *kv) packClockKey(name string) fdb.Key {
+	return x.Pack(tuple.Tuple{"clock", name})
+}
+
+func (x *kv) unpackClockKey(key fdb.Key) (string, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return "", fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return "", fmt.Errorf("tuple element 1 is not a string")
+	}
+	return name, nil
+}
+
+func (x *kv) packClockValue(sample clockKV) []byte {
+	return tuple.Tuple{sample.version, sample.local.UnixNano()}.Pack()
+}
+
+func (x *kv) unpackClockValue(val []byte) (clockKV, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return clockKV{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) < 2 {
+		return clockKV{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	version, ok := tup[0].(int64)
+	if !ok {
+		return clockKV{}, fmt.Errorf("tuple element 0 is not an int64")
+	}
+	nanos, ok := tup[1].(int64)
+	if !ok {
+		return clockKV{}, fmt.Errorf("tuple element 1 is not an int64")
+	}
+	return clockKV{version: version, local: time.Unix(0, nanos)}, nil
+}
+
+func (x *kv) packQueueWaitRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"queueWait"}))
+}
+
+func (x *kv) packQueueWaitKey() (fdb.Key, error) {
+	tup := tuple.Tuple{"queueWait", tuple.IncompleteVersionstamp(0)}
+	return tup.PackWithVersionstamp(x.Bytes())
+}
+
+func (x *kv) unpackQueueWaitKey(key fdb.Key) (tuple.Versionstamp, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[1].(tuple.Versionstamp)
+	if !ok {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple element 1 is not a versionstamp")
+	}
+	return vstamp, nil
+}
+
+func (x *kv) packQueueWaitValue(name string, enqueued, promoted time.Time) []byte {
+	return tuple.Tuple{name, enqueued.UnixNano(), promoted.UnixNano()}.Pack()
+}
+
+func (x *kv) unpackQueueWaitValue(val []byte) (string, time.Time, time.Time, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) < 3 {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[0].(string)
+	if !ok {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("tuple element 0 is not a string")
+	}
+	enqueued, ok := tup[1].(int64)
+	if !ok {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("tuple element 1 is not an int64")
+	}
+	promoted, ok := tup[2].(int64)
+	if !ok {
+		return "", time.Time{}, time.Time{}, fmt.Errorf("tuple element 2 is not an int64")
+	}
+	return name, time.Unix(0, enqueued), time.Unix(0, promoted), nil
 }
@@ -0,0 +1,116 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// typeLatch marks a subspace as holding a [[Latch]].
+const typeLatch = "latch"
+
+// Latch is a distributed countdown latch. It's initialized with a count,
+// workers count it down as they finish, and waiters block until the count
+// reaches zero. Count downs are atomic adds, so workers never conflict
+// with each other. Once the count reaches zero, the latch stays open.
+type Latch struct{ subspace.Subspace }
+
+// NewLatch constructs a latch stored in 'root'. If the latch doesn't exist,
+// it's created with the given count. Otherwise, the count is ignored. If
+// 'root' holds another kind of primitive, [[ErrWrongType]] is returned.
+func NewLatch(db fdb.Transactor, root subspace.Subspace, count int64) (_ *Latch, err error) {
+	defer wrapErr(&err)
+
+	if count < 0 {
+		return nil, fmt.Errorf("latch count must not be negative")
+	}
+
+	x := &Latch{root}
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		k := kv{Subspace: root}
+		if err := k.claimType(tr, typeLatch); err != nil {
+			return nil, err
+		}
+
+		val, err := tr.Get(x.packCountKey()).Get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get count: %w", err)
+		}
+		if val == nil {
+			tr.Set(x.packCountKey(), packCounter(count))
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// CountDown decrements the count of the latch. Once the count reaches zero,
+// the latch opens & the waiters are released. Counting down an open latch
+// has no effect.
+func (x *Latch) CountDown(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		// The count is decremented with an atomic add so workers
+		// don't conflict. A count below zero is treated as zero.
+		tr.Add(x.packCountKey(), packCounter(-1))
+		return nil, nil
+	})
+	return err
+}
+
+// Count returns the number of count downs remaining
+// before the latch opens. An open latch returns zero.
+func (x *Latch) Count(db fdb.Transactor) (_ int64, err error) {
+	defer wrapErr(&err)
+
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(x.packCountKey()).Get()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return max(unpackCounter(val.([]byte)), 0), nil
+}
+
+// Wait blocks until the latch opens or the context ends.
+func (x *Latch) Wait(ctx context.Context, db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	for {
+		// Check the count & watch for a count down
+		// atomically so the last one isn't missed.
+		var open bool
+		watch := watch(ctx, db, func(tr fdb.Transaction) (fdb.Key, error) {
+			val, err := tr.Get(x.packCountKey()).Get()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get count: %w", err)
+			}
+			open = unpackCounter(val) <= 0
+			if open {
+				return nil, nil
+			}
+			return x.packCountKey(), nil
+		})
+
+		if err := <-watch; err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to watch count: %w", err)
+		}
+		if open {
+			return nil
+		}
+	}
+}
+
+func (x *Latch) packCountKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"count"})
+}
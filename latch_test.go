@@ -0,0 +1,62 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatch(t *testing.T) {
+	tests := map[string]testFn{
+		"wait": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewLatch(db, root, 2)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() { done <- x.Wait(ctx, db) }()
+
+			require.NoError(t, x.CountDown(db))
+			count, err := x.Count(db)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), count)
+
+			select {
+			case err := <-done:
+				t.Fatalf("wait returned early: %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			require.NoError(t, x.CountDown(db))
+			require.NoError(t, <-done)
+
+			// The latch stays open.
+			require.NoError(t, x.CountDown(db))
+			count, err = x.Count(db)
+			require.NoError(t, err)
+			require.Zero(t, count)
+			require.NoError(t, x.Wait(ctx, db))
+		},
+
+		"existing": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewLatch(db, root, 2)
+			require.NoError(t, err)
+			require.NoError(t, x.CountDown(db))
+
+			// The count of an existing latch is kept.
+			x, err = NewLatch(db, root, 5)
+			require.NoError(t, err)
+			count, err := x.Count(db)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), count)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,52 @@
+package mutex
+
+import (
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// The functions below expose the key layout of a mutex so clients written
+// in other languages can interoperate with this package. All keys are tuple
+// encoded within the mutex's root subspace:
+//
+//	("owner", name) = (heartbeat, time)
+//	("queue", versionstamp) = (name, enqueued)
+//
+// There is at most one owner key. An empty name means the mutex is free.
+// The heartbeat is the versionstamp of the owner's latest heartbeat
+// transaction & the time is the owner's clock, in unix nanoseconds, when
+// it wrote the heartbeat. The value is empty if the owner hasn't yet
+// heartbeat.
+// The queue is ordered by versionstamp, oldest first, and each value
+// holds the name of the waiting client & when it was enqueued. The full
+// layout is described by [[SchemaVersion]] & encoded by [[Schema]].
+
+// OwnerKey returns the key marking 'name' as the owner of the mutex stored
+// in 'root'. The key's value is the owner's heartbeat. See [[HeartbeatKey]].
+func OwnerKey(root subspace.Subspace, name string) fdb.Key {
+	x := kv{Subspace: root}
+	return x.packOwnerKey(name)
+}
+
+// OwnerRange returns the range containing the owner key of the mutex
+// stored in 'root'. Reading this range reveals the current owner.
+func OwnerRange(root subspace.Subspace) (fdb.KeyRange, error) {
+	x := kv{Subspace: root}
+	return x.packOwnerRange()
+}
+
+// HeartbeatKey returns the key holding the heartbeat of 'name' while it owns
+// the mutex stored in 'root'. Heartbeats are stored as the value of the owner
+// key, so this is the same key as [[OwnerKey]]. A heartbeat is written with
+// a versionstamped value. See [[fdb.Transaction.SetVersionstampedValue]].
+func HeartbeatKey(root subspace.Subspace, name string) fdb.Key {
+	return OwnerKey(root, name)
+}
+
+// QueueRange returns the range containing the queue of the mutex stored
+// in 'root'. Keys within the range are ordered by the time the client
+// was queued.
+func QueueRange(root subspace.Subspace) (fdb.KeyRange, error) {
+	x := kv{Subspace: root}
+	return x.packQueueRange()
+}
@@ -0,0 +1,35 @@
+package mutex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout(t *testing.T) {
+	root := subspace.Sub("app", "lock")
+	key := func(tup tuple.Tuple) fdb.Key {
+		return fdb.Key(append(tuple.Tuple{"app", "lock"}.Pack(), tup.Pack()...))
+	}
+
+	// The layout is a contract with clients written in
+	// other languages, so the bytes are spelled out.
+	require.Equal(t, key(tuple.Tuple{"owner", "client"}), OwnerKey(root, "client"))
+	require.Equal(t, OwnerKey(root, "client"), HeartbeatKey(root, "client"))
+
+	rng, err := OwnerRange(root)
+	require.NoError(t, err)
+	begin, end := rng.FDBRangeKeys()
+	require.Equal(t, key(tuple.Tuple{"owner"}), begin.FDBKey())
+	require.Positive(t, bytes.Compare(OwnerKey(root, "client"), begin.FDBKey()))
+	require.Negative(t, bytes.Compare(OwnerKey(root, "client"), end.FDBKey()))
+
+	rng, err = QueueRange(root)
+	require.NoError(t, err)
+	begin, _ = rng.FDBRangeKeys()
+	require.Equal(t, key(tuple.Tuple{"queue"}), begin.FDBKey())
+}
@@ -0,0 +1,245 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// localLocks holds the in-process locks used by [[WithLocalArbitration]],
+// keyed by the subspace prefix of the mutex. A lock is only stored while a
+// handle holds or waits for it, so handles which are created per request,
+// such as by [[Middleware]], don't grow the map without bound.
+var localLocks = struct {
+	sync.Mutex
+	m map[string]*localLock
+}{m: make(map[string]*localLock)}
+
+// localLock is an in-process lock shared by the handles of a mutex.
+type localLock struct {
+	ch chan struct{}
+
+	// refs counts the handles holding or waiting for the
+	// lock. It's guarded by the mutex of [[localLocks]].
+	refs int
+
+	// carrier is the handle whose client holds the mutex in FDB
+	// on behalf of the process. It's nil while the process doesn't
+	// hold the mutex. It's guarded by the mutex of [[localLocks]].
+	carrier *Mutex
+
+	// handoffs counts the times the hold was passed between
+	// handles since the carrier acquired the mutex. It's
+	// guarded by the mutex of [[localLocks]].
+	handoffs int
+}
+
+// refLocal returns the in-process lock for 'key',
+// creating it if no other handle is using it.
+func refLocal(key string) *localLock {
+	localLocks.Lock()
+	defer localLocks.Unlock()
+
+	l, ok := localLocks.m[key]
+	if !ok {
+		l = &localLock{ch: make(chan struct{}, 1)}
+		localLocks.m[key] = l
+	}
+	l.refs++
+	return l
+}
+
+// unrefLocal undoes [[refLocal]], removing the in-process lock once no
+// handle is holding or waiting for it. If the process still holds the
+// mutex in FDB at that point, the hold was passed to a handle which gave
+// up waiting for it. The carrier is returned so the caller can release
+// the mutex. Otherwise, nil is returned.
+func unrefLocal(key string, l *localLock) *Mutex {
+	localLocks.Lock()
+	defer localLocks.Unlock()
+
+	l.refs--
+	if l.refs > 0 {
+		return nil
+	}
+	delete(localLocks.m, key)
+	carrier := l.carrier
+	l.carrier = nil
+	return carrier
+}
+
+// WithLocalArbitration causes goroutines of this process which contend for the
+// same mutex to first arbitrate amongst themselves using an in-process lock.
+// Only the winner of the in-process lock interacts with FDB, so at most one
+// client per process is queued for or holds the mutex at any time. This
+// greatly reduces the number of FDB transactions when a mutex is highly
+// contended within a single process. The process holds the mutex in FDB
+// once: when a handle releases the mutex while another handle of the
+// process waits for it, the hold is passed to the waiting handle without
+// touching FDB. The client of the handle which acquired the mutex remains
+// the owner in FDB & keeps heartbeating until the last handle releases.
+// After 16 such handoffs, the mutex is released to the queue so other
+// processes aren't starved. Every handle for the mutex in the process
+// must use this option for the arbitration to take effect.
+func WithLocalArbitration() Option {
+	return func(x *Mutex) {
+		x.local = &localSlot{key: string(x.Bytes())}
+	}
+}
+
+// localSlot is a handle's view of an in-process lock.
+type localSlot struct {
+	key string
+
+	// held is the in-process lock while it's held by this handle.
+	held atomic.Pointer[localLock]
+}
+
+// lockLocal blocks until the in-process lock is held by this
+// handle or the context is canceled. If the handle doesn't use
+// local arbitration then this method returns immediately.
+func (x *Mutex) lockLocal(ctx context.Context, db fdb.Transactor) error {
+	if x.local == nil || x.local.held.Load() != nil {
+		return nil
+	}
+	l := refLocal(x.local.key)
+	select {
+	case l.ch <- struct{}{}:
+		x.local.held.Store(l)
+		return nil
+	case <-ctx.Done():
+		// If the hold was passed to us, no other
+		// handle is left to release it.
+		if carrier := unrefLocal(x.local.key, l); carrier != nil {
+			return errors.Join(ctx.Err(), carrier.releaseOwner(db))
+		}
+		return ctx.Err()
+	}
+}
+
+// tryLockLocal returns true if the in-process lock is held by this
+// handle, attempting to lock it without blocking if necessary. If
+// the handle doesn't use local arbitration then it returns true.
+func (x *Mutex) tryLockLocal() bool {
+	if x.local == nil || x.local.held.Load() != nil {
+		return true
+	}
+	l := refLocal(x.local.key)
+	select {
+	case l.ch <- struct{}{}:
+		x.local.held.Store(l)
+		return true
+	default:
+		unrefLocal(x.local.key, l)
+		return false
+	}
+}
+
+// localCarrier returns the handle holding the mutex in FDB on behalf of
+// the process. If this handle doesn't hold the in-process lock or the
+// process doesn't hold the mutex, nil is returned.
+func (x *Mutex) localCarrier() *Mutex {
+	if x.local == nil {
+		return nil
+	}
+	l := x.local.held.Load()
+	if l == nil {
+		return nil
+	}
+	localLocks.Lock()
+	defer localLocks.Unlock()
+	return l.carrier
+}
+
+// shareLocal takes the hold of the carrier if the process already holds
+// the mutex, returning its fencing token & true. Otherwise, the handle
+// must acquire the mutex from FDB & false is returned.
+func (x *Mutex) shareLocal() (int64, bool) {
+	carrier := x.localCarrier()
+	if carrier == nil {
+		return 0, false
+	}
+	token := carrier.token.Load()
+	x.token.Store(token)
+	return token, true
+}
+
+// carryLocal records this handle as the carrier after its client acquires
+// the mutex in FDB. If the handle doesn't hold the in-process lock, such as
+// when it doesn't use local arbitration, this method does nothing.
+func (x *Mutex) carryLocal() {
+	if x.local == nil {
+		return
+	}
+	l := x.local.held.Load()
+	if l == nil {
+		return
+	}
+	localLocks.Lock()
+	defer localLocks.Unlock()
+	l.carrier = x
+	l.handoffs = 0
+}
+
+// dropLocal clears the carrier if it's this handle. It's called once the
+// client of this handle no longer holds the mutex in FDB.
+func (x *Mutex) dropLocal() {
+	if x.local == nil {
+		return
+	}
+	localLocks.Lock()
+	defer localLocks.Unlock()
+	if l, ok := localLocks.m[x.local.key]; ok && l.carrier == x {
+		l.carrier = nil
+	}
+}
+
+// carriesLocal returns true if this handle is the carrier.
+func (x *Mutex) carriesLocal() bool {
+	if x.local == nil {
+		return false
+	}
+	localLocks.Lock()
+	defer localLocks.Unlock()
+	l, ok := localLocks.m[x.local.key]
+	return ok && l.carrier == x
+}
+
+// handOffLocal returns true if the hold should be passed to another
+// handle waiting for the in-process lock instead of being released.
+// See [[WithLocalArbitration]].
+func (x *Mutex) handOffLocal() bool {
+	if x.local == nil {
+		return false
+	}
+	l := x.local.held.Load()
+	if l == nil {
+		return false
+	}
+	localLocks.Lock()
+	defer localLocks.Unlock()
+	if l.carrier == nil || l.refs < 2 || l.handoffs >= maxLocalHandoffs {
+		return false
+	}
+	l.handoffs++
+	return true
+}
+
+// unlockLocal releases the in-process lock if it's held by this handle.
+func (x *Mutex) unlockLocal() {
+	if x.local == nil {
+		return
+	}
+	l := x.local.held.Swap(nil)
+	if l == nil {
+		return
+	}
+	<-l.ch
+
+	// The carrier is dropped before the last holder
+	// unlocks, so no hold is left behind here.
+	unrefLocal(x.local.key, l)
+}
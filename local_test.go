@@ -0,0 +1,159 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalArbitration(t *testing.T) {
+	tests := map[string]testFn{
+		"non-blocking": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1", WithLocalArbitration())
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2", WithLocalArbitration())
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// The local loser never reached FDB,
+			// so it shouldn't have been enqueued.
+			name, err := x2.dequeue(db)
+			require.NoError(t, err)
+			require.Empty(t, name)
+
+			err = x1.Release(db)
+			require.NoError(t, err)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+		},
+		"blocking": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1", WithLocalArbitration())
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2", WithLocalArbitration())
+			require.NoError(t, err)
+
+			err = x1.Acquire(context.Background(), db)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			err = x2.Acquire(ctx, db)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			err = x1.Release(db)
+			require.NoError(t, err)
+
+			err = x2.Acquire(context.Background(), db)
+			require.NoError(t, err)
+		},
+		"cleanup": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1", WithLocalArbitration())
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2", WithLocalArbitration())
+			require.NoError(t, err)
+
+			stored := func() bool {
+				localLocks.Lock()
+				defer localLocks.Unlock()
+				_, ok := localLocks.m[string(root.Bytes())]
+				return ok
+			}
+			require.False(t, stored())
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.True(t, stored())
+
+			// The local loser doesn't keep the lock stored.
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			err = x1.Release(db)
+			require.NoError(t, err)
+			require.False(t, stored())
+		},
+		"one hold": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			const n = 4
+
+			handles := make([]*Mutex, n)
+			for i := range handles {
+				x, err := NewMutex(db, root, fmt.Sprintf("client%d", i), WithLocalArbitration())
+				require.NoError(t, err)
+				handles[i] = x
+			}
+
+			token, err := handles[0].AcquireToken(context.Background(), db)
+			require.NoError(t, err)
+
+			refs := func() int {
+				localLocks.Lock()
+				defer localLocks.Unlock()
+				return localLocks.m[string(root.Bytes())].refs
+			}
+
+			// The other handles wait for the in-process lock
+			// & are passed the hold without touching FDB.
+			var wg sync.WaitGroup
+			tokens := make(chan int64, n-1)
+			for _, x := range handles[1:] {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					token, err := x.AcquireToken(context.Background(), db)
+					if err != nil {
+						t.Errorf("failed to acquire: %v", err)
+						return
+					}
+					tokens <- token
+					if err := x.Release(db); err != nil {
+						t.Errorf("failed to release: %v", err)
+					}
+				}()
+			}
+			require.Eventually(t, func() bool { return refs() == n }, 5*time.Second, 10*time.Millisecond)
+
+			require.NoError(t, handles[0].Release(db))
+			wg.Wait()
+			close(tokens)
+			for shared := range tokens {
+				require.Equal(t, token, shared)
+			}
+
+			owner, err := handles[0].getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+
+			// The process wrote the owner once.
+			events, err := handles[0].Events(db)
+			require.NoError(t, err)
+			var kinds []EventKind
+			for _, e := range events {
+				kinds = append(kinds, e.Kind)
+			}
+			require.Equal(t, []EventKind{EventAcquired, EventReleased}, kinds)
+		},
+	}
+
+	runTests(t, tests)
+}
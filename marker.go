@@ -0,0 +1,87 @@
+package mutex
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// ErrWrongType is returned when a primitive is constructed in a
+// subspace which is marked as holding a different kind of primitive.
+var ErrWrongType = errors.New("subspace holds a different primitive")
+
+// ErrSchemaTooNew is returned when a mutex is marked with a schema version
+// newer than [[SchemaVersion]]. The mutex was written by a newer version of
+// this package whose layout this version can't safely read or write.
+var ErrSchemaTooNew = errors.New("mutex was written by a newer schema version")
+
+// typeMutex marks a subspace as holding a [[Mutex]].
+const typeMutex = "mutex"
+
+// claimType marks the subspace as holding the given type of primitive. If
+// the subspace is already marked as holding another type, [[ErrWrongType]]
+// is returned so two primitives never corrupt each other's state. Subspaces
+// written before markers existed are unmarked and are claimed silently.
+func (x *kv) claimType(db fdb.Transactor, typ string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		marked, ok, err := x.getType(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get type: %w", err)
+		}
+		if ok && marked != typ {
+			return nil, fmt.Errorf("%w: expected %s but found %s", ErrWrongType, typ, marked)
+		}
+		if !ok {
+			tr.Set(x.packTypeKey(), []byte(typ))
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// IsMutex returns true if 'root' holds a mutex. Mutexes are marked with
+// an identifying record when they're created, so tooling can tell them
+// apart from unrelated data stored under the same parent directory.
+// Mutexes created before the marker existed are recognized by their
+// owner key until a handle is constructed for them.
+func IsMutex(db fdb.Transactor, root subspace.Subspace) (_ bool, err error) {
+	defer wrapErr(&err)
+
+	x := kv{Subspace: root}
+	ok, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		return x.isMutex(tr)
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok.(bool), nil
+}
+
+// isMutex returns true if the subspace is marked as a mutex or,
+// lacking a marker, contains an initialized owner key.
+func (x *kv) isMutex(db fdb.Transactor) (bool, error) {
+	typ, marked, err := x.getType(db)
+	if err != nil {
+		return false, fmt.Errorf("failed to get type: %w", err)
+	}
+	if marked {
+		return typ == typeMutex, nil
+	}
+	return x.exists(db)
+}
+
+// checkSchema returns [[ErrSchemaTooNew]] if the subspace is marked with
+// a schema version newer than the one implemented by this package. An
+// unmarked subspace is assumed to use a compatible version.
+func (x *kv) checkSchema(db fdb.Transactor) error {
+	version, ok, err := x.getSchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if ok && version > SchemaVersion {
+		return fmt.Errorf("%w: found version %d but only %d is supported", ErrSchemaTooNew, version, SchemaVersion)
+	}
+	return nil
+}
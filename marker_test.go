@@ -0,0 +1,96 @@
+package mutex
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeMarker(t *testing.T) {
+	tests := map[string]testFn{
+		"wrong type": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x := kv{Subspace: root}
+			_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Set(x.packTypeKey(), []byte("semaphore"))
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			_, err = NewMutex(db, root, "client")
+			require.ErrorIs(t, err, ErrWrongType)
+
+			_, err = NewObserver(db, root)
+			require.ErrorIs(t, err, ErrWrongType)
+
+			exists, err := x.exists(db)
+			require.NoError(t, err)
+			require.False(t, exists)
+
+			isMutex, err := IsMutex(db, root)
+			require.NoError(t, err)
+			require.False(t, isMutex)
+		},
+		"unmarked": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			// Remove the marker, as if the mutex was
+			// created before markers existed.
+			x := kv{Subspace: root}
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Clear(x.packTypeKey())
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			_, err = NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			typ, ok, err := x.getType(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, typeMutex, typ)
+		},
+		"marked": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			isMutex, err := IsMutex(db, root)
+			require.NoError(t, err)
+			require.False(t, isMutex)
+
+			_, err = NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			isMutex, err = IsMutex(db, root)
+			require.NoError(t, err)
+			require.True(t, isMutex)
+
+			x := kv{Subspace: root}
+			version, ok, err := x.getSchemaVersion(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, SchemaVersion, version)
+		},
+		"schema too new": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x := kv{Subspace: root}
+			require.NoError(t, x.setSchemaVersion(db, SchemaVersion+1))
+
+			_, err = NewMutex(db, root, "client2")
+			require.ErrorIs(t, err, ErrSchemaTooNew)
+
+			_, err = NewObserver(db, root)
+			require.ErrorIs(t, err, ErrSchemaTooNew)
+
+			// Nothing was rewritten by the refused handles.
+			version, ok, err := x.getSchemaVersion(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, SchemaVersion+1, version)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,61 @@
+package mutex
+
+import (
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// Metadata describes the creation of a mutex. It's recorded by the client
+// which creates the mutex so stale locks can be traced back to their owners.
+type Metadata struct {
+	// Created is when the mutex was created, according
+	// to the clock of the client which created it.
+	Created time.Time
+
+	// Creator is the name of the client which created the mutex.
+	Creator string
+
+	// Description is a human-readable description of the mutex.
+	// See [[WithDescription]].
+	Description string
+}
+
+// WithDescription sets the description recorded in the mutex's
+// [[Metadata]] if this client creates the mutex. If the mutex
+// already exists, the description is ignored.
+func WithDescription(description string) Option {
+	return func(x *Mutex) {
+		x.description = description
+	}
+}
+
+// Metadata returns the creation metadata of the mutex. If the
+// mutex was created before metadata was recorded, false is returned.
+func (x *Mutex) Metadata(db fdb.Transactor) (_ Metadata, _ bool, err error) {
+	defer wrapErr(&err)
+	return x.kv.metadata(x.withBreaker(db))
+}
+
+// Metadata is like [[Mutex.Metadata]].
+func (x *Observer) Metadata(db fdb.Transactor) (_ Metadata, _ bool, err error) {
+	defer wrapErr(&err)
+	return x.kv.metadata(x.inspect(db, true))
+}
+
+// metadata returns the creation metadata in its exported form.
+func (x *kv) metadata(db fdb.Transactor) (Metadata, bool, error) {
+	meta, ok, err := x.getMetadata(db)
+	if err != nil || !ok {
+		return Metadata{}, false, err
+	}
+	return toMetadata(meta), true, nil
+}
+
+func toMetadata(meta metadataKV) Metadata {
+	return Metadata{
+		Created:     meta.created,
+		Creator:     meta.creator,
+		Description: meta.description,
+	}
+}
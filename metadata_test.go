@@ -0,0 +1,51 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata(t *testing.T) {
+	tests := map[string]testFn{
+		"creator": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			start := time.Now()
+
+			x1, err := NewMutex(db, root, "client1", WithDescription("nightly backups"))
+			require.NoError(t, err)
+
+			// The mutex already exists, so this
+			// client's description is ignored.
+			_, err = NewMutex(db, root, "client2", WithDescription("ignored"))
+			require.NoError(t, err)
+
+			meta, ok, err := x1.Metadata(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "client1", meta.Creator)
+			require.Equal(t, "nightly backups", meta.Description)
+			require.False(t, meta.Created.Before(start.Truncate(time.Second)))
+		},
+		"list": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dir, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			_, err = NewMutex(db, dir, "client", WithDescription("desc"))
+			require.NoError(t, err)
+
+			list, err := List(db, parent, nil)
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			require.Equal(t, "client", list[0].Metadata.Creator)
+			require.Equal(t, "desc", list[0].Metadata.Description)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,93 @@
+package mutex
+
+import (
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// WithDualRead prepares the client for a gradual upgrade from version 1 of
+// the schema. Readers always understand both the old & new layouts, but in
+// this mode the handle also writes heartbeats & queue entries using the old
+// layout and leaves existing state as it is, so clients which haven't been
+// upgraded yet can keep sharing the mutex. Heartbeats written in this mode
+// don't record the writer's clock. Once every client has been upgraded,
+// finalize the new layout with [[MigrateSchema]] & drop this option.
+func WithDualRead() Option {
+	return func(x *Mutex) {
+		x.dualRead = true
+	}
+}
+
+// MigrateSchema rewrites the mutex stored in 'root' using version 'target'
+// of the schema and marks it with that version. See [[SchemaVersion]]. The
+// mutex may be in use while it's migrated: the whole subspace is read
+// within a single transaction, so any acquire, release, or heartbeat which
+// commits during the rewrite conflicts with it and the rewrite is retried
+// against the new state. Clients resume as soon as the rewrite commits.
+//
+// Migrations only move forward. If 'target' is older than the version the
+// mutex is marked with, or newer than [[SchemaVersion]], an error is
+// returned. If the mutex was written by a newer version of this package,
+// [[ErrSchemaTooNew]] is returned. If 'root' doesn't hold a mutex,
+// [[ErrNotFound]] is returned. Migrating to the current version is a noop.
+func MigrateSchema(db fdb.Transactor, root subspace.Subspace, target int) (err error) {
+	defer wrapErr(&err)
+
+	if target < 1 || target > SchemaVersion {
+		return fmt.Errorf("schema version %d is unsupported", target)
+	}
+
+	x := kv{Subspace: root}
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		isMutex, err := x.isMutex(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for mutex: %w", err)
+		}
+		if !isMutex {
+			return nil, ErrNotFound
+		}
+		if err := x.checkSchema(tr); err != nil {
+			return nil, err
+		}
+
+		// Quiesce the mutex by conflicting with
+		// every concurrent write to its subspace.
+		begin, end := x.FDBRangeKeys()
+		if err := tr.AddReadConflictRange(fdb.KeyRange{Begin: begin, End: end}); err != nil {
+			return nil, fmt.Errorf("failed to add read conflict: %w", err)
+		}
+		return nil, x.migrate(tr, target)
+	})
+	return err
+}
+
+// migrate upgrades the records of the mutex to version 'target' of the
+// schema. Mutexes without a version marker are assumed to be version 1,
+// which never wrote one. Rewriting is idempotent, so a mutex partially
+// written by a lingering older handle is upgraded again.
+func (x *kv) migrate(tr fdb.Transaction, target int) error {
+	version, ok, err := x.getSchemaVersion(tr)
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if !ok {
+		version = 1
+	}
+	if target < version {
+		return fmt.Errorf("can't migrate from schema version %d to %d", version, target)
+	}
+
+	if target >= 2 {
+		if err := x.migrateValues(tr); err != nil {
+			return fmt.Errorf("failed to migrate values: %w", err)
+		}
+	}
+	if !ok || version != target {
+		if err := x.setSchemaVersion(tr, target); err != nil {
+			return fmt.Errorf("failed to set schema version: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateSchema(t *testing.T) {
+	tests := map[string]testFn{
+		"version 1": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			require.NoError(t, x1.Acquire(context.Background(), db))
+
+			// Rewrite the mutex as version 1 of the
+			// schema would have left it.
+			x := kv{Subspace: root}
+			vstamp := tuple.Versionstamp{TransactionVersion: [10]byte{1}}
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Clear(x.packSchemaVersionKey())
+				tr.Set(x.packOwnerKey("client1"), append(vstamp.TransactionVersion[:], 0, 0))
+				tr.Set(x.Pack(tuple.Tuple{"queue", vstamp}), []byte("client2"))
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, MigrateSchema(db, root, 1))
+			version, ok, err := x.getSchemaVersion(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, 1, version)
+
+			require.NoError(t, MigrateSchema(db, root, SchemaVersion))
+			version, ok, err = x.getSchemaVersion(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, SchemaVersion, version)
+
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+			require.Equal(t, tuple.Tuple{vstamp}.Pack(), owner.hbeat)
+
+			queue, err := x.getQueue(db)
+			require.NoError(t, err)
+			require.Equal(t, []queueKV{{name: "client2", vstamp: vstamp}}, queue)
+
+			// The owner resumes once the rewrite commits.
+			require.NoError(t, x1.Release(db))
+		},
+		"dual read": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1", WithDualRead())
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "client2", WithDualRead())
+			require.NoError(t, err)
+
+			x := kv{Subspace: root}
+			version, ok, err := x.getSchemaVersion(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, 1, version)
+
+			require.NoError(t, x1.Acquire(context.Background(), db))
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				return nil, x1.AddHeartbeat(tr)
+			})
+			require.NoError(t, err)
+
+			acquired, err := x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// Both values use the old layout.
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+			require.Len(t, owner.hbeat, 12)
+
+			queue, err := x.getQueue(db)
+			require.NoError(t, err)
+			require.Len(t, queue, 1)
+			val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+				return tr.Get(x.Pack(tuple.Tuple{"queue", queue[0].vstamp})).Get()
+			})
+			require.NoError(t, err)
+			require.Equal(t, []byte("client2"), val)
+
+			// Finalizing upgrades the values in place.
+			require.NoError(t, MigrateSchema(db, root, SchemaVersion))
+			owner, err = x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+			require.Greater(t, len(owner.hbeat), 12)
+
+			require.NoError(t, x1.Release(db))
+			owner, err = x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner.name)
+		},
+		"current": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			// A lingering older handle writes a version 1
+			// heartbeat to a mutex marked with the current
+			// version.
+			x := kv{Subspace: root}
+			vstamp := tuple.Versionstamp{TransactionVersion: [10]byte{1}}
+			old := append(vstamp.TransactionVersion[:], 0, 0)
+			require.NoError(t, x.setOwner(db, "client1"))
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Set(x.packOwnerKey("client1"), old)
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			// Opening the mutex doesn't rewrite it.
+			_, err = NewMutex(db, root, "client2")
+			require.NoError(t, err)
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, old, owner.hbeat)
+
+			// Re-running the migration does.
+			require.NoError(t, MigrateSchema(db, root, SchemaVersion))
+			owner, err = x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, tuple.Tuple{vstamp}.Pack(), owner.hbeat)
+		},
+		"backwards": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			require.Error(t, MigrateSchema(db, root, 1))
+			require.Error(t, MigrateSchema(db, root, SchemaVersion+1))
+			require.NoError(t, MigrateSchema(db, root, SchemaVersion))
+		},
+		"not found": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			err := MigrateSchema(db, root, SchemaVersion)
+			require.ErrorIs(t, err, ErrNotFound)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,264 @@
+package mutex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// localPollInterval is how often [[acquireAny]] retries mutexes whose
+// in-process lock is held by another handle. See [[WithLocalArbitration]].
+const localPollInterval = 100 * time.Millisecond
+
+// AcquireAny blocks until one of the given mutexes is acquired, then returns
+// its index. The client waits in the queue of every mutex at once, watching
+// all their owners, and takes whichever is handed to it first. The client
+// then withdraws from the other queues. This is useful for distributing work
+// over sharded resources. Like [[Mutex.Acquire]], a rate limited mutex is
+// retried once its interval ends. If one of the mutexes is disabled, the
+// client gives up with [[ErrDisabled]]. The mutexes must be distinct. See
+// [[Pool.AcquireAny]].
+func AcquireAny(ctx context.Context, db fdb.Transactor, mutexes ...*Mutex) (_ int, err error) {
+	defer wrapErr(&err)
+
+	if len(mutexes) == 0 {
+		return -1, fmt.Errorf("no mutexes given")
+	}
+	if err := checkDistinct(mutexes); err != nil {
+		return -1, err
+	}
+	return acquireAny(ctx, db, mutexes)
+}
+
+// AcquireAll blocks until every given mutex is acquired. If the full set
+// isn't acquired before 'timeout' passes or the context ends, every mutex
+// obtained so far is released, the client leaves the queue it was waiting
+// in, and the error is returned. No partial hold outlives the call. A zero
+// timeout only relies on the context. The mutexes are acquired in the order
+// of their keys, so clients acquiring overlapping sets don't deadlock. The
+// mutexes must be distinct.
+func AcquireAll(ctx context.Context, db fdb.Transactor, timeout time.Duration, mutexes ...*Mutex) (err error) {
+	defer wrapErr(&err)
+
+	if err := checkDistinct(mutexes); err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	sorted := slices.Clone(mutexes)
+	slices.SortFunc(sorted, func(a, b *Mutex) int {
+		return bytes.Compare(a.Bytes(), b.Bytes())
+	})
+
+	for i, x := range sorted {
+		err := x.Acquire(ctx, db)
+		if err == nil {
+			continue
+		}
+
+		// Roll back, starting with the mutex we
+		// were waiting for. It may have been handed
+		// to us after the context ended.
+		err = fmt.Errorf("failed to acquire mutex: %w", err)
+		if werr := x.withdraw(x.withBreaker(db)); werr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to withdraw: %w", werr))
+		}
+		for j := i - 1; j >= 0; j-- {
+			if rerr := sorted[j].Release(db); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to release: %w", rerr))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// checkDistinct returns an error if two of the
+// handles are for the mutex in the same subspace.
+func checkDistinct(mutexes []*Mutex) error {
+	seen := make(map[string]int, len(mutexes))
+	for i, x := range mutexes {
+		key := string(x.Bytes())
+		if j, ok := seen[key]; ok {
+			return fmt.Errorf("mutexes %d and %d are the same", j, i)
+		}
+		seen[key] = i
+	}
+	return nil
+}
+
+// acquireAny blocks until one of the mutexes is held, then returns its
+// index. The client waits in every queue at once, watching each owner key
+// & checking each mutex as [[Mutex.acquire]] does, and withdraws from the
+// other queues once a mutex is acquired. If another mutex is handed to the
+// client before it withdraws, that mutex is released.
+func acquireAny(ctx context.Context, db fdb.Transactor, mutexes []*Mutex) (won int, err error) {
+	// Tracks the mutexes whose in-process lock is
+	// held and whose queue the client may be in.
+	joined := make([]bool, len(mutexes))
+
+	// Tracks when each joined mutex should be checked
+	// again even if its owner doesn't change. See
+	// [[Mutex.checkWait]].
+	wakes := make([]time.Time, len(mutexes))
+
+	won = -1
+	defer func() {
+		for i, x := range mutexes {
+			if i != won && joined[i] {
+				if werr := x.withdraw(x.withBreaker(db)); werr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to withdraw from mutex %d: %w", i, werr))
+				}
+			}
+		}
+		if err != nil && won >= 0 {
+			_ = mutexes[won].Release(db)
+			won = -1
+		}
+	}()
+
+	for {
+		// Join the queue of every mutex whose in-process lock is free.
+		// A rate limited mutex is left unjoined & retried once the
+		// next attempt may be accepted.
+		var (
+			blocked bool
+			retry   time.Duration
+		)
+		for i, x := range mutexes {
+			if joined[i] {
+				continue
+			}
+			if !x.tryLockLocal() {
+				blocked = true
+				continue
+			}
+			joined[i] = true
+			if _, ok := x.shareLocal(); ok {
+				return i, nil
+			}
+
+			_, acquired, err := x.tryAcquire(x.withBreaker(db))
+			var rerr *RateLimitError
+			if errors.As(err, &rerr) {
+				x.unlockLocal()
+				joined[i] = false
+				if retry == 0 || rerr.RetryAfter < retry {
+					retry = rerr.RetryAfter
+				}
+				continue
+			}
+			if err != nil {
+				return -1, fmt.Errorf("failed to try acquire mutex %d: %w", i, err)
+			}
+			if acquired {
+				return i, nil
+			}
+		}
+
+		// Watch the owner keys before checking the owners
+		// so a change made after the check isn't missed.
+		watchCtx, cancel := context.WithCancel(ctx)
+		signal := make(chan error, len(mutexes))
+		for i, x := range mutexes {
+			if joined[i] {
+				watch := x.watchOwner(watchCtx, x.withBreaker(db))
+				go func() { signal <- <-watch }()
+			}
+		}
+
+		// Check each mutex, noting the earliest time
+		// one should be checked again regardless.
+		var next time.Time
+		for i, x := range mutexes {
+			if !joined[i] {
+				continue
+			}
+			due := !wakes[i].IsZero() && !time.Now().Before(wakes[i])
+			wakes[i] = time.Time{}
+
+			check, err := x.checkWait(x.withBreaker(db), due)
+			if err != nil {
+				cancel()
+				return -1, fmt.Errorf("mutex %d: %w", i, err)
+			}
+			if check.acquired {
+				cancel()
+				return i, nil
+			}
+			if check.wake > 0 {
+				wakes[i] = time.Now().Add(check.wake)
+				if next.IsZero() || wakes[i].Before(next) {
+					next = wakes[i]
+				}
+			}
+		}
+		var wake <-chan time.Time
+		if !next.IsZero() {
+			wake = time.After(time.Until(next))
+		}
+
+		var poll <-chan time.Time
+		if blocked {
+			poll = time.After(localPollInterval)
+		}
+		var limited <-chan time.Time
+		if retry > 0 {
+			limited = time.After(retry)
+		}
+
+		select {
+		case err := <-signal:
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return -1, ctx.Err()
+				}
+				return -1, fmt.Errorf("failed to watch owner: %w", err)
+			}
+		case <-wake:
+			cancel()
+		case <-poll:
+			cancel()
+		case <-limited:
+			cancel()
+		case <-ctx.Done():
+			cancel()
+			return -1, ctx.Err()
+		}
+	}
+}
+
+// withdraw removes this client from the mutex's queue. If the mutex was
+// handed to this client in the meantime, it's released. Either way, the
+// in-process lock is released.
+func (x *Mutex) withdraw(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name != x.name {
+			return nil, x.removeFromQueue(tr, x.name)
+		}
+		if err := x.logEvent(tr, EventReleased, x.name); err != nil {
+			return nil, fmt.Errorf("failed to log event: %w", err)
+		}
+		if err := x.clearAttrs(tr, x.name); err != nil {
+			return nil, fmt.Errorf("failed to clear attributes: %w", err)
+		}
+		_, err = x.release(tr)
+		return nil, err
+	})
+	x.relinquish()
+	return err
+}
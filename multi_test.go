@@ -0,0 +1,204 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireAny(t *testing.T) {
+	tests := map[string]testFn{
+		"first free": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			holderA, err := NewMutex(db, root.Sub("a"), "holder")
+			require.NoError(t, err)
+			holderB, err := NewMutex(db, root.Sub("b"), "holder")
+			require.NoError(t, err)
+
+			xA, err := NewMutex(db, root.Sub("a"), "client")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, root.Sub("b"), "client")
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{holderA, holderB} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			type result struct {
+				i   int
+				err error
+			}
+			done := make(chan result, 1)
+			go func() {
+				i, err := AcquireAny(ctx, db, xA, xB)
+				done <- result{i, err}
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, holderB.Release(db))
+			require.Equal(t, result{i: 1}, <-done)
+
+			// The client withdrew from the other queue.
+			candidates, err := xA.Candidates(db)
+			require.NoError(t, err)
+			require.Empty(t, candidates)
+		},
+		"not distinct": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			_, err = AcquireAny(context.Background(), db, x1, x2)
+			require.Error(t, err)
+		},
+		"window": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			holder, err := NewMutex(db, root.Sub("a"), "holder")
+			require.NoError(t, err)
+			acquired, err := holder.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			xA, err := NewMutex(db, root.Sub("a"), "client")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, root.Sub("b"), "client")
+			require.NoError(t, err)
+
+			// Mutex 'b' is free, but its window is closed.
+			require.NoError(t, xB.SetSchedule(db, windowIn(300*time.Millisecond, false)))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			i, err := AcquireAny(ctx, db, xA, xB)
+			require.NoError(t, err)
+			require.Equal(t, 1, i)
+		},
+		"disabled": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			holderA, err := NewMutex(db, root.Sub("a"), "holder")
+			require.NoError(t, err)
+			holderB, err := NewMutex(db, root.Sub("b"), "holder")
+			require.NoError(t, err)
+
+			xA, err := NewMutex(db, root.Sub("a"), "client")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, root.Sub("b"), "client")
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{holderA, holderB} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := AcquireAny(ctx, db, xA, xB)
+				done <- err
+			}()
+
+			require.Eventually(t, func() bool {
+				queued, err := holderA.isQueued(db, "client")
+				return err == nil && queued
+			}, time.Second, 10*time.Millisecond)
+
+			require.NoError(t, holderA.Disable(db))
+			require.NoError(t, holderA.Release(db))
+			require.ErrorIs(t, <-done, ErrDisabled)
+
+			// The client withdrew from both queues.
+			for _, x := range []*Mutex{xA, xB} {
+				candidates, err := x.Candidates(db)
+				require.NoError(t, err)
+				require.Empty(t, candidates)
+			}
+		},
+		"rate limited": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			holder, err := NewMutex(db, root.Sub("b"), "holder")
+			require.NoError(t, err)
+			acquired, err := holder.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			other, err := NewMutex(db, root.Sub("a"), "other")
+			require.NoError(t, err)
+			xA, err := NewMutex(db, root.Sub("a"), "client")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, root.Sub("b"), "client")
+			require.NoError(t, err)
+
+			// Use up the attempts of mutex 'a'.
+			require.NoError(t, other.SetRateLimit(db, 1, 300*time.Millisecond))
+			acquired, err = other.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, other.Release(db))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			i, err := AcquireAny(ctx, db, xA, xB)
+			require.NoError(t, err)
+			require.Equal(t, 0, i)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestAcquireAll(t *testing.T) {
+	tests := map[string]testFn{
+		"acquired": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			xA, err := NewMutex(db, root.Sub("a"), "client")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, root.Sub("b"), "client")
+			require.NoError(t, err)
+
+			require.NoError(t, AcquireAll(context.Background(), db, time.Second, xB, xA))
+
+			for _, x := range []*Mutex{xA, xB} {
+				owner, err := x.getOwner(db)
+				require.NoError(t, err)
+				require.Equal(t, "client", owner.name)
+			}
+		},
+		"rollback": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			holder, err := NewMutex(db, root.Sub("b"), "holder")
+			require.NoError(t, err)
+			acquired, err := holder.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			xA, err := NewMutex(db, root.Sub("a"), "client")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, root.Sub("b"), "client")
+			require.NoError(t, err)
+
+			err = AcquireAll(context.Background(), db, 100*time.Millisecond, xA, xB)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			// Mutex 'a' was released and the
+			// client left the queue of 'b'.
+			owner, err := xA.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+
+			candidates, err := xB.Candidates(db)
+			require.NoError(t, err)
+			require.Empty(t, candidates)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -4,25 +4,209 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 )
 
+// Mutex is a client's handle to a distributed mutex. A handle is safe for
+// concurrent use by multiple goroutines. All goroutines sharing a handle act
+// as the same client, so they share ownership of the mutex: if one goroutine
+// acquires the mutex, the others will see it as acquired as well. Acquiring
+// an already acquired mutex and releasing an already released mutex are both
+// noops.
 type Mutex struct {
 	kv
-	name string
+	name    string
+	clients *ClientRegistry
+	breaker *breaker
+
+	// priority determines the order clients are
+	// dequeued. See [[WithPriority]].
+	priority int64
+
+	// sticky is how long a dead owner's reservation
+	// lasts. See [[WithStickyGrace]].
+	sticky time.Duration
+
+	// description is recorded when this client creates
+	// the mutex. See [[WithDescription]].
+	description string
+
+	// failSafe is how long the heartbeat may fail before
+	// the mutex is assumed lost. See [[WithFailSafe]].
+	failSafe time.Duration
+	guards   guards
+
+	// local arbitrates between goroutines of this process
+	// which use the same mutex. See [[WithLocalArbitration]].
+	local *localSlot
+
+	// coalesce, if not nil, hands the hold between the
+	// goroutines of this handle. See [[WithCoalescing]].
+	coalesce *coalescer
+
+	// mu protects the heartbeat's stop channel.
+	// The channel is nil when not heartbeating.
+	mu   sync.Mutex
 	stop chan struct{}
+
+	// lastBeat is the time of the latest successful heartbeat,
+	// stored as unix nanoseconds. It's shared with the fail-safe
+	// watchdog and updated by [[Mutex.AddHeartbeat]].
+	lastBeat atomic.Int64
+
+	// token is the fencing token of the current hold, shared
+	// by coalesced goroutines. See [[Mutex.AcquireToken]].
+	token atomic.Int64
+
+	// validated is when ownership was last confirmed by a
+	// read, stored as unix nanoseconds. See [[Mutex.Validate]].
+	validated atomic.Int64
+
+	// state is the [[HeartbeatState]] of the current hold.
+	// ttl & budget configure the heartbeat loop. See
+	// [[WithLeaseTTL]] & [[WithHeartbeatErrorBudget]].
+	state  atomic.Int32
+	ttl    time.Duration
+	budget int
+
+	// beatRestarts counts the panics recovered from the
+	// heartbeat loop. See [[WithHeartbeatPanicHandler]].
+	beatRestarts atomic.Int64
+	onBeatPanic  func(recovered any)
+
+	// slowLatency & slowRetries determine when a heartbeat is
+	// slow. beatLatency is the latency of the latest heartbeat
+	// & slowBeats counts the slow ones. See [[WithHeartbeatQoS]].
+	slowLatency time.Duration
+	slowRetries int
+	beatLatency atomic.Int64
+	slowBeats   atomic.Int64
+
+	// attrs describe the identity of this client and are
+	// attached to its acquisitions. See [[Identity]].
+	attrs map[string]string
+
+	// delegator, if not blank, names the client acquiring
+	// on behalf of this one. See [[WithDelegator]].
+	delegator string
+
+	// recorder, if not nil, receives a record of
+	// each operation. See [[WithRecorder]].
+	recorder Recorder
+
+	// throttle, if not nil, limits acquisition
+	// attempts. See [[WithAcquireRateLimit]].
+	throttle *throttle
+
+	// clock estimates the drift of the local
+	// clock. See [[Mutex.ClockDrift]].
+	clock clockTracker
+
+	// profiler, if not nil, aggregates contention on
+	// the mutex. See [[WithContentionProfiler]].
+	profiler *ContentionProfiler
+
+	// staleReads & staleness configure the reads
+	// of observers. See [[WithStaleReads]].
+	staleReads bool
+	staleness  time.Duration
+
+	// optErr records the options which were invalid.
+	// It's returned when the mutex is constructed.
+	optErr error
+}
+
+// Option configures optional behavior of a [[Mutex]].
+type Option func(*Mutex)
+
+// WithClientRegistry attaches the mutex to a [[ClientRegistry]]. The client's
+// session is registered when the mutex is constructed and the registry is
+// updated whenever this mutex changes ownership or heartbeats.
+func WithClientRegistry(reg ClientRegistry) Option {
+	return func(x *Mutex) {
+		x.clients = &reg
+	}
+}
+
+// WithPriority sets the client's priority. When the mutex is released, the
+// queued client with the highest priority acquires it, regardless of how
+// long other clients have been waiting. Clients with equal priority acquire
+// in the order they were queued. The default priority is zero, so if no
+// client sets a priority then the queue is strictly first-in first-out.
+func WithPriority(priority int64) Option {
+	return func(x *Mutex) {
+		x.priority = priority
+	}
 }
 
+// WithStickyGrace causes [[Mutex.AutoRelease]] to prefer the previous owner when
+// it releases a dead owner. Instead of handing the mutex to the queue, the mutex
+// is left vacant and reserved for the previous owner until 'grace' has passed.
+// If the owner restarts within the grace period, it may acquire the mutex ahead
+// of the queue. Otherwise, the mutex is handed to the queue. This reduces churn
+// from transient failures, such as process restarts. The option only affects
+// the handles running AutoRelease. Reservation deadlines are compared against
+// the local clock, so clocks should be roughly synchronized.
+func WithStickyGrace(grace time.Duration) Option {
+	return func(x *Mutex) {
+		x.sticky = grace
+	}
+}
+
+// ErrNotFound is returned when a mutex is expected
+// to exist in a subspace but doesn't.
+var ErrNotFound = errors.New("mutex doesn't exist")
+
+// ErrExists is returned by [[CreateMutex]] when
+// a mutex already exists in the subspace.
+var ErrExists = errors.New("mutex already exists")
+
 // NewMutex constructs a distributed mutex. 'root' is the directory where the
 // mutex state is stored and unqiuely identifies the mutex. 'name' uniquely
 // identifies the client interacting with the mutex. If name is left blank
-// then a random name is chosen.
-func NewMutex(db fdb.Transactor, root subspace.Subspace, name string) (Mutex, error) {
+// then a random name is chosen. If the mutex doesn't exist, it's created.
+// To catch misconfigured paths, use [[OpenMutex]] or [[CreateMutex]]. If
+// the mutex was written by a newer, incompatible version of this package,
+// [[ErrSchemaTooNew]] is returned rather than risk corrupting its state.
+func NewMutex(db fdb.Transactor, root subspace.Subspace, name string, opts ...Option) (*Mutex, error) {
+	return newMutex(db, root, name, createOrOpen, opts)
+}
+
+// OpenMutex is like [[NewMutex]] but returns [[ErrNotFound]]
+// if the mutex hasn't already been created.
+func OpenMutex(db fdb.Transactor, root subspace.Subspace, name string, opts ...Option) (*Mutex, error) {
+	return newMutex(db, root, name, openOnly, opts)
+}
+
+// CreateMutex is like [[NewMutex]] but returns [[ErrExists]]
+// if the mutex has already been created.
+func CreateMutex(db fdb.Transactor, root subspace.Subspace, name string, opts ...Option) (*Mutex, error) {
+	return newMutex(db, root, name, createOnly, opts)
+}
+
+// openMode determines how [[newMutex]] treats
+// the existence of the mutex's state.
+type openMode int
+
+const (
+	createOrOpen openMode = iota
+	openOnly
+	createOnly
+)
+
+func newMutex(db fdb.Transactor, root subspace.Subspace, name string, mode openMode, opts []Option) (_ *Mutex, err error) {
+	defer wrapErr(&err)
+
 	if name == "" {
 		var randBytes [32]byte
 		if _, err := rand.Read(randBytes[:]); err != nil {
@@ -31,27 +215,112 @@ func NewMutex(db fdb.Transactor, root subspace.Subspace, name string) (Mutex, er
 		name = hex.EncodeToString(randBytes[:])
 	}
 
-	kv := kv{root}
+	x := &Mutex{
+		kv:   kv{Subspace: root},
+		name: name,
+	}
+	for _, opt := range opts {
+		opt(x)
+	}
+	if x.optErr != nil {
+		return nil, fmt.Errorf("invalid option: %w", x.optErr)
+	}
+	db = x.withBreaker(db)
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		// Refuse subspaces holding other primitives or written
+		// by a newer version of this package before anything
+		// else is read or written.
+		if err := x.claimType(tr, typeMutex); err != nil {
+			return nil, err
+		}
+		if err := x.checkSchema(tr); err != nil {
+			return nil, err
+		}
 
-	// Set a blank owner to initialize the owner key.
-	// This allows kv.watchOwner() to trigger on the
-	// first acquire.
-	err := kv.setOwner(db, "")
+		exists, err := x.exists(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existence: %w", err)
+		}
+
+		switch {
+		case exists && mode == createOnly:
+			return nil, ErrExists
+		case !exists && mode == openOnly:
+			return nil, ErrNotFound
+		case exists:
+			// Upgrade any values left by older versions of
+			// this package, unless they're still running.
+			if x.dualRead {
+				return nil, nil
+			}
+
+			// A mutex marked with the current version was already
+			// migrated, so opening it doesn't rewrite the queue.
+			// Values written afterwards by a lingering older handle
+			// are upgraded by re-running [[MigrateSchema]].
+			version, ok, err := x.getSchemaVersion(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get schema version: %w", err)
+			}
+			if ok && version == SchemaVersion {
+				return nil, nil
+			}
+			if err := x.migrate(tr, SchemaVersion); err != nil {
+				return nil, fmt.Errorf("failed to migrate: %w", err)
+			}
+			return nil, nil
+		}
+
+		// Set a blank owner to initialize the owner key.
+		// This allows kv.watchOwner() to trigger on the
+		// first acquire.
+		if err := x.setOwner(tr, ""); err != nil {
+			return nil, fmt.Errorf("failed to initialize owner key: %w", err)
+		}
+
+		meta := metadataKV{
+			created:     time.Now(),
+			creator:     x.name,
+			description: x.description,
+		}
+		if err := x.setMetadata(tr, meta); err != nil {
+			return nil, fmt.Errorf("failed to set metadata: %w", err)
+		}
+		if err := x.setLastUsed(tr, meta.created); err != nil {
+			return nil, fmt.Errorf("failed to set last use: %w", err)
+		}
+		version := SchemaVersion
+		if x.dualRead {
+			version = 1
+		}
+		if err := x.setSchemaVersion(tr, version); err != nil {
+			return nil, fmt.Errorf("failed to set schema version: %w", err)
+		}
+		return nil, nil
+	})
 	if err != nil {
-		return Mutex{}, fmt.Errorf("failed to initialize owner key: %w", err)
+		return nil, err
 	}
 
-	return Mutex{
-		kv:   kv,
-		name: name,
-		stop: make(chan struct{}),
-	}, nil
+	if x.clients != nil {
+		if err := x.clients.register(db, name); err != nil {
+			return nil, fmt.Errorf("failed to register client: %w", err)
+		}
+		x.clients.beatSession(withoutDeadline(db), name)
+	}
+
+	return x, nil
 }
 
 // AutoRelease runs a loop that checks if the current owner's latest heartbeat is older than the
 // specified duration. If so, the owner is assumed to have died and the mutex is released.
-// Multiple instances of this function may be run.
-func (x *Mutex) AutoRelease(ctx context.Context, db fdb.Database, maxAge time.Duration) error {
+// Multiple instances of this function may be run. This function returns on the first
+// error, so long-running deployments should use a [[Releaser]] to restart it.
+func (x *Mutex) AutoRelease(ctx context.Context, db fdb.Transactor, maxAge time.Duration) (err error) {
+	defer wrapErr(&err)
+	db = x.withBreaker(db)
+
 	// NOTE: We cannot defer a call to cancel because
 	// the variable is reassigned at the end of each
 	// loop. We need the newest cancel function to be
@@ -65,7 +334,7 @@ func (x *Mutex) AutoRelease(ctx context.Context, db fdb.Database, maxAge time.Du
 
 	owner, err := x.getOwner(db)
 	if err != nil {
-		return fmt.Errorf("failed to get owner: %v", err)
+		return fmt.Errorf("failed to get owner: %w", err)
 	}
 
 	for {
@@ -81,38 +350,23 @@ func (x *Mutex) AutoRelease(ctx context.Context, db fdb.Database, maxAge time.Du
 		}
 
 		// Check the age of the heartbeat and release the mutex if necessary.
-		ret, err := db.Transact(func(tr fdb.Transaction) (any, error) {
-			curOwner, err := x.getOwner(tr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get owner: %w", err)
-			}
-
-			// If the owner changed, the heartbeat was updated,
-			// or the heartbeat isn't old enough, return the
-			// current owner without releasing the mutex.
-			switch {
-			case owner.name != curOwner.name:
-				fallthrough
-			case !bytes.Equal(owner.hbeat, curOwner.hbeat):
-				fallthrough
-			case time.Since(tstamp) < maxAge:
-				return curOwner, nil
-			}
-
-			// The owner hasn't sent a heartbeat in a while.
-			// Assume they are dead and release the lock.
-			name, err := x.release(tr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to release mutex: %w", err)
-			}
-			return ownerKV{name: name}, nil
+		rec, cycleDB := x.startRecording("Expire", db)
+		ret, err := cycleDB.Transact(func(tr fdb.Transaction) (any, error) {
+			return x.expire(tr, owner, tstamp, maxAge)
 		})
 		if err != nil {
+			rec.report()
 			cancel()
 			return err
 		}
 
-		curOwner := ret.(ownerKV)
+		result := ret.(autoReleaseResult)
+		curOwner := result.owner
+		if result.expired != "" {
+			rec.finish(result.expired, false, nil)
+		} else {
+			rec.report()
+		}
 
 		// If the owner or heartbeat was updated, then
 		// store the new ownerKV and reset the timer.
@@ -125,6 +379,12 @@ func (x *Mutex) AutoRelease(ctx context.Context, db fdb.Database, maxAge time.Du
 			owner = curOwner
 		}
 
+		// If the mutex is reserved, check
+		// again when the reservation expires.
+		if result.wait > 0 {
+			timer.Reset(result.wait)
+		}
+
 		// Cancel the current watch and create a new one.
 		// This ensures we are watching the latest owner KV
 		// in case the owner has changed during this cycle.
@@ -134,90 +394,747 @@ func (x *Mutex) AutoRelease(ctx context.Context, db fdb.Database, maxAge time.Du
 	}
 }
 
-func (x *Mutex) TryAcquire(db fdb.Database) (bool, error) {
-	acquired, err := db.Transact(func(tr fdb.Transaction) (any, error) {
-		owner, err := x.getOwner(tr)
+// expire releases the mutex if its owner hasn't heartbeat for 'maxAge'.
+// 'owner' is the owner as last observed, and 'since' is when it was first
+// observed. If the owner or heartbeat has changed since, the mutex isn't
+// released and the current owner is returned. Vacant mutexes reserved for
+// their previous owner are handed to the queue once the reservation ends.
+func (x *Mutex) expire(tr fdb.Transaction, owner ownerKV, since time.Time, maxAge time.Duration) (autoReleaseResult, error) {
+	curOwner, err := x.getOwner(tr)
+	if err != nil {
+		return autoReleaseResult{}, fmt.Errorf("failed to get owner: %w", err)
+	}
+
+	// If the owner changed or the heartbeat was updated,
+	// return the current owner without releasing the mutex.
+	switch {
+	case owner.name != curOwner.name:
+		fallthrough
+	case !bytes.Equal(owner.hbeat, curOwner.hbeat):
+		return autoReleaseResult{owner: curOwner}, nil
+	}
+
+	// A vacant mutex may be reserved for its previous
+	// owner. Hand the mutex to the queue once the
+	// reservation expires. See [[WithStickyGrace]].
+	if curOwner.name == "" {
+		sticky, ok, err := x.getSticky(tr)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get owner: %w", err)
+			return autoReleaseResult{}, fmt.Errorf("failed to get sticky: %w", err)
+		}
+		if ok {
+			if wait := time.Until(sticky.deadline); wait > 0 {
+				return autoReleaseResult{owner: curOwner, wait: wait}, nil
+			}
+			name, err := x.release(tr)
+			if err != nil {
+				return autoReleaseResult{}, fmt.Errorf("failed to release mutex: %w", err)
+			}
+			return autoReleaseResult{owner: ownerKV{name: name}}, nil
 		}
 
-		switch owner.name {
-		case x.name:
-			return true, nil
+		// A vacant mutex with an empty
+		// queue has nothing to release.
+		next, err := x.peekQueue(tr)
+		if err != nil {
+			return autoReleaseResult{}, fmt.Errorf("failed to peek queue: %w", err)
+		}
+		if next == "" {
+			return autoReleaseResult{owner: curOwner}, nil
+		}
+	}
 
-		case "":
-			err := x.setOwner(tr, x.name)
-			if err != nil {
-				return nil, fmt.Errorf("failed to set owner: %w", err)
+	// If the heartbeat isn't old enough, return the
+	// current owner without releasing the mutex.
+	if time.Since(since) < maxAge {
+		return autoReleaseResult{owner: curOwner}, nil
+	}
+
+	// The owner hasn't sent a heartbeat in a while.
+	// Assume they are dead. If sticky leadership is
+	// enabled, reserve the mutex for them in case
+	// they are restarting. Otherwise, release it.
+	if curOwner.name != "" {
+		if err := x.recordPreemption(tr, curOwner.name); err != nil {
+			return autoReleaseResult{}, fmt.Errorf("failed to record preemption: %w", err)
+		}
+		if err := x.logEvent(tr, EventExpired, curOwner.name); err != nil {
+			return autoReleaseResult{}, fmt.Errorf("failed to log event: %w", err)
+		}
+		if err := x.clearAttrs(tr, curOwner.name); err != nil {
+			return autoReleaseResult{}, fmt.Errorf("failed to clear attributes: %w", err)
+		}
+	}
+	if curOwner.name != "" && x.sticky > 0 {
+		if err := x.reserve(tr, curOwner.name); err != nil {
+			return autoReleaseResult{}, fmt.Errorf("failed to reserve mutex: %w", err)
+		}
+		return autoReleaseResult{wait: x.sticky, expired: curOwner.name}, nil
+	}
+	name, err := x.release(tr)
+	if err != nil {
+		return autoReleaseResult{}, fmt.Errorf("failed to release mutex: %w", err)
+	}
+	return autoReleaseResult{owner: ownerKV{name: name}, expired: curOwner.name}, nil
+}
+
+// autoReleaseResult is returned by the transaction in [[Mutex.AutoRelease]].
+type autoReleaseResult struct {
+	// owner is the current owner of the mutex.
+	owner ownerKV
+
+	// wait is non-zero if the mutex is reserved
+	// for its previous owner. It's the time left
+	// until the reservation expires.
+	wait time.Duration
+
+	// expired is the name of the owner whose
+	// hold expired during the cycle, if any.
+	expired string
+}
+
+func (x *Mutex) TryAcquire(db fdb.Transactor) (bool, error) {
+	_, acquired, err := x.tryAcquireToken(db)
+	return acquired, err
+}
+
+// tryAcquireToken implements [[Mutex.TryAcquireToken]].
+func (x *Mutex) tryAcquireToken(db fdb.Transactor) (token int64, acquired bool, err error) {
+	defer wrapErr(&err)
+	rec, db := x.startRecording("TryAcquire", db)
+	defer func() { rec.finish("", acquired, err) }()
+	db = x.withBreaker(db)
+
+	if x.coalesce != nil {
+		if !x.coalesce.tryEnter() {
+			return 0, false, nil
+		}
+		defer func() {
+			if !acquired {
+				x.coalesce.leave()
 			}
-			return true, nil
+		}()
+	}
+	if !x.tryLockLocal() {
+		return 0, false, nil
+	}
+	if token, ok := x.shareLocal(); ok {
+		return token, true, nil
+	}
+	if err := x.allowAttempt(); err != nil {
+		x.unlockLocal()
+		return 0, false, err
+	}
 
-		default:
-			return false, x.enqueue(db, x.name)
+	token, acquired, err = x.tryAcquire(db)
+	if err != nil || !acquired {
+		x.unlockLocal()
+	}
+	return token, acquired, err
+}
+
+func (x *Mutex) Acquire(ctx context.Context, db fdb.Transactor) error {
+	_, err := x.acquireToken(ctx, db)
+	return err
+}
+
+// acquireToken implements [[Mutex.AcquireToken]].
+func (x *Mutex) acquireToken(ctx context.Context, db fdb.Transactor) (token int64, err error) {
+	defer wrapErr(&err)
+	defer x.profileWait(x.withBreaker(db), time.Now(), &err)
+	rec, db := x.startRecording("Acquire", db)
+	defer func() { rec.finish("", err == nil, err) }()
+	db = x.withBreaker(db)
+
+	// With coalescing, another goroutine of this
+	// handle may pass its hold to us. See
+	// [[WithCoalescing]].
+	if x.coalesce != nil {
+		held, err := x.coalesce.enter(ctx)
+		if err != nil {
+			if held {
+				_ = x.Release(db)
+			}
+			return 0, err
 		}
-	})
+		if held {
+			return x.token.Load(), nil
+		}
+		defer func() {
+			if err != nil {
+				x.coalesce.leave()
+			}
+		}()
+	}
+
+	start := time.Now()
+	var diag AcquireError
+	token, err = x.acquire(ctx, withDeadline(ctx, db), &diag)
 	if err != nil {
-		return false, err
+		// When the context ends, the watch fails with an
+		// FDB error. Report the context's error instead.
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		diag.Waited = time.Since(start)
+		diag.Position = x.queuePosition(db)
+		diag.Err = err
+		return 0, &diag
 	}
+	return token, nil
+}
 
-	if acquired.(bool) {
-		x.startBeating(db)
-		return true, nil
+// queuePosition returns the position of this client in the queue,
+// starting at 0. It scans the queue, so it's only called to describe
+// a failed acquisition. If the client isn't queued or the queue can't
+// be read, -1 is returned.
+func (x *Mutex) queuePosition(db fdb.Transactor) int {
+	queue, err := x.getQueue(db)
+	if err != nil {
+		return -1
+	}
+	for i, q := range queue {
+		if q.name == x.name {
+			return i
+		}
 	}
-	return false, nil
+	return -1
 }
 
-func (x *Mutex) Acquire(ctx context.Context, db fdb.Database) error {
-	acquired, err := x.TryAcquire(db)
+// acquire implements [[Mutex.Acquire]]. While waiting,
+// the owner is recorded in 'diag'.
+func (x *Mutex) acquire(ctx context.Context, db fdb.Transactor, diag *AcquireError) (_ int64, err error) {
+	if err := x.waitAttempt(ctx); err != nil {
+		return 0, err
+	}
+	if err := x.lockLocal(ctx, db); err != nil {
+		return 0, err
+	}
+	if token, ok := x.shareLocal(); ok {
+		return token, nil
+	}
+	defer func() {
+		if err != nil {
+			x.unlockLocal()
+		}
+	}()
+
+	token, acquired, err := x.tryAcquireLimited(ctx, db)
 	if err != nil {
-		return fmt.Errorf("failed to try aquire: %w", err)
+		return 0, fmt.Errorf("failed to try aquire: %w", err)
 	}
 	if acquired {
-		return nil
+		return token, nil
+	}
+
+	retry := false
+	for {
+		// Watch the owner key before checking the mutex
+		// so a change made after the check isn't missed.
+		watchCtx, cancel := context.WithCancel(ctx)
+		watch := x.watchOwner(watchCtx, db)
+
+		check, err := x.checkWait(db, retry)
+		if err != nil {
+			cancel()
+			if errors.Is(err, ErrDisabled) {
+				return 0, errors.Join(err, x.withdraw(db))
+			}
+			return 0, err
+		}
+		if check.acquired {
+			cancel()
+			return check.token, nil
+		}
+
+		diag.Owner = check.owner
+
+		var wake <-chan time.Time
+		if check.wake > 0 {
+			wake = time.After(check.wake)
+		}
+
+		select {
+		case err = <-watch:
+			cancel()
+			if err != nil {
+				return 0, fmt.Errorf("failed to watch owner: %w", err)
+			}
+			retry = false
+
+		case <-wake:
+			cancel()
+			retry = true
+		}
+	}
+}
+
+// waitCheck is the outcome of [[Mutex.checkWait]].
+type waitCheck struct {
+	// acquired is true if the client holds the mutex.
+	// token is the fencing token of the hold.
+	acquired bool
+	token    int64
+
+	// owner is the current owner of the mutex.
+	owner string
+
+	// wake, if positive, is how long until the mutex should
+	// be checked again even if its owner doesn't change.
+	wake time.Duration
+}
+
+// checkWait checks on the mutex for a client waiting in its queue. It's
+// the step shared by every loop which waits for a mutex, such as
+// [[Mutex.acquire]] & [[acquireAny]]. The caller watches the owner key
+// before the check, so a change made afterwards isn't missed, and checks
+// again once the watch fires or the returned wake time passes. In the
+// latter case, 'retry' should be true so the client attempts to acquire
+// the mutex again before checking it.
+//
+// If the mutex was handed to the client, its heartbeat is started. If the
+// mutex is disabled, [[ErrDisabled]] is returned and the caller is
+// responsible for leaving the queue.
+func (x *Mutex) checkWait(db fdb.Transactor, retry bool) (waitCheck, error) {
+	// A vacant mutex held back by its schedule or a rate
+	// limited attempt is retried once the wait ends.
+	if retry {
+		token, acquired, err := x.tryAcquire(db)
+		var rerr *RateLimitError
+		if errors.As(err, &rerr) {
+			return waitCheck{wake: rerr.RetryAfter}, nil
+		}
+		if err != nil {
+			return waitCheck{}, fmt.Errorf("failed to try aquire: %w", err)
+		}
+		if acquired {
+			return waitCheck{acquired: true, token: token}, nil
+		}
+	}
+
+	owner, err := x.getOwner(db)
+	if err != nil {
+		return waitCheck{}, fmt.Errorf("failed to get owner: %w", err)
+	}
+	if owner.name == x.name {
+		token := x.loadToken(db)
+		x.startBeating(db)
+		return waitCheck{acquired: true, token: token}, nil
+	}
+	check := waitCheck{owner: owner.name}
+
+	// Waiters give up once they notice the mutex
+	// was disabled. See [[Mutex.Disable]].
+	disabled, err := x.getDisabled(db)
+	if err != nil {
+		return waitCheck{}, fmt.Errorf("failed to get disabled: %w", err)
+	}
+	if disabled {
+		return waitCheck{}, ErrDisabled
+	}
+
+	// The client may have been removed from the queue, such as
+	// by a two-phase release which gave up waiting for it to
+	// acknowledge. See [[Mutex.HandoffTo]]. Join it again.
+	queued, err := x.isQueued(db, x.name)
+	if err != nil {
+		return waitCheck{}, fmt.Errorf("failed to check queue: %w", err)
 	}
+	if !queued && !retry {
+		token, acquired, err := x.tryAcquire(db)
+		var rerr *RateLimitError
+		if errors.As(err, &rerr) {
+			return waitCheck{owner: owner.name, wake: rerr.RetryAfter}, nil
+		}
+		if err != nil {
+			return waitCheck{}, fmt.Errorf("failed to try aquire: %w", err)
+		}
+		if acquired {
+			return waitCheck{acquired: true, token: token}, nil
+		}
+	}
+
+	// A vacant mutex is held back while its acquisition
+	// window is closed, so try again once it opens.
+	if owner.name == "" {
+		wait, _, err := x.untilWindow(db, time.Now())
+		if err != nil {
+			return waitCheck{}, fmt.Errorf("failed to check schedule: %w", err)
+		}
+		check.wake = wait
+		return check, nil
+	}
+
+	// A preemptible owner is evicted once its grace period
+	// ends, which wakes the caller's watch. See
+	// [[Mutex.SetPreemptible]].
+	wait, ok, err := x.untilPreempted(db, owner.name)
+	if err != nil {
+		return waitCheck{}, fmt.Errorf("failed to check preemption: %w", err)
+	}
+	switch {
+	case ok && wait <= 0:
+		if err := x.expirePreemption(db); err != nil {
+			return waitCheck{}, fmt.Errorf("failed to preempt owner: %w", err)
+		}
+	case ok:
+		check.wake = wait
+	}
+	return check, nil
+}
+
+// WaitUntilFree blocks until the mutex has no owner or the context is
+// cancelled. Unlike [[Mutex.Acquire]], the client doesn't join the queue.
+// This is useful for processes which only need to know when the holder's
+// work, such as a maintenance operation, has finished. By the time this
+// method returns, another client may have acquired the mutex.
+func (x *Mutex) WaitUntilFree(ctx context.Context, db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	db = x.withBreaker(db)
 
 	for {
-		watch, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		// If the mutex is free, the watch signals
+		// immediately. Otherwise, it signals when
+		// the current owner changes.
+		var free bool
+		watch := watch(ctx, db, func(tr fdb.Transaction) (fdb.Key, error) {
 			owner, err := x.getOwner(tr)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get owner: %w", err)
 			}
-
-			// Return a nil watch to signal that
-			// we are now the owner of the mutex.
-			if owner.name == x.name {
+			free = owner.name == ""
+			if free {
 				return nil, nil
 			}
-
-			return x.watchOwner(ctx, tr), nil
+			return x.packOwnerKey(owner.name), nil
 		})
+
+		if err := <-watch; err != nil {
+			return fmt.Errorf("failed to watch owner: %w", err)
+		}
+		if free {
+			return nil
+		}
+	}
+}
+
+// tryAcquireLimited is like [[Mutex.tryAcquire]], but when the mutex is
+// rate limited, it waits until the next attempt may be accepted.
+func (x *Mutex) tryAcquireLimited(ctx context.Context, db fdb.Transactor) (int64, bool, error) {
+	for {
+		token, acquired, err := x.tryAcquire(db)
+		var rerr *RateLimitError
+		if !errors.As(err, &rerr) {
+			return token, acquired, err
+		}
+		select {
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		case <-time.After(rerr.RetryAfter):
+		}
+	}
+}
+
+func (x *Mutex) tryAcquire(db fdb.Transactor) (int64, bool, error) {
+	res, err := x.withProfiler(db).Transact(func(tr fdb.Transaction) (any, error) {
+		return x.grant(tr, true)
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	r := res.(grantResult)
+	if r.rejected != nil {
+		return 0, false, r.rejected
+	}
+	if r.acquired {
+		token := r.holdToken()
+		x.token.Store(token)
+		x.startBeating(db)
+		return token, true, nil
+	}
+	return 0, false, nil
+}
+
+// grantResult is the outcome of [[Mutex.grant]].
+type grantResult struct {
+	// acquired is true if the client holds the mutex.
+	acquired bool
+
+	// rejected, if not nil, is why the attempt was refused.
+	// It's a result rather than an error so the writes of
+	// the transaction, such as leaving the queue, commit.
+	rejected error
+
+	// token is the fencing token of a hold which the client
+	// already had. If the hold was granted by the transaction,
+	// its token is the commit version, which is resolved from
+	// vstamp. See [[Mutex.AcquireToken]].
+	token  int64
+	vstamp fdb.FutureKey
+}
+
+// holdToken returns the fencing token of the hold. It must be called
+// after the transaction of the grant committed. If the token can't be
+// resolved, it's zero.
+func (r grantResult) holdToken() int64 {
+	if r.vstamp == nil {
+		return r.token
+	}
+	vstamp, err := r.vstamp.Get()
+	if err != nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(vstamp[:8]))
+}
+
+// grant tries to acquire the mutex within the transaction, applying every
+// check made by an acquisition. If the mutex can't be acquired yet and
+// 'wait' is true, the client joins the queue & may preempt the owner.
+// Otherwise, the client is left out of the queue. The caller is responsible
+// for heartbeating once the transaction commits.
+func (x *Mutex) grant(tr fdb.Transaction, wait bool) (grantResult, error) {
+	// Attribute the acquisition to the identity
+	// of this client. See [[NewMutexFromContext]].
+	if len(x.attrs) > 0 {
+		if err := x.setAttrs(tr, x.name, x.attrs); err != nil {
+			return grantResult{}, fmt.Errorf("failed to set attributes: %w", err)
+		}
+	}
+	if x.delegator != "" {
+		if err := x.setDelegator(tr, x.name, x.delegator); err != nil {
+			return grantResult{}, fmt.Errorf("failed to set delegator: %w", err)
+		}
+	}
+
+	// A reserved place in the queue becomes eligible
+	// once we start acquiring. See [[Mutex.ReserveSlot]].
+	reserved, err := x.activateReservation(tr, x.name)
+	if err != nil {
+		return grantResult{}, fmt.Errorf("failed to activate reservation: %w", err)
+	}
+
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return grantResult{}, fmt.Errorf("failed to get owner: %w", err)
+	}
+
+	if owner.name != x.name {
+		// A disabled mutex rejects every attempt, removing
+		// us from the queue. See [[Mutex.Disable]].
+		disabled, err := x.getDisabled(tr)
 		if err != nil {
-			return err
+			return grantResult{}, fmt.Errorf("failed to get disabled: %w", err)
+		}
+		if disabled {
+			return grantResult{rejected: ErrDisabled}, x.removeFromQueue(tr, x.name)
+		}
+
+		// Clients on the denylist are rejected the
+		// same way. See [[AdminClient.Deny]].
+		denied, err := x.isDenied(tr, x.name)
+		if err != nil {
+			return grantResult{}, fmt.Errorf("failed to check denylist: %w", err)
+		}
+		if denied {
+			return grantResult{rejected: ErrDenied}, x.removeFromQueue(tr, x.name)
+		}
+
+		// The attempt is counted even if it's rejected so a
+		// hot retry loop can't bypass the limit. See
+		// [[Mutex.SetRateLimit]].
+		retry, err := x.countAttempt(tr)
+		if err != nil {
+			return grantResult{}, fmt.Errorf("failed to count attempt: %w", err)
+		}
+		if retry > 0 {
+			return grantResult{rejected: &RateLimitError{RetryAfter: retry}}, nil
 		}
 
-		// If watch is nil then we are the owner.
-		// Otherwise, wait for the watch to fire
-		// and check again.
-		if watch == nil {
+		// Outside of the mutex's acquisition windows, either
+		// reject the attempt or wait in the queue until the
+		// next window opens. See [[Mutex.SetSchedule]].
+		until, reject, err := x.untilWindow(tr, time.Now())
+		if err != nil {
+			return grantResult{}, fmt.Errorf("failed to check schedule: %w", err)
+		}
+		if until != 0 && reject {
+			return grantResult{}, ErrOutsideWindow
+		}
+		if until != 0 && owner.name == "" {
+			return x.park(tr, wait)
+		}
+
+		// During maintenance, new acquisitions wait in the
+		// queue. See [[AdminClient.SetMaintenance]].
+		maintenance, err := x.getMaintenance(tr)
+		if err != nil {
+			return grantResult{}, fmt.Errorf("failed to get maintenance: %w", err)
+		}
+		if maintenance {
+			return x.park(tr, wait)
+		}
+
+		// While frozen, new acquisitions are rejected but
+		// waiters stay parked. See [[AdminClient.Freeze]].
+		frozen, err := x.getFrozen(tr)
+		if err != nil {
+			return grantResult{}, fmt.Errorf("failed to get frozen: %w", err)
+		}
+		if frozen {
+			queued, err := x.isQueued(tr, x.name)
+			if err != nil {
+				return grantResult{}, fmt.Errorf("failed to check queue: %w", err)
+			}
+			if !queued {
+				return grantResult{}, ErrFrozen
+			}
+			return grantResult{}, nil
+		}
+	}
+
+	// A vacant mutex may be reserved for its previous owner.
+	// If we aren't that owner, wait in the queue until the
+	// reservation expires. If it already expired, hand the
+	// mutex to the queue. See [[WithStickyGrace]].
+	if owner.name == "" {
+		sticky, ok, err := x.getSticky(tr)
+		if err != nil {
+			return grantResult{}, fmt.Errorf("failed to get sticky: %w", err)
+		}
+		if ok && sticky.name != x.name {
+			if time.Now().Before(sticky.deadline) {
+				x.profileContention(tr)
+				x.countContended(tr)
+				return x.park(tr, wait)
+			}
+			owner.name, err = x.release(tr)
+			if err != nil {
+				return grantResult{}, fmt.Errorf("failed to release mutex: %w", err)
+			}
+		}
+	}
+
+	switch owner.name {
+	case x.name:
+		token, err := x.getToken(tr)
+		if err != nil {
+			return grantResult{}, fmt.Errorf("failed to get token: %w", err)
+		}
+		return grantResult{acquired: true, token: token}, nil
+
+	case "":
+		// The reserved place is no longer needed.
+		if reserved {
+			if err := x.removeFromQueue(tr, x.name); err != nil {
+				return grantResult{}, fmt.Errorf("failed to remove from queue: %w", err)
+			}
+		}
+		if err := x.setOwner(tr, x.name); err != nil {
+			return grantResult{}, fmt.Errorf("failed to set owner: %w", err)
+		}
+		if x.clients != nil {
+			err := x.clients.addLock(tr, x.name, lockID(x.Subspace))
+			if err != nil {
+				return grantResult{}, fmt.Errorf("failed to register lock: %w", err)
+			}
+		}
+		return grantResult{acquired: true, vstamp: tr.GetVersionstamp()}, nil
+
+	default:
+		x.profileContention(tr)
+		x.countContended(tr)
+		if !wait {
+			return grantResult{}, nil
+		}
+		if err := x.preempt(tr); err != nil {
+			return grantResult{}, fmt.Errorf("failed to preempt owner: %w", err)
+		}
+		return x.park(tr, wait)
+	}
+}
+
+// park places the client in the queue if 'wait'
+// is true. The attempt isn't granted either way.
+func (x *Mutex) park(tr fdb.Transaction, wait bool) (grantResult, error) {
+	if !wait {
+		return grantResult{}, nil
+	}
+	return grantResult{}, x.enqueue(tr, x.name, x.priority)
+}
+
+func (x *Mutex) Release(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	rec, db := x.startRecording("Release", db)
+	defer func() { rec.finish("", false, err) }()
+	db = x.withBreaker(db)
+
+	if x.coalesce != nil {
+		if x.coalesce.handOff() {
 			return nil
 		}
-		if err := <-watch.(<-chan error); err != nil {
-			return fmt.Errorf("failed to watch owner: %w", err)
+		defer x.coalesce.leave()
+	}
+
+	// With local arbitration, the process holds the mutex
+	// through a single carrier. See [[WithLocalArbitration]].
+	if x.local != nil {
+		carrier := x.localCarrier()
+		if x.handOffLocal() {
+			if carrier != x {
+				x.token.Store(0)
+			}
+			x.guards.reset()
+			x.unlockLocal()
+			return nil
+		}
+		if carrier != nil && carrier != x {
+			if err := carrier.releaseOwner(db); err != nil {
+				return err
+			}
+			x.relinquish()
+			return nil
+		}
+		if carrier == nil && x.carriesLocal() {
+			// The hold was passed to another
+			// handle, which releases it.
+			return nil
 		}
 	}
+
+	return x.releaseOwner(db)
 }
 
-func (x *Mutex) Release(db fdb.Transactor) error {
-	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+// releaseOwner releases the mutex in FDB if it's owned by the client of this
+// handle, or by a client it's the delegator of, & cleans up the local state.
+func (x *Mutex) releaseOwner(db fdb.Transactor) error {
+	_, err := x.withProfiler(db).Transact(func(tr fdb.Transaction) (any, error) {
 		owner, err := x.getOwner(tr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get owner: %w", err)
 		}
 
-		if x.name != owner.name {
+		if owner.name == "" {
 			return nil, nil
 		}
+		if x.name != owner.name {
+			// The delegator of the owner may release
+			// on its behalf. See [[WithDelegator]].
+			delegator, err := x.getDelegator(tr, owner.name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get delegator: %w", err)
+			}
+			if x.name != delegator {
+				return nil, nil
+			}
+		}
 
+		if err := x.logEvent(tr, EventReleased, owner.name); err != nil {
+			return nil, fmt.Errorf("failed to log event: %w", err)
+		}
+		if err := x.clearAttrs(tr, owner.name); err != nil {
+			return nil, fmt.Errorf("failed to clear attributes: %w", err)
+		}
 		_, err = x.release(tr)
 		return nil, err
 	})
@@ -225,17 +1142,137 @@ func (x *Mutex) Release(db fdb.Transactor) error {
 		return err
 	}
 
-	x.stopBeating()
+	x.relinquish()
 	return nil
 }
 
+// SetLabels replaces the labels attached to the mutex. Labels
+// describe the mutex (team, service, environment, etc.) and
+// can be used to filter the results of [[List]].
+func (x *Mutex) SetLabels(db fdb.Transactor, labels map[string]string) (err error) {
+	defer wrapErr(&err)
+	return x.setLabels(x.withBreaker(db), labels)
+}
+
+// Labels returns the labels attached to the mutex.
+func (x *Mutex) Labels(db fdb.Transactor) (_ map[string]string, err error) {
+	defer wrapErr(&err)
+	return x.getLabels(x.withBreaker(db))
+}
+
+// RequestRelease asks the current owner of the mutex to release it. The
+// owner is notified via [[Guard.ReleaseRequested]]. The request is only a
+// suggestion: the owner may ignore it. Requests are cleared whenever the
+// mutex changes ownership, so they only apply to the current owner.
+func (x *Mutex) RequestRelease(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	return x.requestRelease(x.withBreaker(db), x.name)
+}
+
+// Candidate describes a client waiting in the queue of a mutex.
+type Candidate struct {
+	// Name identifies the client.
+	Name string
+
+	// Version is the versionstamp of the transaction
+	// which placed the client in the queue. Clients
+	// are dequeued in version order.
+	Version tuple.Versionstamp
+
+	// Enqueued is when the client joined the queue, according
+	// to its own clock. It's zero for clients queued by a
+	// version of this package older than schema version 2.
+	Enqueued time.Time
+
+	// Priority is the effective priority of the client when the
+	// queue was read, including the boost it has earned while
+	// waiting. See [[WithPriority]] & [[Mutex.SetPriorityAging]].
+	Priority int64
+
+	// Attrs are the identity attributes of the client. If the
+	// client has no attributes, it's nil. See [[Identity]].
+	Attrs map[string]string
+}
+
+// Candidates returns the clients waiting to acquire the mutex, in the
+// order they will acquire it: highest effective priority first, with
+// equal priorities in the order they were queued. The owner isn't
+// included. Aging changes the effective priorities over time, so the
+// order is only accurate as of the read.
+func (x *Mutex) Candidates(db fdb.Transactor) (_ []Candidate, err error) {
+	defer wrapErr(&err)
+	return x.getCandidates(x.withBreaker(db))
+}
+
+// WatchCandidates returns a channel which signals a change to the set of
+// candidates. When a client joins or leaves the queue, the channel returns
+// nil. If the watch setup fails or the provided context is canceled, the
+// channel returns an error.
+func (x *Mutex) WatchCandidates(ctx context.Context, db fdb.Transactor) <-chan error {
+	return x.watchQueue(ctx, db)
+}
+
+// reserve vacates the mutex but reserves it for the client with the
+// provided name until the sticky grace period ends. During this time,
+// only the reserved client may acquire the mutex.
+func (x *Mutex) reserve(db fdb.Transactor, name string) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.setOwner(tr, ""); err != nil {
+			return nil, fmt.Errorf("failed to set owner: %w", err)
+		}
+		if err := x.setSticky(tr, name, time.Now().Add(x.sticky)); err != nil {
+			return nil, fmt.Errorf("failed to set sticky: %w", err)
+		}
+		if x.clients != nil {
+			if err := x.clients.removeLock(tr, name, lockID(x.Subspace)); err != nil {
+				return nil, fmt.Errorf("failed to unregister lock: %w", err)
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
 func (x *Mutex) release(db fdb.Transactor) (string, error) {
 	name, err := db.Transact(func(tr fdb.Transaction) (any, error) {
-		name, err := x.dequeue(tr)
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+
+		// Outside of the mutex's acquisition windows or during
+		// maintenance, the mutex is left vacant and the queue
+		// waits until acquisitions are allowed again.
+		blocked, err := x.blocked(tr)
 		if err != nil {
-			return nil, fmt.Errorf("failed to dequeue: %w", err)
+			return nil, err
 		}
-		return name, x.setOwner(tr, name)
+		var name string
+		if !blocked {
+			name, err = x.dequeue(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to dequeue: %w", err)
+			}
+		}
+		if err := x.setOwner(tr, name); err != nil {
+			return nil, fmt.Errorf("failed to set owner: %w", err)
+		}
+		if err := x.clearSticky(tr); err != nil {
+			return nil, fmt.Errorf("failed to clear sticky: %w", err)
+		}
+
+		// Move the lock from the old owner's
+		// session to the new owner's session.
+		if x.clients != nil {
+			id := lockID(x.Subspace)
+			if err := x.clients.removeLock(tr, owner.name, id); err != nil {
+				return nil, fmt.Errorf("failed to unregister lock: %w", err)
+			}
+			if err := x.clients.addLock(tr, name, id); err != nil {
+				return nil, fmt.Errorf("failed to register lock: %w", err)
+			}
+		}
+		return name, nil
 	})
 	if err != nil {
 		return "", err
@@ -243,23 +1280,104 @@ func (x *Mutex) release(db fdb.Transactor) (string, error) {
 	return name.(string), nil
 }
 
-func (x *Mutex) startBeating(db fdb.Database) {
+func (x *Mutex) startBeating(db fdb.Transactor) {
+	x.carryLocal()
+	if stop := x.beginBeating(db); stop != nil {
+		x.trackHold(stop)
+	}
+}
+
+// beginBeating starts the heartbeat loop and returns its stop
+// channel. If the loop is already running, nil is returned.
+func (x *Mutex) beginBeating(db fdb.Transactor) chan struct{} {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	// If we're already heartbeating then there
+	// is nothing to do. This occurs when an
+	// acquired mutex is acquired again.
+	if x.stop != nil {
+		return nil
+	}
+	stop := make(chan struct{})
+	x.stop = stop
+
+	// The heartbeat outlives the call which acquired
+	// the mutex, so it isn't bound by its deadline.
+	db = withoutDeadline(db)
+
+	x.lastBeat.Store(time.Now().UnixNano())
+	x.state.Store(int32(HeartbeatHealthy))
+
+	// Closed when the heartbeat loop exits
+	// so the watchdog knows to exit as well.
+	done := make(chan struct{})
+
 	go func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
+		defer close(done)
+		x.superviseBeat(db, stop)
+	}()
 
-		for {
-			select {
-			case <-x.stop:
-				return
+	if x.failSafe > 0 {
+		go x.watchdog(done, &x.lastBeat, x.guards.lose)
+	}
+	return stop
+}
 
-			case <-ticker.C:
-				_ = x.heartbeat(db, x.name)
-			}
+// AddHeartbeat includes a heartbeat in the given transaction, allowing busy
+// holders to extend their lease with the transactions doing their actual
+// work. If the client doesn't own the mutex, [[ErrNotOwner]] is returned.
+// Like [[Guard.Transact]], the ownership check conflicts with any change
+// of ownership before commit. Once the transaction commits, the mutex's
+// own heartbeat transaction is skipped until the next interval.
+func (x *Mutex) AddHeartbeat(tr fdb.Transaction) error {
+	if err := x.fence(tr); err != nil {
+		return err
+	}
+	val, err := x.packOwnerValue(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to pack owner value: %w", err)
+	}
+	tr.SetVersionstampedValue(x.packOwnerKey(x.name), val)
+	if x.clients != nil {
+		if err := x.clients.heartbeat(tr, x.name); err != nil {
+			return fmt.Errorf("failed to heartbeat client session: %w", err)
+		}
+	}
+
+	// The versionstamp is only available once the transaction
+	// commits. If the transaction fails or is retried, the
+	// future returns an error and the heartbeat isn't counted.
+	vstamp := tr.GetVersionstamp()
+	go func() {
+		if _, err := vstamp.Get(); err == nil {
+			x.lastBeat.Store(time.Now().UnixNano())
 		}
 	}()
+	return nil
+}
+
+// relinquish cleans up the local state of a hold after
+// the mutex has been released or given to another client.
+func (x *Mutex) relinquish() {
+	x.token.Store(0)
+	x.stopBeating()
+	x.guards.reset()
+	x.dropLocal()
+	x.unlockLocal()
 }
 
 func (x *Mutex) stopBeating() {
-	x.stop <- struct{}{}
+	x.mu.Lock()
+	stop := x.stop
+	if stop != nil {
+		close(stop)
+		x.stop = nil
+		x.state.Store(int32(HeartbeatStopped))
+	}
+	x.mu.Unlock()
+
+	if stop != nil {
+		x.untrackHold(stop)
+	}
 }
@@ -1,23 +1,26 @@
 package mutex
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 	"github.com/stretchr/testify/require"
 )
 
 func TestKV(t *testing.T) {
 	tests := map[string]testFn{
 		"empty": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
-			x := kv{root}
+			x := kv{Subspace: root}
 
 			name, err := x.dequeue(db)
 			require.NoError(t, err)
@@ -32,20 +35,102 @@ func TestKV(t *testing.T) {
 			require.Empty(t, owner.hbeat)
 		},
 		"queue": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
-			x := kv{root}
+			x := kv{Subspace: root}
 
-			err := x.enqueue(db, "clientZ")
+			err := x.enqueue(db, "clientZ", 0)
 			require.NoError(t, err)
 
-			err = x.enqueue(db, "clientA")
+			err = x.enqueue(db, "clientA", 0)
 			require.NoError(t, err)
 
 			name, err := x.dequeue(db)
 			require.NoError(t, err)
 			require.Equal(t, "clientZ", name)
 		},
+		"queue membership": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x := kv{Subspace: root}
+
+			// Enqueuing twice is a noop.
+			for range 2 {
+				err := x.enqueue(db, "clientA", 0)
+				require.NoError(t, err)
+			}
+			err := x.enqueue(db, "clientB", 0)
+			require.NoError(t, err)
+
+			queue, err := x.getQueue(db)
+			require.NoError(t, err)
+			require.Len(t, queue, 2)
+
+			queued, err := x.isQueued(db, "clientA")
+			require.NoError(t, err)
+			require.True(t, queued)
+
+			err = x.removeFromQueue(db, "clientA")
+			require.NoError(t, err)
+
+			queued, err = x.isQueued(db, "clientA")
+			require.NoError(t, err)
+			require.False(t, queued)
+
+			name, err := x.dequeue(db)
+			require.NoError(t, err)
+			require.Equal(t, "clientB", name)
+
+			val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+				return tr.Get(x.packMemberKey("clientB")).Get()
+			})
+			require.NoError(t, err)
+			require.Nil(t, val)
+		},
+		"unindexed queue": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "clientA")
+			require.NoError(t, err)
+
+			err = x.enqueue(db, "clientB", 0)
+			require.NoError(t, err)
+
+			// Simulate an entry written before the index existed.
+			rngMember, err := x.packMemberRange()
+			require.NoError(t, err)
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.ClearRange(rngMember)
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			queued, err := x.isQueued(db, "clientB")
+			require.NoError(t, err)
+			require.False(t, queued)
+
+			// Constructing a handle indexes the queue.
+			_, err = NewMutex(db, root, "clientC")
+			require.NoError(t, err)
+
+			queued, err = x.isQueued(db, "clientB")
+			require.NoError(t, err)
+			require.True(t, queued)
+		},
+		"priority queue": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x := kv{Subspace: root}
+
+			err := x.enqueue(db, "clientA", 0)
+			require.NoError(t, err)
+
+			err = x.enqueue(db, "clientB", 5)
+			require.NoError(t, err)
+
+			err = x.enqueue(db, "clientC", 5)
+			require.NoError(t, err)
+
+			for _, expected := range []string{"clientB", "clientC", "clientA", ""} {
+				name, err := x.dequeue(db)
+				require.NoError(t, err)
+				require.Equal(t, expected, name)
+			}
+		},
 		"owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
-			x := kv{root}
+			x := kv{Subspace: root}
 
 			err := x.setOwner(db, "client")
 			require.NoError(t, err)
@@ -56,7 +141,7 @@ func TestKV(t *testing.T) {
 			require.Empty(t, owner.hbeat)
 		},
 		"heartbeat": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
-			x := kv{root}
+			x := kv{Subspace: root}
 
 			err := x.setOwner(db, "client")
 			require.NoError(t, err)
@@ -69,7 +154,7 @@ func TestKV(t *testing.T) {
 			require.NotEmpty(t, owner.hbeat)
 		},
 		"non-owner heartbeat": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
-			x := kv{root}
+			x := kv{Subspace: root}
 
 			err := x.setOwner(db, "clientA")
 			require.NoError(t, err)
@@ -82,7 +167,7 @@ func TestKV(t *testing.T) {
 			require.Empty(t, owner.hbeat)
 		},
 		"watch owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
-			x := kv{root}
+			x := kv{Subspace: root}
 
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
@@ -98,7 +183,7 @@ func TestKV(t *testing.T) {
 			require.NoError(t, <-watch)
 		},
 		"cancel watch": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
-			x := kv{root}
+			x := kv{Subspace: root}
 
 			ctx, cancel := context.WithCancel(context.Background())
 			watch := x.watchOwner(ctx, db)
@@ -111,6 +196,79 @@ func TestKV(t *testing.T) {
 	runTests(t, tests)
 }
 
+// BenchmarkEnqueue measures a queued client re-enqueuing itself, which
+// happens on every acquisition attempt, behind queues of various depths.
+// Thanks to the membership index, the cost doesn't grow with the depth.
+func BenchmarkEnqueue(b *testing.B) {
+	fdb.MustAPIVersion(710)
+	db := fdb.MustOpenDefault()
+
+	for _, depth := range []int{1, 100, 1000} {
+		b.Run(fmt.Sprintf("depth %d", depth), func(b *testing.B) {
+			dirName := fmt.Sprintf("bench-enqueue-%d-%d", depth, time.Now().UnixNano())
+			root, err := directory.CreateOrOpen(db, []string{dirName}, nil)
+			require.NoError(b, err)
+			defer func() {
+				_, err := directory.Root().Remove(db, []string{dirName})
+				require.NoError(b, err)
+			}()
+
+			x := kv{Subspace: root}
+			for i := range depth {
+				require.NoError(b, x.enqueue(db, fmt.Sprintf("client%d", i), 0))
+			}
+
+			b.ResetTimer()
+			for range b.N {
+				if err := x.enqueue(db, fmt.Sprintf("client%d", depth-1), 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestConstruct(t *testing.T) {
+	tests := map[string]testFn{
+		"open missing": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := OpenMutex(db, root, "client")
+			require.ErrorIs(t, err, ErrNotFound)
+
+			_, err = CreateMutex(db, root, "client")
+			require.NoError(t, err)
+
+			_, err = OpenMutex(db, root, "client")
+			require.NoError(t, err)
+		},
+		"create existing": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			_, err = CreateMutex(db, root, "client2")
+			require.ErrorIs(t, err, ErrExists)
+		},
+		"keeps owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Constructing another handle doesn't
+			// reset the state of an existing mutex.
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			owner, err := x2.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+		},
+	}
+
+	runTests(t, tests)
+}
+
 func TestAcquire(t *testing.T) {
 	tests := map[string]testFn{
 		"non-blocking": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
@@ -162,6 +320,215 @@ func TestAcquire(t *testing.T) {
 			require.NoError(t, err)
 			require.Equal(t, owner.name, "client2")
 		},
+		"idempotent": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "")
+			require.NoError(t, err)
+
+			// Releasing a mutex we don't own is a noop.
+			err = x.Release(db)
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+
+			for i := 0; i < 2; i++ {
+				err = x.Release(db)
+				require.NoError(t, err)
+			}
+
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+		},
+		"concurrent": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "")
+			require.NoError(t, err)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := x.TryAcquire(db); err != nil {
+						t.Errorf("failed to try acquire: %v", err)
+					}
+					if err := x.Release(db); err != nil {
+						t.Errorf("failed to release: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+		},
+		"priority": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			x3, err := NewMutex(db, root, "client3", WithPriority(10))
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{x1, x2, x3} {
+				_, err := x.TryAcquire(db)
+				require.NoError(t, err)
+			}
+
+			err = x1.Release(db)
+			require.NoError(t, err)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client3", owner.name)
+		},
+		"candidates": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			x3, err := NewMutex(db, root, "client3")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			watch := x1.WatchCandidates(ctx, db)
+
+			_, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+
+			require.NoError(t, <-watch)
+
+			_, err = x3.TryAcquire(db)
+			require.NoError(t, err)
+
+			candidates, err := x1.Candidates(db)
+			require.NoError(t, err)
+			require.Len(t, candidates, 2)
+			require.Equal(t, "client2", candidates[0].Name)
+			require.Equal(t, "client3", candidates[1].Name)
+			require.Negative(t, bytes.Compare(candidates[0].Version.Bytes(), candidates[1].Version.Bytes()))
+		},
+		"candidates by priority": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner, err := NewMutex(db, root, "owner")
+			require.NoError(t, err)
+
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			waiters := make(map[string]*Mutex)
+			for _, w := range []struct {
+				name string
+				opts []Option
+			}{
+				{name: "low1"},
+				{name: "high", opts: []Option{WithPriority(10), withAttrs(map[string]string{"role": "primary"})}},
+				{name: "low2"},
+				{name: "background", opts: []Option{WithPriority(-1)}},
+			} {
+				x, err := NewMutex(db, root, w.name, w.opts...)
+				require.NoError(t, err)
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.False(t, acquired)
+				waiters[w.name] = x
+			}
+
+			candidates, err := owner.Candidates(db)
+			require.NoError(t, err)
+
+			var (
+				names      []string
+				priorities []int64
+			)
+			for _, c := range candidates {
+				names = append(names, c.Name)
+				priorities = append(priorities, c.Priority)
+			}
+			require.Equal(t, []string{"high", "low1", "low2", "background"}, names)
+			require.Equal(t, []int64{10, 0, 0, -1}, priorities)
+			require.Equal(t, map[string]string{"role": "primary"}, candidates[0].Attrs)
+			require.Nil(t, candidates[1].Attrs)
+
+			// The candidates acquire in the order listed.
+			prev := owner
+			for _, name := range names {
+				require.NoError(t, prev.Release(db))
+				cur, err := owner.getOwner(db)
+				require.NoError(t, err)
+				require.Equal(t, name, cur.name)
+				prev = waiters[name]
+			}
+		},
+		"timeout diagnostics": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			err = x2.Acquire(ctx, db)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.True(t, IsCancelled(err))
+
+			var acqErr *AcquireError
+			require.ErrorAs(t, err, &acqErr)
+			require.Equal(t, "client1", acqErr.Owner)
+			require.Equal(t, 0, acqErr.Position)
+			require.GreaterOrEqual(t, acqErr.Waited, 200*time.Millisecond)
+		},
+		"wait until free": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			// A free mutex returns immediately.
+			err = x2.WaitUntilFree(context.Background(), db)
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- x2.WaitUntilFree(context.Background(), db)
+			}()
+
+			select {
+			case err := <-errCh:
+				t.Fatalf("returned before release: %v", err)
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			err = x1.Release(db)
+			require.NoError(t, err)
+			require.NoError(t, <-errCh)
+
+			// Waiting didn't join the queue.
+			candidates, err := x1.Candidates(db)
+			require.NoError(t, err)
+			require.Empty(t, candidates)
+		},
 		"heartbeat": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
 			x, err := NewMutex(db, root, "")
 			require.NoError(t, err)
@@ -182,7 +549,7 @@ func TestAcquire(t *testing.T) {
 }
 
 func TestAutoRelease(t *testing.T) {
-	tests := map[string]testFn {
+	tests := map[string]testFn{
 		"empty": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
 			x, err := NewMutex(db, root, "client")
 			require.NoError(t, err)
@@ -233,6 +600,118 @@ func TestAutoRelease(t *testing.T) {
 		},
 		"heartbeat": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
 		},
+		"piggyback heartbeat": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Only heartbeat via application transactions.
+			x1.stopBeating()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			goAutoRelease(t, x2, ctx, db, 500*time.Millisecond)
+
+			for i := 0; i < 10; i++ {
+				_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+					tr.Set(root.Pack(tuple.Tuple{"work"}), []byte{byte(i)})
+					return nil, x1.AddHeartbeat(tr)
+				})
+				require.NoError(t, err)
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner.name)
+
+			// A client which isn't the owner can't heartbeat.
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				return nil, x2.AddHeartbeat(tr)
+			})
+			require.ErrorIs(t, err, ErrNotOwner)
+		},
+		"sticky reacquire": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			runner, err := NewMutex(db, root, "runner", WithStickyGrace(time.Minute))
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// Stop heartbeating so auto release is triggered.
+			x1.stopBeating()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			goAutoRelease(t, runner, ctx, db, 500*time.Millisecond)
+
+			// Wait for the owner to be reserved.
+			<-x1.watchOwner(context.Background(), db)
+
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+
+			// Other clients can't acquire during the grace period.
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// The previous owner can.
+			acquired, err = x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+		},
+		"sticky expired": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			runner, err := NewMutex(db, root, "runner", WithStickyGrace(500*time.Millisecond))
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// Stop heartbeating so auto release is triggered.
+			x1.stopBeating()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			goAutoRelease(t, runner, ctx, db, 500*time.Millisecond)
+
+			// Once the reservation expires, the
+			// queued client acquires the mutex.
+			err = x2.Acquire(context.Background(), db)
+			require.NoError(t, err)
+		},
 	}
 
 	runTests(t, tests)
@@ -274,13 +753,11 @@ func runTest(t *testing.T, test testFn) {
 	test(t, db, root)
 }
 
-func goAutoRelease(t *testing.T, x Mutex, ctx context.Context, db fdb.Database, maxAge time.Duration) {
+func goAutoRelease(t *testing.T, x *Mutex, ctx context.Context, db fdb.Database, maxAge time.Duration) {
 	go func() {
 		err := x.AutoRelease(ctx, db, maxAge)
 		if err != nil {
-			var ferr fdb.Error
-			if errors.As(err, &ferr) && ferr.Code == 1101 {
-				// Ignore "operation cancelled" errors.
+			if IsCancelled(err) {
 				return
 			}
 			t.Errorf("auto release exited: %v", err)
@@ -0,0 +1,474 @@
+// Package mutexsim provides a chaos harness for [[mutex.Mutex]]. It spawns
+// many simulated clients which contend for a single mutex stored in a real
+// FoundationDB cluster while injecting crashes, pauses, and network delays.
+// Throughout the run, the harness asserts the mutual-exclusion and liveness
+// invariants. Users may run the harness with the options they intend to use
+// in production to validate their configuration.
+package mutexsim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+
+	"github.com/janderland/fdb-mutex"
+)
+
+// Config describes a simulation. Zero values are
+// replaced with the defaults listed for each field.
+type Config struct {
+	// Clients is the number of simulated clients. Defaults to 8.
+	Clients int
+
+	// Duration is how long the simulation runs. Defaults to 30s.
+	Duration time.Duration
+
+	// HoldTime is the maximum time a client holds the mutex. Each
+	// hold lasts a random duration up to this value. Defaults to 1s.
+	HoldTime time.Duration
+
+	// MaxAge is the heartbeat age after which a holder is assumed
+	// dead. See [[mutex.Mutex.AutoRelease]]. Defaults to 3s.
+	MaxAge time.Duration
+
+	// CrashProb is the probability that a client crashes while
+	// holding the mutex. A crashed client stops interacting with
+	// the database without releasing the mutex. After RestartDelay,
+	// the client restarts with the same name.
+	CrashProb float64
+
+	// RestartDelay is how long a crashed client stays down.
+	// Defaults to MaxAge.
+	RestartDelay time.Duration
+
+	// PauseProb is the probability that a client pauses while holding
+	// the mutex, simulating a stop-the-world GC or a suspended VM. All
+	// of the client's transactions block while it's paused.
+	PauseProb float64
+
+	// PauseTime is how long a paused client stays paused.
+	// Defaults to twice MaxAge, long enough for the mutex
+	// to be released while the client is paused.
+	PauseTime time.Duration
+
+	// NetworkDelay is the maximum delay added before each of
+	// a client's transactions. Each delay is random.
+	NetworkDelay time.Duration
+
+	// LivenessTimeout is the longest the mutex may go without being
+	// acquired while clients are waiting. Defaults to enough time for
+	// a hold, a crash detection, and a restart to complete.
+	LivenessTimeout time.Duration
+
+	// Options are applied to each client's mutex, allowing the
+	// simulation to exercise a particular configuration. They're
+	// applied after [[mutex.WithLeaseTTL]] with MaxAge, so healthy
+	// holders heartbeat often enough to keep the mutex.
+	Options []mutex.Option
+
+	// OnViolation, if not nil, is called for each violation
+	// as it's found, before the simulation finishes.
+	OnViolation func(Violation)
+}
+
+// Report summarizes a simulation.
+type Report struct {
+	// Acquisitions is the number of times a client acquired the mutex.
+	Acquisitions int64
+
+	// Crashes is the number of simulated client crashes.
+	Crashes int64
+
+	// Pauses is the number of simulated client pauses.
+	Pauses int64
+
+	// LostHolds is the number of holds which ended because the client
+	// discovered it was no longer the owner. These are expected after
+	// crashes & pauses and don't violate any invariant.
+	LostHolds int64
+
+	// Errors is the number of unexpected errors returned
+	// by the mutex, excluding those caused by crashes.
+	Errors int64
+
+	// Violations lists every broken invariant.
+	Violations []Violation
+}
+
+// Violation describes a broken invariant.
+type Violation struct {
+	// Time is when the violation was found.
+	Time time.Time
+
+	// Invariant is the name of the broken invariant,
+	// either "mutual exclusion" or "liveness".
+	Invariant string
+
+	// Detail describes what was observed.
+	Detail string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s violated: %s", v.Invariant, v.Detail)
+}
+
+// errCrashed is returned by the transactions of a crashed client.
+var errCrashed = errors.New("client crashed")
+
+// Run executes the simulation described by 'cfg' against the mutex stored
+// in 'root' and blocks until it completes or 'ctx' is cancelled. An error
+// is only returned if the simulation couldn't be set up. Invariant
+// violations are listed in the returned report.
+func Run(ctx context.Context, db fdb.Database, root subspace.Subspace, cfg Config) (Report, error) {
+	cfg = cfg.withDefaults()
+
+	s := &sim{
+		cfg:  cfg,
+		db:   db,
+		root: root,
+	}
+	s.lastAcquire.Store(time.Now().UnixNano())
+
+	reaper, err := mutex.NewMutex(db, root, "mutexsim-reaper")
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to create reaper: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.reap(ctx, reaper)
+	}()
+	go func() {
+		defer wg.Done()
+		s.monitor(ctx)
+	}()
+
+	for i := 0; i < cfg.Clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.client(ctx, fmt.Sprintf("client%d", i))
+		}()
+	}
+	wg.Wait()
+
+	// Stop the heartbeats of crashed incarnations
+	// which never got the chance to clean up.
+	for _, x := range s.stale {
+		_ = x.Release(db)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Report{
+		Acquisitions: s.acquisitions.Load(),
+		Crashes:      s.crashes.Load(),
+		Pauses:       s.pauses.Load(),
+		LostHolds:    s.lostHolds.Load(),
+		Errors:       s.errors.Load(),
+		Violations:   s.violations,
+	}, nil
+}
+
+func (c Config) withDefaults() Config {
+	if c.Clients <= 0 {
+		c.Clients = 8
+	}
+	if c.Duration <= 0 {
+		c.Duration = 30 * time.Second
+	}
+	if c.HoldTime <= 0 {
+		c.HoldTime = time.Second
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = 3 * time.Second
+	}
+	if c.RestartDelay <= 0 {
+		c.RestartDelay = c.MaxAge
+	}
+	if c.PauseTime <= 0 {
+		c.PauseTime = 2 * c.MaxAge
+	}
+	if c.LivenessTimeout <= 0 {
+		c.LivenessTimeout = c.HoldTime + c.PauseTime + 2*c.MaxAge + c.RestartDelay + 10*c.NetworkDelay
+	}
+	c.Options = slices.Concat([]mutex.Option{mutex.WithLeaseTTL(c.MaxAge)}, c.Options)
+	return c
+}
+
+type sim struct {
+	cfg  Config
+	db   fdb.Database
+	root subspace.Subspace
+
+	acquisitions atomic.Int64
+	crashes      atomic.Int64
+	pauses       atomic.Int64
+	lostHolds    atomic.Int64
+	errors       atomic.Int64
+
+	// lastAcquire is the unix nano time of the latest
+	// acquisition. waiting is the number of clients
+	// blocked in [[mutex.Mutex.AcquireGuard]].
+	lastAcquire atomic.Int64
+	waiting     atomic.Int64
+
+	// holders is the number of clients in the middle of a
+	// step of their critical section. It's tracked in memory,
+	// outside the mutex, so overlapping holds are caught
+	// even when the mutex grants them.
+	holders atomic.Int64
+
+	mu         sync.Mutex
+	violations []Violation
+	stale      []*mutex.Mutex
+}
+
+// violate records a broken invariant.
+func (s *sim) violate(invariant string, format string, args ...any) {
+	v := Violation{
+		Time:      time.Now(),
+		Invariant: invariant,
+		Detail:    fmt.Sprintf(format, args...),
+	}
+
+	s.mu.Lock()
+	s.violations = append(s.violations, v)
+	s.mu.Unlock()
+
+	if s.cfg.OnViolation != nil {
+		s.cfg.OnViolation(v)
+	}
+}
+
+// reap runs [[mutex.Mutex.AutoRelease]] until the
+// context is cancelled, restarting it on failure.
+func (s *sim) reap(ctx context.Context, x *mutex.Mutex) {
+	for ctx.Err() == nil {
+		err := x.AutoRelease(ctx, s.db, s.cfg.MaxAge)
+		if err == nil || mutex.IsCancelled(err) {
+			continue
+		}
+		s.errors.Add(1)
+		sleep(ctx, time.Second)
+	}
+}
+
+// monitor asserts the liveness invariant: while clients are
+// waiting, the mutex must be acquired every LivenessTimeout.
+func (s *sim) monitor(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.LivenessTimeout / 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		last := time.Unix(0, s.lastAcquire.Load())
+		if s.waiting.Load() > 0 && time.Since(last) > s.cfg.LivenessTimeout {
+			s.violate("liveness", "%d clients waiting but mutex not acquired since %s",
+				s.waiting.Load(), last.Format(time.RFC3339Nano))
+
+			// Only report each stall once.
+			s.lastAcquire.Store(time.Now().UnixNano())
+		}
+	}
+}
+
+// client runs a simulated client until the context is cancelled.
+// Each iteration of the loop is an incarnation of the client,
+// which ends when the client crashes.
+func (s *sim) client(ctx context.Context, name string) {
+	for ctx.Err() == nil {
+		t := &faultyTransactor{Transactor: s.db, delay: s.cfg.NetworkDelay}
+
+		x, err := mutex.NewMutex(t, s.root, name, s.cfg.Options...)
+		if err != nil {
+			s.errors.Add(1)
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		if s.incarnation(ctx, x, t, name) {
+			s.mu.Lock()
+			s.stale = append(s.stale, x)
+			s.mu.Unlock()
+			sleep(ctx, s.cfg.RestartDelay)
+			continue
+		}
+		_ = x.Release(s.db)
+	}
+}
+
+// incarnation repeatedly acquires & holds the mutex
+// until the context is cancelled or the client
+// crashes, in which case true is returned.
+func (s *sim) incarnation(ctx context.Context, x *mutex.Mutex, t *faultyTransactor, name string) bool {
+	for ctx.Err() == nil {
+		s.waiting.Add(1)
+		g, err := x.AcquireGuard(ctx, t)
+		s.waiting.Add(-1)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.errors.Add(1)
+				sleep(ctx, time.Second)
+			}
+			continue
+		}
+		s.acquisitions.Add(1)
+		s.lastAcquire.Store(time.Now().UnixNano())
+
+		if crashed := s.hold(ctx, g, t, name); crashed {
+			return true
+		}
+		if err := g.Release(t); err != nil && ctx.Err() == nil {
+			s.errors.Add(1)
+		}
+	}
+	return false
+}
+
+// hold performs the critical section of a client, asserting the
+// mutual-exclusion invariant. The hold is split into steps. Before
+// each step, the client confirms it still owns the mutex with a fenced
+// transaction. During each step, the client counts itself as a holder,
+// so any other client counted at the same time is a violation. Faults
+// are injected between steps. If the client crashes during the hold,
+// true is returned.
+func (s *sim) hold(ctx context.Context, g *mutex.Guard, t *faultyTransactor, name string) bool {
+	start := time.Now()
+	length := rand.N(s.cfg.HoldTime) + 1
+	end := start.Add(length)
+
+	// Choose a random point during the
+	// hold to inject the chosen fault.
+	faultAt := start.Add(rand.N(length))
+	crash := rand.Float64() < s.cfg.CrashProb
+	pause := !crash && rand.Float64() < s.cfg.PauseProb
+
+	for time.Now().Before(end) {
+		if !faultAt.IsZero() && time.Now().After(faultAt) {
+			faultAt = time.Time{}
+			if crash {
+				s.crashes.Add(1)
+				t.crash()
+				return true
+			}
+			if pause {
+				s.pauses.Add(1)
+				t.pause(s.cfg.PauseTime)
+				if !sleep(ctx, s.cfg.PauseTime) {
+					return false
+				}
+			}
+		}
+
+		_, err := g.Transact(t, func(fdb.Transaction, subspace.Subspace) (any, error) {
+			return nil, nil
+		})
+		if err != nil {
+			s.endHold(ctx, err)
+			return false
+		}
+
+		if n := s.holders.Add(1); n > 1 {
+			s.violate("mutual exclusion", "%s holds the mutex along with %d other clients", name, n-1)
+		}
+		step := min(s.cfg.HoldTime/10, time.Until(end))
+		ok := sleep(g.Context(), step)
+		s.holders.Add(-1)
+
+		if !ok {
+			s.endHold(ctx, context.Cause(g.Context()))
+			return false
+		}
+	}
+	return false
+}
+
+// endHold classifies an error which ended a hold.
+func (s *sim) endHold(ctx context.Context, err error) {
+	switch {
+	case ctx.Err() != nil:
+	case errors.Is(err, mutex.ErrNotOwner), errors.Is(err, mutex.ErrLockLost):
+		s.lostHolds.Add(1)
+	default:
+		s.errors.Add(1)
+	}
+}
+
+// faultyTransactor injects faults into the transactions of a client.
+type faultyTransactor struct {
+	fdb.Transactor
+	delay time.Duration
+
+	crashed     atomic.Bool
+	pausedUntil atomic.Int64
+}
+
+func (t *faultyTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	if err := t.inject(); err != nil {
+		return nil, err
+	}
+	return t.Transactor.Transact(f)
+}
+
+func (t *faultyTransactor) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	if err := t.inject(); err != nil {
+		return nil, err
+	}
+	return t.Transactor.ReadTransact(f)
+}
+
+// inject delays the caller according to the configured network delay
+// and any active pause. If the client has crashed, an error is returned.
+func (t *faultyTransactor) inject() error {
+	if t.delay > 0 {
+		time.Sleep(rand.N(t.delay))
+	}
+	if wait := time.Until(time.Unix(0, t.pausedUntil.Load())); wait > 0 {
+		time.Sleep(wait)
+	}
+	if t.crashed.Load() {
+		return errCrashed
+	}
+	return nil
+}
+
+func (t *faultyTransactor) crash() {
+	t.crashed.Store(true)
+}
+
+func (t *faultyTransactor) pause(d time.Duration) {
+	t.pausedUntil.Store(time.Now().Add(d).UnixNano())
+}
+
+// sleep waits for the given duration. If the context is
+// cancelled first, false is returned.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,81 @@
+package mutexsim
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/janderland/fdb-mutex"
+)
+
+func TestRun(t *testing.T) {
+	db, root := newRoot(t)
+
+	report, err := Run(context.Background(), db, root, Config{
+		Clients:      4,
+		Duration:     3 * time.Second,
+		HoldTime:     100 * time.Millisecond,
+		MaxAge:       500 * time.Millisecond,
+		CrashProb:    0.1,
+		PauseProb:    0.1,
+		NetworkDelay: 10 * time.Millisecond,
+		OnViolation: func(v Violation) {
+			t.Log(v)
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, report.Violations)
+	require.Greater(t, report.Acquisitions, int64(0))
+}
+
+func TestRunViolation(t *testing.T) {
+	db, root := newRoot(t)
+
+	// Holders heartbeat far less often than the reaper
+	// expects, so healthy holders are released while
+	// they're still working and others acquire the mutex.
+	report, err := Run(context.Background(), db, root, Config{
+		Clients:  4,
+		Duration: 2 * time.Second,
+		HoldTime: 500 * time.Millisecond,
+		MaxAge:   50 * time.Millisecond,
+		Options:  []mutex.Option{mutex.WithLeaseTTL(time.Minute)},
+	})
+	require.NoError(t, err)
+
+	found := slices.ContainsFunc(report.Violations, func(v Violation) bool {
+		return v.Invariant == "mutual exclusion"
+	})
+	require.True(t, found, "violations: %v", report.Violations)
+}
+
+func newRoot(t *testing.T) (fdb.Database, directory.DirectorySubspace) {
+	fdb.MustAPIVersion(710)
+	db := fdb.MustOpenDefault()
+
+	// Generate a random directory name.
+	randBytes := make([]byte, 8)
+	if _, err := rand.Read(randBytes); err != nil {
+		t.Fatalf("failed to generate random bytes: %v", err)
+	}
+	dirName := hex.EncodeToString(randBytes)
+
+	root, err := directory.CreateOrOpen(db, []string{dirName}, nil)
+	if err != nil {
+		t.Fatalf("failed to create root directory: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := directory.Root().Remove(db, []string{dirName}); err != nil {
+			t.Errorf("failed to delete root directory: %v", err)
+		}
+	})
+	return db, root
+}
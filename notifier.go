@@ -0,0 +1,237 @@
+package mutex
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrWebhookQueueFull is reported to the error handler of a [[Notifier]]
+// when an event is dropped because too many webhook calls are pending.
+// See [[WithWebhookQueue]].
+var ErrWebhookQueueFull = errors.New("webhook queue is full")
+
+const (
+	// defaultWebhookQueue is the number of webhook calls
+	// a [[Notifier]] buffers before dropping events.
+	defaultWebhookQueue = 100
+
+	// defaultWebhookTimeout bounds each webhook call.
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// NotifierOption configures a [[Notifier]].
+type NotifierOption func(*Notifier)
+
+// WithWebhook causes the notifier to POST a JSON payload
+// describing each event to the given URL. See [[EventPayload]].
+func WithWebhook(url string) NotifierOption {
+	return func(n *Notifier) {
+		n.webhooks = append(n.webhooks, url)
+	}
+}
+
+// WithCallback causes the notifier to call 'fn' for each event.
+func WithCallback(fn func(Event)) NotifierOption {
+	return func(n *Notifier) {
+		n.callbacks = append(n.callbacks, fn)
+	}
+}
+
+// WithHTTPClient sets the client used to call webhooks.
+// By default, [[http.DefaultClient]] is used. Calls are
+// bounded by [[WithWebhookTimeout]] either way.
+func WithHTTPClient(client *http.Client) NotifierOption {
+	return func(n *Notifier) {
+		n.client = client
+	}
+}
+
+// WithWebhookQueue sets how many webhook calls may be pending. Webhooks are
+// called in the background, so a slow endpoint doesn't hold up the events.
+// When the queue is full, the event is dropped & [[ErrWebhookQueueFull]] is
+// passed to the error handler. By default, 100 calls may be pending.
+func WithWebhookQueue(size int) NotifierOption {
+	return func(n *Notifier) {
+		n.queueSize = size
+	}
+}
+
+// WithWebhookTimeout sets how long a single webhook call may take before
+// it's abandoned. By default, calls time out after 10 seconds.
+func WithWebhookTimeout(timeout time.Duration) NotifierOption {
+	return func(n *Notifier) {
+		n.timeout = timeout
+	}
+}
+
+// WithNotifyErrorHandler sets a function which is called when a
+// webhook fails. By default, webhook failures are ignored.
+func WithNotifyErrorHandler(fn func(error)) NotifierOption {
+	return func(n *Notifier) {
+		n.onError = fn
+	}
+}
+
+// EventPayload is the JSON body POSTed to webhooks by a [[Notifier]].
+type EventPayload struct {
+	Mutex   string    `json:"mutex"`
+	Event   string    `json:"event"`
+	Client  string    `json:"client"`
+	Time    time.Time `json:"time"`
+	Version string    `json:"version"`
+}
+
+// Notifier forwards the events of a mutex to webhooks and callbacks,
+// allowing lock activity to be integrated with alerting and chat
+// systems. Events can be fed to the notifier using [[Notifier.Run]]
+// or by passing [[Notifier.Notify]] to [[Mutex.StreamEvents]].
+// Webhooks are called by a background goroutine, which is stopped
+// by [[Notifier.Close]].
+type Notifier struct {
+	webhooks  []string
+	callbacks []func(Event)
+	client    *http.Client
+	onError   func(error)
+	queueSize int
+	timeout   time.Duration
+
+	// mu guards 'closed' & sending on 'queue',
+	// so events aren't queued after a close.
+	mu     sync.RWMutex
+	closed bool
+	queue  chan webhookCall
+
+	// done is closed once the queue is drained.
+	done chan struct{}
+}
+
+// webhookCall is a pending POST of 'body' to 'url'.
+type webhookCall struct {
+	url  string
+	body []byte
+}
+
+// NewNotifier constructs a notifier configured by the given options.
+// If any webhooks are configured, the notifier must be closed.
+func NewNotifier(opts ...NotifierOption) *Notifier {
+	n := &Notifier{
+		client:    http.DefaultClient,
+		queueSize: defaultWebhookQueue,
+		timeout:   defaultWebhookTimeout,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	if len(n.webhooks) > 0 {
+		n.queue = make(chan webhookCall, max(n.queueSize, 0))
+		n.done = make(chan struct{})
+		go n.callWebhooks()
+	}
+	return n
+}
+
+// Run forwards the events of the given mutex until the context is
+// cancelled or an error occurs. See [[Mutex.StreamEvents]].
+func (n *Notifier) Run(ctx context.Context, db fdb.Transactor, x *Mutex) error {
+	return x.StreamEvents(ctx, db, n.Notify)
+}
+
+// Notify forwards a single event to the configured callbacks & webhooks.
+// Callbacks are called before returning. Webhook calls are queued, so a
+// slow endpoint doesn't block the caller. See [[WithWebhookQueue]].
+func (n *Notifier) Notify(event Event) {
+	for _, fn := range n.callbacks {
+		fn(event)
+	}
+	if len(n.webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(EventPayload{
+		Mutex:   event.Mutex,
+		Event:   event.Kind.String(),
+		Client:  event.Client,
+		Time:    event.Time,
+		Version: hex.EncodeToString(event.Version.Bytes()),
+	})
+	if err != nil {
+		n.fail(fmt.Errorf("failed to marshal payload: %w", err))
+		return
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.closed {
+		return
+	}
+	for _, url := range n.webhooks {
+		select {
+		case n.queue <- webhookCall{url: url, body: body}:
+		default:
+			n.fail(fmt.Errorf("failed to call webhook %s: %w", url, ErrWebhookQueueFull))
+		}
+	}
+}
+
+// Close stops queuing webhook calls & waits for the pending calls
+// to finish. Events notified afterwards are only passed to the
+// callbacks. Closing a notifier more than once is a noop.
+func (n *Notifier) Close() {
+	if n.queue == nil {
+		return
+	}
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+	<-n.done
+}
+
+// callWebhooks makes the queued webhook calls until the queue is closed.
+func (n *Notifier) callWebhooks() {
+	defer close(n.done)
+	for call := range n.queue {
+		if err := n.post(call.url, call.body); err != nil {
+			n.fail(fmt.Errorf("failed to call webhook %s: %w", call.url, err))
+		}
+	}
+}
+
+func (n *Notifier) post(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *Notifier) fail(err error) {
+	if n.onError != nil {
+		n.onError(err)
+	}
+}
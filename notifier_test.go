@@ -0,0 +1,100 @@
+package mutex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	tests := map[string]testFn{
+		"webhook": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			payloads := make(chan EventPayload, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload EventPayload
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("failed to decode payload: %v", err)
+				}
+				payloads <- payload
+			}))
+			defer srv.Close()
+
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			var called bool
+			n := NewNotifier(
+				WithWebhook(srv.URL),
+				WithCallback(func(Event) { called = true }),
+				WithNotifyErrorHandler(func(err error) { t.Error(err) }),
+			)
+			defer n.Close()
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			events, err := x.Events(db)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+
+			n.Notify(events[0])
+			require.True(t, called)
+
+			payload := <-payloads
+			require.Equal(t, "acquired", payload.Event)
+			require.Equal(t, "client", payload.Client)
+		},
+		"slow webhook": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			unblock := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-unblock:
+				case <-r.Context().Done():
+				}
+			}))
+			defer srv.Close()
+			defer close(unblock)
+
+			errs := make(chan error, 10)
+			n := NewNotifier(
+				WithWebhook(srv.URL),
+				WithWebhookQueue(1),
+				WithWebhookTimeout(100*time.Millisecond),
+				WithNotifyErrorHandler(func(err error) { errs <- err }),
+			)
+
+			// Notifying doesn't wait for the webhook. Once
+			// the queue is full, events are dropped.
+			start := time.Now()
+			for range 3 {
+				n.Notify(Event{Kind: EventAcquired, Client: "client"})
+			}
+			require.Less(t, time.Since(start), 100*time.Millisecond)
+			require.ErrorIs(t, <-errs, ErrWebhookQueueFull)
+
+			// Calls to the hung webhook time out.
+			n.Close()
+			close(errs)
+			var timeouts int
+			for err := range errs {
+				if errors.Is(err, ErrWebhookQueueFull) {
+					continue
+				}
+				require.ErrorIs(t, err, context.DeadlineExceeded)
+				timeouts++
+			}
+			require.NotZero(t, timeouts)
+		},
+	}
+
+	runTests(t, tests)
+}
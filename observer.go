@@ -0,0 +1,142 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// Observer is a read-only handle to a mutex. It can inspect the owner and
+// queue, compute the owner's heartbeat age, and subscribe to events, but
+// it cannot acquire or modify the mutex. Unlike [[NewMutex]], constructing
+// an observer doesn't write to the database, making it suitable for
+// dashboards and tools with read-only access.
+type Observer struct {
+	kv
+
+	// stale, if true, relaxes the consistency of the
+	// inspection methods. See [[WithStaleReads]].
+	stale bool
+	reads *readVersionCache
+}
+
+// NewObserver constructs a read-only handle to the mutex stored in 'root'.
+// If the mutex hasn't been initialized by [[NewMutex]], [[ErrNotFound]] is
+// returned. If it was written by a newer schema, [[ErrSchemaTooNew]] is.
+// Only the options which affect how the mutex is decoded, like
+// [[WithSerializer]], or how it's read, like [[WithStaleReads]],
+// apply to observers. The rest are ignored.
+func NewObserver(db fdb.Transactor, root subspace.Subspace, opts ...Option) (_ *Observer, err error) {
+	defer wrapErr(&err)
+
+	var cfg Mutex
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	x := &Observer{kv: kv{Subspace: root, serializer: cfg.serializer}, stale: cfg.staleReads}
+	if cfg.staleReads && cfg.staleness > 0 {
+		x.reads = &readVersionCache{staleness: cfg.staleness}
+	}
+	typ, marked, err := x.getType(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type: %w", err)
+	}
+	if marked && typ != typeMutex {
+		return nil, fmt.Errorf("%w: expected %s but found %s", ErrWrongType, typeMutex, typ)
+	}
+	if err := x.checkSchema(db); err != nil {
+		return nil, err
+	}
+
+	exists, err := x.exists(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existence: %w", err)
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return x, nil
+}
+
+// Owner returns the name of the client holding the mutex.
+// If the mutex is free, a blank name is returned.
+func (x *Observer) Owner(db fdb.Transactor) (_ string, err error) {
+	defer wrapErr(&err)
+
+	owner, err := x.getOwner(x.inspect(db, true))
+	if err != nil {
+		return "", err
+	}
+	return owner.name, nil
+}
+
+// HeartbeatAge returns the approximate time since the owner's latest
+// heartbeat. The age is derived from database versions rather than
+// the clocks of the clients. If the mutex is free, false is returned.
+func (x *Observer) HeartbeatAge(db fdb.Transactor) (_ time.Duration, _ bool, err error) {
+	defer wrapErr(&err)
+	return x.getHeartbeatAge(x.inspect(db, false))
+}
+
+// HeartbeatTime returns the time of the owner's latest heartbeat according
+// to the owner's clock. Unlike [[Observer.HeartbeatAge]], this isn't affected
+// by unusual version rates, but it is affected by clock skew. If the mutex
+// is free or the heartbeat was written by an older version of this package,
+// false is returned.
+func (x *Observer) HeartbeatTime(db fdb.Transactor) (_ time.Time, _ bool, err error) {
+	defer wrapErr(&err)
+	return x.getHeartbeatTime(x.inspect(db, true))
+}
+
+// Candidates is like [[Mutex.Candidates]].
+func (x *Observer) Candidates(db fdb.Transactor) (_ []Candidate, err error) {
+	defer wrapErr(&err)
+	return x.getCandidates(x.inspect(db, true))
+}
+
+// WatchOwner returns a channel which signals an ownership change. When the
+// owner changes, the channel returns nil. If the watch setup fails or the
+// provided context is canceled, the channel returns an error.
+func (x *Observer) WatchOwner(ctx context.Context, db fdb.Transactor) <-chan error {
+	return x.watchOwner(ctx, db)
+}
+
+// WatchCandidates is like [[Mutex.WatchCandidates]].
+func (x *Observer) WatchCandidates(ctx context.Context, db fdb.Transactor) <-chan error {
+	return x.watchQueue(ctx, db)
+}
+
+// Events is like [[Mutex.Events]].
+func (x *Observer) Events(db fdb.Transactor) (_ []Event, err error) {
+	defer wrapErr(&err)
+	return x.getEvents(x.inspect(db, true), nil)
+}
+
+// StreamEvents is like [[Mutex.StreamEvents]].
+func (x *Observer) StreamEvents(ctx context.Context, db fdb.Transactor, fn func(Event)) (err error) {
+	defer wrapErr(&err)
+	return x.streamEvents(ctx, db, fn)
+}
+
+// Labels returns the labels attached to the mutex.
+func (x *Observer) Labels(db fdb.Transactor) (_ map[string]string, err error) {
+	defer wrapErr(&err)
+	return x.getLabels(x.inspect(db, true))
+}
+
+// inspect wraps the database for an inspection method if stale reads are
+// enabled. If 'reuse' is false, cached read versions aren't used. If 'db' is
+// a transaction, it's returned as is. See [[WithStaleReads]].
+func (x *Observer) inspect(db fdb.Transactor, reuse bool) fdb.Transactor {
+	d, ok := db.(fdb.Database)
+	if !x.stale || !ok {
+		return db
+	}
+	if !reuse {
+		return staleTransactor{Database: d}
+	}
+	return staleTransactor{Database: d, cache: x.reads}
+}
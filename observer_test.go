@@ -0,0 +1,90 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserver(t *testing.T) {
+	tests := map[string]testFn{
+		"not found": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewObserver(db, root)
+			require.ErrorIs(t, err, ErrNotFound)
+
+			// Nothing was written.
+			kvs, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+				return tr.GetRange(root, fdb.RangeOptions{}).GetSliceWithError()
+			})
+			require.NoError(t, err)
+			require.Empty(t, kvs)
+		},
+		"inspect": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			owner, err := obs.Owner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner)
+
+			_, ok, err := obs.HeartbeatAge(db)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			owner, err = obs.Owner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner)
+
+			_, ok, err = obs.HeartbeatAge(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			candidates, err := obs.Candidates(db)
+			require.NoError(t, err)
+			require.Len(t, candidates, 1)
+			require.Equal(t, "client2", candidates[0].Name)
+
+			events, err := obs.Events(db)
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+		},
+		"heartbeat time": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			defer func() { require.NoError(t, x.Release(db)) }()
+
+			require.NoError(t, x.heartbeat(db, "client"))
+
+			hbeat, ok, err := obs.HeartbeatTime(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.WithinDuration(t, time.Now(), hbeat, time.Minute)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,76 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// DoOnce runs 'fn' at most once per 'key' across every client sharing 'root',
+// like a distributed singleflight. Each key is guarded by a mutex stored in
+// the subspace (key) of 'root'. The client which acquires it runs 'fn' and
+// records the result alongside the mutex before releasing it. Clients which
+// acquire the mutex later, or call DoOnce after the result was recorded,
+// skip 'fn' and return the recorded result instead.
+//
+// If 'fn' fails, nothing is recorded and its error is returned, so the next
+// client to acquire the mutex runs 'fn' again. The context given to 'fn' is
+// cancelled if the mutex is assumed lost, see [[WithFailSafe]]. If the
+// client running 'fn' dies, another can only take over once the hold expires,
+// so a [[Mutex.AutoRelease]] instance should be running for the keys. The
+// options are applied to the mutex of the key. See [[NewMutex]].
+func DoOnce(ctx context.Context, db fdb.Transactor, root subspace.Subspace, key string, fn func(ctx context.Context) ([]byte, error), opts ...Option) (_ []byte, err error) {
+	defer wrapErr(&err)
+
+	x, err := NewMutex(db, root.Sub(key), "", opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mutex: %w", err)
+	}
+
+	// Avoid joining the queue if the work is already done.
+	result, ok, err := x.getResult(x.withBreaker(db))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result: %w", err)
+	}
+	if ok {
+		return result, nil
+	}
+
+	g, err := x.AcquireGuard(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if releaseErr := g.Release(db); releaseErr != nil && err == nil {
+			err = fmt.Errorf("failed to release mutex: %w", releaseErr)
+		}
+	}()
+
+	// Another client may have finished the
+	// work while this one was in the queue.
+	result, ok, err = x.getResult(x.withBreaker(db))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result: %w", err)
+	}
+	if ok {
+		return result, nil
+	}
+
+	result, err = fn(g.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	// The result is only recorded if this client still
+	// holds the mutex, otherwise another client may be
+	// running 'fn' concurrently.
+	_, err = g.Transact(db, func(tr fdb.Transaction, _ subspace.Subspace) (any, error) {
+		return nil, x.setResult(tr, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set result: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoOnce(t *testing.T) {
+	tests := map[string]testFn{
+		"once": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			var runs atomic.Int64
+			fn := func(context.Context) ([]byte, error) {
+				runs.Add(1)
+				time.Sleep(50 * time.Millisecond)
+				return []byte("result"), nil
+			}
+
+			type result struct {
+				val []byte
+				err error
+			}
+			done := make(chan result, 3)
+			for range 3 {
+				go func() {
+					val, err := DoOnce(ctx, db, root, "key", fn)
+					done <- result{val, err}
+				}()
+			}
+			for range 3 {
+				require.Equal(t, result{val: []byte("result")}, <-done)
+			}
+			require.Equal(t, int64(1), runs.Load())
+
+			// Other keys are independent.
+			val, err := DoOnce(ctx, db, root, "other", fn)
+			require.NoError(t, err)
+			require.Equal(t, []byte("result"), val)
+			require.Equal(t, int64(2), runs.Load())
+		},
+
+		"failed": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			errFn := errors.New("failed")
+			_, err := DoOnce(ctx, db, root, "key", func(context.Context) ([]byte, error) {
+				return nil, errFn
+			})
+			require.ErrorIs(t, err, errFn)
+
+			// Nothing was recorded, so the work is retried.
+			val, err := DoOnce(ctx, db, root, "key", func(context.Context) ([]byte, error) {
+				return []byte("result"), nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []byte("result"), val)
+		},
+	}
+
+	runTests(t, tests)
+}
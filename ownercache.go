@@ -0,0 +1,97 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// OwnerCache answers [[Observer.Owner]] from memory. The owner is read once
+// along with a watch which invalidates the cached name when the ownership
+// changes, so frequent readers like dashboards & routers cost a read per
+// ownership change rather than per call. Heartbeats don't invalidate the
+// cache. OwnerCaches are created by [[Observer.CacheOwner]].
+type OwnerCache struct {
+	x   *Observer
+	db  fdb.Transactor
+	ctx context.Context
+
+	mu    sync.Mutex
+	owner string
+	valid bool
+
+	// gen is incremented whenever the cache is filled
+	// so an old watch can't invalidate a newer entry.
+	gen int
+}
+
+// CacheOwner returns a cache of the mutex's owner. The cache's watches
+// are cancelled when the context ends, after which every call to
+// [[OwnerCache.Owner]] reads the owner from the database.
+func (x *Observer) CacheOwner(ctx context.Context, db fdb.Transactor) *OwnerCache {
+	return &OwnerCache{x: x, db: db, ctx: ctx}
+}
+
+// Owner returns the name of the client holding the mutex. If the
+// mutex is free, a blank name is returned. See [[Observer.Owner]].
+func (c *OwnerCache) Owner() (_ string, err error) {
+	defer wrapErr(&err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.valid {
+		return c.owner, nil
+	}
+	if c.ctx.Err() != nil {
+		owner, err := c.x.getOwner(c.db)
+		if err != nil {
+			return "", err
+		}
+		return owner.name, nil
+	}
+
+	// Read the owner & watch for a change of ownership in
+	// the same transaction, so no change can be missed.
+	// The owner since key changes with each new owner.
+	var owner ownerKV
+	ctx, cancel := context.WithCancel(c.ctx)
+	ch := watch(ctx, c.db, func(tr fdb.Transaction) (fdb.Key, error) {
+		var err error
+		if owner, err = c.x.getOwner(tr); err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		return c.x.packOwnerSinceKey(), nil
+	})
+
+	// If the watch couldn't be set up, the
+	// owner is read again on the next call.
+	select {
+	case err := <-ch:
+		cancel()
+		if err != nil {
+			return "", err
+		}
+		return owner.name, nil
+	default:
+	}
+
+	c.gen++
+	c.owner, c.valid = owner.name, true
+	go c.invalidate(ch, cancel, c.gen)
+	return owner.name, nil
+}
+
+// invalidate clears the cache once the watch fires or fails.
+func (c *OwnerCache) invalidate(ch <-chan error, cancel context.CancelFunc, gen int) {
+	<-ch
+	cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		c.valid = false
+	}
+}
@@ -0,0 +1,58 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwnerCache(t *testing.T) {
+	tests := map[string]testFn{
+		"invalidated": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client", WithLeaseTTL(100*time.Millisecond))
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			counter := &attemptCounter{Transactor: db}
+			cache := obs.CacheOwner(ctx, counter)
+
+			owner, err := cache.Owner()
+			require.NoError(t, err)
+			require.Empty(t, owner)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			require.Eventually(t, func() bool {
+				owner, err := cache.Owner()
+				return err == nil && owner == "client"
+			}, time.Second, 10*time.Millisecond)
+
+			// Heartbeats don't invalidate the cache.
+			reads := counter.attempts.Load()
+			time.Sleep(200 * time.Millisecond)
+			owner, err = cache.Owner()
+			require.NoError(t, err)
+			require.Equal(t, "client", owner)
+			require.Equal(t, reads, counter.attempts.Load())
+
+			require.NoError(t, x.Release(db))
+			require.Eventually(t, func() bool {
+				owner, err := cache.Owner()
+				return err == nil && owner == ""
+			}, time.Second, 10*time.Millisecond)
+		},
+	}
+
+	runTests(t, tests)
+}
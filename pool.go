@@ -0,0 +1,138 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// typePool marks a subspace as holding a [[Pool]].
+const typePool = "pool"
+
+// Pool is a set of interchangeable mutexes, called slots, such as a fixed
+// number of identical worker slots. A client holds at most one slot at a
+// time. Each slot is an ordinary mutex with its own queue, so the slots
+// can be observed & administered like any other mutex.
+type Pool struct {
+	slots []*Mutex
+
+	mu   sync.Mutex
+	held int
+}
+
+// NewPool constructs a pool of 'size' slots stored in 'root'. The slot
+// with index 'i' is stored in the subspace ("slot", i) of 'root'. The
+// name & options are applied to every slot. See [[NewMutex]]. If 'root'
+// holds another kind of primitive, [[ErrWrongType]] is returned.
+func NewPool(db fdb.Transactor, root subspace.Subspace, name string, size int, opts ...Option) (_ *Pool, err error) {
+	defer wrapErr(&err)
+
+	if size <= 0 {
+		return nil, fmt.Errorf("pool size must be positive")
+	}
+
+	x := kv{Subspace: root}
+	if err := x.claimType(db, typePool); err != nil {
+		return nil, err
+	}
+	slots, err := newSlots(db, root, name, 0, size, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Pool{slots: slots, held: -1}, nil
+}
+
+// newSlots constructs the slots with indexes in the range ['from', 'to')
+// stored in 'root'. The slot with index 'i' is stored in the subspace
+// ("slot", i) of 'root'. The name & options are applied to every slot.
+// See [[NewPool]].
+func newSlots(db fdb.Transactor, root subspace.Subspace, name string, from, to int, opts []Option) ([]*Mutex, error) {
+	var slots []*Mutex
+	for i := from; i < to; i++ {
+		x, err := NewMutex(db, root.Sub("slot", int64(i)), name, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create slot %d: %w", i, err)
+		}
+
+		// Every slot is given the same name so that
+		// an unnamed client is the same across slots.
+		name = x.name
+		slots = append(slots, x)
+	}
+	return slots, nil
+}
+
+// Size returns the number of slots in the pool.
+func (p *Pool) Size() int {
+	return len(p.slots)
+}
+
+// Slot returns the mutex of the slot with the given index.
+func (p *Pool) Slot(i int) *Mutex {
+	return p.slots[i]
+}
+
+// Held returns the index of the slot held by this client. If
+// no slot is held, false is returned.
+func (p *Pool) Held() (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.held, p.held >= 0
+}
+
+// AcquireAny blocks until this client holds one of the pool's slots, then
+// returns its index. The client waits in the queue of every slot and takes
+// whichever is free first, withdrawing from the other queues. If a slot is
+// already held, its index is returned immediately. The pool isn't locked
+// while waiting, so [[Pool.Held]] & [[Pool.Release]] don't block on it.
+func (p *Pool) AcquireAny(ctx context.Context, db fdb.Transactor) (_ int, err error) {
+	defer wrapErr(&err)
+
+	p.mu.Lock()
+	held, slots := p.held, p.slots
+	p.mu.Unlock()
+
+	if held >= 0 {
+		return held, nil
+	}
+	i, err := acquireAny(ctx, db, slots)
+	if err != nil {
+		return -1, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Another goroutine may have acquired a different slot
+	// while we waited. The client holds at most one slot,
+	// so the one acquired by this call is released.
+	if p.held >= 0 && p.held != i {
+		if err := p.slots[i].Release(db); err != nil {
+			return -1, fmt.Errorf("failed to release slot %d: %w", i, err)
+		}
+		return p.held, nil
+	}
+	p.held = i
+	return i, nil
+}
+
+// Release releases the slot held by this client. If
+// no slot is held, this method is a noop.
+func (p *Pool) Release(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.held < 0 {
+		return nil
+	}
+	if err := p.slots[p.held].Release(db); err != nil {
+		return err
+	}
+	p.held = -1
+	return nil
+}
@@ -0,0 +1,76 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	tests := map[string]testFn{
+		"acquire any": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			p1, err := NewPool(db, root, "client1", 2)
+			require.NoError(t, err)
+			p2, err := NewPool(db, root, "client2", 2)
+			require.NoError(t, err)
+			p3, err := NewPool(db, root, "client3", 2)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			i1, err := p1.AcquireAny(ctx, db)
+			require.NoError(t, err)
+			i2, err := p2.AcquireAny(ctx, db)
+			require.NoError(t, err)
+			require.NotEqual(t, i1, i2)
+
+			held, ok := p1.Held()
+			require.True(t, ok)
+			require.Equal(t, i1, held)
+
+			// Both slots are held, so the third
+			// client waits for either to be freed.
+			type result struct {
+				i   int
+				err error
+			}
+			done := make(chan result, 1)
+			go func() {
+				i3, err := p3.AcquireAny(ctx, db)
+				done <- result{i3, err}
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, p2.Release(db))
+			require.Equal(t, result{i: i2}, <-done)
+
+			_, ok = p2.Held()
+			require.False(t, ok)
+
+			// The third client withdrew from the other slot's queue.
+			candidates, err := p1.Slot(i1).Candidates(db)
+			require.NoError(t, err)
+			require.Empty(t, candidates)
+
+			// A waiting client doesn't block its own pool.
+			go func() {
+				i3, err := p2.AcquireAny(ctx, db)
+				done <- result{i3, err}
+			}()
+			time.Sleep(100 * time.Millisecond)
+			_, ok = p2.Held()
+			require.False(t, ok)
+			require.NoError(t, p2.Release(db))
+
+			require.NoError(t, p1.Release(db))
+			require.Equal(t, result{i: i1}, <-done)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,127 @@
+package mutex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// SetPreemptible declares the current hold preemptible, for background work
+// which opportunistically uses a shared resource. When a client with a
+// priority of zero or more starts waiting for the mutex, the holder is
+// notified via [[Guard.ReleaseRequested]]. If it hasn't released the mutex
+// within 'grace', the waiting clients evict it and the mutex is handed to
+// the queue. The eviction is logged as an [[EventEvicted]] and the holder
+// discovers the loss with its next heartbeat. The declaration only applies
+// to the current hold. If this client doesn't own the mutex, [[ErrNotOwner]]
+// is returned. The grace period is timed by the clocks of the waiters.
+func (x *Mutex) SetPreemptible(db fdb.Transactor, grace time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	if grace < 0 {
+		return fmt.Errorf("grace period must not be negative")
+	}
+
+	_, err = x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+
+		// If a waiter already arrived, the
+		// grace period starts right away.
+		queue, err := x.getQueue(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get queue: %w", err)
+		}
+		p := preemptKV{name: x.name, grace: grace}
+		for _, q := range queue {
+			priority, err := x.getPriority(tr, q.name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get priority: %w", err)
+			}
+			if priority >= 0 {
+				p.deadline = time.Now().Add(grace)
+				if err := x.requestRelease(tr, q.name); err != nil {
+					return nil, fmt.Errorf("failed to request release: %w", err)
+				}
+				break
+			}
+		}
+		return nil, x.setPreempt(tr, p)
+	})
+	return err
+}
+
+// ClearPreemptible undoes [[Mutex.SetPreemptible]]. If a waiter has
+// already asked for the mutex, the request to release it remains. If
+// this client doesn't own the mutex, [[ErrNotOwner]] is returned.
+func (x *Mutex) ClearPreemptible(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	_, err = x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+		return nil, x.clearPreempt(tr)
+	})
+	return err
+}
+
+// preempt starts the grace period of a preemptible hold on behalf of this
+// client, which is waiting for the mutex. If the hold isn't preemptible,
+// or this client's priority is negative, nothing is done.
+func (x *Mutex) preempt(tr fdb.Transaction) error {
+	if x.priority < 0 {
+		return nil
+	}
+	p, ok, err := x.getPreempt(tr)
+	if err != nil {
+		return fmt.Errorf("failed to get preempt: %w", err)
+	}
+	if !ok || !p.deadline.IsZero() {
+		return nil
+	}
+	p.deadline = time.Now().Add(p.grace)
+	if err := x.setPreempt(tr, p); err != nil {
+		return fmt.Errorf("failed to set preempt: %w", err)
+	}
+	return x.requestRelease(tr, x.name)
+}
+
+// untilPreempted returns how long until the preemptible hold of the
+// client 'owner' may be evicted. If the hold isn't preemptible or no
+// waiter has asked for the mutex, false is returned.
+func (x *kv) untilPreempted(db fdb.Transactor, owner string) (time.Duration, bool, error) {
+	p, ok, err := x.getPreempt(db)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok || p.name != owner || p.deadline.IsZero() {
+		return 0, false, nil
+	}
+	return time.Until(p.deadline), true, nil
+}
+
+// expirePreemption evicts the owner if its preemptible
+// hold outlasted the grace period. See [[kv.evict]].
+func (x *kv) expirePreemption(db fdb.Transactor) error {
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		wait, ok, err := x.untilPreempted(tr, owner.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get preempt: %w", err)
+		}
+		if !ok || wait > 0 {
+			return nil, nil
+		}
+		if _, err := x.evict(tr, owner.name); err != nil {
+			return nil, fmt.Errorf("failed to evict owner: %w", err)
+		}
+		return nil, nil
+	})
+	return err
+}
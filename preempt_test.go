@@ -0,0 +1,131 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreemptible(t *testing.T) {
+	tests := map[string]testFn{
+		"evicted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			background, err := NewMutex(db, root, "background", WithPriority(-1))
+			require.NoError(t, err)
+
+			normal, err := NewMutex(db, root, "normal")
+			require.NoError(t, err)
+
+			g, err := background.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+
+			err = background.SetPreemptible(db, 100*time.Millisecond)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() { done <- normal.Acquire(ctx, db) }()
+
+			select {
+			case <-g.ReleaseRequested():
+			case <-ctx.Done():
+				t.Fatal("holder wasn't notified")
+			}
+
+			// The holder ignores the request, so it's
+			// evicted once the grace period ends.
+			require.NoError(t, <-done)
+
+			owner, err := normal.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "normal", owner.name)
+		},
+		"evicted for any": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			background, err := NewMutex(db, root.Sub(0), "background", WithPriority(-1))
+			require.NoError(t, err)
+			other, err := NewMutex(db, root.Sub(1), "background", WithPriority(-1))
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{background, other} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+			err = background.SetPreemptible(db, 100*time.Millisecond)
+			require.NoError(t, err)
+
+			normal1, err := NewMutex(db, root.Sub(0), "normal")
+			require.NoError(t, err)
+			normal2, err := NewMutex(db, root.Sub(1), "normal")
+			require.NoError(t, err)
+
+			// A waiter in AcquireAny evicts the
+			// preemptible holder like Acquire does.
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			i, err := AcquireAny(ctx, db, normal1, normal2)
+			require.NoError(t, err)
+			require.Equal(t, 0, i)
+
+			owner, err := normal1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "normal", owner.name)
+		},
+		"low priority waiter": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			background, err := NewMutex(db, root, "background", WithPriority(-1))
+			require.NoError(t, err)
+
+			other, err := NewMutex(db, root, "other", WithPriority(-1))
+			require.NoError(t, err)
+
+			acquired, err := background.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = background.SetPreemptible(db, time.Millisecond)
+			require.NoError(t, err)
+
+			acquired, err = other.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			_, ok, err := other.untilPreempted(db, "background")
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+		"cleared": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			background, err := NewMutex(db, root, "background")
+			require.NoError(t, err)
+
+			other, err := NewMutex(db, root, "other")
+			require.NoError(t, err)
+
+			err = background.SetPreemptible(db, time.Millisecond)
+			require.ErrorIs(t, err, ErrNotOwner)
+
+			acquired, err := background.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = background.SetPreemptible(db, time.Millisecond)
+			require.NoError(t, err)
+			err = background.ClearPreemptible(db)
+			require.NoError(t, err)
+
+			acquired, err = other.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			_, ok, err := other.untilPreempted(db, "background")
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+	}
+
+	runTests(t, tests)
+}
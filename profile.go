@@ -0,0 +1,284 @@
+package mutex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// waitBuckets are the upper bounds of the wait-time histogram
+// buckets. The final bucket holds every longer wait.
+var waitBuckets = []time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+	10 * time.Minute,
+	math.MaxInt64,
+}
+
+// ContentionProfiler aggregates how often clients contend on mutexes, how
+// often their transactions retry, and how long they wait to acquire. Like
+// [[ActivityFeed]], a single profiler is typically stored in the parent
+// directory of many mutexes. Mutexes report to the profiler when
+// constructed with the [[WithContentionProfiler]] option.
+type ContentionProfiler struct{ subspace.Subspace }
+
+// NewContentionProfiler constructs a contention profiler. 'root' is
+// the directory where the profiler's statistics are stored.
+func NewContentionProfiler(root subspace.Subspace) ContentionProfiler {
+	return ContentionProfiler{root}
+}
+
+// WithContentionProfiler causes the mutex to report its contention to the
+// given profiler. Reporting adds atomic writes to acquisition transactions
+// and a small transaction after each call to [[Mutex.Acquire]].
+func WithContentionProfiler(p ContentionProfiler) Option {
+	return func(x *Mutex) {
+		x.profiler = &p
+	}
+}
+
+// Contention summarizes how a client has contended on a mutex.
+type Contention struct {
+	// Mutex identifies the mutex. See [[ClientSession.Locks]].
+	Mutex string
+
+	// Client is the name of the client.
+	Client string
+
+	// Contended counts the acquisition attempts
+	// which found the mutex held by another client.
+	Contended int64
+
+	// Retries counts the acquisition & release transactions
+	// which were retried, usually because of a conflict.
+	Retries int64
+
+	// Waits is a histogram of the time spent in [[Mutex.Acquire]].
+	Waits []WaitBucket
+}
+
+// WaitBucket counts the waits no longer than Max and
+// longer than the Max of the previous bucket.
+type WaitBucket struct {
+	Max   time.Duration
+	Count int64
+}
+
+// Top returns the 'n' client & mutex pairs with the most contention,
+// ordered by [[Contention.Contended]] then [[Contention.Retries]]. If
+// 'n' isn't positive, every pair is returned.
+func (p *ContentionProfiler) Top(db fdb.Transactor, n int) (_ []Contention, err error) {
+	defer wrapErr(&err)
+
+	rngProfile, err := p.packProfileRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack profile range: %w", err)
+	}
+
+	list, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var list []Contention
+		iter := tr.GetRange(rngProfile, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv := iter.MustGet()
+			tup, err := p.Unpack(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack profile key: %w", err)
+			}
+			if len(tup) < 4 {
+				return nil, fmt.Errorf("profile key tuple is incorrect length %d", len(tup))
+			}
+			mutex, ok := tup[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("tuple element 1 is not a string")
+			}
+			client, ok := tup[2].(string)
+			if !ok {
+				return nil, fmt.Errorf("tuple element 2 is not a string")
+			}
+
+			// Keys are grouped by mutex & client, so
+			// we only need to check the latest entry.
+			if len(list) == 0 || list[len(list)-1].Mutex != mutex || list[len(list)-1].Client != client {
+				list = append(list, newContention(mutex, client))
+			}
+			c := &list[len(list)-1]
+
+			switch tup[3] {
+			case "contended":
+				c.Contended = unpackCounter(kv.Value)
+
+			case "retries":
+				c.Retries = unpackCounter(kv.Value)
+
+			case "wait":
+				if len(tup) != 5 {
+					return nil, fmt.Errorf("wait key tuple is incorrect length %d", len(tup))
+				}
+				i, ok := tup[4].(int64)
+				if !ok || i < 0 || int(i) >= len(c.Waits) {
+					return nil, fmt.Errorf("tuple element 4 is not a bucket index")
+				}
+				c.Waits[i].Count = unpackCounter(kv.Value)
+			}
+		}
+		return list, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	top := list.([]Contention)
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].Contended != top[j].Contended {
+			return top[i].Contended > top[j].Contended
+		}
+		return top[i].Retries > top[j].Retries
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top, nil
+}
+
+// Reset clears the profiler's statistics.
+func (p *ContentionProfiler) Reset(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	rngProfile, err := p.packProfileRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack profile range: %w", err)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.ClearRange(rngProfile)
+		return nil, nil
+	})
+	return err
+}
+
+func newContention(mutex, client string) Contention {
+	c := Contention{Mutex: mutex, Client: client, Waits: make([]WaitBucket, len(waitBuckets))}
+	for i, bound := range waitBuckets {
+		c.Waits[i].Max = bound
+	}
+	return c
+}
+
+// contend counts an acquisition attempt which found the mutex held.
+func (p *ContentionProfiler) contend(tr fdb.Transaction, mutex, client string) {
+	tr.Add(p.packStatKey(mutex, client, "contended"), packIncrement())
+}
+
+// retry counts the retries of a transaction. It's called on every
+// attempt, but only the writes of the committed attempt persist.
+func (p *ContentionProfiler) retry(tr fdb.Transaction, mutex, client string, retries int64) {
+	if retries > 0 {
+		tr.Add(p.packStatKey(mutex, client, "retries"), packCounter(retries))
+	}
+}
+
+// wait records the time spent acquiring the mutex, along with the
+// stack of the goroutine which waited. See [[ContentionProfiler.WriteBlockProfile]].
+func (p *ContentionProfiler) wait(db fdb.Transactor, mutex, client string, d time.Duration, stack []frame) error {
+	id := stackID(stack)
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.Add(p.packWaitKey(mutex, client, waitBucket(d)), packIncrement())
+		tr.Set(p.packBlockKey(mutex, client, id), packStack(stack))
+		tr.Add(p.packBlockStatKey(mutex, client, id, "count"), packIncrement())
+		tr.Add(p.packBlockStatKey(mutex, client, id, "delay"), packCounter(int64(d)))
+		return nil, nil
+	})
+	return err
+}
+
+// waitBucket returns the index of the histogram bucket holding 'd'.
+func waitBucket(d time.Duration) int {
+	return sort.Search(len(waitBuckets), func(i int) bool {
+		return d <= waitBuckets[i]
+	})
+}
+
+// profilerTransactor counts the retries of the
+// transactions it runs. See [[ContentionProfiler.retry]].
+type profilerTransactor struct {
+	fdb.Transactor
+	p      *ContentionProfiler
+	mutex  string
+	client string
+}
+
+func (t profilerTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	var attempts int64
+	return t.Transactor.Transact(func(tr fdb.Transaction) (any, error) {
+		attempts++
+		t.p.retry(tr, t.mutex, t.client, attempts-1)
+		return f(tr)
+	})
+}
+
+// withProfiler wraps the transactor so retries are reported to the
+// mutex's profiler. If the mutex doesn't have a profiler, the transactor
+// is returned as is.
+func (x *Mutex) withProfiler(db fdb.Transactor) fdb.Transactor {
+	if x.profiler == nil {
+		return db
+	}
+	if _, ok := db.(profilerTransactor); ok {
+		return db
+	}
+	return profilerTransactor{
+		Transactor: db,
+		p:          x.profiler,
+		mutex:      lockID(x.Subspace),
+		client:     x.name,
+	}
+}
+
+// profileContention reports that the mutex was found held.
+func (x *Mutex) profileContention(tr fdb.Transaction) {
+	if x.profiler != nil {
+		x.profiler.contend(tr, lockID(x.Subspace), x.name)
+	}
+}
+
+// profileWait reports the time spent acquiring the mutex, which began at
+// 'start', if the acquisition succeeded. It's deferred, so it's given the
+// named error of the caller. Reporting is best effort, so a failure
+// doesn't fail the acquisition.
+func (x *Mutex) profileWait(db fdb.Transactor, start time.Time, err *error) {
+	if x.profiler != nil && *err == nil {
+		// Skip this function so the stack begins
+		// with the method which waited.
+		stack := callers(1)
+		_ = x.profiler.wait(db, lockID(x.Subspace), x.name, time.Since(start), stack)
+	}
+}
+
+func (p *ContentionProfiler) packProfileRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(p.Pack(tuple.Tuple{"profile"}))
+}
+
+func (p *ContentionProfiler) packStatKey(mutex, client, field string) fdb.Key {
+	return p.Pack(tuple.Tuple{"profile", mutex, client, field})
+}
+
+func (p *ContentionProfiler) packWaitKey(mutex, client string, bucket int) fdb.Key {
+	return p.Pack(tuple.Tuple{"profile", mutex, client, "wait", int64(bucket)})
+}
+
+func (p *ContentionProfiler) packBlockKey(mutex, client string, stack int64) fdb.Key {
+	return p.Pack(tuple.Tuple{"profile", mutex, client, "block", stack})
+}
+
+func (p *ContentionProfiler) packBlockStatKey(mutex, client string, stack int64, field string) fdb.Key {
+	return p.Pack(tuple.Tuple{"profile", mutex, client, "block", stack, field})
+}
@@ -0,0 +1,85 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitBucket(t *testing.T) {
+	require.Equal(t, 0, waitBucket(0))
+	require.Equal(t, 0, waitBucket(time.Millisecond))
+	require.Equal(t, 1, waitBucket(2*time.Millisecond))
+	require.Equal(t, 3, waitBucket(time.Second))
+	require.Equal(t, len(waitBuckets)-1, waitBucket(time.Hour))
+}
+
+func TestContentionProfiler(t *testing.T) {
+	tests := map[string]testFn{
+		"top": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+			dirProfile, err := parent.CreateOrOpen(db, []string{"profile"}, nil)
+			require.NoError(t, err)
+
+			profiler := NewContentionProfiler(dirProfile)
+			opt := WithContentionProfiler(profiler)
+
+			xA1, err := NewMutex(db, dirA, "client1", opt)
+			require.NoError(t, err)
+			xA2, err := NewMutex(db, dirA, "client2", opt)
+			require.NoError(t, err)
+			xB1, err := NewMutex(db, dirB, "client1", opt)
+			require.NoError(t, err)
+
+			require.NoError(t, xA1.Acquire(context.Background(), db))
+			require.NoError(t, xB1.Acquire(context.Background(), db))
+
+			// client2 contends on mutex 'a' twice.
+			for range 2 {
+				acquired, err := xA2.TryAcquire(db)
+				require.NoError(t, err)
+				require.False(t, acquired)
+			}
+
+			require.NoError(t, xA1.Release(db))
+			require.NoError(t, xA2.Acquire(context.Background(), db))
+			require.NoError(t, xA2.Release(db))
+			require.NoError(t, xB1.Release(db))
+
+			top, err := profiler.Top(db, 0)
+			require.NoError(t, err)
+			require.Len(t, top, 3)
+
+			require.Equal(t, lockID(dirA), top[0].Mutex)
+			require.Equal(t, "client2", top[0].Client)
+			require.Equal(t, int64(2), top[0].Contended)
+
+			var waits int64
+			for _, b := range top[0].Waits {
+				waits += b.Count
+			}
+			require.Equal(t, int64(1), waits)
+
+			top, err = profiler.Top(db, 1)
+			require.NoError(t, err)
+			require.Len(t, top, 1)
+
+			require.NoError(t, profiler.Reset(db))
+			top, err = profiler.Top(db, 0)
+			require.NoError(t, err)
+			require.Empty(t, top)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,123 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// AcquireOrResult is a variant of [[Mutex.AcquireGuard]] for mutexes which
+// guard a computation whose result is shared, avoiding duplicate work. The
+// client which wins the mutex receives a [[Guard]], runs the computation, and
+// publishes its result with [[Guard.Publish]]. The clients which lose block
+// until the result is published and receive it instead of the mutex.
+//
+// On success, exactly one of the guard & the result is returned. If the
+// result was published before this method was called, it's returned
+// immediately. If the winner releases the mutex without publishing, for
+// instance because the computation failed, the next client in the queue
+// wins the mutex and is given the chance to run the computation instead.
+func (x *Mutex) AcquireOrResult(ctx context.Context, db fdb.Transactor) (_ *Guard, _ []byte, err error) {
+	defer wrapErr(&err)
+	db = x.withBreaker(db)
+
+	result, ok, err := x.getResult(db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get result: %w", err)
+	}
+	if ok {
+		return nil, result, nil
+	}
+
+	// Wait for the mutex as [[Mutex.Acquire]] does,
+	// but give up once a result is published.
+	acquireCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go x.awaitResult(acquireCtx, db, cancel)
+
+	token, err := x.AcquireToken(acquireCtx, db)
+	if err != nil {
+		cause := context.Cause(acquireCtx)
+		if !errors.Is(cause, errPublished) {
+			if ctx.Err() == nil && cause != nil {
+				err = cause
+			}
+			return nil, nil, errors.Join(err, x.withdraw(db))
+		}
+		if err := x.withdraw(db); err != nil {
+			return nil, nil, fmt.Errorf("failed to withdraw: %w", err)
+		}
+		result, _, err := x.getResult(db)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get result: %w", err)
+		}
+		return nil, result, nil
+	}
+	cancel(nil)
+
+	// The result may have been published between the first
+	// check & the acquisition, in which case the winner has
+	// already released the mutex.
+	g := x.newGuard(ctx, db, token)
+	result, ok, err = x.getResult(db)
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("failed to get result: %w", err), g.Release(db))
+	}
+	if ok {
+		return nil, result, g.Release(db)
+	}
+	return g, nil, nil
+}
+
+// errPublished is the cause given when [[Mutex.AcquireOrResult]]
+// stops waiting for the mutex because a result was published.
+var errPublished = errors.New("result published")
+
+// awaitResult cancels the context with [[errPublished]] once a result is
+// published. If watching the result fails, the context is cancelled with
+// the failure instead. It returns once the context ends.
+func (x *Mutex) awaitResult(ctx context.Context, db fdb.Transactor, cancel context.CancelCauseFunc) {
+	for {
+		var published bool
+		changed := watch(ctx, db, func(tr fdb.Transaction) (fdb.Key, error) {
+			_, ok, err := x.getResult(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get result: %w", err)
+			}
+			published = ok
+			if published {
+				return nil, nil
+			}
+			return x.packResultKey(), nil
+		})
+
+		err := <-changed
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			cancel(fmt.Errorf("failed to watch result: %w", err))
+			return
+		case published:
+			cancel(errPublished)
+			return
+		}
+	}
+}
+
+// Publish records the result of the computation guarded by the mutex and
+// releases it. Clients blocked in [[Mutex.AcquireOrResult]] receive the
+// result. If the client no longer holds the mutex, [[ErrNotOwner]] is
+// returned and nothing is recorded.
+func (g *Guard) Publish(db fdb.Transactor, result []byte) error {
+	_, err := g.Transact(db, func(tr fdb.Transaction, _ subspace.Subspace) (any, error) {
+		return nil, g.x.setResult(tr, result)
+	})
+	if err != nil {
+		return err
+	}
+	return g.Release(db)
+}
@@ -0,0 +1,132 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireOrResult(t *testing.T) {
+	tests := map[string]testFn{
+		"published": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			g, result, err := x1.AcquireOrResult(ctx, db)
+			require.NoError(t, err)
+			require.NotNil(t, g)
+			require.Nil(t, result)
+
+			type loss struct {
+				won    bool
+				result []byte
+				err    error
+			}
+			done := make(chan loss, 1)
+			go func() {
+				g, result, err := x2.AcquireOrResult(ctx, db)
+				done <- loss{g != nil, result, err}
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, g.Publish(db, []byte("result")))
+			require.Equal(t, loss{result: []byte("result")}, <-done)
+
+			// The loser doesn't end up holding the mutex.
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+			candidates, err := x1.Candidates(db)
+			require.NoError(t, err)
+			require.Empty(t, candidates)
+
+			// Later callers receive the result immediately.
+			g, result, err = x1.AcquireOrResult(ctx, db)
+			require.NoError(t, err)
+			require.Nil(t, g)
+			require.Equal(t, []byte("result"), result)
+		},
+
+		"abandoned": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			g, _, err := x1.AcquireOrResult(ctx, db)
+			require.NoError(t, err)
+			require.NotNil(t, g)
+
+			done := make(chan *Guard, 1)
+			go func() {
+				g, _, err := x2.AcquireOrResult(ctx, db)
+				if err != nil {
+					g = nil
+				}
+				done <- g
+			}()
+
+			// The winner gives up without publishing,
+			// so the loser wins the mutex instead.
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, g.Release(db))
+			g = <-done
+			require.NotNil(t, g)
+			require.NoError(t, g.Publish(db, []byte("result")))
+		},
+
+		"window": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			// The mutex is free, but its window is closed.
+			require.NoError(t, x.SetSchedule(db, windowIn(300*time.Millisecond, false)))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			g, result, err := x.AcquireOrResult(ctx, db)
+			require.NoError(t, err)
+			require.NotNil(t, g)
+			require.Nil(t, result)
+		},
+
+		"rate limited": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			// Use up the attempts of the mutex.
+			require.NoError(t, x1.SetRateLimit(db, 1, 300*time.Millisecond))
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x1.Release(db))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			// The attempt is retried once the interval ends
+			// rather than failing with a rate limit error.
+			g, result, err := x2.AcquireOrResult(ctx, db)
+			require.NoError(t, err)
+			require.NotNil(t, g)
+			require.Nil(t, result)
+		},
+	}
+
+	runTests(t, tests)
+}
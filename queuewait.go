@@ -0,0 +1,78 @@
+package mutex
+
+import (
+	"slices"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// maxQueueWaits is the number of queue wait samples retained per mutex.
+const maxQueueWaits = 1000
+
+// QueueWaitStats summarizes the time clients spent in the queue
+// before being promoted to owner. Clients which acquired a vacant
+// mutex without queueing aren't included. See [[Mutex.QueueWaitStats]].
+type QueueWaitStats struct {
+	// Count is the number of promotions in the window.
+	Count int
+
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// QueueWaitStats reports the time spent in the queue by the clients promoted
+// to owner within the latest 'window'. Only the latest 1000 promotions are
+// retained, so busy mutexes may report fewer promotions than occurred. The
+// times are measured with the clocks of the clients which queued and
+// promoted each client, so they're affected by clock skew.
+func (x *Mutex) QueueWaitStats(db fdb.Transactor, window time.Duration) (_ QueueWaitStats, err error) {
+	defer wrapErr(&err)
+
+	waits, err := x.getQueueWaits(x.withBreaker(db), time.Now().Add(-window))
+	if err != nil {
+		return QueueWaitStats{}, err
+	}
+	return toQueueWaitStats(waits), nil
+}
+
+// QueueWaitStats is like [[Mutex.QueueWaitStats]].
+func (x *Observer) QueueWaitStats(db fdb.Transactor, window time.Duration) (_ QueueWaitStats, err error) {
+	defer wrapErr(&err)
+
+	waits, err := x.getQueueWaits(db, time.Now().Add(-window))
+	if err != nil {
+		return QueueWaitStats{}, err
+	}
+	return toQueueWaitStats(waits), nil
+}
+
+func toQueueWaitStats(waits []time.Duration) QueueWaitStats {
+	if len(waits) == 0 {
+		return QueueWaitStats{}
+	}
+	slices.Sort(waits)
+
+	var total time.Duration
+	for _, w := range waits {
+		total += w
+	}
+
+	// percentile uses the nearest-rank method.
+	percentile := func(p int) time.Duration {
+		rank := (p*len(waits) + 99) / 100
+		return waits[max(rank, 1)-1]
+	}
+
+	return QueueWaitStats{
+		Count: len(waits),
+		Mean:  total / time.Duration(len(waits)),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P99:   percentile(99),
+		Max:   waits[len(waits)-1],
+	}
+}
@@ -0,0 +1,61 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueWaitStats(t *testing.T) {
+	t.Run("percentiles", func(t *testing.T) {
+		var waits []time.Duration
+		for i := 100; i >= 1; i-- {
+			waits = append(waits, time.Duration(i)*time.Millisecond)
+		}
+		stats := toQueueWaitStats(waits)
+		require.Equal(t, 100, stats.Count)
+		require.Equal(t, 50500*time.Microsecond, stats.Mean)
+		require.Equal(t, 50*time.Millisecond, stats.P50)
+		require.Equal(t, 90*time.Millisecond, stats.P90)
+		require.Equal(t, 99*time.Millisecond, stats.P99)
+		require.Equal(t, 100*time.Millisecond, stats.Max)
+
+		require.Equal(t, QueueWaitStats{}, toQueueWaitStats(nil))
+	})
+
+	tests := map[string]testFn{
+		"promotion": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			time.Sleep(50 * time.Millisecond)
+			require.NoError(t, x1.Release(db))
+
+			stats, err := x1.QueueWaitStats(db, time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, 1, stats.Count)
+			require.GreaterOrEqual(t, stats.Max, 50*time.Millisecond)
+
+			// Promotions outside the window are excluded.
+			stats, err = x1.QueueWaitStats(db, -time.Minute)
+			require.NoError(t, err)
+			require.Zero(t, stats.Count)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,57 @@
+package mutex
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrRateLimited is matched by the [[RateLimitError]] returned when
+// the mutex's acquisition rate limit is exceeded.
+var ErrRateLimited = errors.New("mutex acquisition rate limit exceeded")
+
+// RateLimitError is returned by [[Mutex.TryAcquire]] when the fleet-wide
+// acquisition rate limit of the mutex has been exceeded. See
+// [[Mutex.SetRateLimit]].
+type RateLimitError struct {
+	// RetryAfter is how long until the
+	// next attempt may be accepted.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrRateLimited, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// SetRateLimit limits the mutex to 'limit' acquisition attempts per
+// 'interval' across every client. Attempts are counted in the mutex's
+// subspace, protecting FDB from hot retry loops across the whole fleet,
+// unlike [[WithAcquireRateLimit]] which only limits a single handle. Once
+// the limit is reached, attempts by clients other than the owner fail with
+// a [[RateLimitError]] until the interval ends. [[Mutex.Acquire]] waits
+// out the interval instead of failing. Intervals are aligned to the unix
+// epoch & timed by the clocks of the clients. A zero limit removes it.
+func (x *Mutex) SetRateLimit(db fdb.Transactor, limit int64, interval time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	if limit < 0 {
+		return fmt.Errorf("rate limit must not be negative")
+	}
+	if limit > 0 && interval <= 0 {
+		return fmt.Errorf("rate limit interval must be positive")
+	}
+	return x.setRateLimit(x.withBreaker(db), limit, interval)
+}
+
+// RateLimit returns the limit set by [[Mutex.SetRateLimit]]. If
+// the mutex isn't rate limited, a zero limit is returned.
+func (x *Mutex) RateLimit(db fdb.Transactor) (_ int64, _ time.Duration, err error) {
+	defer wrapErr(&err)
+	return x.getRateLimit(x.withBreaker(db))
+}
@@ -0,0 +1,88 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := map[string]testFn{
+		"rejected": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			owner, err := NewMutex(db, root, "owner")
+			require.NoError(t, err)
+
+			err = owner.SetRateLimit(db, 3, time.Hour)
+			require.NoError(t, err)
+
+			limit, interval, err := owner.RateLimit(db)
+			require.NoError(t, err)
+			require.Equal(t, int64(3), limit)
+			require.Equal(t, time.Hour, interval)
+
+			acquired, err := owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Attempts are counted across every client.
+			for i := range 2 {
+				x, err := NewMutex(db, root, fmt.Sprintf("client%d", i))
+				require.NoError(t, err)
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.False(t, acquired)
+			}
+
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+			_, err = x.TryAcquire(db)
+			require.ErrorIs(t, err, ErrRateLimited)
+			require.True(t, IsRetryable(err))
+
+			var rerr *RateLimitError
+			require.True(t, errors.As(err, &rerr))
+			require.Greater(t, rerr.RetryAfter, time.Duration(0))
+			require.LessOrEqual(t, rerr.RetryAfter, time.Hour)
+
+			// The owner isn't limited.
+			acquired, err = owner.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = owner.SetRateLimit(db, 0, 0)
+			require.NoError(t, err)
+
+			acquired, err = x.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+		},
+		"acquire waits": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			err = x1.SetRateLimit(db, 1, 200*time.Millisecond)
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x1.Release(db))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			err = x2.Acquire(ctx, db)
+			require.NoError(t, err)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,371 @@
+package mutex
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// Record describes a single mutex operation and its outcome.
+// Records are captured by a [[Recorder]] for debugging.
+type Record struct {
+	// Mutex identifies the mutex. See [[ClientSession.Locks]].
+	Mutex string `json:"mutex"`
+
+	// Client is the name of the client which performed the operation.
+	Client string `json:"client"`
+
+	// Op names the operation: "Acquire", "TryAcquire", "Release",
+	// "TransferTo", "CommitRelease", or "Expire". Expire operations
+	// are performed by [[Mutex.AutoRelease]].
+	Op string `json:"op"`
+
+	// Target is the recipient of a TransferTo or CommitRelease,
+	// or the expired owner of an Expire.
+	Target string `json:"target,omitempty"`
+
+	// Start & End are when the operation was called & returned.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Version is the latest database version observed by the
+	// operation: the commit version of its latest write or the
+	// read version of its latest read. Versions order operations
+	// across clients without depending on their clocks.
+	Version int64 `json:"version"`
+
+	// Acquired is true if the operation acquired the mutex.
+	Acquired bool `json:"acquired,omitempty"`
+
+	// Epoch is the fencing epoch of the hold started by the
+	// operation. It's zero if the operation didn't acquire the
+	// mutex or the hold was lost before its epoch was read.
+	Epoch int64 `json:"epoch,omitempty"`
+
+	// Err is the error returned by the operation, if any.
+	Err string `json:"err,omitempty"`
+}
+
+// Recorder captures the operations of a mutex. See [[WithRecorder]].
+type Recorder interface {
+	Record(Record)
+}
+
+// WithRecorder causes the mutex to pass a [[Record]] of each of
+// its operations to the given recorder. The records of many clients
+// can be merged and passed to [[Replay]] to debug races in lock usage.
+func WithRecorder(r Recorder) Option {
+	return func(x *Mutex) {
+		x.recorder = r
+	}
+}
+
+// JSONRecorder writes records to a stream as JSON lines,
+// typically a local file. See [[ReadRecords]].
+type JSONRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONRecorder constructs a recorder which writes to 'w'.
+func NewJSONRecorder(w io.Writer) *JSONRecorder {
+	return &JSONRecorder{enc: json.NewEncoder(w)}
+}
+
+// Record writes a record as a single line of JSON.
+func (r *JSONRecorder) Record(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(rec); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// Err returns the first error encountered while writing.
+func (r *JSONRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// ReadRecords reads the records written by a [[JSONRecorder]].
+func ReadRecords(rd io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal record: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	return records, nil
+}
+
+// SubspaceRecorder stores records in a subspace, allowing the
+// records of many clients to be collected in one place.
+type SubspaceRecorder struct {
+	subspace.Subspace
+	db fdb.Transactor
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewSubspaceRecorder constructs a recorder which stores records in 'root'.
+// Each record is written in its own transaction using 'db'.
+func NewSubspaceRecorder(db fdb.Transactor, root subspace.Subspace) *SubspaceRecorder {
+	return &SubspaceRecorder{Subspace: root, db: db}
+}
+
+// Record stores a record.
+func (r *SubspaceRecorder) Record(rec Record) {
+	val, err := json.Marshal(rec)
+	if err == nil {
+		_, err = r.db.Transact(func(tr fdb.Transaction) (any, error) {
+			key, err := tuple.Tuple{rec.Version, tuple.IncompleteVersionstamp(0)}.PackWithVersionstamp(r.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			tr.SetVersionstampedKey(fdb.Key(key), val)
+			return nil, nil
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// Err returns the first error encountered while storing records.
+func (r *SubspaceRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Records returns the stored records in version order.
+func (r *SubspaceRecorder) Records(db fdb.Transactor) ([]Record, error) {
+	records, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var records []Record
+		iter := tr.GetRange(r, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			var rec Record
+			if err := json.Unmarshal(iter.MustGet().Value, &rec); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal record: %w", err)
+			}
+			records = append(records, rec)
+		}
+		return records, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records.([]Record), nil
+}
+
+// ReplayStep is a single step of a replay. See [[Replay]].
+type ReplayStep struct {
+	Record
+
+	// Owner is the owner of the record's mutex after the step,
+	// as implied by the records. It's blank if the mutex is free.
+	Owner string
+
+	// Conflict is true if the step acquired the mutex while
+	// the records imply another client owned it. This
+	// indicates a race in the lock's usage.
+	Conflict bool
+}
+
+// Replay reconstructs the interleaving of the given records, which may
+// come from many clients and mutexes. Records are ordered by version, then
+// by end time. For each step, the implied owner of the mutex is tracked so
+// overlapping holds can be spotted.
+func Replay(records []Record) []ReplayStep {
+	records = sortRecords(records)
+
+	owners := make(map[string]string)
+	steps := make([]ReplayStep, len(records))
+	for i, rec := range records {
+		step := ReplayStep{Record: rec}
+		owner := owners[rec.Mutex]
+
+		if rec.Err == "" {
+			switch {
+			case rec.Acquired:
+				step.Conflict = owner != "" && owner != rec.Client
+				owner = rec.Client
+
+			case rec.Op == "Release" && owner == rec.Client:
+				owner = ""
+
+			case (rec.Op == "TransferTo" || rec.Op == "CommitRelease") && owner == rec.Client:
+				owner = rec.Target
+
+			case rec.Op == "Expire" && owner == rec.Target:
+				owner = ""
+			}
+		}
+
+		owners[rec.Mutex] = owner
+		step.Owner = owner
+		steps[i] = step
+	}
+	return steps
+}
+
+// sortRecords returns a copy of the records ordered
+// by version, then by end time. See [[Replay]].
+func sortRecords(records []Record) []Record {
+	records = append([]Record(nil), records...)
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Version != records[j].Version {
+			return records[i].Version < records[j].Version
+		}
+		return records[i].End.Before(records[j].End)
+	})
+	return records
+}
+
+// recording captures a single operation of a mutex for the recorder
+// & the metrics hook. A nil recording ignores all method calls.
+type recording struct {
+	x       *Mutex
+	db      fdb.Transactor
+	op      string
+	start   time.Time
+	version atomic.Int64
+
+	// counter, if not nil, counts the operation's transactions
+	// for the metrics hook. See [[MetricsHook.Transactions]].
+	counter *attemptCounter
+}
+
+// startRecording begins recording an operation. The returned transactor
+// must be used for the operation's transactions so the latest version &
+// the transaction attempts can be captured. If the mutex doesn't have a
+// recorder & no metrics hook is set, the recording is nil and the
+// transactor is returned as is.
+func (x *Mutex) startRecording(op string, db fdb.Transactor) (*recording, fdb.Transactor) {
+	hooked := txnHook() != nil
+	if x.recorder == nil && !hooked {
+		return nil, db
+	}
+	r := &recording{x: x, db: db, op: op, start: time.Now()}
+	if hooked {
+		r.counter = &attemptCounter{Transactor: db}
+		db = r.counter
+	}
+	if x.recorder != nil {
+		db = recordingTransactor{Transactor: db, r: r}
+	}
+	return r, db
+}
+
+// finish passes the record of the operation to the recorder
+// and reports its transactions to the metrics hook.
+func (r *recording) finish(target string, acquired bool, err error) {
+	if r == nil {
+		return
+	}
+	r.report()
+	if r.x.recorder == nil {
+		return
+	}
+	rec := Record{
+		Mutex:    lockID(r.x.Subspace),
+		Client:   r.x.name,
+		Op:       r.op,
+		Target:   target,
+		Start:    r.start,
+		End:      time.Now(),
+		Version:  r.version.Load(),
+		Acquired: acquired,
+	}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	if acquired {
+		// Failing to read the epoch
+		// leaves it unknown.
+		rec.Epoch, _, _ = r.x.getHoldEpoch(r.db, r.x.name)
+	}
+	r.x.recorder.Record(rec)
+}
+
+// report passes the operation's transaction counts to the metrics
+// hook without recording the operation. See [[recording.finish]].
+func (r *recording) report() {
+	if r == nil || r.counter == nil {
+		return
+	}
+	r.x.reportTxns(r.op, r.counter)
+}
+
+// observe updates the recording's version if the given version is later.
+func (r *recording) observe(version int64) {
+	for {
+		cur := r.version.Load()
+		if version <= cur || r.version.CompareAndSwap(cur, version) {
+			return
+		}
+	}
+}
+
+// recordingTransactor captures the versions of the
+// transactions performed during a [[recording]].
+type recordingTransactor struct {
+	fdb.Transactor
+	r *recording
+}
+
+func (t recordingTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	var rv fdb.FutureInt64
+	var vs fdb.FutureKey
+	ret, err := t.Transactor.Transact(func(tr fdb.Transaction) (any, error) {
+		rv = tr.GetReadVersion()
+		vs = tr.GetVersionstamp()
+		return f(tr)
+	})
+	if err == nil {
+		// Read-only transactions don't have a versionstamp.
+		if stamp, err := vs.Get(); err == nil && len(stamp) >= 8 {
+			t.r.observe(int64(binary.BigEndian.Uint64(stamp[:8])))
+		} else if version, err := rv.Get(); err == nil {
+			t.r.observe(version)
+		}
+	}
+	return ret, err
+}
+
+func (t recordingTransactor) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	var rv fdb.FutureInt64
+	ret, err := t.Transactor.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		rv = tr.GetReadVersion()
+		return f(tr)
+	})
+	if err == nil {
+		if version, err := rv.Get(); err == nil {
+			t.r.observe(version)
+		}
+	}
+	return ret, err
+}
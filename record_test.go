@@ -0,0 +1,94 @@
+package mutex
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	tests := map[string]testFn{
+		"json": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			var buf bytes.Buffer
+			rec := NewJSONRecorder(&buf)
+
+			x1, err := NewMutex(db, root, "client1", WithRecorder(rec))
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2", WithRecorder(rec))
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			err = x1.Release(db)
+			require.NoError(t, err)
+			require.NoError(t, rec.Err())
+
+			records, err := ReadRecords(&buf)
+			require.NoError(t, err)
+			require.Len(t, records, 3)
+
+			steps := Replay(records)
+			require.Equal(t, "TryAcquire", steps[0].Op)
+			require.Equal(t, "client1", steps[0].Owner)
+			require.Equal(t, "TryAcquire", steps[1].Op)
+			require.Equal(t, "client1", steps[1].Owner)
+			require.Equal(t, "Release", steps[2].Op)
+			require.Empty(t, steps[2].Owner)
+
+			for _, step := range steps {
+				require.False(t, step.Conflict)
+				require.NotZero(t, step.Version)
+			}
+		},
+		"subspace": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			rec := NewSubspaceRecorder(db, root.Sub("records"))
+
+			x, err := NewMutex(db, root.Sub("mutex"), "client", WithRecorder(rec))
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = x.Release(db)
+			require.NoError(t, err)
+			require.NoError(t, rec.Err())
+
+			records, err := rec.Records(db)
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			require.True(t, records[0].Acquired)
+			require.Equal(t, "Release", records[1].Op)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestReplay(t *testing.T) {
+	steps := Replay([]Record{
+		{Mutex: "m", Client: "b", Op: "Acquire", Version: 3, Acquired: true},
+		{Mutex: "m", Client: "a", Op: "Acquire", Version: 1, Acquired: true},
+		{Mutex: "m", Client: "a", Op: "Release", Version: 4},
+	})
+
+	require.Equal(t, "a", steps[0].Client)
+	require.False(t, steps[0].Conflict)
+
+	// Client b acquired before client a released.
+	require.Equal(t, "b", steps[1].Client)
+	require.True(t, steps[1].Conflict)
+	require.Equal(t, "b", steps[1].Owner)
+
+	require.Equal(t, "b", steps[2].Owner)
+}
@@ -0,0 +1,151 @@
+package mutex
+
+import (
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+)
+
+// MutexInfo describes a mutex found by [[List]].
+type MutexInfo struct {
+	// Path is the directory path of the mutex.
+	Path []string
+
+	// Owner is the name of the client holding the
+	// mutex. It's blank if the mutex is free.
+	Owner string
+
+	// Labels are the labels attached to the mutex.
+	Labels map[string]string
+
+	// Metadata describes the creation of the mutex. It's
+	// zero if the mutex was created before metadata was
+	// recorded. See [[Mutex.Metadata]].
+	Metadata Metadata
+
+	// SchemaVersion is the schema version the mutex is marked
+	// with. It's zero if the mutex hasn't been opened since
+	// markers were introduced. See [[SchemaVersion]].
+	SchemaVersion int
+}
+
+// List returns the mutexes stored in the immediate subdirectories of 'parent'.
+// Subdirectories which don't contain a mutex are skipped, including those
+// marked as another kind of primitive. See [[IsMutex]]. If 'filter' is not
+// empty then only mutexes whose labels include every key/value pair in
+// 'filter' are returned.
+func List(db fdb.Transactor, parent directory.Directory, filter map[string]string) (_ []MutexInfo, err error) {
+	defer wrapErr(&err)
+
+	list, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		names, err := parent.List(tr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subdirectories: %w", err)
+		}
+
+		var list []MutexInfo
+		for _, name := range names {
+			info, ok, err := readInfo(tr, parent, name, filter)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				list = append(list, info)
+			}
+		}
+		return list, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list.([]MutexInfo), nil
+}
+
+// LocksHeldBy returns the mutexes stored in the immediate subdirectories of
+// 'parent' which are currently held by the client with the provided name.
+// Like [[List]], the mutexes are read in a single transaction, so the result
+// is a consistent snapshot. This is useful for scoping the impact of a
+// misbehaving client. See [[ReleaseAllOwnedBy]].
+func LocksHeldBy(db fdb.Transactor, parent directory.Directory, name string) (_ []MutexInfo, err error) {
+	defer wrapErr(&err)
+
+	if name == "" {
+		return nil, fmt.Errorf("client name is empty")
+	}
+
+	list, err := List(db, parent, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var held []MutexInfo
+	for _, info := range list {
+		if info.Owner == name {
+			held = append(held, info)
+		}
+	}
+	return held, nil
+}
+
+// readInfo describes the mutex stored in the subdirectory of 'parent' with
+// the given name. If the subdirectory doesn't contain a mutex or its labels
+// don't match the filter, false is returned. See [[List]].
+func readInfo(tr fdb.Transaction, parent directory.Directory, name string, filter map[string]string) (MutexInfo, bool, error) {
+	dir, err := parent.Open(tr, []string{name}, nil)
+	if err != nil {
+		return MutexInfo{}, false, fmt.Errorf("failed to open subdirectory %s: %w", name, err)
+	}
+
+	x := kv{Subspace: dir}
+	isMutex, err := x.isMutex(tr)
+	if err != nil {
+		return MutexInfo{}, false, fmt.Errorf("failed to check for mutex in %s: %w", name, err)
+	}
+	if !isMutex {
+		return MutexInfo{}, false, nil
+	}
+
+	labels, err := x.getLabels(tr)
+	if err != nil {
+		return MutexInfo{}, false, fmt.Errorf("failed to get labels of %s: %w", name, err)
+	}
+	if !matchLabels(labels, filter) {
+		return MutexInfo{}, false, nil
+	}
+
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return MutexInfo{}, false, fmt.Errorf("failed to get owner of %s: %w", name, err)
+	}
+
+	meta, _, err := x.getMetadata(tr)
+	if err != nil {
+		return MutexInfo{}, false, fmt.Errorf("failed to get metadata of %s: %w", name, err)
+	}
+
+	version, _, err := x.getSchemaVersion(tr)
+	if err != nil {
+		return MutexInfo{}, false, fmt.Errorf("failed to get schema version of %s: %w", name, err)
+	}
+
+	return MutexInfo{
+		Path:     dir.GetPath(),
+		Owner:    owner.name,
+		Labels:   labels,
+		Metadata: toMetadata(meta),
+
+		SchemaVersion: version,
+	}, true, nil
+}
+
+// matchLabels returns true if 'labels' contains every
+// key/value pair in 'filter'.
+func matchLabels(labels, filter map[string]string) bool {
+	for key, val := range filter {
+		if v, ok := labels[key]; !ok || v != val {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package mutex
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	tests := map[string]testFn{
+		"empty": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			list, err := List(db, root.(directory.Directory), nil)
+			require.NoError(t, err)
+			require.Empty(t, list)
+		},
+		"labels": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+
+			// This directory doesn't contain a mutex.
+			_, err = parent.CreateOrOpen(db, []string{"c"}, nil)
+			require.NoError(t, err)
+
+			xA, err := NewMutex(db, dirA, "clientA")
+			require.NoError(t, err)
+
+			err = xA.SetLabels(db, map[string]string{"team": "red", "env": "prod"})
+			require.NoError(t, err)
+
+			xB, err := NewMutex(db, dirB, "clientB")
+			require.NoError(t, err)
+
+			err = xB.SetLabels(db, map[string]string{"team": "blue", "env": "prod"})
+			require.NoError(t, err)
+
+			labels, err := xA.Labels(db)
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{"team": "red", "env": "prod"}, labels)
+
+			list, err := List(db, parent, nil)
+			require.NoError(t, err)
+			require.Len(t, list, 2)
+
+			list, err = List(db, parent, map[string]string{"env": "prod"})
+			require.NoError(t, err)
+			require.Len(t, list, 2)
+
+			list, err = List(db, parent, map[string]string{"team": "blue"})
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			require.Equal(t, dirB.GetPath(), list[0].Path)
+
+			list, err = List(db, parent, map[string]string{"team": "green"})
+			require.NoError(t, err)
+			require.Empty(t, list)
+		},
+		"other type": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+
+			_, err = NewMutex(db, dirA, "client")
+			require.NoError(t, err)
+
+			// Directory 'b' is marked as another kind of primitive.
+			x := kv{Subspace: dirB}
+			require.NoError(t, x.claimType(db, "other"))
+
+			list, err := List(db, parent, nil)
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			require.Equal(t, dirA.GetPath(), list[0].Path)
+			require.Equal(t, SchemaVersion, list[0].SchemaVersion)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestLocksHeldBy(t *testing.T) {
+	tests := map[string]testFn{
+		"owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			parent := root.(directory.Directory)
+
+			dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+			require.NoError(t, err)
+			dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+			require.NoError(t, err)
+			dirC, err := parent.CreateOrOpen(db, []string{"c"}, nil)
+			require.NoError(t, err)
+
+			xA, err := NewMutex(db, dirA, "client1")
+			require.NoError(t, err)
+			xB, err := NewMutex(db, dirB, "client2")
+			require.NoError(t, err)
+			xC, err := NewMutex(db, dirC, "client1")
+			require.NoError(t, err)
+
+			for _, x := range []*Mutex{xA, xB, xC} {
+				acquired, err := x.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+			}
+
+			held, err := LocksHeldBy(db, parent, "client1")
+			require.NoError(t, err)
+			require.Len(t, held, 2)
+			require.Equal(t, dirA.GetPath(), held[0].Path)
+			require.Equal(t, dirC.GetPath(), held[1].Path)
+
+			require.NoError(t, xC.Release(db))
+
+			held, err = LocksHeldBy(db, parent, "client1")
+			require.NoError(t, err)
+			require.Len(t, held, 1)
+			require.Equal(t, dirA.GetPath(), held[0].Path)
+
+			held, err = LocksHeldBy(db, parent, "client3")
+			require.NoError(t, err)
+			require.Empty(t, held)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,66 @@
+package mutex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ReserveSlot takes a place at the back of the queue on behalf of an
+// acquisition planned for later. While reserved, the place is passed over
+// when the mutex is handed out, so it keeps its position as the queue moves.
+// When this client calls [[Mutex.Acquire]] or [[Mutex.TryAcquire]], the
+// place becomes eligible, so a planned operation starts near the head of the
+// queue. If the reservation isn't used within 'ttl', it's dropped and later
+// acquisitions join the back of the queue. If this client already owns the
+// mutex or is waiting in the queue, this method has no effect.
+func (x *Mutex) ReserveSlot(db fdb.Transactor, ttl time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	if ttl <= 0 {
+		return fmt.Errorf("reservation ttl must be positive")
+	}
+
+	_, err = x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		owner, err := x.getOwner(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name == x.name {
+			return nil, nil
+		}
+
+		queued, err := x.isQueued(tr, x.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check queue: %w", err)
+		}
+		if queued {
+			return nil, nil
+		}
+
+		if err := x.enqueue(tr, x.name, x.priority); err != nil {
+			return nil, fmt.Errorf("failed to enqueue: %w", err)
+		}
+		return nil, x.setReservation(tr, x.name, time.Now().Add(ttl))
+	})
+	return err
+}
+
+// CancelReservation gives up the place reserved by [[Mutex.ReserveSlot]].
+// If this client has no reservation, this method has no effect.
+func (x *Mutex) CancelReservation(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	_, err = x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		reserved, err := x.getReservations(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reservations: %w", err)
+		}
+		if _, ok := reserved[x.name]; !ok {
+			return nil, nil
+		}
+		return nil, x.removeFromQueue(tr, x.name)
+	})
+	return err
+}
@@ -0,0 +1,120 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveSlot(t *testing.T) {
+	tests := map[string]testFn{
+		"promoted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			a, err := NewMutex(db, root, "a")
+			require.NoError(t, err)
+			b, err := NewMutex(db, root, "b")
+			require.NoError(t, err)
+			c, err := NewMutex(db, root, "c")
+			require.NoError(t, err)
+			d, err := NewMutex(db, root, "d")
+			require.NoError(t, err)
+
+			acquired, err := a.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = b.ReserveSlot(db, time.Minute)
+			require.NoError(t, err)
+
+			acquired, err = c.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// The dormant reservation is passed over.
+			err = a.Release(db)
+			require.NoError(t, err)
+			owner, err := a.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "c", owner.name)
+
+			acquired, err = d.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			// Once b starts acquiring, it's ahead of d.
+			acquired, err = b.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			err = c.Release(db)
+			require.NoError(t, err)
+			owner, err = a.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "b", owner.name)
+		},
+		"expired": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			a, err := NewMutex(db, root, "a")
+			require.NoError(t, err)
+			b, err := NewMutex(db, root, "b")
+			require.NoError(t, err)
+			c, err := NewMutex(db, root, "c")
+			require.NoError(t, err)
+
+			acquired, err := a.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = b.ReserveSlot(db, time.Millisecond)
+			require.NoError(t, err)
+
+			acquired, err = c.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+			time.Sleep(10 * time.Millisecond)
+
+			// The expired reservation joins the back of the queue.
+			acquired, err = b.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			err = a.Release(db)
+			require.NoError(t, err)
+			owner, err := a.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "c", owner.name)
+
+			queue, err := a.getQueue(db)
+			require.NoError(t, err)
+			require.Len(t, queue, 1)
+			require.Equal(t, "b", queue[0].name)
+		},
+		"cancel": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			a, err := NewMutex(db, root, "a")
+			require.NoError(t, err)
+			b, err := NewMutex(db, root, "b")
+			require.NoError(t, err)
+
+			acquired, err := a.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = b.ReserveSlot(db, time.Minute)
+			require.NoError(t, err)
+
+			err = b.CancelReservation(db)
+			require.NoError(t, err)
+
+			queue, err := a.getQueue(db)
+			require.NoError(t, err)
+			require.Empty(t, queue)
+
+			reserved, err := a.getReservations(db)
+			require.NoError(t, err)
+			require.Empty(t, reserved)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,111 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// RestartCoordinator lets a fleet of instances restart in a rolling fashion,
+// with at most a fixed number of instances restarting at any time. Before
+// restarting, an instance holds one of the coordinator's restart slots,
+// which are the slots of a [[Pool]]. The slot stays held while the instance
+// is down and is released once the restarted instance signals readiness,
+// letting the next instance restart.
+//
+// The instance name identifies the instance across its restart, so it must
+// be stable & unique within the fleet, such as the hostname. The slots are
+// held while their owners aren't heartbeating, so they mustn't be expired
+// by [[Mutex.AutoRelease]] with a max age shorter than the longest restart.
+// If an instance never comes back, its slot is freed by constructing a
+// coordinator with its name & calling [[RestartCoordinator.Ready]].
+type RestartCoordinator struct {
+	pool *Pool
+}
+
+// NewRestartCoordinator constructs a coordinator allowing 'concurrency'
+// instances to restart at once. The slots are stored as described by
+// [[NewPool]]. 'name' identifies this instance and must not be empty.
+func NewRestartCoordinator(db fdb.Transactor, root subspace.Subspace, name string, concurrency int, opts ...Option) (*RestartCoordinator, error) {
+	if name == "" {
+		return nil, errors.New("instance name must not be empty")
+	}
+	pool, err := NewPool(db, root, name, concurrency, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &RestartCoordinator{pool: pool}, nil
+}
+
+// BeginRestart blocks until this instance holds a restart slot, after which
+// the instance may restart. The index of the slot is returned. If the
+// instance already holds a slot, such as when it restarted before
+// signaling readiness, its index is returned immediately.
+func (c *RestartCoordinator) BeginRestart(ctx context.Context, db fdb.Transactor) (_ int, err error) {
+	defer wrapErr(&err)
+
+	slot, ok, err := c.heldSlot(db)
+	if err != nil {
+		return -1, err
+	}
+	if ok {
+		return slot, nil
+	}
+	return c.pool.AcquireAny(ctx, db)
+}
+
+// Ready signals that this instance has restarted and is serving again,
+// releasing its restart slot. It's called by the restarted process, which
+// doesn't share any state with the process which began the restart. If
+// the instance doesn't hold a slot, this method is a noop.
+func (c *RestartCoordinator) Ready(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	if err := c.pool.Release(db); err != nil {
+		return err
+	}
+	slot, ok, err := c.heldSlot(db)
+	if err != nil || !ok {
+		return err
+	}
+	return c.pool.Slot(slot).Release(db)
+}
+
+// Restarting returns the names of the instances
+// which currently hold a restart slot.
+func (c *RestartCoordinator) Restarting(db fdb.Transactor) (_ []string, err error) {
+	defer wrapErr(&err)
+
+	var names []string
+	for i := range c.pool.Size() {
+		owner, err := c.pool.Slot(i).getOwner(db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner of slot %d: %w", i, err)
+		}
+		if owner.name != "" {
+			names = append(names, owner.name)
+		}
+	}
+	return names, nil
+}
+
+// heldSlot returns the index of the slot owned by this instance. Unlike
+// [[Pool.Held]], this reads the owners from FDB so a slot held by the
+// process which began the restart is found. If no slot is held,
+// false is returned.
+func (c *RestartCoordinator) heldSlot(db fdb.Transactor) (int, bool, error) {
+	for i := range c.pool.Size() {
+		x := c.pool.Slot(i)
+		owner, err := x.getOwner(db)
+		if err != nil {
+			return -1, false, fmt.Errorf("failed to get owner of slot %d: %w", i, err)
+		}
+		if owner.name == x.name {
+			return i, true, nil
+		}
+	}
+	return -1, false, nil
+}
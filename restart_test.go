@@ -0,0 +1,71 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartCoordinator(t *testing.T) {
+	tests := map[string]testFn{
+		"rolling": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewRestartCoordinator(db, root, "", 1)
+			require.Error(t, err)
+
+			a, err := NewRestartCoordinator(db, root, "a", 1)
+			require.NoError(t, err)
+			b, err := NewRestartCoordinator(db, root, "b", 1)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			slot, err := a.BeginRestart(ctx, db)
+			require.NoError(t, err)
+			require.Equal(t, 0, slot)
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := b.BeginRestart(ctx, db)
+				done <- err
+			}()
+
+			select {
+			case err := <-done:
+				t.Fatalf("restarted concurrently: %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			restarting, err := a.Restarting(db)
+			require.NoError(t, err)
+			require.Equal(t, []string{"a"}, restarting)
+
+			// The restarted process shares no state with
+			// the process which began the restart.
+			restarted, err := NewRestartCoordinator(db, root, "a", 1)
+			require.NoError(t, err)
+
+			slot, err = restarted.BeginRestart(ctx, db)
+			require.NoError(t, err)
+			require.Equal(t, 0, slot)
+
+			require.NoError(t, restarted.Ready(db))
+			require.NoError(t, <-done)
+
+			restarting, err = a.Restarting(db)
+			require.NoError(t, err)
+			require.Equal(t, []string{"b"}, restarting)
+
+			require.NoError(t, b.Ready(db))
+			restarting, err = a.Restarting(db)
+			require.NoError(t, err)
+			require.Empty(t, restarting)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,216 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// Turn describes the current hold of a mutex which rotates
+// among a set of clients. See [[Mutex.Rotate]].
+type Turn struct {
+	// Owner is the name of the client whose turn it is.
+	// If the mutex is free, it's blank.
+	Owner string
+
+	// Epoch is the fencing epoch of the turn. Each turn
+	// given to a different client starts a new epoch, so
+	// writes made on behalf of an earlier turn can be
+	// rejected by comparing epochs. When the only member
+	// gets another turn, it keeps its epoch.
+	Epoch int64
+
+	// Started is when the turn started, according to the
+	// clock of the client which started it.
+	Started time.Time
+}
+
+// JoinRotation adds this client to the set of clients the mutex
+// rotates among. See [[Mutex.Rotate]].
+func (x *Mutex) JoinRotation(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	return x.setRotation(x.withBreaker(db), x.name, true)
+}
+
+// LeaveRotation removes this client from the set of clients the mutex
+// rotates among. If it's this client's turn, the turn isn't cut short.
+func (x *Mutex) LeaveRotation(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	return x.setRotation(x.withBreaker(db), x.name, false)
+}
+
+// CurrentTurn returns the current hold of the mutex.
+func (x *Mutex) CurrentTurn(db fdb.Transactor) (_ Turn, err error) {
+	defer wrapErr(&err)
+
+	turn, err := x.withBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		return x.getTurn(tr)
+	})
+	if err != nil {
+		return Turn{}, err
+	}
+	return turn.(Turn), nil
+}
+
+// Rotate cycles ownership of the mutex among the clients which joined the
+// rotation, giving each a turn of length 'period' in order of their names.
+// This allows fair sharing of a resource, such as a rate-limited external
+// API. Each turn given to a different member starts a new fencing epoch.
+// See [[Mutex.CurrentTurn]].
+//
+// When a turn ends, the mutex is handed to the next member, bypassing the
+// queue. If the owner releases the mutex early, the next turn starts right
+// away. Members should wait for their turn with [[Mutex.AcquireGuard]] so
+// they heartbeat while it lasts. When the turn ends, the heartbeat notices
+// the loss and the guard's context is cancelled with [[ErrLockLost]].
+//
+// Like [[Mutex.AutoRelease]], any client may drive the rotation and several
+// instances may be run. This method runs until the context ends or an error
+// occurs. Turns are timed by the clocks of the clients running it.
+func (x *Mutex) Rotate(ctx context.Context, db fdb.Transactor, period time.Duration) (err error) {
+	defer wrapErr(&err)
+	db = x.withBreaker(db)
+
+	for {
+		// Watch the owner key before rotating so
+		// an early release isn't missed.
+		watchCtx, cancel := context.WithCancel(ctx)
+		watch := x.watchOwner(watchCtx, db)
+
+		started, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+			return x.rotate(tr, period)
+		})
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to rotate: %w", err)
+		}
+
+		// Without members, the rotation is checked
+		// again each period or when the owner changes.
+		wake := period
+		if t := started.(time.Time); !t.IsZero() {
+			wake = time.Until(t.Add(period))
+		}
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			return ctx.Err()
+		case err := <-watch:
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("failed to watch owner: %w", err)
+			}
+		case <-time.After(wake):
+			cancel()
+		}
+	}
+}
+
+// rotate hands the mutex to the next member of the rotation if the current
+// turn is over or the mutex is free. The start of the current turn is
+// returned. If the rotation has no members, the zero time is returned.
+func (x *Mutex) rotate(tr fdb.Transaction, period time.Duration) (time.Time, error) {
+	turn, err := x.getTurn(tr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if turn.Owner != "" && time.Since(turn.Started) < period {
+		return turn.Started, nil
+	}
+
+	members, err := x.getRotation(tr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get rotation: %w", err)
+	}
+	if len(members) == 0 {
+		return time.Time{}, nil
+	}
+
+	// The next member is the first whose name sorts after the
+	// owner's, wrapping around. This works even if the owner
+	// isn't a member.
+	i, found := slices.BinarySearch(members, turn.Owner)
+	if found {
+		i++
+	}
+	next := members[i%len(members)]
+
+	if next == turn.Owner {
+		// The only member gets another turn. Restart the turn,
+		// but keep the hold & its fencing epoch, so the owner's
+		// token stays valid.
+		if err := x.recordHandoff(tr, turn.Owner, next); err != nil {
+			return time.Time{}, fmt.Errorf("failed to record handoff: %w", err)
+		}
+		return time.Now(), nil
+	}
+
+	if err := x.rotateTo(tr, turn.Owner, next); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now(), nil
+}
+
+// rotateTo makes the client 'next' the owner of the mutex in place of 'prev',
+// bypassing the queue. Either name may be blank. See [[Mutex.handOver]].
+func (x *Mutex) rotateTo(tr fdb.Transaction, prev, next string) error {
+	if err := x.removeFromQueue(tr, next); err != nil {
+		return fmt.Errorf("failed to remove member from queue: %w", err)
+	}
+	if prev != "" {
+		if err := x.logEvent(tr, EventReleased, prev); err != nil {
+			return fmt.Errorf("failed to log event: %w", err)
+		}
+	}
+	if err := x.setOwner(tr, next); err != nil {
+		return fmt.Errorf("failed to set owner: %w", err)
+	}
+
+	if x.clients != nil {
+		id := lockID(x.Subspace)
+		if prev != "" {
+			if err := x.clients.removeLock(tr, prev, id); err != nil {
+				return fmt.Errorf("failed to unregister lock: %w", err)
+			}
+		}
+		if err := x.clients.addLock(tr, next, id); err != nil {
+			return fmt.Errorf("failed to register lock: %w", err)
+		}
+	}
+	return nil
+}
+
+// getTurn returns the current owner, fencing epoch, & the
+// time the owner acquired the mutex.
+func (x *kv) getTurn(tr fdb.Transaction) (Turn, error) {
+	owner, err := x.getOwner(tr)
+	if err != nil {
+		return Turn{}, fmt.Errorf("failed to get owner: %w", err)
+	}
+	epoch, err := x.getEpoch(tr)
+	if err != nil {
+		return Turn{}, fmt.Errorf("failed to get epoch: %w", err)
+	}
+
+	turn := Turn{Owner: owner.name, Epoch: epoch}
+	if owner.name == "" {
+		return turn, nil
+	}
+	val, err := tr.Get(x.packOwnerSinceKey()).Get()
+	if err != nil {
+		return Turn{}, fmt.Errorf("failed to get owner since: %w", err)
+	}
+	if val != nil {
+		if turn.Started, err = x.unpackTimeValue(val); err != nil {
+			return Turn{}, fmt.Errorf("failed to unpack owner since: %w", err)
+		}
+	}
+	return turn, nil
+}
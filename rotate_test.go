@@ -0,0 +1,98 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotate(t *testing.T) {
+	tests := map[string]testFn{
+		"rotate": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "a")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "b")
+			require.NoError(t, err)
+			require.NoError(t, x1.JoinRotation(db))
+			require.NoError(t, x2.JoinRotation(db))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			const period = 300 * time.Millisecond
+			done := make(chan error, 1)
+			go func() { done <- x1.Rotate(ctx, db, period) }()
+
+			turnOf := func(name string) Turn {
+				var turn Turn
+				require.Eventually(t, func() bool {
+					turn, err = x1.CurrentTurn(db)
+					require.NoError(t, err)
+					return turn.Owner == name
+				}, 2*period, 10*time.Millisecond)
+				return turn
+			}
+
+			// Turns follow the order of the names
+			// and each starts a new fencing epoch.
+			a := turnOf("a")
+			b := turnOf("b")
+			require.Greater(t, b.Epoch, a.Epoch)
+			a = turnOf("a")
+			require.Greater(t, a.Epoch, b.Epoch)
+
+			// Releasing early starts the next turn.
+			require.NoError(t, x2.LeaveRotation(db))
+			g, err := x1.AcquireGuard(ctx, db)
+			require.NoError(t, err)
+			require.NoError(t, g.Release(db))
+			turnOf("a")
+
+			cancel()
+			require.ErrorIs(t, <-done, context.Canceled)
+		},
+		"only member": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "a")
+			require.NoError(t, err)
+			require.NoError(t, x.JoinRotation(db))
+
+			g, err := x.AcquireGuard(context.Background(), db)
+			require.NoError(t, err)
+			defer func() { _ = g.Release(db) }()
+			first, err := x.CurrentTurn(db)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			const period = 100 * time.Millisecond
+			done := make(chan error, 1)
+			go func() { done <- x.Rotate(ctx, db, period) }()
+
+			// The member gets another turn, which restarts
+			// the turn but keeps the hold & its epoch.
+			var turn Turn
+			require.Eventually(t, func() bool {
+				turn, err = x.CurrentTurn(db)
+				require.NoError(t, err)
+				return turn.Started.After(first.Started)
+			}, 5*period, 10*time.Millisecond)
+			require.Equal(t, "a", turn.Owner)
+			require.Equal(t, first.Epoch, turn.Epoch)
+
+			token, err := x.getToken(db)
+			require.NoError(t, err)
+			require.Equal(t, token, g.FencingToken())
+			require.NoError(t, g.Context().Err())
+
+			cancel()
+			require.ErrorIs(t, <-done, context.Canceled)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,344 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// ErrReadHeld is returned by [[RWMutex.Lock]] when the client holds the
+// mutex for reading. Upgrading a read hold could deadlock with another
+// reader doing the same, so the read hold must be released first.
+var ErrReadHeld = errors.New("mutex is held for reading")
+
+// RWMutex is a distributed reader/writer mutex. Any number of clients may
+// hold it for reading, or a single client may hold it for writing. Writers
+// use an ordinary [[Mutex]] stored in the same subspace, so the writer side
+// has a queue & heartbeat, can be observed & administered like any other
+// mutex, and dead writers are expired by [[Mutex.AutoRelease]]. Readers are
+// registered alongside it and heartbeat on their own.
+//
+// Writers take precedence: while a writer holds or waits for the mutex, new
+// readers wait, so a steady stream of readers can't starve writers. A reader
+// whose heartbeat is older than the lease TTL is expired by the next writer.
+// See [[WithLeaseTTL]]. By default, readers heartbeat every second and are
+// expired after 4 seconds. Like a writer, a reader assumes its hold is lost
+// once it's expired, its heartbeat exhausts the error budget, or the
+// fail-safe duration passes without a heartbeat. See [[RWMutex.RLockContext]],
+// [[WithHeartbeatErrorBudget]], & [[WithFailSafe]]. The mutex must only be
+// locked through RWMutex handles, as a plain Mutex handle ignores the readers.
+type RWMutex struct {
+	x *Mutex
+
+	// mu protects the read hold, which is
+	// nil when not held for reading.
+	mu   sync.Mutex
+	read *readHold
+}
+
+// readHold is a hold of an [[RWMutex]] for reading.
+type readHold struct {
+	// ctx is cancelled when the hold ends. See
+	// [[RWMutex.RLockContext]].
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	// stop is closed when the hold ends, stopping
+	// the heartbeat & the fail-safe watchdog.
+	stop chan struct{}
+
+	// lastBeat is the time of the latest successful
+	// heartbeat, stored as unix nanoseconds.
+	lastBeat atomic.Int64
+}
+
+// NewRWMutex constructs a distributed reader/writer mutex stored in 'root'.
+// The name & options are those of the writer's mutex. See [[NewMutex]].
+func NewRWMutex(db fdb.Transactor, root subspace.Subspace, name string, opts ...Option) (*RWMutex, error) {
+	x, err := NewMutex(db, root, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &RWMutex{x: x}, nil
+}
+
+// Mutex returns the mutex used by writers.
+func (rw *RWMutex) Mutex() *Mutex {
+	return rw.x
+}
+
+// Lock blocks until this client holds the mutex for writing, or the context
+// is cancelled. The client first acquires the writer's mutex, which blocks
+// new readers, and then waits for the current readers to release the mutex
+// or expire. If the client holds the mutex for reading, [[ErrReadHeld]] is
+// returned.
+func (rw *RWMutex) Lock(ctx context.Context, db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	if rw.readHeld() {
+		return ErrReadHeld
+	}
+	if err := rw.x.Acquire(ctx, db); err != nil {
+		return err
+	}
+	if err := rw.waitForReaders(ctx, rw.x.withBreaker(db)); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return errors.Join(err, rw.x.Release(db))
+	}
+	return nil
+}
+
+// Unlock releases the mutex held for writing. If
+// the client doesn't hold it, this method is a noop.
+func (rw *RWMutex) Unlock(db fdb.Transactor) error {
+	return rw.x.Release(db)
+}
+
+// RLock blocks until this client holds the mutex for reading, or the context
+// is cancelled. While held, the client heartbeats until [[RWMutex.RUnlock]] is
+// called. If the client already holds the mutex for reading, this method
+// returns immediately. A client holding the mutex for writing may also hold it
+// for reading, which allows a writer to downgrade its hold without letting
+// another writer in.
+func (rw *RWMutex) RLock(ctx context.Context, db fdb.Transactor) error {
+	_, err := rw.RLockContext(ctx, db)
+	return err
+}
+
+// RLockContext is like [[RWMutex.RLock]] but returns a context which is
+// cancelled when the read hold ends. The context derives from 'ctx'. If the
+// hold is lost rather than unlocked, [[context.Cause]] returns [[ErrLockLost]].
+// If the client already holds the mutex for reading, the context of the
+// existing hold is returned.
+func (rw *RWMutex) RLockContext(ctx context.Context, db fdb.Transactor) (_ context.Context, err error) {
+	defer wrapErr(&err)
+	db = rw.x.withBreaker(db)
+
+	// The lock isn't held while waiting so the hold
+	// may be inspected or released in the meantime.
+	if h := rw.readHold(); h != nil {
+		return h.ctx, nil
+	}
+	for {
+		// The watch is set up in the same transaction which
+		// checks for writers, so a writer leaving after the
+		// check isn't missed.
+		var acquired bool
+		watchCtx, cancel := context.WithCancel(ctx)
+		watch := watch(watchCtx, db, func(tr fdb.Transaction) (fdb.Key, error) {
+			acquired = false
+			owner, err := rw.x.getOwner(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get owner: %w", err)
+			}
+			if owner.name != "" && owner.name != rw.x.name {
+				return rw.x.packOwnerKey(owner.name), nil
+			}
+			if owner.name == "" {
+				next, err := rw.x.peekQueue(tr)
+				if err != nil {
+					return nil, fmt.Errorf("failed to peek queue: %w", err)
+				}
+				if next != "" {
+					return rw.x.packQueueVersionKey(), nil
+				}
+			}
+			if err := rw.x.addReader(tr, rw.x.name); err != nil {
+				return nil, fmt.Errorf("failed to add reader: %w", err)
+			}
+			acquired = true
+			return nil, nil
+		})
+
+		err := <-watch
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to watch writers: %w", err)
+		}
+		if acquired {
+			return rw.hold(ctx, db), nil
+		}
+	}
+}
+
+// RUnlock releases the mutex held for reading. If
+// the client doesn't hold it, this method is a noop.
+func (rw *RWMutex) RUnlock(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	h := rw.read
+	if h == nil {
+		return nil
+	}
+	rw.read = nil
+	close(h.stop)
+	h.cancel(nil)
+	return rw.x.removeReader(rw.x.withBreaker(db), rw.x.name)
+}
+
+// Readers returns the names of the clients holding the mutex for
+// reading, including readers which have stopped heartbeating but
+// haven't been expired yet.
+func (rw *RWMutex) Readers(db fdb.Transactor) (_ []string, err error) {
+	defer wrapErr(&err)
+
+	readers, err := rw.x.getReaders(rw.x.withBreaker(db), 0)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(readers))
+	for i, r := range readers {
+		names[i] = r.name
+	}
+	return names, nil
+}
+
+// readHold returns the read hold, or nil if
+// the client doesn't hold the mutex for reading.
+func (rw *RWMutex) readHold() *readHold {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.read
+}
+
+// readHeld returns true if the client holds the mutex for reading.
+func (rw *RWMutex) readHeld() bool {
+	return rw.readHold() != nil
+}
+
+// hold records the read hold once the client is registered as a reader,
+// and starts its heartbeat & fail-safe watchdog. If another goroutine of
+// this handle recorded a hold in the meantime, that hold is shared.
+func (rw *RWMutex) hold(ctx context.Context, db fdb.Transactor) context.Context {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.read != nil {
+		return rw.read.ctx
+	}
+	h := &readHold{stop: make(chan struct{})}
+	h.ctx, h.cancel = context.WithCancelCause(ctx)
+	h.lastBeat.Store(time.Now().UnixNano())
+	rw.read = h
+
+	// The heartbeat outlives the call which
+	// acquired the hold, so it isn't bound
+	// by its deadline.
+	go rw.beatReader(withoutDeadline(db), h)
+	if rw.x.failSafe > 0 {
+		go rw.x.watchdog(h.stop, &h.lastBeat, func() { rw.lose(h) })
+	}
+	return h.ctx
+}
+
+// lose ends the read hold after it was expired or its heartbeat failed,
+// cancelling its context with [[ErrLockLost]]. If the hold already ended,
+// this method is a noop.
+func (rw *RWMutex) lose(h *readHold) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.read != h {
+		return
+	}
+	rw.read = nil
+	close(h.stop)
+	h.cancel(ErrLockLost)
+}
+
+// readerTTL is how old a reader's heartbeat may be before
+// the reader is expired. Like [[WithLeaseTTL]], readers
+// heartbeat 4 times per TTL.
+func (rw *RWMutex) readerTTL() time.Duration {
+	return 4 * rw.x.beatInterval()
+}
+
+// waitForReaders blocks until no other client holds the mutex for reading.
+// Readers which stopped heartbeating are expired. If one is still alive,
+// the wait ends when the readers change or the oldest reader may expire.
+func (rw *RWMutex) waitForReaders(ctx context.Context, db fdb.Transactor) error {
+	ttl := rw.readerTTL()
+	for {
+		watchCtx, cancel := context.WithCancel(ctx)
+		watch := rw.x.watchReaders(watchCtx, db)
+
+		readers, err := rw.x.getReaders(db, ttl)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to get readers: %w", err)
+		}
+		if len(readers) == 0 {
+			cancel()
+			return nil
+		}
+
+		wait := ttl
+		for _, r := range readers {
+			wait = min(wait, ttl-r.age)
+		}
+
+		select {
+		case err := <-watch:
+			cancel()
+			if err != nil {
+				return fmt.Errorf("failed to watch readers: %w", err)
+			}
+		case <-time.After(max(wait, 0) + time.Millisecond):
+			cancel()
+		}
+	}
+}
+
+// beatReader heartbeats the read hold until it ends. If the reader was
+// expired by a writer or the error budget is exhausted, the hold is lost.
+// Like [[Mutex.beatLoop]], failed heartbeats are retried with exponential
+// backoff, capped at the heartbeat interval.
+func (rw *RWMutex) beatReader(db fdb.Transactor, h *readHold) {
+	interval := rw.x.beatInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	var failures int
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-timer.C:
+		}
+
+		ok, err := rw.x.beatReader(db, rw.x.name)
+		switch {
+		case err == nil && ok:
+			failures = 0
+			h.lastBeat.Store(time.Now().UnixNano())
+			timer.Reset(interval)
+
+		case err == nil:
+			// A writer expired the reader.
+			rw.lose(h)
+			return
+
+		default:
+			failures++
+			if rw.x.budget > 0 && failures >= rw.x.budget {
+				rw.lose(h)
+				return
+			}
+			timer.Reset(min(minBackoff<<min(failures-1, 16), interval))
+		}
+	}
+}
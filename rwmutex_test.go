@@ -0,0 +1,186 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRWMutex(t *testing.T) {
+	tests := map[string]testFn{
+		"shared readers": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.Background()
+
+			r1, err := NewRWMutex(db, root, "reader1")
+			require.NoError(t, err)
+
+			r2, err := NewRWMutex(db, root, "reader2")
+			require.NoError(t, err)
+
+			require.NoError(t, r1.RLock(ctx, db))
+			require.NoError(t, r2.RLock(ctx, db))
+
+			readers, err := r1.Readers(db)
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{"reader1", "reader2"}, readers)
+
+			require.NoError(t, r1.RUnlock(db))
+			require.NoError(t, r2.RUnlock(db))
+
+			readers, err = r1.Readers(db)
+			require.NoError(t, err)
+			require.Empty(t, readers)
+		},
+		"writer waits for readers": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.Background()
+
+			r, err := NewRWMutex(db, root, "reader")
+			require.NoError(t, err)
+
+			w, err := NewRWMutex(db, root, "writer")
+			require.NoError(t, err)
+
+			require.NoError(t, r.RLock(ctx, db))
+
+			locked := make(chan error, 1)
+			go func() { locked <- w.Lock(ctx, db) }()
+
+			select {
+			case err := <-locked:
+				t.Fatalf("writer locked while read held: %v", err)
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			// The queued writer holds the writer's mutex,
+			// so new readers wait.
+			r2, err := NewRWMutex(db, root, "reader2")
+			require.NoError(t, err)
+
+			shortCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			require.ErrorIs(t, r2.RLock(shortCtx, db), context.DeadlineExceeded)
+
+			require.NoError(t, r.RUnlock(db))
+			require.NoError(t, <-locked)
+
+			owner, err := w.Mutex().getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "writer", owner.name)
+
+			// Readers proceed once the writer unlocks.
+			rlocked := make(chan error, 1)
+			go func() { rlocked <- r2.RLock(ctx, db) }()
+			require.NoError(t, w.Unlock(db))
+			require.NoError(t, <-rlocked)
+			require.NoError(t, r2.RUnlock(db))
+		},
+		"expired reader": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.Background()
+
+			w, err := NewRWMutex(db, root, "writer", WithLeaseTTL(200*time.Millisecond))
+			require.NoError(t, err)
+
+			// A reader which died without unlocking.
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				return nil, w.Mutex().addReader(tr, "dead")
+			})
+			require.NoError(t, err)
+
+			lockCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			require.NoError(t, w.Lock(lockCtx, db))
+			defer func() { _ = w.Unlock(db) }()
+
+			readers, err := w.Readers(db)
+			require.NoError(t, err)
+			require.Empty(t, readers)
+		},
+		"downgrade": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.Background()
+
+			rw, err := NewRWMutex(db, root, "client")
+			require.NoError(t, err)
+
+			require.NoError(t, rw.Lock(ctx, db))
+			require.NoError(t, rw.RLock(ctx, db))
+			require.NoError(t, rw.Unlock(db))
+
+			readers, err := rw.Readers(db)
+			require.NoError(t, err)
+			require.Equal(t, []string{"client"}, readers)
+
+			// Upgrading isn't allowed.
+			require.ErrorIs(t, rw.Lock(ctx, db), ErrReadHeld)
+			require.NoError(t, rw.RUnlock(db))
+		},
+		"lost reader": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.Background()
+
+			r, err := NewRWMutex(db, root, "reader", WithLeaseTTL(200*time.Millisecond))
+			require.NoError(t, err)
+
+			rctx, err := r.RLockContext(ctx, db)
+			require.NoError(t, err)
+
+			// Simulate a writer expiring the reader.
+			require.NoError(t, r.Mutex().removeReader(db, "reader"))
+
+			select {
+			case <-rctx.Done():
+			case <-time.After(time.Second):
+				t.Fatal("lost read hold wasn't signaled")
+			}
+			require.ErrorIs(t, context.Cause(rctx), ErrLockLost)
+			require.False(t, r.readHeld())
+		},
+		"waiting reader": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.Background()
+
+			w, err := NewRWMutex(db, root, "writer")
+			require.NoError(t, err)
+			require.NoError(t, w.Lock(ctx, db))
+
+			r, err := NewRWMutex(db, root, "reader")
+			require.NoError(t, err)
+
+			rlocked := make(chan error, 1)
+			go func() { rlocked <- r.RLock(ctx, db) }()
+			time.Sleep(100 * time.Millisecond)
+
+			// A waiting reader doesn't block the handle.
+			unlocked := make(chan error, 1)
+			go func() { unlocked <- r.RUnlock(db) }()
+			select {
+			case err := <-unlocked:
+				require.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("unlock blocked behind a waiting reader")
+			}
+
+			require.NoError(t, w.Unlock(db))
+			require.NoError(t, <-rlocked)
+			require.NoError(t, r.RUnlock(db))
+		},
+		"reader heartbeat": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			ctx := context.Background()
+
+			r, err := NewRWMutex(db, root, "reader", WithLeaseTTL(200*time.Millisecond))
+			require.NoError(t, err)
+
+			require.NoError(t, r.RLock(ctx, db))
+			defer func() { _ = r.RUnlock(db) }()
+
+			time.Sleep(500 * time.Millisecond)
+
+			readers, err := r.Mutex().getReaders(db, r.readerTTL())
+			require.NoError(t, err)
+			require.Len(t, readers, 1)
+			require.Less(t, readers[0].age, r.readerTTL())
+		},
+	}
+	runTests(t, tests)
+}
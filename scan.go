@@ -0,0 +1,353 @@
+package mutex
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+)
+
+// defaultMaxWatches is the number of mutexes [[AutoReleaseAll]]
+// watches at once when no limit is configured.
+const defaultMaxWatches = 100
+
+// ScanOption configures [[AutoReleaseAll]].
+type ScanOption func(*scanner)
+
+// WithMaxWatches bounds the number of mutexes [[AutoReleaseAll]] watches at
+// once. The held mutexes which change owner most often are watched, and the
+// rest are polled. A limit of zero disables watches. The default is 100.
+func WithMaxWatches(n int) ScanOption {
+	return func(s *scanner) {
+		s.maxWatches = n
+	}
+}
+
+// WithIdlePoll sets how often [[AutoReleaseAll]] polls vacant mutexes and
+// lists the directory to discover new ones. The default is 4 times the
+// 'maxAge' given to AutoReleaseAll.
+func WithIdlePoll(interval time.Duration) ScanOption {
+	return func(s *scanner) {
+		s.idlePoll = interval
+	}
+}
+
+// AutoReleaseAll is like [[Mutex.AutoRelease]] but manages expiry for every
+// mutex stored in the immediate subdirectories of 'parent', so a fleet with
+// thousands of locks doesn't need a goroutine & watch per mutex.
+//
+// Attention is spent according to activity. Vacant mutexes are polled
+// infrequently, see [[WithIdlePoll]]. Held mutexes are polled 4 times per
+// 'maxAge', except for the busiest, which are watched instead so their
+// expiry is detected precisely, see [[WithMaxWatches]]. This bounds both the
+// number of watches and the transaction load. An expired hold is released
+// within 1.25 times 'maxAge' of its latest heartbeat, or later by up to the
+// idle poll interval if the mutex was acquired since it was last polled.
+// Mutexes created after the scan starts are discovered on the next listing.
+//
+// Holds are never reserved for their previous owner, but reservations made
+// by handles configured with [[WithStickyGrace]] are honored. Expirations
+// aren't reported to [[ClientRegistry]] sessions. Like AutoRelease, this
+// function runs until the context ends or an error occurs, and multiple
+// instances may be run.
+func AutoReleaseAll(ctx context.Context, db fdb.Transactor, parent directory.Directory, maxAge time.Duration, opts ...ScanOption) (err error) {
+	defer wrapErr(&err)
+
+	s := scanner{
+		parent:     parent,
+		maxAge:     maxAge,
+		maxWatches: defaultMaxWatches,
+		idlePoll:   4 * maxAge,
+		tracked:    make(map[string]*scanned),
+		fired:      make(chan string, 1),
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s.run(ctx, db)
+}
+
+// scanner tracks the mutexes beneath a directory for [[AutoReleaseAll]].
+type scanner struct {
+	parent     directory.Directory
+	maxAge     time.Duration
+	maxWatches int
+	idlePoll   time.Duration
+
+	// tracked maps the name of each
+	// subdirectory to its scan state.
+	tracked map[string]*scanned
+
+	// fired receives the name of
+	// a watched mutex which changed.
+	fired chan string
+
+	// hot, if not nil, configures the detection of
+	// hot mutexes. See [[WithHotLockDetection]].
+	hot   *HotLockThresholds
+	onHot func(HotLock)
+}
+
+// scanned is the scan state of a single subdirectory.
+type scanned struct {
+	name string
+	dir  directory.DirectorySubspace
+
+	// x is nil until the subdirectory
+	// is found to contain a mutex.
+	x *Mutex
+
+	// owner is the owner as last observed,
+	// and since is when it was first observed.
+	owner ownerKV
+	since time.Time
+
+	// next is when the mutex is polled next.
+	next time.Time
+
+	// heat is the number of ownership changes
+	// observed, halved every idle poll interval.
+	// heated is when heat was last updated.
+	heat   float64
+	heated time.Time
+
+	// unwatch cancels the watch on the mutex.
+	// It's nil if the mutex isn't watched.
+	unwatch context.CancelFunc
+
+	// sample holds the contention metrics as of
+	// 'sampled', and hot is true if they exceeded
+	// the thresholds. See [[WithHotLockDetection]].
+	sample  heatKV
+	sampled time.Time
+	hot     bool
+}
+
+// run polls & watches the mutexes until
+// the context ends or an error occurs.
+func (s *scanner) run(ctx context.Context, db fdb.Transactor) error {
+	defer func() {
+		for _, sc := range s.tracked {
+			s.unwatch(sc)
+		}
+	}()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	var listed time.Time
+	for {
+		now := time.Now()
+		if !now.Before(listed.Add(s.idlePoll)) {
+			if err := s.list(db, now); err != nil {
+				return err
+			}
+			listed = now
+		}
+
+		for _, sc := range s.tracked {
+			if now.Before(sc.next) {
+				continue
+			}
+			// Each mutex is handled in its own transaction so a
+			// large directory doesn't exceed transaction limits.
+			if err := s.poll(db, sc); err != nil {
+				return fmt.Errorf("failed to poll %s: %w", sc.name, err)
+			}
+		}
+		s.rebalance(ctx, db)
+
+		wake := listed.Add(s.idlePoll)
+		for _, sc := range s.tracked {
+			if sc.next.Before(wake) {
+				wake = sc.next
+			}
+		}
+		timer.Reset(time.Until(wake))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-timer.C:
+
+		case name := <-s.fired:
+			// Poll the mutex now. Its watch
+			// is renewed by the rebalance.
+			if sc, ok := s.tracked[name]; ok {
+				s.unwatch(sc)
+				sc.next = time.Time{}
+			}
+		}
+	}
+}
+
+// list starts tracking new subdirectories
+// & forgets those which were removed.
+func (s *scanner) list(db fdb.Transactor, now time.Time) error {
+	names, err := s.parent.List(db, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list subdirectories: %w", err)
+	}
+
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		listed[name] = true
+		if _, ok := s.tracked[name]; ok {
+			continue
+		}
+		dir, err := s.parent.Open(db, []string{name}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to open subdirectory %s: %w", name, err)
+		}
+		s.tracked[name] = &scanned{name: name, dir: dir, heated: now}
+	}
+
+	for name, sc := range s.tracked {
+		if !listed[name] {
+			s.unwatch(sc)
+			delete(s.tracked, name)
+		}
+	}
+	return nil
+}
+
+// poll checks a single subdirectory, releasing its mutex if the
+// owner's heartbeat is older than 'maxAge', & schedules the next poll.
+func (s *scanner) poll(db fdb.Transactor, sc *scanned) error {
+	now := time.Now()
+	if sc.x == nil {
+		x := &Mutex{kv: kv{Subspace: sc.dir}}
+		owner, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+			isMutex, err := x.isMutex(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check for mutex: %w", err)
+			}
+			if !isMutex {
+				return nil, nil
+			}
+			return x.getOwner(tr)
+		})
+		if err != nil {
+			return err
+		}
+		if owner != nil {
+			sc.x = x
+			sc.owner = owner.(ownerKV)
+			sc.since = now
+		}
+		s.schedule(sc, now, 0)
+		return nil
+	}
+
+	ret, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		return sc.x.expire(tr, sc.owner, sc.since, s.maxAge)
+	})
+	if err != nil {
+		return err
+	}
+	result := ret.(autoReleaseResult)
+
+	// Decay the heat so past activity is forgotten,
+	// then count any change of ownership.
+	sc.heat *= math.Pow(0.5, float64(now.Sub(sc.heated))/float64(s.idlePoll))
+	sc.heated = now
+	if result.owner.name != sc.owner.name {
+		sc.heat++
+	}
+
+	if result.owner.name != sc.owner.name || !bytes.Equal(result.owner.hbeat, sc.owner.hbeat) {
+		sc.owner = result.owner
+		sc.since = now
+	}
+	s.schedule(sc, now, result.wait)
+
+	if s.hot != nil {
+		return s.sampleHeat(db, sc)
+	}
+	return nil
+}
+
+// schedule sets when the mutex is polled next. If 'wait' isn't zero,
+// the mutex is reserved and is polled again once the reservation ends.
+func (s *scanner) schedule(sc *scanned, now time.Time, wait time.Duration) {
+	switch {
+	case sc.x == nil || sc.owner.name == "":
+		sc.next = now.Add(s.idlePoll)
+	case sc.unwatch != nil:
+		// Changes are reported by the watch, so the
+		// hold can only expire once 'maxAge' passes.
+		sc.next = sc.since.Add(s.maxAge)
+	default:
+		sc.next = now.Add(s.maxAge / 4)
+	}
+	if wait > 0 && now.Add(wait).Before(sc.next) {
+		sc.next = now.Add(wait)
+	}
+}
+
+// rebalance watches the busiest held mutexes, up to 'maxWatches',
+// and stops watching the rest.
+func (s *scanner) rebalance(ctx context.Context, db fdb.Transactor) {
+	var held []*scanned
+	for _, sc := range s.tracked {
+		if sc.x != nil && sc.owner.name != "" {
+			held = append(held, sc)
+		} else {
+			s.unwatch(sc)
+		}
+	}
+	slices.SortFunc(held, func(a, b *scanned) int {
+		if c := cmp.Compare(b.heat, a.heat); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+
+	for i, sc := range held {
+		switch {
+		case i >= s.maxWatches && sc.unwatch != nil:
+			s.unwatch(sc)
+			s.schedule(sc, time.Now(), 0)
+		case i < s.maxWatches && sc.unwatch == nil:
+			s.watch(ctx, db, sc)
+		}
+	}
+}
+
+// watch starts watching the owner key of the mutex. When it
+// changes, the name of its subdirectory is sent to 'fired'.
+func (s *scanner) watch(ctx context.Context, db fdb.Transactor, sc *scanned) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	sc.unwatch = cancel
+
+	ch := sc.x.watchOwner(watchCtx, db)
+	go func() {
+		select {
+		case <-ch:
+		case <-watchCtx.Done():
+			return
+		}
+		// A failed watch is reported as a change
+		// so the mutex is polled & rewatched.
+		select {
+		case s.fired <- sc.name:
+		case <-watchCtx.Done():
+		}
+	}()
+	s.schedule(sc, time.Now(), 0)
+}
+
+// unwatch stops watching the mutex, if it's watched.
+func (s *scanner) unwatch(sc *scanned) {
+	if sc.unwatch != nil {
+		sc.unwatch()
+		sc.unwatch = nil
+	}
+}
@@ -0,0 +1,89 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/directory"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoReleaseAll(t *testing.T) {
+	tests := map[string]testFn{
+		"watched": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			testAutoReleaseAll(t, db, root, WithIdlePoll(100*time.Millisecond))
+		},
+		"polled": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			testAutoReleaseAll(t, db, root, WithMaxWatches(0), WithIdlePoll(100*time.Millisecond))
+		},
+		"few watches": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			testAutoReleaseAll(t, db, root, WithMaxWatches(1), WithIdlePoll(100*time.Millisecond))
+		},
+		"idle": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			// Vacant mutexes are only polled every idle poll
+			// interval, so a hold is discovered late but the
+			// expiry still happens.
+			testAutoReleaseAll(t, db, root)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+// testAutoReleaseAll checks that an expired hold is released while a
+// hold which keeps heartbeating isn't, using the given options.
+func testAutoReleaseAll(t *testing.T, db fdb.Database, root subspace.Subspace, opts ...ScanOption) {
+	parent := root.(directory.Directory)
+	const maxAge = 300 * time.Millisecond
+
+	dirA, err := parent.CreateOrOpen(db, []string{"a"}, nil)
+	require.NoError(t, err)
+	dirB, err := parent.CreateOrOpen(db, []string{"b"}, nil)
+	require.NoError(t, err)
+
+	// This directory doesn't contain a mutex.
+	_, err = parent.CreateOrOpen(db, []string{"c"}, nil)
+	require.NoError(t, err)
+
+	xA1, err := NewMutex(db, dirA, "client1")
+	require.NoError(t, err)
+	xA2, err := NewMutex(db, dirA, "client2")
+	require.NoError(t, err)
+	xB, err := NewMutex(db, dirB, "client1", WithLeaseTTL(maxAge))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- AutoReleaseAll(ctx, db, parent, maxAge, opts...) }()
+
+	// client1 dies while holding 'a' & 'client2' waits.
+	acquired, err := xA1.TryAcquire(db)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	xA1.stopBeating()
+
+	acquired, err = xA2.TryAcquire(db)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	// client1 keeps heartbeating 'b'.
+	acquired, err = xB.TryAcquire(db)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	require.Eventually(t, func() bool {
+		owner, err := xA2.getOwner(db)
+		return err == nil && owner.name == "client2"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	owner, err := xB.getOwner(db)
+	require.NoError(t, err)
+	require.Equal(t, "client1", owner.name)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.NoError(t, xB.Release(db))
+}
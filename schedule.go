@@ -0,0 +1,118 @@
+package mutex
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrOutsideWindow is returned when acquiring a mutex whose [[Schedule]]
+// rejects acquisitions outside of its windows while they're closed.
+var ErrOutsideWindow = errors.New("mutex is outside of its acquisition window")
+
+// Schedule restricts when a mutex may be acquired, such as to a nightly
+// maintenance window. Windows recur every period. See [[Mutex.SetSchedule]].
+type Schedule struct {
+	// Period is how often the windows recur, such as 24 hours.
+	// Periods are aligned to the unix epoch, so daily windows
+	// are measured from midnight UTC.
+	Period time.Duration
+
+	// Windows are the times within each period
+	// during which the mutex may be acquired.
+	Windows []Window
+
+	// Reject causes acquisitions attempted outside of the windows
+	// to fail with [[ErrOutsideWindow]]. Otherwise, the clients
+	// are queued until the next window opens.
+	Reject bool
+}
+
+// Window is a span of time within a [[Schedule]]'s period. A window
+// which extends past the end of the period wraps around to its start.
+type Window struct {
+	// Start is the offset of the window from the start of the period.
+	Start time.Duration
+
+	// Length is how long the window stays open.
+	Length time.Duration
+}
+
+// SetSchedule restricts acquisitions of the mutex to the windows of the given
+// schedule. The schedule is stored with the mutex and enforced by the acquire
+// transaction of every client, so clients needn't be configured. A hold which
+// started within a window isn't cut short when it closes, but when it's
+// released, the mutex isn't handed to the queue until the next window opens.
+// Acquisitions are checked against the clocks of the clients.
+func (x *Mutex) SetSchedule(db fdb.Transactor, s Schedule) (err error) {
+	defer wrapErr(&err)
+
+	if s.Period <= 0 {
+		return fmt.Errorf("schedule period must be positive")
+	}
+	for _, w := range s.Windows {
+		if w.Start < 0 || w.Start >= s.Period || w.Length <= 0 {
+			return fmt.Errorf("window %v+%v is invalid for the period %v", w.Start, w.Length, s.Period)
+		}
+	}
+	return x.setSchedule(x.withBreaker(db), s)
+}
+
+// ClearSchedule removes the mutex's schedule, allowing
+// it to be acquired at any time. See [[Mutex.SetSchedule]].
+func (x *Mutex) ClearSchedule(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	return x.clearSchedule(x.withBreaker(db))
+}
+
+// Schedule returns the mutex's schedule. If the mutex has no
+// schedule, false is returned. See [[Mutex.SetSchedule]].
+func (x *Mutex) Schedule(db fdb.Transactor) (_ Schedule, _ bool, err error) {
+	defer wrapErr(&err)
+	return x.getSchedule(x.withBreaker(db))
+}
+
+// untilOpen returns how long until a window of the schedule opens.
+// If a window is open at 't', zero is returned. If the schedule has
+// no windows, false is returned.
+func (s Schedule) untilOpen(t time.Time) (time.Duration, bool) {
+	offset := time.Duration(t.UnixNano() % int64(s.Period))
+
+	var (
+		wait  time.Duration
+		found bool
+	)
+	for _, w := range s.Windows {
+		// Windows may wrap around the end of the period.
+		if offset >= w.Start && offset < w.Start+w.Length ||
+			offset+s.Period < w.Start+w.Length {
+			return 0, true
+		}
+		d := (w.Start - offset + s.Period) % s.Period
+		if !found || d < wait {
+			wait, found = d, true
+		}
+	}
+	return wait, found
+}
+
+// untilWindow returns how long until the mutex may be acquired according to
+// its schedule. If the mutex may be acquired at 't', zero is returned. If the
+// schedule has no windows, the result is negative. Whether the schedule
+// rejects acquisitions outside of its windows is also returned.
+func (x *kv) untilWindow(db fdb.Transactor, t time.Time) (time.Duration, bool, error) {
+	s, ok, err := x.getSchedule(db)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	if !ok {
+		return 0, false, nil
+	}
+	wait, found := s.untilOpen(t)
+	if !found {
+		return -1, s.Reject, nil
+	}
+	return wait, s.Reject, nil
+}
@@ -0,0 +1,106 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+// windowIn returns an hourly schedule whose only
+// window opens 'delay' from now.
+func windowIn(delay time.Duration, reject bool) Schedule {
+	offset := time.Duration(time.Now().UnixNano() % int64(time.Hour))
+	return Schedule{
+		Period:  time.Hour,
+		Windows: []Window{{Start: (offset + delay) % time.Hour, Length: time.Minute}},
+		Reject:  reject,
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	tests := map[string]testFn{
+		"reject": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			s := windowIn(30*time.Minute, true)
+			require.NoError(t, x.SetSchedule(db, s))
+			stored, ok, err := x.Schedule(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, s, stored)
+
+			_, err = x.TryAcquire(db)
+			require.ErrorIs(t, err, ErrOutsideWindow)
+
+			require.NoError(t, x.ClearSchedule(db))
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+		},
+
+		"queue": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// The hold outlives the window, but the
+			// queue waits for the next window.
+			require.NoError(t, x1.SetSchedule(db, windowIn(300*time.Millisecond, false)))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() { done <- x2.Acquire(ctx, db) }()
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, x1.Release(db))
+			owner, err := x1.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+
+			require.NoError(t, <-done)
+			owner, err = x1.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client2", owner.name)
+		},
+
+		"invalid": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			err = x.SetSchedule(db, Schedule{Period: time.Hour, Windows: []Window{{Start: 2 * time.Hour, Length: time.Minute}}})
+			require.Error(t, err)
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestScheduleUntilOpen(t *testing.T) {
+	s := Schedule{Period: time.Hour, Windows: []Window{{Start: 50 * time.Minute, Length: 20 * time.Minute}}}
+	at := func(d time.Duration) time.Time { return time.Unix(0, int64(d)) }
+
+	wait, ok := s.untilOpen(at(40 * time.Minute))
+	require.True(t, ok)
+	require.Equal(t, 10*time.Minute, wait)
+
+	// The window wraps around the end of the period.
+	wait, _ = s.untilOpen(at(5 * time.Minute))
+	require.Zero(t, wait)
+	wait, _ = s.untilOpen(at(15 * time.Minute))
+	require.Equal(t, 35*time.Minute, wait)
+
+	_, ok = Schedule{Period: time.Hour}.untilOpen(at(0))
+	require.False(t, ok)
+}
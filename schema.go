@@ -0,0 +1,618 @@
+package mutex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// SchemaVersion is the major version of the layout used to store a mutex.
+// Within a major version, the layout only grows: existing key shapes and
+// value encodings never change, new records may be added, and readers must
+// ignore keys they don't recognize. Any other change increments the version.
+//
+// The records of version 2 are tuple encoded within the mutex's subspace.
+// Values described as tuples are tuple encoded. Values described as
+// counters are 8 byte little-endian integers maintained by atomic adds.
+//
+//	("owner", client) = (heartbeat, time)
+//	("queue", versionstamp) = (client, enqueued)
+//	("member", client) = (versionstamp)
+//	("queueVersion") = counter
+//	("priority", client) = (priority)
+//	("reservation", client) = (deadline)
+//	("label", key) = value
+//	("sticky") = (client, deadline)
+//	("epoch") = counter
+//	("token") = (versionstamp)
+//	("ownership") = counter
+//	("reader", client) = (heartbeat)
+//	("readerVersion") = counter
+//	("transfer", client) = empty
+//	("rotation", client) = empty
+//	("schedule") = (period, reject, start, length, ...)
+//	("aging") = (interval)
+//	("maintenance") = (since)
+//	("frozen") = (since)
+//	("disabled") = (since)
+//	("deny", pattern) = empty
+//	("rateLimit") = (limit, interval)
+//	("attempts", window) = counter
+//	("contended") = counter
+//	("preempt") = (client, grace, deadline)
+//	("operation", epoch, id) = (client, started)
+//	("operationVersion") = counter
+//	("releaseRequest") = client
+//	("ownerSince") = (time)
+//	("stats", client, field) = counter
+//	("event", versionstamp) = (kind, client, time)
+//	("eventVersion") = counter
+//	("metadata") = (created, creator, description)
+//	("result") = application result
+//	("idle") = (deadline)
+//	("lastUsed") = (time)
+//	("attr", client, key) = value
+//	("delegator", client) = client
+//	("store", key) = value
+//	("clock", client) = (version, time)
+//	("queueWait", versionstamp) = (client, enqueued, promoted)
+//	("handoff") = (successor, acked, prepared)
+//	("data", ...) = application data
+//	("_meta", "type") = type name
+//	("_meta", "schema") = (version)
+//
+// Times are unix nanoseconds. Strings without a tuple are UTF-8 bytes.
+// If a [[Serializer]] is configured, the metadata is instead the byte
+// 0xff followed by the serialized [[Metadata]].
+// The member key indexes each client's queue entry. Queue entries written
+// before the index existed are indexed whenever a handle is constructed.
+// The version counters are only used to trigger watches. Readers ignore
+// trailing tuple elements they don't recognize, so fields may be added.
+//
+// Version 1 stored the heartbeat as a bare 12 byte versionstamp and the
+// queue value as the bare client name. These are still decoded & they
+// are rewritten whenever a handle is constructed for the mutex, or by
+// [[MigrateSchema]] without constructing a handle.
+const SchemaVersion = 2
+
+// Schema encodes and decodes the records of a mutex, allowing tools and
+// bindings to read & write the state of a mutex without this package's
+// higher level types. See [[SchemaVersion]] for the layout.
+type Schema struct{ x kv }
+
+// NewSchema returns the schema of the mutex stored in 'root'.
+func NewSchema(root subspace.Subspace) Schema {
+	return Schema{kv{Subspace: root}}
+}
+
+// Version returns the major version of the schema.
+func (s Schema) Version() int {
+	return SchemaVersion
+}
+
+// OwnerRecord marks the owner of the mutex. An empty client means the
+// mutex is free. The heartbeat is the versionstamp of the owner's latest
+// heartbeat transaction. It's zero if the owner hasn't heartbeat yet. The
+// heartbeat time is the owner's clock when it wrote the heartbeat. It's
+// zero for heartbeats written by older versions of this package.
+type OwnerRecord struct {
+	Client        string
+	Heartbeat     tuple.Versionstamp
+	HeartbeatTime time.Time
+}
+
+func (s Schema) EncodeOwner(r OwnerRecord) fdb.KeyValue {
+	kv := fdb.KeyValue{Key: s.x.packOwnerKey(r.Client)}
+	if r.Heartbeat != (tuple.Versionstamp{}) {
+		tup := tuple.Tuple{r.Heartbeat}
+		if !r.HeartbeatTime.IsZero() {
+			tup = append(tup, r.HeartbeatTime.UnixNano())
+		}
+		kv.Value = tup.Pack()
+	}
+	return kv
+}
+
+func (s Schema) DecodeOwner(kv fdb.KeyValue) (OwnerRecord, error) {
+	name, err := s.x.unpackOwnerKey(kv.Key)
+	if err != nil {
+		return OwnerRecord{}, fmt.Errorf("failed to unpack owner key: %w", err)
+	}
+	hbeat, _ := s.x.unpackOwnerValue(kv.Value)
+	return OwnerRecord{Client: name, Heartbeat: hbeat.vstamp, HeartbeatTime: hbeat.time}, nil
+}
+
+// QueueRecord is a client waiting for the mutex. Clients are dequeued in
+// version order, unless reordered by their [[PriorityRecord]]. When
+// encoding, the version must be complete. New clients are queued with
+// [[fdb.Transaction.SetVersionstampedKey]].
+type QueueRecord struct {
+	Version  tuple.Versionstamp
+	Client   string
+	Enqueued time.Time
+}
+
+func (s Schema) EncodeQueue(r QueueRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.Pack(tuple.Tuple{"queue", r.Version}),
+		Value: s.x.packQueueValue(r.Client, r.Enqueued),
+	}
+}
+
+func (s Schema) DecodeQueue(kv fdb.KeyValue) (QueueRecord, error) {
+	vstamp, err := s.x.unpackQueueKey(kv.Key)
+	if err != nil {
+		return QueueRecord{}, fmt.Errorf("failed to unpack queue key: %w", err)
+	}
+	name, enqueued := s.x.unpackQueueValue(kv.Value)
+	return QueueRecord{Version: vstamp, Client: name, Enqueued: enqueued}, nil
+}
+
+// MemberRecord indexes the queue entry of a client, so a client's membership
+// is found without scanning the queue. Writers must keep it alongside the
+// [[QueueRecord]], which is done by setting both in the same transaction
+// with versionstamps. When encoding, the version must be complete.
+type MemberRecord struct {
+	Client  string
+	Version tuple.Versionstamp
+}
+
+func (s Schema) EncodeMember(r MemberRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.packMemberKey(r.Client),
+		Value: tuple.Tuple{r.Version}.Pack(),
+	}
+}
+
+func (s Schema) DecodeMember(kv fdb.KeyValue) (MemberRecord, error) {
+	tup, err := s.x.Unpack(kv.Key)
+	if err != nil {
+		return MemberRecord{}, fmt.Errorf("failed to unpack member key: %w", err)
+	}
+	if len(tup) != 2 {
+		return MemberRecord{}, fmt.Errorf("member key tuple is incorrect length %d", len(tup))
+	}
+	name, ok := tup[1].(string)
+	if !ok {
+		return MemberRecord{}, fmt.Errorf("tuple element 1 is not a string")
+	}
+	vstamp, err := s.x.unpackMemberValue(kv.Value)
+	if err != nil {
+		return MemberRecord{}, fmt.Errorf("failed to unpack member value: %w", err)
+	}
+	return MemberRecord{Client: name, Version: vstamp}, nil
+}
+
+// PriorityRecord is the priority of a queued client. See [[WithPriority]].
+type PriorityRecord struct {
+	Client   string
+	Priority int64
+}
+
+func (s Schema) EncodePriority(r PriorityRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.packPriorityKey(r.Client),
+		Value: s.x.packPriorityValue(r.Priority),
+	}
+}
+
+func (s Schema) DecodePriority(kv fdb.KeyValue) (PriorityRecord, error) {
+	name, err := s.x.unpackPriorityKey(kv.Key)
+	if err != nil {
+		return PriorityRecord{}, fmt.Errorf("failed to unpack priority key: %w", err)
+	}
+	priority, err := s.x.unpackPriorityValue(kv.Value)
+	if err != nil {
+		return PriorityRecord{}, fmt.Errorf("failed to unpack priority value: %w", err)
+	}
+	return PriorityRecord{Client: name, Priority: priority}, nil
+}
+
+// LabelRecord is a label of the mutex. See [[Mutex.SetLabels]].
+type LabelRecord struct {
+	Key   string
+	Value string
+}
+
+func (s Schema) EncodeLabel(r LabelRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.packLabelKey(r.Key),
+		Value: s.x.packLabelValue(r.Value),
+	}
+}
+
+func (s Schema) DecodeLabel(kv fdb.KeyValue) (LabelRecord, error) {
+	key, err := s.x.unpackLabelKey(kv.Key)
+	if err != nil {
+		return LabelRecord{}, fmt.Errorf("failed to unpack label key: %w", err)
+	}
+	return LabelRecord{Key: key, Value: s.x.unpackLabelValue(kv.Value)}, nil
+}
+
+// StickyRecord reserves a vacant mutex for its previous
+// owner until the deadline. See [[WithStickyGrace]].
+type StickyRecord struct {
+	Client   string
+	Deadline time.Time
+}
+
+func (s Schema) EncodeSticky(r StickyRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.packStickyKey(),
+		Value: s.x.packStickyValue(r.Client, r.Deadline),
+	}
+}
+
+func (s Schema) DecodeSticky(kv fdb.KeyValue) (StickyRecord, error) {
+	sticky, err := s.x.unpackStickyValue(kv.Value)
+	if err != nil {
+		return StickyRecord{}, fmt.Errorf("failed to unpack sticky value: %w", err)
+	}
+	return StickyRecord{Client: sticky.name, Deadline: sticky.deadline}, nil
+}
+
+// EpochRecord counts the acquisitions of the mutex.
+type EpochRecord struct {
+	Epoch int64
+}
+
+func (s Schema) EncodeEpoch(r EpochRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packEpochKey(), Value: packCounter(r.Epoch)}
+}
+
+func (s Schema) DecodeEpoch(kv fdb.KeyValue) (EpochRecord, error) {
+	return EpochRecord{Epoch: s.x.unpackEpochValue(kv.Value)}, nil
+}
+
+// TokenRecord holds the versionstamp of the transaction which granted the
+// current hold. Its commit version is the hold's fencing token. See
+// [[Mutex.AcquireToken]]. When encoding, the version must be complete.
+type TokenRecord struct {
+	Version tuple.Versionstamp
+}
+
+func (s Schema) EncodeToken(r TokenRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packTokenKey(), Value: tuple.Tuple{r.Version}.Pack()}
+}
+
+func (s Schema) DecodeToken(kv fdb.KeyValue) (TokenRecord, error) {
+	vstamp, err := s.x.unpackTokenValue(kv.Value)
+	if err != nil {
+		return TokenRecord{}, fmt.Errorf("failed to unpack token value: %w", err)
+	}
+	return TokenRecord{Version: vstamp}, nil
+}
+
+// ReaderRecord marks a client holding the mutex for reading. The
+// heartbeat is the versionstamp of the reader's latest heartbeat
+// transaction. See [[RWMutex]].
+type ReaderRecord struct {
+	Client    string
+	Heartbeat tuple.Versionstamp
+}
+
+func (s Schema) EncodeReader(r ReaderRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packReaderKey(r.Client), Value: tuple.Tuple{r.Heartbeat}.Pack()}
+}
+
+func (s Schema) DecodeReader(kv fdb.KeyValue) (ReaderRecord, error) {
+	name, err := s.x.unpackReaderKey(kv.Key)
+	if err != nil {
+		return ReaderRecord{}, fmt.Errorf("failed to unpack reader key: %w", err)
+	}
+	vstamp, err := s.x.unpackReaderValue(kv.Value)
+	if err != nil {
+		return ReaderRecord{}, fmt.Errorf("failed to unpack reader value: %w", err)
+	}
+	return ReaderRecord{Client: name, Heartbeat: vstamp}, nil
+}
+
+// TransferRecord marks a client as accepting
+// transfers of the mutex. See [[Mutex.TransferTo]].
+type TransferRecord struct {
+	Client string
+}
+
+func (s Schema) EncodeTransfer(r TransferRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packTransferKey(r.Client)}
+}
+
+func (s Schema) DecodeTransfer(kv fdb.KeyValue) (TransferRecord, error) {
+	name, err := s.x.unpackTransferKey(kv.Key)
+	if err != nil {
+		return TransferRecord{}, fmt.Errorf("failed to unpack transfer key: %w", err)
+	}
+	return TransferRecord{Client: name}, nil
+}
+
+// ReleaseRequestRecord asks the owner to release the
+// mutex early. See [[Mutex.RequestRelease]].
+type ReleaseRequestRecord struct {
+	Client string
+}
+
+func (s Schema) EncodeReleaseRequest(r ReleaseRequestRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packReleaseRequestKey(), Value: []byte(r.Client)}
+}
+
+func (s Schema) DecodeReleaseRequest(kv fdb.KeyValue) (ReleaseRequestRecord, error) {
+	return ReleaseRequestRecord{Client: string(kv.Value)}, nil
+}
+
+// OwnerSinceRecord is when the current owner acquired the mutex.
+type OwnerSinceRecord struct {
+	Time time.Time
+}
+
+func (s Schema) EncodeOwnerSince(r OwnerSinceRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packOwnerSinceKey(), Value: s.x.packTimeValue(r.Time)}
+}
+
+func (s Schema) DecodeOwnerSince(kv fdb.KeyValue) (OwnerSinceRecord, error) {
+	t, err := s.x.unpackTimeValue(kv.Value)
+	if err != nil {
+		return OwnerSinceRecord{}, fmt.Errorf("failed to unpack owner since: %w", err)
+	}
+	return OwnerSinceRecord{Time: t}, nil
+}
+
+// StatRecord is a statistic of a client. The fields are "acquisitions",
+// "holdNanos", & "preemptions". See [[Mutex.Stats]].
+type StatRecord struct {
+	Client string
+	Field  string
+	Value  int64
+}
+
+func (s Schema) EncodeStat(r StatRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.packStatKey(r.Client, r.Field),
+		Value: packCounter(r.Value),
+	}
+}
+
+func (s Schema) DecodeStat(kv fdb.KeyValue) (StatRecord, error) {
+	name, field, err := s.x.unpackStatKey(kv.Key)
+	if err != nil {
+		return StatRecord{}, fmt.Errorf("failed to unpack stat key: %w", err)
+	}
+	return StatRecord{Client: name, Field: field, Value: unpackCounter(kv.Value)}, nil
+}
+
+// EncodeEvent encodes an entry of the mutex's event log. The event's
+// version must be complete. The mutex ID isn't stored. See [[Event]].
+func (s Schema) EncodeEvent(e Event) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.Pack(tuple.Tuple{"event", e.Version}),
+		Value: packEventValue(e.Kind, e.Client, e.Time),
+	}
+}
+
+func (s Schema) DecodeEvent(kv fdb.KeyValue) (Event, error) {
+	return s.x.unpackEvent(kv.Key, kv.Value)
+}
+
+// EncodeMetadata encodes the metadata of the mutex. See [[Metadata]].
+func (s Schema) EncodeMetadata(m Metadata) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key: s.x.packMetadataKey(),
+		Value: s.x.packMetadataValue(metadataKV{
+			created:     m.Created,
+			creator:     m.Creator,
+			description: m.Description,
+		}),
+	}
+}
+
+func (s Schema) DecodeMetadata(kv fdb.KeyValue) (Metadata, error) {
+	meta, err := s.x.unpackMetadataValue(kv.Value)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to unpack metadata: %w", err)
+	}
+	return toMetadata(meta), nil
+}
+
+// IdleRecord marks the mutex as idle until the
+// deadline. See [[ExpireIdle]] & [[IdlePolicy]].
+type IdleRecord struct {
+	Deadline time.Time
+}
+
+func (s Schema) EncodeIdle(r IdleRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packIdleKey(), Value: s.x.packTimeValue(r.Deadline)}
+}
+
+func (s Schema) DecodeIdle(kv fdb.KeyValue) (IdleRecord, error) {
+	t, err := s.x.unpackTimeValue(kv.Value)
+	if err != nil {
+		return IdleRecord{}, fmt.Errorf("failed to unpack idle deadline: %w", err)
+	}
+	return IdleRecord{Deadline: t}, nil
+}
+
+// LastUsedRecord is when the mutex was created or last changed
+// owners. See [[ExpireIdle]] & [[IdlePolicy]].
+type LastUsedRecord struct {
+	Time time.Time
+}
+
+func (s Schema) EncodeLastUsed(r LastUsedRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packLastUsedKey(), Value: s.x.packTimeValue(r.Time)}
+}
+
+func (s Schema) DecodeLastUsed(kv fdb.KeyValue) (LastUsedRecord, error) {
+	t, err := s.x.unpackTimeValue(kv.Value)
+	if err != nil {
+		return LastUsedRecord{}, fmt.Errorf("failed to unpack last used: %w", err)
+	}
+	return LastUsedRecord{Time: t}, nil
+}
+
+// AttrRecord is an identity attribute of a client. See [[Identity]].
+type AttrRecord struct {
+	Client string
+	Key    string
+	Value  string
+}
+
+func (s Schema) EncodeAttr(r AttrRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packAttrKey(r.Client, r.Key), Value: []byte(r.Value)}
+}
+
+func (s Schema) DecodeAttr(kv fdb.KeyValue) (AttrRecord, error) {
+	name, key, err := s.x.unpackAttrKey(kv.Key)
+	if err != nil {
+		return AttrRecord{}, fmt.Errorf("failed to unpack attribute key: %w", err)
+	}
+	return AttrRecord{Client: name, Key: key, Value: string(kv.Value)}, nil
+}
+
+// DelegatorRecord names the client which acquires on behalf
+// of another. See [[WithDelegator]].
+type DelegatorRecord struct {
+	Client    string
+	Delegator string
+}
+
+func (s Schema) EncodeDelegator(r DelegatorRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packDelegatorKey(r.Client), Value: []byte(r.Delegator)}
+}
+
+func (s Schema) DecodeDelegator(kv fdb.KeyValue) (DelegatorRecord, error) {
+	name, err := s.x.unpackDelegatorKey(kv.Key)
+	if err != nil {
+		return DelegatorRecord{}, fmt.Errorf("failed to unpack delegator key: %w", err)
+	}
+	return DelegatorRecord{Client: name, Delegator: string(kv.Value)}, nil
+}
+
+// StoreRecord is an entry of a [[ConfigStore]].
+type StoreRecord struct {
+	Key   string
+	Value []byte
+}
+
+func (s Schema) EncodeStore(r StoreRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packStoreKey(r.Key), Value: r.Value}
+}
+
+func (s Schema) DecodeStore(kv fdb.KeyValue) (StoreRecord, error) {
+	key, err := s.x.unpackStoreKey(kv.Key)
+	if err != nil {
+		return StoreRecord{}, fmt.Errorf("failed to unpack store key: %w", err)
+	}
+	return StoreRecord{Key: key, Value: kv.Value}, nil
+}
+
+// ClockRecord is the latest clock sample of a client: a read version
+// paired with the client's clock. See [[Mutex.ClockSkews]].
+type ClockRecord struct {
+	Client  string
+	Version int64
+	Time    time.Time
+}
+
+func (s Schema) EncodeClock(r ClockRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.packClockKey(r.Client),
+		Value: s.x.packClockValue(clockKV{version: r.Version, local: r.Time}),
+	}
+}
+
+func (s Schema) DecodeClock(kv fdb.KeyValue) (ClockRecord, error) {
+	name, err := s.x.unpackClockKey(kv.Key)
+	if err != nil {
+		return ClockRecord{}, fmt.Errorf("failed to unpack clock key: %w", err)
+	}
+	sample, err := s.x.unpackClockValue(kv.Value)
+	if err != nil {
+		return ClockRecord{}, fmt.Errorf("failed to unpack clock value: %w", err)
+	}
+	return ClockRecord{Client: name, Version: sample.version, Time: sample.local}, nil
+}
+
+// QueueWaitRecord is the time a client spent in the queue
+// before it was promoted to owner. See [[Mutex.QueueWaitStats]].
+// When encoding, the version must be complete.
+type QueueWaitRecord struct {
+	Version  tuple.Versionstamp
+	Client   string
+	Enqueued time.Time
+	Promoted time.Time
+}
+
+func (s Schema) EncodeQueueWait(r QueueWaitRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.Pack(tuple.Tuple{"queueWait", r.Version}),
+		Value: s.x.packQueueWaitValue(r.Client, r.Enqueued, r.Promoted),
+	}
+}
+
+func (s Schema) DecodeQueueWait(kv fdb.KeyValue) (QueueWaitRecord, error) {
+	vstamp, err := s.x.unpackQueueWaitKey(kv.Key)
+	if err != nil {
+		return QueueWaitRecord{}, fmt.Errorf("failed to unpack queue wait key: %w", err)
+	}
+	name, enqueued, promoted, err := s.x.unpackQueueWaitValue(kv.Value)
+	if err != nil {
+		return QueueWaitRecord{}, fmt.Errorf("failed to unpack queue wait value: %w", err)
+	}
+	return QueueWaitRecord{Version: vstamp, Client: name, Enqueued: enqueued, Promoted: promoted}, nil
+}
+
+// HandoffRecord is a release prepared by the owner for the successor.
+// See [[Mutex.PrepareRelease]].
+type HandoffRecord struct {
+	Successor string
+	Acked     bool
+	Prepared  time.Time
+}
+
+func (s Schema) EncodeHandoff(r HandoffRecord) fdb.KeyValue {
+	return fdb.KeyValue{
+		Key:   s.x.packHandoffKey(),
+		Value: s.x.packHandoffValue(handoffKV{successor: r.Successor, acked: r.Acked, prepared: r.Prepared}),
+	}
+}
+
+func (s Schema) DecodeHandoff(kv fdb.KeyValue) (HandoffRecord, error) {
+	handoff, err := s.x.unpackHandoffValue(kv.Value)
+	if err != nil {
+		return HandoffRecord{}, fmt.Errorf("failed to unpack handoff value: %w", err)
+	}
+	return HandoffRecord{Successor: handoff.successor, Acked: handoff.acked, Prepared: handoff.prepared}, nil
+}
+
+// TypeRecord marks the kind of primitive stored in the
+// subspace. For a mutex, the type is "mutex". See [[IsMutex]].
+type TypeRecord struct {
+	Type string
+}
+
+func (s Schema) EncodeType(r TypeRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packTypeKey(), Value: []byte(r.Type)}
+}
+
+func (s Schema) DecodeType(kv fdb.KeyValue) (TypeRecord, error) {
+	return TypeRecord{Type: string(kv.Value)}, nil
+}
+
+// SchemaVersionRecord marks the schema version of the mutex.
+type SchemaVersionRecord struct {
+	Version int
+}
+
+func (s Schema) EncodeSchemaVersion(r SchemaVersionRecord) fdb.KeyValue {
+	return fdb.KeyValue{Key: s.x.packSchemaVersionKey(), Value: s.x.packSchemaVersionValue(r.Version)}
+}
+
+func (s Schema) DecodeSchemaVersion(kv fdb.KeyValue) (SchemaVersionRecord, error) {
+	version, err := s.x.unpackSchemaVersionValue(kv.Value)
+	if err != nil {
+		return SchemaVersionRecord{}, fmt.Errorf("failed to unpack schema version: %w", err)
+	}
+	return SchemaVersionRecord{Version: version}, nil
+}
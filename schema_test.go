@@ -0,0 +1,253 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	root := subspace.Sub("schema")
+	s := NewSchema(root)
+	now := time.Unix(0, 1234567890)
+	vstamp := tuple.Versionstamp{TransactionVersion: [10]byte{1, 2, 3}, UserVersion: 1}
+
+	require.Equal(t, SchemaVersion, s.Version())
+
+	// roundTrip encodes then decodes the record and
+	// checks the record comes back unchanged.
+	roundTrip := func(t *testing.T, want, got any, err error) {
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	t.Run("owner", func(t *testing.T) {
+		r := OwnerRecord{Client: "client", Heartbeat: vstamp}
+		got, err := s.DecodeOwner(s.EncodeOwner(r))
+		roundTrip(t, r, got, err)
+		require.Equal(t, OwnerKey(root, "client"), s.EncodeOwner(r).Key)
+
+		r = OwnerRecord{Client: "client", Heartbeat: vstamp, HeartbeatTime: now}
+		got, err = s.DecodeOwner(s.EncodeOwner(r))
+		roundTrip(t, r, got, err)
+
+		r = OwnerRecord{Client: "client"}
+		got, err = s.DecodeOwner(s.EncodeOwner(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		r := QueueRecord{Version: vstamp, Client: "client", Enqueued: now}
+		got, err := s.DecodeQueue(s.EncodeQueue(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("member", func(t *testing.T) {
+		r := MemberRecord{Client: "client", Version: vstamp}
+		got, err := s.DecodeMember(s.EncodeMember(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("version 1", func(t *testing.T) {
+		owner := s.EncodeOwner(OwnerRecord{Client: "client"})
+		owner.Value = append(vstamp.TransactionVersion[:], 0, 1)
+		gotOwner, err := s.DecodeOwner(owner)
+		roundTrip(t, OwnerRecord{Client: "client", Heartbeat: vstamp}, gotOwner, err)
+
+		queue := s.EncodeQueue(QueueRecord{Version: vstamp})
+		queue.Value = []byte("client")
+		gotQueue, err := s.DecodeQueue(queue)
+		roundTrip(t, QueueRecord{Version: vstamp, Client: "client"}, gotQueue, err)
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		r := PriorityRecord{Client: "client", Priority: -3}
+		got, err := s.DecodePriority(s.EncodePriority(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("label", func(t *testing.T) {
+		r := LabelRecord{Key: "team", Value: "red"}
+		got, err := s.DecodeLabel(s.EncodeLabel(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("sticky", func(t *testing.T) {
+		r := StickyRecord{Client: "client", Deadline: now}
+		got, err := s.DecodeSticky(s.EncodeSticky(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("epoch", func(t *testing.T) {
+		r := EpochRecord{Epoch: 42}
+		got, err := s.DecodeEpoch(s.EncodeEpoch(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("token", func(t *testing.T) {
+		r := TokenRecord{Version: vstamp}
+		got, err := s.DecodeToken(s.EncodeToken(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		r := ReaderRecord{Client: "client", Heartbeat: vstamp}
+		got, err := s.DecodeReader(s.EncodeReader(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("transfer", func(t *testing.T) {
+		r := TransferRecord{Client: "client"}
+		got, err := s.DecodeTransfer(s.EncodeTransfer(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("release request", func(t *testing.T) {
+		r := ReleaseRequestRecord{Client: "client"}
+		got, err := s.DecodeReleaseRequest(s.EncodeReleaseRequest(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("owner since", func(t *testing.T) {
+		r := OwnerSinceRecord{Time: now}
+		got, err := s.DecodeOwnerSince(s.EncodeOwnerSince(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		r := StatRecord{Client: "client", Field: "acquisitions", Value: 7}
+		got, err := s.DecodeStat(s.EncodeStat(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("event", func(t *testing.T) {
+		e := Event{
+			Mutex:   lockID(root),
+			Kind:    EventReleased,
+			Client:  "client",
+			Time:    now,
+			Version: vstamp,
+		}
+		got, err := s.DecodeEvent(s.EncodeEvent(e))
+		roundTrip(t, e, got, err)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		m := Metadata{Created: now, Creator: "client", Description: "desc"}
+		got, err := s.DecodeMetadata(s.EncodeMetadata(m))
+		roundTrip(t, m, got, err)
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		r := IdleRecord{Deadline: now}
+		got, err := s.DecodeIdle(s.EncodeIdle(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("last used", func(t *testing.T) {
+		r := LastUsedRecord{Time: now}
+		got, err := s.DecodeLastUsed(s.EncodeLastUsed(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("attr", func(t *testing.T) {
+		r := AttrRecord{Client: "client", Key: "pod", Value: "web-1"}
+		got, err := s.DecodeAttr(s.EncodeAttr(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("delegator", func(t *testing.T) {
+		r := DelegatorRecord{Client: "workload", Delegator: "sidecar"}
+		got, err := s.DecodeDelegator(s.EncodeDelegator(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("store", func(t *testing.T) {
+		r := StoreRecord{Key: "leader", Value: []byte("addr")}
+		got, err := s.DecodeStore(s.EncodeStore(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		r := ClockRecord{Client: "client", Version: 99, Time: now}
+		got, err := s.DecodeClock(s.EncodeClock(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("queue wait", func(t *testing.T) {
+		r := QueueWaitRecord{Version: vstamp, Client: "client", Enqueued: now, Promoted: now.Add(time.Second)}
+		got, err := s.DecodeQueueWait(s.EncodeQueueWait(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("handoff", func(t *testing.T) {
+		r := HandoffRecord{Successor: "client", Acked: true, Prepared: now}
+		got, err := s.DecodeHandoff(s.EncodeHandoff(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("type", func(t *testing.T) {
+		r := TypeRecord{Type: "mutex"}
+		got, err := s.DecodeType(s.EncodeType(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("schema version", func(t *testing.T) {
+		r := SchemaVersionRecord{Version: SchemaVersion}
+		got, err := s.DecodeSchemaVersion(s.EncodeSchemaVersion(r))
+		roundTrip(t, r, got, err)
+	})
+
+	t.Run("bad key", func(t *testing.T) {
+		_, err := s.DecodeOwner(s.EncodeSticky(StickyRecord{}))
+		require.Error(t, err)
+	})
+}
+
+func TestMigrateValues(t *testing.T) {
+	tests := map[string]testFn{
+		"version 1": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			// Write the owner & queue as version 1 of the
+			// schema would, then reopen the mutex.
+			x := kv{Subspace: root}
+			vstamp := tuple.Versionstamp{TransactionVersion: [10]byte{1}}
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				rngOwner, err := x.packOwnerRange()
+				if err != nil {
+					return nil, err
+				}
+				tr.ClearRange(rngOwner)
+				tr.Set(x.packOwnerKey("client1"), append(vstamp.TransactionVersion[:], 0, 0))
+				tr.Set(x.Pack(tuple.Tuple{"queue", vstamp}), []byte("client2"))
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			_, err = NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Equal(t, tuple.Tuple{vstamp}.Pack(), owner.hbeat)
+
+			val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+				return tr.Get(x.Pack(tuple.Tuple{"queue", vstamp})).Get()
+			})
+			require.NoError(t, err)
+			require.Equal(t, x.packQueueValue("client2", time.Time{}), val)
+
+			queue, err := x.getQueue(db)
+			require.NoError(t, err)
+			require.Equal(t, []queueKV{{name: "client2", vstamp: vstamp}}, queue)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,109 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+)
+
+// Section serializes work through distributed mutexes chosen by key. Work
+// with the same key runs one at a time across every process using sections
+// over the same root, while work with different keys runs concurrently.
+// It's the transport-independent core of [[Middleware]] and of the gRPC
+// interceptors in the grpcmutex module.
+//
+// The mutex for key 'k' is stored in the subspace ("k") of the root and is
+// constructed with the section's options for each call. The handles use
+// [[WithLocalArbitration]] so calls made by the same process queue
+// in-process.
+type Section struct {
+	db      fdb.Transactor
+	root    subspace.Subspace
+	timeout time.Duration
+	opts    []Option
+}
+
+// NewSection constructs a section storing its mutexes in 'root'. Each
+// call waits up to 'timeout' to acquire its mutex.
+func NewSection(db fdb.Transactor, root subspace.Subspace, timeout time.Duration, opts ...Option) *Section {
+	return &Section{
+		db:      db,
+		root:    root,
+		timeout: timeout,
+		opts:    slices.Concat(opts, []Option{WithLocalArbitration()}),
+	}
+}
+
+// SectionError is returned by [[Section.Run]] when the mutex
+// isn't acquired, in which case the work isn't run.
+type SectionError struct {
+	// RetryAfter is how long the caller should wait before
+	// retrying, such as when the mutex is contended or rate
+	// limited. It's zero if retrying won't help.
+	RetryAfter time.Duration
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *SectionError) Error() string {
+	return fmt.Sprintf("failed to enter section: %v", e.Err)
+}
+
+func (e *SectionError) Unwrap() error {
+	return e.Err
+}
+
+// Run acquires the mutex for key 'k', calls 'fn', and releases the mutex
+// once 'fn' returns. 'fn' is given the context of the hold's [[Guard]], so
+// it's cancelled if the mutex is lost. If the mutex isn't acquired within
+// the section's timeout, or if acquiring fails, 'fn' isn't called and a
+// [[SectionError]] is returned. Otherwise, the error of 'fn' is returned.
+func (s *Section) Run(ctx context.Context, k string, fn func(context.Context) error) error {
+	x, err := NewMutex(s.db, s.root.Sub(k), "", s.opts...)
+	if err != nil {
+		return s.fail(fmt.Errorf("failed to open mutex: %w", err))
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	token, err := x.AcquireToken(acquireCtx, s.db)
+	if err != nil {
+		// Each call is its own client, so leave the queue
+		// rather than be handed a mutex no one will release.
+		_ = x.withdraw(s.db)
+		return s.fail(err)
+	}
+
+	g := x.newGuard(ctx, s.db, token)
+	defer func() { _ = g.Release(s.db) }()
+	return fn(g.Context())
+}
+
+// fail wraps an acquisition failure in a [[SectionError]].
+func (s *Section) fail(err error) error {
+	return &SectionError{RetryAfter: retryAfter(err, s.timeout), Err: err}
+}
+
+// retryAfter returns how long a caller should wait before retrying work
+// whose acquisition failed with 'err'. If retrying won't help, zero is
+// returned.
+func retryAfter(err error, timeout time.Duration) time.Duration {
+	var rerr *RateLimitError
+	switch {
+	case errors.As(err, &rerr):
+		return rerr.RetryAfter
+	case errors.Is(err, context.DeadlineExceeded):
+		return timeout
+	case errors.Is(err, ErrDisabled), errors.Is(err, ErrFrozen), errors.Is(err, ErrOutsideWindow),
+		errors.Is(err, ErrThrottled), errors.Is(err, ErrCircuitOpen):
+		return timeout
+	}
+	return 0
+}
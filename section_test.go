@@ -0,0 +1,61 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSection(t *testing.T) {
+	tests := map[string]testFn{
+		"run": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			s := NewSection(db, root, time.Second)
+			x := kv{Subspace: root.Sub("orders")}
+
+			oops := errors.New("oops")
+			err := s.Run(context.Background(), "orders", func(ctx context.Context) error {
+				owner, err := x.getOwner(db)
+				require.NoError(t, err)
+				require.NotEmpty(t, owner.name)
+				require.NoError(t, ctx.Err())
+				return oops
+			})
+			require.Equal(t, oops, err)
+
+			// The mutex was released.
+			owner, err := x.getOwner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner.name)
+		},
+		"contended": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			holder, err := NewMutex(db, root.Sub("orders"), "holder")
+			require.NoError(t, err)
+			acquired, err := holder.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			s := NewSection(db, root, 100*time.Millisecond)
+			err = s.Run(context.Background(), "orders", func(context.Context) error {
+				t.Error("ran without the mutex")
+				return nil
+			})
+
+			var serr *SectionError
+			require.ErrorAs(t, err, &serr)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Equal(t, 100*time.Millisecond, serr.RetryAfter)
+
+			// The caller left the queue.
+			queue, err := holder.getQueue(db)
+			require.NoError(t, err)
+			require.Empty(t, queue)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,558 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// typeSemaphore marks a subspace as holding a [[Semaphore]].
+const typeSemaphore = "semaphore"
+
+// ErrPermitsMismatch is returned by [[NewSemaphore]] when the
+// semaphore was created with a different number of permits.
+var ErrPermitsMismatch = errors.New("semaphore has a different number of permits")
+
+// blockedPollInterval is how often [[Semaphore.Acquire]] tries again
+// while free slots are held back, such as during maintenance or outside
+// of an acquisition window, as no watch fires when they're let go.
+const blockedPollInterval = time.Second
+
+// errSlotsBlocked aborts a grant of permits which can't be completed
+// because free slots are held back. See [[Semaphore.tryAcquire]].
+var errSlotsBlocked = errors.New("free slots are held back")
+
+// errSlotsGrown aborts a grant of permits made without a handle for every
+// slot, which happens after another client grew the semaphore. The handles
+// are constructed & the grant is tried again. See [[Semaphore.Resize]].
+var errSlotsGrown = errors.New("semaphore has more slots than handles")
+
+// Semaphore is a distributed counting semaphore. Like a [[Pool]], each
+// permit is an ordinary mutex, called a slot, so held permits heartbeat like
+// any other hold and the permits of dead holders are given back by
+// [[Semaphore.AutoRelease]]. Unlike a pool, a client may hold many permits,
+// and the permits requested by a single call are granted all at once, so
+// clients requesting overlapping permits can't deadlock. A slot with clients
+// waiting in its own queue isn't granted, but clients waiting for permits
+// aren't queued: whichever client finds enough free permits first takes
+// them, so large requests may wait behind a steady stream of small ones.
+//
+// The number of permits is stored alongside the slots and may be changed
+// while the semaphore is in use. See [[Semaphore.Resize]].
+type Semaphore struct {
+	subspace.Subspace
+	opts []Option
+
+	// mu protects the slot handles & the indexes of
+	// the held slots. It's held while permits are
+	// granted or released but not while waiting.
+	mu    sync.Mutex
+	slots []*Mutex
+	held  []int
+}
+
+// NewSemaphore constructs a semaphore with 'permits' permits stored in
+// 'root'. The slots are stored as described by [[NewPool]] and the number
+// of permits is stored in the key ("permits") of 'root'. The number of
+// slots, which is never less than the number of permits, is stored in the
+// key ("slots"). The name & options are applied to every slot. See
+// [[NewMutex]]. If the semaphore already exists with a different number of
+// permits, [[ErrPermitsMismatch]] is returned. Use [[OpenSemaphore]] to
+// join a semaphore whatever its size. If 'root' holds another kind of
+// primitive, [[ErrWrongType]] is returned.
+func NewSemaphore(db fdb.Transactor, root subspace.Subspace, name string, permits int, opts ...Option) (_ *Semaphore, err error) {
+	defer wrapErr(&err)
+
+	if permits <= 0 {
+		return nil, fmt.Errorf("permits must be positive")
+	}
+	return newSemaphore(db, root, name, permits, opts)
+}
+
+// OpenSemaphore is like [[NewSemaphore]] but uses the stored number of
+// permits. If the semaphore hasn't been created, [[ErrNotFound]] is returned.
+func OpenSemaphore(db fdb.Transactor, root subspace.Subspace, name string, opts ...Option) (_ *Semaphore, err error) {
+	defer wrapErr(&err)
+	return newSemaphore(db, root, name, 0, opts)
+}
+
+// newSemaphore implements [[NewSemaphore]] & [[OpenSemaphore]].
+// If 'permits' is zero, the semaphore must already exist.
+func newSemaphore(db fdb.Transactor, root subspace.Subspace, name string, permits int, opts []Option) (*Semaphore, error) {
+	s := &Semaphore{Subspace: root, opts: opts}
+	_, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		x := kv{Subspace: root}
+		if err := x.claimType(tr, typeSemaphore); err != nil {
+			return nil, err
+		}
+
+		stored, ok, err := s.getPermits(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get permits: %w", err)
+		}
+		switch {
+		case !ok && permits == 0:
+			return nil, ErrNotFound
+		case !ok:
+			tr.Set(s.packPermitsKey(), packCounter(int64(permits)))
+			tr.Set(s.packSlotsKey(), packCounter(int64(permits)))
+		case permits != 0 && stored != permits:
+			return nil, fmt.Errorf("%w: expected %d but found %d", ErrPermitsMismatch, permits, stored)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.grow(db, name); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Permits returns the number of permits of the semaphore.
+func (s *Semaphore) Permits(db fdb.Transactor) (_ int, err error) {
+	defer wrapErr(&err)
+
+	permits, _, err := s.getPermits(db)
+	return permits, err
+}
+
+// WatchPermits returns a channel which signals a change to the number of
+// permits. Holders may watch it to learn when the semaphore shrinks below
+// its usage and release their permits early. When the number of permits
+// changes, the channel returns nil. If the watch setup fails or the
+// provided context is canceled, the channel returns an error.
+func (s *Semaphore) WatchPermits(ctx context.Context, db fdb.Transactor) <-chan error {
+	return watch(ctx, db, func(fdb.Transaction) (fdb.Key, error) {
+		return s.packPermitsKey(), nil
+	})
+}
+
+// Resize changes the number of permits of the semaphore. Growing the
+// semaphore creates any missing slots & wakes the clients waiting for
+// permits. Shrinking it doesn't affect the current holders: no permits
+// are granted until the holders release enough permits for the usage to
+// drop below the new number. Slots left over from a larger size are kept
+// so their holders may release them.
+func (s *Semaphore) Resize(db fdb.Transactor, permits int) (err error) {
+	defer wrapErr(&err)
+
+	if permits <= 0 {
+		return fmt.Errorf("permits must be positive")
+	}
+
+	// The slots are created before they're counted so
+	// other clients never find a permit without a slot.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n := len(s.slots); permits > n {
+		slots, err := newSlots(db, s.Subspace, s.slots[0].name, n, permits, s.opts)
+		if err != nil {
+			return err
+		}
+		s.slots = append(s.slots, slots...)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		slots, err := s.getSlots(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get slots: %w", err)
+		}
+		tr.Set(s.packPermitsKey(), packCounter(int64(permits)))
+		tr.Set(s.packSlotsKey(), packCounter(int64(max(slots, permits))))
+		return nil, nil
+	})
+	return err
+}
+
+// Slot returns the mutex of the slot with the given index.
+func (s *Semaphore) Slot(i int) *Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.slots[i]
+}
+
+// Held returns the number of permits held by this client.
+func (s *Semaphore) Held() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.held)
+}
+
+// Acquire blocks until this client obtains 'n' more permits or the context
+// is cancelled. The permits are granted in a single transaction, so either
+// all of them are obtained or none are. Each slot is granted with the same
+// checks as [[Mutex.Acquire]], so a disabled or frozen slot fails the call
+// and a rate limited slot delays it. While waiting, the client watches the
+// slots held by other clients & the number of permits, and tries again
+// whenever one changes. If 'n' exceeds the number of permits the client
+// doesn't hold, an error is returned.
+func (s *Semaphore) Acquire(ctx context.Context, db fdb.Transactor, n int) (err error) {
+	defer wrapErr(&err)
+
+	if n <= 0 {
+		return fmt.Errorf("permits must be positive")
+	}
+
+	for {
+		granted, watches, err := s.tryAcquire(db, n)
+
+		// When a slot is rate limited, wait
+		// until the next attempt may be accepted.
+		var rerr *RateLimitError
+		if errors.As(err, &rerr) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(rerr.RetryAfter):
+				continue
+			}
+		}
+
+		// Another client grew the semaphore, so
+		// construct handles for the new slots.
+		if errors.Is(err, errSlotsGrown) {
+			if err := s.grow(db, ""); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to try acquire: %w", err)
+		}
+		if granted {
+			return nil
+		}
+
+		// Without watches, the free slots are held back,
+		// so try again once the poll interval passes.
+		if len(watches) == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(blockedPollInterval):
+				continue
+			}
+		}
+		if err := waitAny(ctx, watches); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to watch slots: %w", err)
+		}
+	}
+}
+
+// Release gives back 'n' of the permits held by this client. Permits which
+// were lost because their heartbeat stopped are given back as well, without
+// affecting their new holders. If 'n' exceeds the number of permits held by
+// this client, an error is returned.
+func (s *Semaphore) Release(db fdb.Transactor, n int) (err error) {
+	defer wrapErr(&err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n <= 0 {
+		return fmt.Errorf("permits must be positive")
+	}
+	if n > len(s.held) {
+		return fmt.Errorf("released %d permits but only %d are held", n, len(s.held))
+	}
+
+	// The most recently acquired permits are released first.
+	for n > 0 {
+		i := s.held[len(s.held)-1]
+		if err := s.slots[i].Release(db); err != nil {
+			return fmt.Errorf("failed to release slot %d: %w", i, err)
+		}
+		s.held = s.held[:len(s.held)-1]
+		n--
+	}
+	return nil
+}
+
+// AutoRelease runs [[Mutex.AutoRelease]] for every slot, giving back the
+// permits of holders whose heartbeats are older than 'maxAge'. Slots added
+// by [[Semaphore.Resize]] are picked up as they're created. Like
+// Mutex.AutoRelease, it returns on the first error, stopping every slot.
+func (s *Semaphore) AutoRelease(ctx context.Context, db fdb.Transactor, maxAge time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Once the first slot returns, the rest are cancelled
+	// & their results are drained so none are leaked.
+	var running int
+	errs := make(chan error)
+	defer func() {
+		cancel()
+		for range running {
+			<-errs
+		}
+	}()
+
+	for {
+		// Watch the number of slots before reading it
+		// so a resize made after the read isn't missed.
+		watchCtx, cancelWatch := context.WithCancel(ctx)
+		grown := watch(watchCtx, db, func(fdb.Transaction) (fdb.Key, error) {
+			return s.packSlotsKey(), nil
+		})
+
+		if err := s.grow(db, ""); err != nil {
+			cancelWatch()
+			return err
+		}
+		s.mu.Lock()
+		slots := s.slots[running:]
+		s.mu.Unlock()
+		for _, x := range slots {
+			go func() { errs <- x.AutoRelease(ctx, db, maxAge) }()
+			running++
+		}
+
+		select {
+		case err := <-errs:
+			cancelWatch()
+			running--
+			return err
+		case err := <-grown:
+			cancelWatch()
+			if err != nil {
+				return fmt.Errorf("failed to watch slots: %w", err)
+			}
+		}
+	}
+}
+
+// grow constructs handles for the slots created since the handles were last
+// constructed. 'name' is only used when no handles exist. Otherwise, the
+// name of the existing handles is reused.
+func (s *Semaphore) grow(db fdb.Transactor, name string) error {
+	slots, err := s.getSlots(db)
+	if err != nil {
+		return fmt.Errorf("failed to get slots: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.slots)
+	if slots <= n {
+		return nil
+	}
+	if n > 0 {
+		name = s.slots[0].name
+	}
+	added, err := newSlots(db, s.Subspace, name, n, slots, s.opts)
+	if err != nil {
+		return err
+	}
+	s.slots = append(s.slots, added...)
+	return nil
+}
+
+// tryAcquire grants 'n' permits to this client if enough are free.
+// Otherwise, it returns watches on the slots held by other clients &
+// on the number of permits, which fire when either changes. The check &
+// the watches are made in the same transaction so a release after the
+// check isn't missed. If free slots are held back, nothing is granted &
+// no watches are returned. Watches are cancelled when their transaction
+// times out, so the transaction is made outside of any circuit breaker.
+// See [[withoutBreaker]].
+func (s *Semaphore) tryAcquire(db fdb.Transactor, n int) (bool, []fdb.FutureNil, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type result struct {
+		granted []int
+		grants  []grantResult
+		watches []fdb.FutureNil
+	}
+
+	res, err := withoutBreaker(db).Transact(func(tr fdb.Transaction) (any, error) {
+		permits, _, err := s.getPermits(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get permits: %w", err)
+		}
+		slots, err := s.getSlots(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get slots: %w", err)
+		}
+		if slots > len(s.slots) {
+			return nil, errSlotsGrown
+		}
+		if avail := permits - len(s.held); n > avail {
+			return nil, fmt.Errorf("requested %d permits but only %d aren't held by this client", n, avail)
+		}
+
+		// The usage counts every slot which is held or promised
+		// to its queue, including slots beyond the number of
+		// permits which were held before the semaphore shrank.
+		var (
+			used  = len(s.held)
+			taken []int
+			free  []int
+			busy  []int
+		)
+		for i, x := range s.slots[:slots] {
+			if slices.Contains(s.held, i) {
+				continue
+			}
+			owner, err := x.getOwner(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get owner of slot %d: %w", i, err)
+			}
+
+			// A slot owned under our name, such as one held
+			// before the process restarted, is taken over
+			// without adding to the usage. A vacant slot
+			// with a queue belongs to its queue.
+			switch owner.name {
+			case x.name:
+				used++
+				taken = append(taken, i)
+			case "":
+				next, err := x.peekQueue(tr)
+				if err != nil {
+					return nil, fmt.Errorf("failed to peek queue of slot %d: %w", i, err)
+				}
+				if next != "" {
+					used++
+					busy = append(busy, i)
+				} else if i < permits {
+					free = append(free, i)
+				}
+			default:
+				used++
+				busy = append(busy, i)
+			}
+		}
+
+		taken = taken[:min(len(taken), n)]
+		need := n - len(taken)
+		if need > len(free) || used+need > permits {
+			watches := make([]fdb.FutureNil, 0, len(busy)+1)
+			watches = append(watches, tr.Watch(s.packPermitsKey()))
+			for _, i := range busy {
+				watches = append(watches, tr.Watch(s.slots[i].packOwnershipKey()))
+			}
+			return result{watches: watches}, nil
+		}
+
+		// Each slot is granted with the checks of an ordinary
+		// acquisition. Free slots which are held back are
+		// skipped, and if too few are left, the transaction
+		// is aborted so no partial grant is committed.
+		var r result
+		for _, i := range slices.Concat(taken, free) {
+			g, err := s.slots[i].grant(tr, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to grant slot %d: %w", i, err)
+			}
+			if g.rejected != nil {
+				return nil, fmt.Errorf("failed to grant slot %d: %w", i, g.rejected)
+			}
+			if !g.acquired {
+				continue
+			}
+			r.granted = append(r.granted, i)
+			r.grants = append(r.grants, g)
+			if len(r.granted) == n {
+				return r, nil
+			}
+		}
+		return nil, errSlotsBlocked
+	})
+	if errors.Is(err, errSlotsBlocked) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	r := res.(result)
+	if r.granted == nil {
+		return false, r.watches, nil
+	}
+	for j, i := range r.granted {
+		x := s.slots[i]
+		x.token.Store(r.grants[j].holdToken())
+		x.startBeating(x.withBreaker(db))
+		s.held = append(s.held, i)
+	}
+	return true, nil, nil
+}
+
+// waitAny blocks until one of the watches fires or the context
+// ends. The remaining watches are cancelled before returning.
+func waitAny(ctx context.Context, watches []fdb.FutureNil) error {
+	defer func() {
+		for _, w := range watches {
+			w.Cancel()
+		}
+	}()
+
+	fired := make(chan error, len(watches))
+	for _, w := range watches {
+		go func() { fired <- w.Get() }()
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-fired:
+		return err
+	}
+}
+
+// getPermits returns the number of permits. If the
+// semaphore hasn't been created, false is returned.
+func (s *Semaphore) getPermits(db fdb.Transactor) (int, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(s.packPermitsKey()).Get()
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if val.([]byte) == nil {
+		return 0, false, nil
+	}
+	return int(unpackCounter(val.([]byte))), true, nil
+}
+
+// getSlots returns the number of slots. Semaphores created
+// before they could be resized have a slot per permit.
+func (s *Semaphore) getSlots(db fdb.Transactor) (int, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(s.packSlotsKey()).Get()
+	})
+	if err != nil {
+		return 0, err
+	}
+	if val.([]byte) != nil {
+		return int(unpackCounter(val.([]byte))), nil
+	}
+	permits, _, err := s.getPermits(db)
+	return permits, err
+}
+
+func (s *Semaphore) packPermitsKey() fdb.Key {
+	return s.Pack(tuple.Tuple{"permits"})
+}
+
+func (s *Semaphore) packSlotsKey() fdb.Key {
+	return s.Pack(tuple.Tuple{"slots"})
+}
@@ -0,0 +1,216 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	tests := map[string]testFn{
+		"acquire": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			s1, err := NewSemaphore(db, root, "client1", 3)
+			require.NoError(t, err)
+			s2, err := NewSemaphore(db, root, "client2", 3)
+			require.NoError(t, err)
+			permits, err := s1.Permits(db)
+			require.NoError(t, err)
+			require.Equal(t, 3, permits)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			require.NoError(t, s1.Acquire(ctx, db, 2))
+			require.Equal(t, 2, s1.Held())
+
+			// Only one permit is left, so the
+			// second client waits for another.
+			done := make(chan error, 1)
+			go func() { done <- s2.Acquire(ctx, db, 2) }()
+
+			select {
+			case err := <-done:
+				t.Fatalf("acquired without enough permits: %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			// No partial grant was made.
+			require.Equal(t, 0, s2.Held())
+
+			require.NoError(t, s1.Release(db, 1))
+			require.NoError(t, <-done)
+			require.Equal(t, 1, s1.Held())
+			require.Equal(t, 2, s2.Held())
+
+			var owners []string
+			for i := range permits {
+				owner, err := s1.Slot(i).getOwner(db)
+				require.NoError(t, err)
+				owners = append(owners, owner.name)
+			}
+			require.ElementsMatch(t, []string{"client1", "client2", "client2"}, owners)
+
+			require.NoError(t, s1.Release(db, 1))
+			require.NoError(t, s2.Release(db, 2))
+		},
+		"invalid": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewSemaphore(db, root, "client", 0)
+			require.Error(t, err)
+
+			s, err := NewSemaphore(db, root, "client", 2)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			require.Error(t, s.Acquire(ctx, db, 0))
+			require.Error(t, s.Acquire(ctx, db, 3))
+			require.Error(t, s.Release(db, 1))
+
+			require.NoError(t, s.Acquire(ctx, db, 2))
+			defer func() { _ = s.Release(db, 2) }()
+			require.Error(t, s.Acquire(ctx, db, 1))
+		},
+		"stored permits": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewSemaphore(db, root, "client1", 2)
+			require.NoError(t, err)
+
+			_, err = NewSemaphore(db, root, "client2", 3)
+			require.ErrorIs(t, err, ErrPermitsMismatch)
+
+			// A pool can't share the semaphore's slots.
+			_, err = NewPool(db, root, "client3", 2)
+			require.ErrorIs(t, err, ErrWrongType)
+		},
+		"slot queue": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			s, err := NewSemaphore(db, root, "client", 1)
+			require.NoError(t, err)
+
+			// Leave a client waiting in the queue of the
+			// vacant slot by parking it during maintenance
+			// & ending maintenance without a handoff.
+			other, err := NewMutex(db, root.Sub("slot", int64(0)), "other")
+			require.NoError(t, err)
+			require.NoError(t, other.setMaintenance(db, true))
+			acquired, err := other.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+			_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+				tr.Clear(other.packMaintenanceKey())
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			// The queued client isn't jumped.
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			require.ErrorIs(t, s.Acquire(ctx, db, 1), context.DeadlineExceeded)
+			require.Equal(t, 0, s.Held())
+		},
+		"release while waiting": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			s, err := NewSemaphore(db, root, "client", 2)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			require.NoError(t, s.Acquire(ctx, db, 1))
+
+			other, err := NewSemaphore(db, root, "other", 2)
+			require.NoError(t, err)
+			require.NoError(t, other.Acquire(ctx, db, 1))
+
+			// The handle stays usable while one of
+			// its goroutines waits for permits.
+			done := make(chan error, 1)
+			go func() { done <- s.Acquire(ctx, db, 1) }()
+			time.Sleep(100 * time.Millisecond)
+			require.Equal(t, 1, s.Held())
+
+			require.NoError(t, other.Release(db, 1))
+			require.NoError(t, <-done)
+			require.Equal(t, 2, s.Held())
+			require.NoError(t, s.Release(db, 2))
+		},
+		"resize": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			s1, err := NewSemaphore(db, root, "client1", 3)
+			require.NoError(t, err)
+			s2, err := NewSemaphore(db, root, "client2", 3)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			require.NoError(t, s1.Acquire(ctx, db, 3))
+
+			// Shrinking below the usage doesn't
+			// evict the holder.
+			changed := s2.WatchPermits(ctx, db)
+			require.NoError(t, s2.Resize(db, 1))
+			require.NoError(t, <-changed)
+			require.Equal(t, 3, s1.Held())
+
+			permits, err := s1.Permits(db)
+			require.NoError(t, err)
+			require.Equal(t, 1, permits)
+
+			// New grants wait until the usage drains
+			// below the new number of permits.
+			done := make(chan error, 1)
+			go func() { done <- s2.Acquire(ctx, db, 1) }()
+
+			require.NoError(t, s1.Release(db, 2))
+			select {
+			case err := <-done:
+				t.Fatalf("acquired before usage drained: %v", err)
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			require.NoError(t, s1.Release(db, 1))
+			require.NoError(t, <-done)
+			require.NoError(t, s2.Release(db, 1))
+
+			// Growing creates slots which clients
+			// opened at the old size pick up.
+			require.NoError(t, s1.Resize(db, 4))
+			require.NoError(t, s2.Acquire(ctx, db, 4))
+			require.Equal(t, 4, s2.Held())
+			require.NoError(t, s2.Release(db, 4))
+
+			s3, err := OpenSemaphore(db, root, "client3")
+			require.NoError(t, err)
+			permits, err = s3.Permits(db)
+			require.NoError(t, err)
+			require.Equal(t, 4, permits)
+
+			_, err = OpenSemaphore(db, root.Sub("missing"), "client")
+			require.ErrorIs(t, err, ErrNotFound)
+		},
+		"dead holder": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			dead, err := NewSemaphore(db, root, "dead", 2)
+			require.NoError(t, err)
+			live, err := NewSemaphore(db, root, "live", 2, WithLeaseTTL(100*time.Millisecond))
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			require.NoError(t, dead.Acquire(ctx, db, 2))
+
+			// Simulate the holder dying.
+			for i := range 2 {
+				dead.Slot(i).stopBeating()
+			}
+
+			go func() { _ = live.AutoRelease(ctx, db, 200*time.Millisecond) }()
+
+			require.NoError(t, live.Acquire(ctx, db, 2))
+			require.Equal(t, 2, live.Held())
+			require.NoError(t, live.Release(db, 2))
+		},
+	}
+	runTests(t, tests)
+}
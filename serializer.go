@@ -0,0 +1,122 @@
+package mutex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// customEncoding prefixes values encoded by a [[Serializer]]. It's never the
+// first byte of a tuple, so these values are told apart from the default
+// encoding.
+const customEncoding = 0xff
+
+// errCustomEncoding is returned when decoding a value encoded by a
+// [[Serializer]] without one.
+var errCustomEncoding = errors.New("value was encoded by a custom serializer")
+
+// Serializer encodes the payloads this package stores on behalf of the
+// application, allowing them to interoperate with existing schemas. Any
+// encoding may be plugged in, such as JSON, protobuf, or msgpack. See
+// [[WithSerializer]].
+type Serializer interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONSerializer encodes payloads using [[encoding/json]].
+var JSONSerializer Serializer = jsonSerializer{}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// WithSerializer sets the encoding of the mutex's [[Metadata]] and of the
+// values written by [[ConfigStore.SetValue]]. The metadata is stored with a
+// one byte prefix, followed by the serialized [[Metadata]] struct. Every
+// handle & [[Observer]] of the mutex must use the same serializer. Readers
+// without it report the metadata as missing. By default, metadata is tuple
+// encoded and config values are encoded with [[JSONSerializer]].
+func WithSerializer(s Serializer) Option {
+	return func(x *Mutex) {
+		x.serializer = s
+	}
+}
+
+// SetValue serializes 'v' and writes it to the given key. Only the leader
+// may write. See [[ConfigStore.Set]] & [[WithSerializer]].
+func (c *ConfigStore) SetValue(db fdb.Transactor, key string, v any) (err error) {
+	defer wrapErr(&err)
+
+	val, err := c.serializer().Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to serialize value: %w", err)
+	}
+	return c.Set(db, key, val)
+}
+
+// GetValue reads the value of the given key and deserializes it into 'v',
+// which must be a pointer. If the key doesn't exist, 'v' is left untouched
+// and false is returned. See [[ConfigStore.Get]] & [[WithSerializer]].
+func (c *ConfigStore) GetValue(db fdb.Transactor, key string, v any) (_ bool, err error) {
+	defer wrapErr(&err)
+
+	val, err := c.Get(db, key)
+	if err != nil || val == nil {
+		return false, err
+	}
+	if err := c.serializer().Unmarshal(val, v); err != nil {
+		return false, fmt.Errorf("failed to deserialize value: %w", err)
+	}
+	return true, nil
+}
+
+func (c *ConfigStore) serializer() Serializer {
+	if c.x.serializer != nil {
+		return c.x.serializer
+	}
+	return JSONSerializer
+}
+
+// encodeMetadata encodes the metadata using the configured
+// [[Serializer]], or as a tuple if none is configured.
+func (x *kv) encodeMetadata(meta metadataKV) ([]byte, error) {
+	if x.serializer == nil {
+		return x.packMetadataValue(meta), nil
+	}
+	val, err := x.serializer.Marshal(toMetadata(meta))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{customEncoding}, val...), nil
+}
+
+// decodeMetadata decodes metadata written by [[kv.encodeMetadata]]. If
+// it was encoded by a [[Serializer]] and none is configured, the error
+// wraps [[errCustomEncoding]].
+func (x *kv) decodeMetadata(val []byte) (metadataKV, error) {
+	if len(val) == 0 || val[0] != customEncoding {
+		return x.unpackMetadataValue(val)
+	}
+	if x.serializer == nil {
+		return metadataKV{}, errCustomEncoding
+	}
+
+	var meta Metadata
+	if err := x.serializer.Unmarshal(val[1:], &meta); err != nil {
+		return metadataKV{}, fmt.Errorf("failed to deserialize: %w", err)
+	}
+	return metadataKV{
+		created:     meta.Created,
+		creator:     meta.Creator,
+		description: meta.Description,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package mutex
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializer(t *testing.T) {
+	tests := map[string]testFn{
+		"metadata": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client", WithSerializer(JSONSerializer), WithDescription("desc"))
+			require.NoError(t, err)
+
+			meta, ok, err := x.Metadata(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "client", meta.Creator)
+			require.Equal(t, "desc", meta.Description)
+
+			// Other schemas can read the stored value.
+			val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+				return tr.Get(x.packMetadataKey()).Get()
+			})
+			require.NoError(t, err)
+			require.Equal(t, byte(customEncoding), val.([]byte)[0])
+			var decoded Metadata
+			require.NoError(t, json.Unmarshal(val.([]byte)[1:], &decoded))
+			require.Equal(t, "desc", decoded.Description)
+
+			// Readers must use the same serializer.
+			obs, err := NewObserver(db, root)
+			require.NoError(t, err)
+			_, ok, err = obs.Metadata(db)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			obs, err = NewObserver(db, root, WithSerializer(JSONSerializer))
+			require.NoError(t, err)
+			meta, ok, err = obs.Metadata(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, "desc", meta.Description)
+		},
+
+		"config value": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "leader")
+			require.NoError(t, err)
+			require.NoError(t, x.Acquire(context.Background(), db))
+
+			type endpoint struct {
+				Host string
+				Port int
+			}
+			store := NewConfigStore(x)
+			require.NoError(t, store.SetValue(db, "endpoint", endpoint{"host", 80}))
+
+			var e endpoint
+			ok, err := store.GetValue(db, "endpoint", &e)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, endpoint{"host", 80}, e)
+
+			ok, err = store.GetValue(db, "missing", &e)
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,195 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// typeSharded marks a subspace as holding a [[ShardedMutex]].
+const typeSharded = "sharded"
+
+// ErrShardsMismatch is returned by [[NewShardedMutex]] when the
+// lock was sharded into a different number of shards.
+var ErrShardsMismatch = errors.New("lock has a different number of shards")
+
+// ShardedMutex spreads a hot keyed lock across several mutexes, called
+// shards, so contention on a single key is split between them. Work which
+// only needs some share of the resource, such as appending to a sharded
+// log, holds any one shard via [[ShardedMutex.AcquireAny]]. Exclusive work,
+// such as compacting the log, holds every shard via
+// [[ShardedMutex.AcquireAll]], which excludes all other holders. Each shard
+// is an ordinary mutex with its own queue, like the slots of a [[Pool]].
+type ShardedMutex struct {
+	subspace.Subspace
+	shards []*Mutex
+
+	// mu protects the shards held by this client. Either
+	// one shard is held, every shard is held, or neither.
+	mu   sync.Mutex
+	held int
+	all  bool
+}
+
+// NewShardedMutex is like [[NewKeyedMutex]] but shards the lock identified
+// by 'key' across 'shards' mutexes. The lock is stored in [[KeyedSubspace]]
+// and its shards are stored there as described by [[NewPool]]. The number
+// of shards is stored in the key ("shards") of that subspace. The name &
+// options are applied to every shard. See [[NewMutex]].
+//
+// Every client of the lock must shard it the same way. If the lock was
+// sharded into a different number of shards, [[ErrShardsMismatch]] is
+// returned. If the key is used by an unsharded keyed mutex, or any other
+// kind of primitive, [[ErrWrongType]] is returned.
+func NewShardedMutex(db fdb.Transactor, parent subspace.Subspace, key tuple.Tuple, name string, shards int, opts ...Option) (_ *ShardedMutex, err error) {
+	defer wrapErr(&err)
+
+	if len(key) == 0 {
+		return nil, fmt.Errorf("key must not be empty")
+	}
+	if shards <= 0 {
+		return nil, fmt.Errorf("shards must be positive")
+	}
+
+	root := KeyedSubspace(parent, key)
+	m := &ShardedMutex{Subspace: root, held: -1}
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		x := kv{Subspace: root}
+		if err := x.claimType(tr, typeSharded); err != nil {
+			return nil, err
+		}
+
+		stored, ok, err := m.getShards(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shards: %w", err)
+		}
+		switch {
+		case !ok:
+			tr.Set(m.packShardsKey(), packCounter(int64(shards)))
+		case stored != shards:
+			return nil, fmt.Errorf("%w: expected %d but found %d", ErrShardsMismatch, shards, stored)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.shards, err = newSlots(db, root, name, 0, shards, opts)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Shards returns the number of shards of the lock.
+func (m *ShardedMutex) Shards() int {
+	return len(m.shards)
+}
+
+// Shard returns the mutex of the shard with the given index.
+func (m *ShardedMutex) Shard(i int) *Mutex {
+	return m.shards[i]
+}
+
+// AcquireAny blocks until this client holds one of the shards, then returns
+// its index. The client waits in the queue of every shard and takes whichever
+// is free first. See [[AcquireAny]]. If a shard is already held, its index is
+// returned immediately. If every shard is held, an error is returned.
+func (m *ShardedMutex) AcquireAny(ctx context.Context, db fdb.Transactor) (_ int, err error) {
+	defer wrapErr(&err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.all {
+		return -1, fmt.Errorf("every shard is already held")
+	}
+	if m.held >= 0 {
+		return m.held, nil
+	}
+	i, err := acquireAny(ctx, db, m.shards)
+	if err != nil {
+		return -1, err
+	}
+	m.held = i
+	return i, nil
+}
+
+// AcquireAll blocks until this client holds every shard, excluding all
+// other holders of the lock. If the shards aren't acquired before 'timeout'
+// passes or the context ends, the shards obtained so far are released. See
+// [[AcquireAll]]. If every shard is already held, this method is a noop. If
+// a single shard is held, an error is returned.
+func (m *ShardedMutex) AcquireAll(ctx context.Context, db fdb.Transactor, timeout time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.all {
+		return nil
+	}
+	if m.held >= 0 {
+		return fmt.Errorf("shard %d is already held", m.held)
+	}
+	if err := AcquireAll(ctx, db, timeout, m.shards...); err != nil {
+		return err
+	}
+	m.all = true
+	return nil
+}
+
+// Release releases the shards held by this client. If
+// no shard is held, this method is a noop.
+func (m *ShardedMutex) Release(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case m.all:
+		for i := len(m.shards) - 1; i >= 0; i-- {
+			if rerr := m.shards[i].Release(db); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to release shard %d: %w", i, rerr))
+			}
+		}
+		if err != nil {
+			return err
+		}
+		m.all = false
+
+	case m.held >= 0:
+		if err := m.shards[m.held].Release(db); err != nil {
+			return err
+		}
+		m.held = -1
+	}
+	return nil
+}
+
+// getShards returns the number of shards. If
+// the lock hasn't been created, false is returned.
+func (m *ShardedMutex) getShards(db fdb.Transactor) (int, bool, error) {
+	val, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		return tr.Get(m.packShardsKey()).Get()
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if val.([]byte) == nil {
+		return 0, false, nil
+	}
+	return int(unpackCounter(val.([]byte))), true, nil
+}
+
+func (m *ShardedMutex) packShardsKey() fdb.Key {
+	return m.Pack(tuple.Tuple{"shards"})
+}
@@ -0,0 +1,96 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedMutex(t *testing.T) {
+	key := tuple.Tuple{"orders", int64(42)}
+
+	tests := map[string]testFn{
+		"any": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			m1, err := NewShardedMutex(db, root, key, "client1", 2)
+			require.NoError(t, err)
+			m2, err := NewShardedMutex(db, root, key, "client2", 2)
+			require.NoError(t, err)
+			require.Equal(t, 2, m1.Shards())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			// Both clients hold the lock at once.
+			i1, err := m1.AcquireAny(ctx, db)
+			require.NoError(t, err)
+			i2, err := m2.AcquireAny(ctx, db)
+			require.NoError(t, err)
+			require.NotEqual(t, i1, i2)
+
+			i, err := m1.AcquireAny(ctx, db)
+			require.NoError(t, err)
+			require.Equal(t, i1, i)
+
+			require.NoError(t, m1.Release(db))
+			require.NoError(t, m2.Release(db))
+		},
+		"all": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			m1, err := NewShardedMutex(db, root, key, "client1", 3)
+			require.NoError(t, err)
+			m2, err := NewShardedMutex(db, root, key, "client2", 3)
+			require.NoError(t, err)
+
+			i, err := m1.AcquireAny(context.Background(), db)
+			require.NoError(t, err)
+
+			// The exclusive holder waits for every shard.
+			err = m2.AcquireAll(context.Background(), db, 100*time.Millisecond)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			for j := range m2.Shards() {
+				owner, err := m2.Shard(j).getOwner(db)
+				require.NoError(t, err)
+				if j == i {
+					require.Equal(t, "client1", owner.name)
+				} else {
+					require.Empty(t, owner.name)
+				}
+			}
+
+			require.NoError(t, m1.Release(db))
+			require.NoError(t, m2.AcquireAll(context.Background(), db, time.Second))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err = m1.AcquireAny(ctx, db)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			_, err = m2.AcquireAny(context.Background(), db)
+			require.Error(t, err)
+
+			require.NoError(t, m2.Release(db))
+			for j := range m2.Shards() {
+				owner, err := m2.Shard(j).getOwner(db)
+				require.NoError(t, err)
+				require.Empty(t, owner.name)
+			}
+		},
+		"mismatch": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			_, err := NewShardedMutex(db, root, key, "client1", 2)
+			require.NoError(t, err)
+
+			_, err = NewShardedMutex(db, root, key, "client2", 3)
+			require.ErrorIs(t, err, ErrShardsMismatch)
+
+			// The key can't also be used unsharded.
+			_, err = NewKeyedMutex(db, root, key, "client3")
+			require.ErrorIs(t, err, ErrWrongType)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,132 @@
+package mutex
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// WithStaleReads lets the inspection methods of an [[Observer]] trade
+// freshness for latency, which suits dashboards & other high-frequency
+// owner checks. Read versions are obtained with FDB's causal-read-risky
+// option, so they're cheaper to obtain but may, rarely, miss the latest
+// commits. Additionally, if 'maxStaleness' is positive, a read version
+// is reused for up to 'maxStaleness', skipping the request for a read
+// version entirely. The results may then be up to 'maxStaleness' old.
+// FDB rejects read versions older than about five seconds, so larger
+// values aren't useful. [[Observer.HeartbeatAge]] is measured against the
+// read version, so it never reuses one. The watch & event streaming
+// methods aren't affected. The option only applies to observers. It
+// doesn't affect reads made through a [[Mutex]].
+func WithStaleReads(maxStaleness time.Duration) Option {
+	return func(x *Mutex) {
+		x.staleReads = true
+		x.staleness = maxStaleness
+	}
+}
+
+// readVersionCache holds a recent read version for reuse
+// by transactions which tolerate stale reads.
+type readVersionCache struct {
+	staleness time.Duration
+
+	mu      sync.Mutex
+	version int64
+	at      time.Time
+}
+
+// get returns the cached read version. If there is none
+// or it's older than the staleness bound, false is returned.
+func (c *readVersionCache) get() (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.at.IsZero() || time.Since(c.at) > c.staleness {
+		return 0, false
+	}
+	return c.version, true
+}
+
+// put caches a read version which was requested at time 'at'.
+func (c *readVersionCache) put(version int64, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if at.After(c.at) {
+		c.version = version
+		c.at = at
+	}
+}
+
+// reset drops the cached read version.
+func (c *readVersionCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version = 0
+	c.at = time.Time{}
+}
+
+// staleTransactor wraps a database so its transactions use causal-read-risky
+// read versions and, if cache isn't nil, reuse recent read versions. Only
+// databases are wrapped: transactions passed in by the caller are left as
+// they are. See [[WithStaleReads]].
+type staleTransactor struct {
+	fdb.Database
+	cache *readVersionCache
+}
+
+func (t staleTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	attempt := 0
+	return t.Database.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := t.prepare(tr, attempt); err != nil {
+			return nil, err
+		}
+		attempt++
+		return f(tr)
+	})
+}
+
+func (t staleTransactor) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	attempt := 0
+	return t.Database.ReadTransact(func(rtr fdb.ReadTransaction) (any, error) {
+		if tr, ok := rtr.(fdb.Transaction); ok {
+			if err := t.prepare(tr, attempt); err != nil {
+				return nil, err
+			}
+		}
+		attempt++
+		return f(rtr)
+	})
+}
+
+// prepare configures the transaction before each attempt. The cached read
+// version is only tried on the first attempt. If that attempt fails, the
+// version may be too old, so it's dropped and a new one is obtained.
+func (t staleTransactor) prepare(tr fdb.Transaction, attempt int) error {
+	if err := tr.Options().SetCausalReadRisky(); err != nil {
+		return fmt.Errorf("failed to set causal read risky: %w", err)
+	}
+	if t.cache == nil {
+		return nil
+	}
+
+	if attempt == 0 {
+		if version, ok := t.cache.get(); ok {
+			tr.SetReadVersion(version)
+			return nil
+		}
+	} else {
+		t.cache.reset()
+	}
+
+	at := time.Now()
+	version, err := tr.GetReadVersion().Get()
+	if err != nil {
+		return fmt.Errorf("failed to get read version: %w", err)
+	}
+	t.cache.put(version, at)
+	return nil
+}
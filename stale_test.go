@@ -0,0 +1,94 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaleReads(t *testing.T) {
+	tests := map[string]testFn{
+		"reuse": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			stale, err := NewObserver(db, root, WithStaleReads(time.Minute))
+			require.NoError(t, err)
+
+			risky, err := NewObserver(db, root, WithStaleReads(0))
+			require.NoError(t, err)
+
+			owner, err := stale.Owner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner)
+
+			require.NoError(t, x.Release(db))
+
+			// The cached read version predates the release.
+			owner, err = stale.Owner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner)
+
+			// Without reuse, the release is observed.
+			owner, err = risky.Owner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner)
+
+			// Reads within a caller's transaction aren't affected.
+			owner2, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+				return stale.Owner(tr)
+			})
+			require.NoError(t, err)
+			require.Empty(t, owner2)
+		},
+		"expiry": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root, WithStaleReads(50*time.Millisecond))
+			require.NoError(t, err)
+
+			owner, err := obs.Owner(db)
+			require.NoError(t, err)
+			require.Empty(t, owner)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			time.Sleep(100 * time.Millisecond)
+
+			owner, err = obs.Owner(db)
+			require.NoError(t, err)
+			require.Equal(t, "client1", owner)
+		},
+		"heartbeat age": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			obs, err := NewObserver(db, root, WithStaleReads(time.Minute))
+			require.NoError(t, err)
+
+			_, ok, err := obs.HeartbeatAge(db)
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// The heartbeat age never reuses a read version.
+			_, ok, err = obs.HeartbeatAge(db)
+			require.NoError(t, err)
+			require.True(t, ok)
+		},
+	}
+	runTests(t, tests)
+}
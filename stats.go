@@ -0,0 +1,76 @@
+package mutex
+
+import (
+	"sort"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ClientStats describes how a client has fared when contending
+// for a mutex. Statistics are kept for every client which has
+// ever held the mutex and can be used to spot starvation.
+type ClientStats struct {
+	// Name identifies the client.
+	Name string
+
+	// Acquisitions is the number of times the client
+	// has become the owner of the mutex.
+	Acquisitions int64
+
+	// HoldTime is the total time the client has owned the
+	// mutex, including the current hold if it's the owner.
+	HoldTime time.Duration
+
+	// Preemptions is the number of times the client's hold
+	// was ended by [[Mutex.AutoRelease]] because its
+	// heartbeat expired.
+	Preemptions int64
+}
+
+// Stats returns the statistics of every client which has held
+// the mutex, sorted by name.
+func (x *Mutex) Stats(db fdb.Transactor) (_ []ClientStats, err error) {
+	defer wrapErr(&err)
+
+	stats, err := x.getStats(x.withBreaker(db))
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]ClientStats, 0, len(stats))
+	for name, stat := range stats {
+		list = append(list, ClientStats{
+			Name:         name,
+			Acquisitions: stat.acquisitions,
+			HoldTime:     stat.holdTime,
+			Preemptions:  stat.preemptions,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// ClientStats returns the statistics of the client with the provided
+// name. If the client has never held the mutex then false is returned.
+func (x *Mutex) ClientStats(db fdb.Transactor, name string) (_ ClientStats, _ bool, err error) {
+	defer wrapErr(&err)
+
+	stats, err := x.getStats(x.withBreaker(db))
+	if err != nil {
+		return ClientStats{}, false, err
+	}
+
+	stat, ok := stats[name]
+	if !ok {
+		return ClientStats{}, false, nil
+	}
+	return ClientStats{
+		Name:         name,
+		Acquisitions: stat.acquisitions,
+		HoldTime:     stat.holdTime,
+		Preemptions:  stat.preemptions,
+	}, true, nil
+}
@@ -0,0 +1,85 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	tests := map[string]testFn{
+		"acquisitions": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			for i := 0; i < 2; i++ {
+				acquired, err := x1.TryAcquire(db)
+				require.NoError(t, err)
+				require.True(t, acquired)
+
+				time.Sleep(10 * time.Millisecond)
+
+				err = x1.Release(db)
+				require.NoError(t, err)
+			}
+
+			acquired, err := x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			stats, err := x1.Stats(db)
+			require.NoError(t, err)
+			require.Len(t, stats, 2)
+
+			require.Equal(t, "client1", stats[0].Name)
+			require.Equal(t, int64(2), stats[0].Acquisitions)
+			require.GreaterOrEqual(t, stats[0].HoldTime, 20*time.Millisecond)
+			require.Zero(t, stats[0].Preemptions)
+
+			// The current hold counts towards the hold time.
+			require.Equal(t, "client2", stats[1].Name)
+			require.Equal(t, int64(1), stats[1].Acquisitions)
+			require.Greater(t, stats[1].HoldTime, time.Duration(0))
+
+			_, ok, err := x1.ClientStats(db, "client3")
+			require.NoError(t, err)
+			require.False(t, ok)
+		},
+		"preemptions": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Stop heartbeating so auto release is triggered.
+			x1.stopBeating()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			goAutoRelease(t, x2, ctx, db, 500*time.Millisecond)
+
+			err = x2.Acquire(context.Background(), db)
+			require.NoError(t, err)
+
+			stat, ok, err := x2.ClientStats(db, "client1")
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, int64(1), stat.Preemptions)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,134 @@
+package mutex
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ReleaserState describes the health of a [[Releaser]].
+type ReleaserState int
+
+const (
+	// ReleaserStopped means the releaser isn't running.
+	ReleaserStopped ReleaserState = iota
+
+	// ReleaserRunning means [[Mutex.AutoRelease]] is running.
+	ReleaserRunning
+
+	// ReleaserBackingOff means [[Mutex.AutoRelease]] failed with
+	// a transient error and is waiting to be restarted. Expired
+	// holds aren't released until it restarts.
+	ReleaserBackingOff
+
+	// ReleaserFailed means [[Mutex.AutoRelease]] failed with a
+	// fatal error and the releaser gave up. See [[Releaser.Err]].
+	ReleaserFailed
+)
+
+func (s ReleaserState) String() string {
+	switch s {
+	case ReleaserStopped:
+		return "stopped"
+	case ReleaserRunning:
+		return "running"
+	case ReleaserBackingOff:
+		return "backing off"
+	case ReleaserFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Releaser supervises [[Mutex.AutoRelease]]. AutoRelease returns on the
+// first error, including transient ones like a failed watch, which would
+// silently disable expiry. The releaser restarts it with exponential
+// backoff and only gives up on errors classified as [[ClassFatal]].
+type Releaser struct {
+	x      *Mutex
+	maxAge time.Duration
+
+	state    atomic.Int32
+	restarts atomic.Int64
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewReleaser constructs a releaser which runs [[Mutex.AutoRelease]]
+// for the given mutex with the given 'maxAge'.
+func NewReleaser(x *Mutex, maxAge time.Duration) *Releaser {
+	return &Releaser{x: x, maxAge: maxAge}
+}
+
+// Run runs [[Mutex.AutoRelease]] until the context ends or it fails with a
+// fatal error. Transient errors are retried with exponential backoff, capped
+// at 'maxAge'. The backoff is reset once AutoRelease has run for 'maxAge'
+// without failing. When the context ends, its error is returned.
+func (r *Releaser) Run(ctx context.Context, db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	defer func() {
+		if ctx.Err() != nil {
+			r.state.Store(int32(ReleaserStopped))
+		}
+	}()
+
+	var failures int
+	for {
+		r.state.Store(int32(ReleaserRunning))
+		start := time.Now()
+		err := r.x.AutoRelease(ctx, db, r.maxAge)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		r.mu.Lock()
+		r.err = err
+		r.mu.Unlock()
+
+		if err == nil {
+			r.state.Store(int32(ReleaserStopped))
+			return nil
+		}
+		if Classify(err) == ClassFatal {
+			r.state.Store(int32(ReleaserFailed))
+			return err
+		}
+
+		if time.Since(start) >= r.maxAge {
+			failures = 0
+		}
+		failures++
+		r.restarts.Add(1)
+		r.state.Store(int32(ReleaserBackingOff))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(minBackoff<<min(failures-1, 16), r.maxAge)):
+		}
+	}
+}
+
+// State returns the health of the releaser.
+func (r *Releaser) State() ReleaserState {
+	return ReleaserState(r.state.Load())
+}
+
+// Restarts returns the number of times AutoRelease failed
+// with a transient error and was restarted.
+func (r *Releaser) Restarts() int64 {
+	return r.restarts.Load()
+}
+
+// Err returns the latest error returned by AutoRelease,
+// or nil if it hasn't failed.
+func (r *Releaser) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
@@ -0,0 +1,94 @@
+package mutex
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaser(t *testing.T) {
+	tests := map[string]testFn{
+		"restart": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			x2, err := NewMutex(db, root, "client2")
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Stop heartbeating so auto release is triggered.
+			x1.stopBeating()
+
+			flaky := &retryableTransactor{Transactor: db}
+			flaky.failures.Store(3)
+
+			r := NewReleaser(x2, 200*time.Millisecond)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- r.Run(ctx, flaky) }()
+
+			require.Eventually(t, func() bool {
+				owner, err := x2.getOwner(db)
+				return err == nil && owner.name == ""
+			}, 2*time.Second, 10*time.Millisecond)
+			require.Equal(t, ReleaserRunning, r.State())
+			require.Positive(t, r.Restarts())
+			require.True(t, IsRetryable(r.Err()))
+
+			cancel()
+			require.ErrorIs(t, <-done, context.Canceled)
+			require.Equal(t, ReleaserStopped, r.State())
+		},
+		"fatal": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			flaky := &flakyTransactor{Transactor: db}
+			flaky.failing.Store(true)
+
+			r := NewReleaser(x, time.Second)
+			err = r.Run(context.Background(), flaky)
+			require.Error(t, err)
+			require.Equal(t, ClassFatal, Classify(err))
+			require.Equal(t, ReleaserFailed, r.State())
+			require.Zero(t, r.Restarts())
+		},
+	}
+
+	runTests(t, tests)
+}
+
+// retryableTransactor fails the given number of transactions
+// with a retryable error, then lets the rest through.
+type retryableTransactor struct {
+	fdb.Transactor
+	failures atomic.Int64
+}
+
+func (t *retryableTransactor) fail() error {
+	if t.failures.Add(-1) >= 0 {
+		return fdb.Error{Code: 1004}
+	}
+	return nil
+}
+
+func (t *retryableTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	if err := t.fail(); err != nil {
+		return nil, err
+	}
+	return t.Transactor.Transact(f)
+}
+
+func (t *retryableTransactor) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	if err := t.fail(); err != nil {
+		return nil, err
+	}
+	return t.Transactor.ReadTransact(f)
+}
@@ -0,0 +1,104 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrThrottled is returned by [[Mutex.TryAcquire]] when the handle has
+// exceeded its acquisition rate. See [[WithAcquireRateLimit]].
+var ErrThrottled = errors.New("acquisition rate limit exceeded")
+
+// WithAcquireRateLimit limits how often the handle attempts to acquire the
+// mutex. Attempts are governed by a token bucket which holds up to 'burst'
+// tokens and refills at 'rate' tokens per second. Each call to [[Mutex.Acquire]]
+// or [[Mutex.TryAcquire]] spends a token. When the bucket is empty, TryAcquire
+// returns [[ErrThrottled]] without contacting FDB and Acquire waits for a token
+// or for its context to end. This keeps a hot retry loop in application code
+// from flooding FDB with transactions against a contended mutex.
+func WithAcquireRateLimit(rate float64, burst int) Option {
+	if burst < 1 {
+		burst = 1
+	}
+	return func(x *Mutex) {
+		x.throttle = &throttle{
+			rate:   rate,
+			burst:  float64(burst),
+			tokens: float64(burst),
+			last:   time.Now(),
+		}
+	}
+}
+
+// throttle is a token bucket limiting acquisition attempts.
+type throttle struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// take spends a token if one is available. Otherwise, it
+// returns how long until a token will be available.
+func (b *throttle) take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.rate <= 0 {
+		// The bucket never refills, so the
+		// caller waits for its context to end.
+		return false, time.Hour
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// wait blocks until a token is spent or the context ends.
+func (b *throttle) wait(ctx context.Context) error {
+	for {
+		ok, delay := b.take()
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// allowAttempt spends a token for a non-blocking acquisition
+// attempt. If the handle isn't throttled, it always succeeds.
+func (x *Mutex) allowAttempt() error {
+	if x.throttle == nil {
+		return nil
+	}
+	if ok, _ := x.throttle.take(); !ok {
+		return ErrThrottled
+	}
+	return nil
+}
+
+// waitAttempt blocks until the handle may
+// make a blocking acquisition attempt.
+func (x *Mutex) waitAttempt(ctx context.Context) error {
+	if x.throttle == nil {
+		return nil
+	}
+	return x.throttle.wait(ctx)
+}
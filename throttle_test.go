@@ -0,0 +1,81 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+	t.Run("bucket", func(t *testing.T) {
+		b := throttle{rate: 100, burst: 2, tokens: 2, last: time.Now()}
+
+		ok, _ := b.take()
+		require.True(t, ok)
+		ok, _ = b.take()
+		require.True(t, ok)
+
+		ok, delay := b.take()
+		require.False(t, ok)
+		require.Greater(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, b.wait(ctx))
+	})
+
+	t.Run("wait cancelled", func(t *testing.T) {
+		b := throttle{rate: 0, burst: 1, last: time.Now()}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, b.wait(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestAcquireRateLimit(t *testing.T) {
+	tests := map[string]testFn{
+		"try acquire": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x1, err := NewMutex(db, root, "client1")
+			require.NoError(t, err)
+			defer func() { require.NoError(t, x1.Release(db)) }()
+
+			x2, err := NewMutex(db, root, "client2", WithAcquireRateLimit(0.001, 1))
+			require.NoError(t, err)
+
+			acquired, err := x1.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			acquired, err = x2.TryAcquire(db)
+			require.ErrorIs(t, err, ErrThrottled)
+			require.Equal(t, ClassRetryable, Classify(err))
+			require.False(t, acquired)
+		},
+		"acquire waits": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client", WithAcquireRateLimit(0.001, 1))
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x.Release(db))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			err = x.Acquire(ctx, db)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,235 @@
+package mutex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// typeTopic marks a subspace as holding a [[Topic]].
+const typeTopic = "topic"
+
+// maxMessages is the number of messages retained by a topic.
+const maxMessages = 1000
+
+// Topic is a lightweight publish/subscribe channel for small control-plane
+// signals, such as config reloads or shutdown requests, so systems already
+// coordinating through this package don't need a separate message bus.
+// Publishers append messages keyed by versionstamps, so they never conflict
+// with each other, and subscribers tail the topic with a watch. Only the
+// latest 1000 messages are retained.
+type Topic struct{ subspace.Subspace }
+
+// Message is a message published to a [[Topic]].
+type Message struct {
+	// Payload is the content of the message.
+	Payload []byte
+
+	// Time is when the message was published, according
+	// to the clock of the client which published it.
+	Time time.Time
+
+	// Version orders the messages of the topic. It serves
+	// as a cursor for resuming a subscription. See
+	// [[Topic.Subscribe]].
+	Version tuple.Versionstamp
+}
+
+// NewTopic constructs a topic stored in 'root'. If 'root' holds
+// another kind of primitive, [[ErrWrongType]] is returned.
+func NewTopic(db fdb.Transactor, root subspace.Subspace) (_ *Topic, err error) {
+	defer wrapErr(&err)
+
+	x := kv{Subspace: root}
+	if err := x.claimType(db, typeTopic); err != nil {
+		return nil, err
+	}
+	return &Topic{root}, nil
+}
+
+// Publish appends a message to the topic. Messages published by a
+// single transaction share a versionstamp, so a transaction may only
+// call this method once.
+func (x *Topic) Publish(db fdb.Transactor, payload []byte) (err error) {
+	defer wrapErr(&err)
+
+	rngMessages, err := x.packMessageRange()
+	if err != nil {
+		return fmt.Errorf("failed to pack message range: %w", err)
+	}
+	key, err := x.packMessageKey()
+	if err != nil {
+		return fmt.Errorf("failed to pack message key: %w", err)
+	}
+
+	_, err = db.Transact(func(tr fdb.Transaction) (any, error) {
+		tr.SetVersionstampedKey(key, x.packMessageValue(payload, time.Now()))
+		tr.Add(x.packVersionKey(), packIncrement())
+		trimLog(tr, rngMessages, maxMessages)
+		return nil, nil
+	})
+	return err
+}
+
+// Messages returns the retained messages published after the
+// message with the version 'after', oldest first. If 'after'
+// is the zero version, every retained message is returned.
+func (x *Topic) Messages(db fdb.Transactor, after tuple.Versionstamp) (_ []Message, err error) {
+	defer wrapErr(&err)
+	return x.getMessages(db, after)
+}
+
+// Latest returns the version of the latest message. Subscribing after this
+// version only receives messages published from now on. If no messages are
+// retained, the zero version is returned.
+func (x *Topic) Latest(db fdb.Transactor) (_ tuple.Versionstamp, err error) {
+	defer wrapErr(&err)
+
+	rngMessages, err := x.packMessageRange()
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to pack message range: %w", err)
+	}
+	key, err := getLastKey(db, rngMessages)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to get last message: %w", err)
+	}
+	if key == nil {
+		return tuple.Versionstamp{}, nil
+	}
+	return x.unpackMessageKey(key)
+}
+
+// Subscribe calls 'fn' for each message published after the message with
+// the version 'after', in order. Subscribers persist the version of the
+// latest message they handled and pass it back in to resume where they left
+// off. See [[Topic.Latest]] & [[Topic.Messages]]. This method blocks until
+// the context is cancelled, 'fn' returns an error, or another error occurs.
+// If the subscriber falls behind by more than 1000 messages, the ones which
+// are no longer retained are skipped.
+func (x *Topic) Subscribe(ctx context.Context, db fdb.Transactor, after tuple.Versionstamp, fn func(Message) error) (err error) {
+	defer wrapErr(&err)
+
+	for {
+		// Watch the topic before reading it so a
+		// message published after the read isn't missed.
+		watchCtx, cancel := context.WithCancel(ctx)
+		watch := watch(watchCtx, db, func(fdb.Transaction) (fdb.Key, error) {
+			return x.packVersionKey(), nil
+		})
+
+		msgs, err := x.getMessages(db, after)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+		for _, msg := range msgs {
+			if err := fn(msg); err != nil {
+				cancel()
+				return err
+			}
+			after = msg.Version
+		}
+
+		err = <-watch
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to watch messages: %w", err)
+		}
+	}
+}
+
+// getMessages returns the retained messages which come after
+// the message with the version 'after'.
+func (x *Topic) getMessages(db fdb.Transactor, after tuple.Versionstamp) ([]Message, error) {
+	rngMessages, err := x.packMessageRange()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack message range: %w", err)
+	}
+	if after != (tuple.Versionstamp{}) {
+		rngMessages.Begin = fdb.Key(append(x.Pack(tuple.Tuple{"messages", after}), 0x00))
+	}
+
+	msgs, err := db.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		var msgs []Message
+		iter := tr.GetRange(rngMessages, fdb.RangeOptions{}).Iterator()
+		for iter.Advance() {
+			kv, err := iter.Get()
+			if err != nil {
+				return nil, err
+			}
+			vstamp, err := x.unpackMessageKey(kv.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack message key: %w", err)
+			}
+			msg, err := x.unpackMessageValue(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack message value: %w", err)
+			}
+			msg.Version = vstamp
+			msgs = append(msgs, msg)
+		}
+		return msgs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs.([]Message), nil
+}
+
+func (x *Topic) packMessageRange() (fdb.KeyRange, error) {
+	return fdb.PrefixRange(x.Pack(tuple.Tuple{"messages"}))
+}
+
+func (x *Topic) packMessageKey() (fdb.Key, error) {
+	tup := tuple.Tuple{"messages", tuple.IncompleteVersionstamp(0)}
+	return tup.PackWithVersionstamp(x.Bytes())
+}
+
+func (x *Topic) unpackMessageKey(key fdb.Key) (tuple.Versionstamp, error) {
+	tup, err := x.Unpack(key)
+	if err != nil {
+		return tuple.Versionstamp{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	vstamp, ok := tup[1].(tuple.Versionstamp)
+	if !ok {
+		return tuple.Versionstamp{}, fmt.Errorf("tuple element 1 is not a versionstamp")
+	}
+	return vstamp, nil
+}
+
+func (x *Topic) packMessageValue(payload []byte, t time.Time) []byte {
+	return tuple.Tuple{payload, t.UnixNano()}.Pack()
+}
+
+func (x *Topic) unpackMessageValue(val []byte) (Message, error) {
+	tup, err := tuple.Unpack(val)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to unpack tuple: %w", err)
+	}
+	if len(tup) != 2 {
+		return Message{}, fmt.Errorf("tuple is incorrect length %d", len(tup))
+	}
+	payload, ok := tup[0].([]byte)
+	if !ok {
+		return Message{}, fmt.Errorf("tuple element 0 is not a byte string")
+	}
+	nanos, ok := tup[1].(int64)
+	if !ok {
+		return Message{}, fmt.Errorf("tuple element 1 is not an int64")
+	}
+	return Message{Payload: payload, Time: time.Unix(0, nanos)}, nil
+}
+
+func (x *Topic) packVersionKey() fdb.Key {
+	return x.Pack(tuple.Tuple{"version"})
+}
@@ -0,0 +1,80 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopic(t *testing.T) {
+	tests := map[string]testFn{
+		"messages": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewTopic(db, root)
+			require.NoError(t, err)
+
+			latest, err := x.Latest(db)
+			require.NoError(t, err)
+			require.Equal(t, tuple.Versionstamp{}, latest)
+
+			require.NoError(t, x.Publish(db, []byte("a")))
+			require.NoError(t, x.Publish(db, []byte("b")))
+
+			msgs, err := x.Messages(db, tuple.Versionstamp{})
+			require.NoError(t, err)
+			require.Len(t, msgs, 2)
+			require.Equal(t, []byte("a"), msgs[0].Payload)
+			require.Equal(t, []byte("b"), msgs[1].Payload)
+
+			// Messages are resumed from a cursor.
+			msgs, err = x.Messages(db, msgs[0].Version)
+			require.NoError(t, err)
+			require.Len(t, msgs, 1)
+			require.Equal(t, []byte("b"), msgs[0].Payload)
+
+			latest, err = x.Latest(db)
+			require.NoError(t, err)
+			require.Equal(t, msgs[0].Version, latest)
+		},
+
+		"subscribe": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewTopic(db, root)
+			require.NoError(t, err)
+			require.NoError(t, x.Publish(db, []byte("old")))
+
+			latest, err := x.Latest(db)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			errDone := errors.New("done")
+			received := make(chan []byte, 2)
+			done := make(chan error, 1)
+			go func() {
+				done <- x.Subscribe(ctx, db, latest, func(msg Message) error {
+					received <- msg.Payload
+					if string(msg.Payload) == "b" {
+						return errDone
+					}
+					return nil
+				})
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			require.NoError(t, x.Publish(db, []byte("a")))
+			require.NoError(t, x.Publish(db, []byte("b")))
+
+			require.ErrorIs(t, <-done, errDone)
+			require.Equal(t, []byte("a"), <-received)
+			require.Equal(t, []byte("b"), <-received)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,167 @@
+package mutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// ErrTransferNotAccepted is returned by [[Mutex.TransferTo]] when
+// the recipient hasn't called [[Mutex.AcceptTransfer]].
+var ErrTransferNotAccepted = errors.New("recipient hasn't accepted a transfer")
+
+// AcceptTransfer signals that this client is willing to receive the mutex
+// via [[Mutex.TransferTo]]. The acceptance is consumed by the transfer. Once
+// the mutex is transferred, this client starts heartbeating on its own, so
+// it doesn't need to be waiting in [[Mutex.Acquire]]. If the acceptance is
+// withdrawn before a transfer, such as by [[Mutex.DeclineTransfer]], the
+// client stops waiting for one. Use [[Mutex.AwaitTransfer]] to learn
+// whether the transfer arrived.
+func (x *Mutex) AcceptTransfer(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	db = x.withBreaker(db)
+
+	if err := x.setTransfer(db, x.name, true); err != nil {
+		return err
+	}
+	go func() {
+		// Callers learn of failures via AwaitTransfer.
+		_, _ = x.awaitTransfer(context.Background(), withoutDeadline(db))
+	}()
+	return nil
+}
+
+// AwaitTransfer blocks until a transfer accepted by [[Mutex.AcceptTransfer]]
+// makes this client the owner, then returns the fencing token of the hold.
+// If the context ends first, the error wraps both [[ErrNotOwner]] & the
+// cause of the context. If the acceptance is withdrawn before a transfer,
+// [[ErrTransferNotAccepted]] is returned. The acceptance isn't withdrawn
+// when this method gives up, so the transfer may still arrive afterwards.
+func (x *Mutex) AwaitTransfer(ctx context.Context, db fdb.Transactor) (_ int64, err error) {
+	defer wrapErr(&err)
+	return x.awaitTransfer(ctx, withoutDeadline(x.withBreaker(db)))
+}
+
+// DeclineTransfer withdraws an acceptance made by [[Mutex.AcceptTransfer]].
+func (x *Mutex) DeclineTransfer(db fdb.Transactor) (err error) {
+	defer wrapErr(&err)
+	return x.setTransfer(x.withBreaker(db), x.name, false)
+}
+
+// TransferTo atomically hands ownership of the mutex to the client with the
+// provided name, bypassing the queue. This client must own the mutex and the
+// recipient must have called [[Mutex.AcceptTransfer]]. Otherwise, this method
+// returns [[ErrNotOwner]] or [[ErrTransferNotAccepted]] respectively. The
+// recipient's fencing token, the commit version of the transfer, is
+// returned. See [[Mutex.AcquireToken]]. This is meant for planned
+// handovers, such as blue/green deployments.
+func (x *Mutex) TransferTo(db fdb.Transactor, name string) (_ int64, err error) {
+	defer wrapErr(&err)
+	rec, db := x.startRecording("TransferTo", db)
+	defer func() { rec.finish(name, false, err) }()
+	db = x.withBreaker(db)
+
+	if name == x.name {
+		return x.token.Load(), nil
+	}
+
+	res, err := db.Transact(func(tr fdb.Transaction) (any, error) {
+		if err := x.fence(tr); err != nil {
+			return nil, err
+		}
+
+		accepted, err := x.getTransfer(tr, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transfer: %w", err)
+		}
+		if !accepted {
+			return nil, ErrTransferNotAccepted
+		}
+
+		if err := x.setTransfer(tr, name, false); err != nil {
+			return nil, fmt.Errorf("failed to clear transfer: %w", err)
+		}
+		if err := x.handOver(tr, name); err != nil {
+			return nil, err
+		}
+		return grantResult{acquired: true, vstamp: tr.GetVersionstamp()}, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	x.relinquish()
+	return res.(grantResult).holdToken(), nil
+}
+
+// awaitTransfer starts heartbeating once a transfer accepted by
+// [[Mutex.AcceptTransfer]] makes this client the owner & returns the
+// fencing token of the hold. See [[Mutex.AwaitTransfer]].
+func (x *Mutex) awaitTransfer(ctx context.Context, db fdb.Transactor) (int64, error) {
+	for {
+		// Watch the keys before checking them so
+		// a change made after the check isn't missed.
+		watchCtx, cancel := context.WithCancel(ctx)
+		owned := x.watchOwner(watchCtx, db)
+		accepted := x.watchTransfer(watchCtx, db, x.name)
+
+		owner, err := x.getOwner(db)
+		if err != nil {
+			cancel()
+			return 0, fmt.Errorf("failed to get owner: %w", err)
+		}
+		if owner.name == x.name {
+			cancel()
+			token := x.loadToken(db)
+			x.startBeating(db)
+			return token, nil
+		}
+		ok, err := x.getTransfer(db, x.name)
+		if err != nil {
+			cancel()
+			return 0, fmt.Errorf("failed to get transfer: %w", err)
+		}
+		if !ok {
+			cancel()
+			return 0, ErrTransferNotAccepted
+		}
+
+		select {
+		case <-owned:
+		case <-accepted:
+		case <-ctx.Done():
+			cancel()
+			return 0, fmt.Errorf("%w: no transfer arrived: %w", ErrNotOwner, context.Cause(ctx))
+		}
+		cancel()
+	}
+}
+
+// handOver makes the client with the provided name the owner of the
+// mutex, bypassing the queue. The caller must hold the mutex.
+func (x *Mutex) handOver(tr fdb.Transaction, name string) error {
+	if err := x.removeFromQueue(tr, name); err != nil {
+		return fmt.Errorf("failed to remove recipient from queue: %w", err)
+	}
+	if err := x.logEvent(tr, EventReleased, x.name); err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+	if err := x.setOwner(tr, name); err != nil {
+		return fmt.Errorf("failed to set owner: %w", err)
+	}
+
+	// Move the lock from our session
+	// to the recipient's session.
+	if x.clients != nil {
+		id := lockID(x.Subspace)
+		if err := x.clients.removeLock(tr, x.name, id); err != nil {
+			return fmt.Errorf("failed to unregister lock: %w", err)
+		}
+		if err := x.clients.addLock(tr, name, id); err != nil {
+			return fmt.Errorf("failed to register lock: %w", err)
+		}
+	}
+	return nil
+}
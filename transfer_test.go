@@ -0,0 +1,144 @@
+package mutex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfer(t *testing.T) {
+	tests := map[string]testFn{
+		"accepted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			blue, err := NewMutex(db, root, "blue")
+			require.NoError(t, err)
+
+			green, err := NewMutex(db, root, "green")
+			require.NoError(t, err)
+
+			other, err := NewMutex(db, root, "other")
+			require.NoError(t, err)
+
+			acquired, err := blue.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			// Another client is ahead of green in the queue.
+			acquired, err = other.TryAcquire(db)
+			require.NoError(t, err)
+			require.False(t, acquired)
+
+			err = green.AcceptTransfer(db)
+			require.NoError(t, err)
+
+			token, err := blue.getToken(db)
+			require.NoError(t, err)
+
+			newToken, err := blue.TransferTo(db, "green")
+			require.NoError(t, err)
+			require.Greater(t, newToken, token)
+
+			stored, err := blue.getToken(db)
+			require.NoError(t, err)
+			require.Equal(t, stored, newToken)
+
+			// Green heartbeats without waiting in Acquire.
+			require.Eventually(t, func() bool {
+				return green.HeartbeatLatency() > 0
+			}, 5*time.Second, 10*time.Millisecond)
+
+			acquired, err = green.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			candidates, err := blue.Candidates(db)
+			require.NoError(t, err)
+			require.Len(t, candidates, 1)
+			require.Equal(t, "other", candidates[0].Name)
+		},
+		"not accepted": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			blue, err := NewMutex(db, root, "blue")
+			require.NoError(t, err)
+
+			green, err := NewMutex(db, root, "green")
+			require.NoError(t, err)
+
+			acquired, err := blue.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			_, err = blue.TransferTo(db, "green")
+			require.ErrorIs(t, err, ErrTransferNotAccepted)
+
+			err = green.AcceptTransfer(db)
+			require.NoError(t, err)
+
+			err = green.DeclineTransfer(db)
+			require.NoError(t, err)
+
+			_, err = blue.TransferTo(db, "green")
+			require.ErrorIs(t, err, ErrTransferNotAccepted)
+		},
+		"await": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			blue, err := NewMutex(db, root, "blue")
+			require.NoError(t, err)
+
+			green, err := NewMutex(db, root, "green")
+			require.NoError(t, err)
+
+			acquired, err := blue.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+
+			err = green.AcceptTransfer(db)
+			require.NoError(t, err)
+
+			// Without a transfer, the wait times out.
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err = green.AwaitTransfer(ctx, db)
+			require.ErrorIs(t, err, ErrNotOwner)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			type result struct {
+				token int64
+				err   error
+			}
+			done := make(chan result, 1)
+			go func() {
+				token, err := green.AwaitTransfer(context.Background(), db)
+				done <- result{token, err}
+			}()
+
+			token, err := blue.TransferTo(db, "green")
+			require.NoError(t, err)
+			require.Equal(t, result{token: token}, <-done)
+
+			// A withdrawn acceptance ends the wait.
+			err = blue.AcceptTransfer(db)
+			require.NoError(t, err)
+			err = blue.DeclineTransfer(db)
+			require.NoError(t, err)
+			_, err = blue.AwaitTransfer(context.Background(), db)
+			require.ErrorIs(t, err, ErrTransferNotAccepted)
+		},
+		"not owner": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			blue, err := NewMutex(db, root, "blue")
+			require.NoError(t, err)
+
+			green, err := NewMutex(db, root, "green")
+			require.NoError(t, err)
+
+			err = green.AcceptTransfer(db)
+			require.NoError(t, err)
+
+			_, err = blue.TransferTo(db, "green")
+			require.ErrorIs(t, err, ErrNotOwner)
+		},
+	}
+
+	runTests(t, tests)
+}
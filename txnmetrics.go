@@ -0,0 +1,95 @@
+package mutex
+
+import (
+	"sync/atomic"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// TxnMetrics counts the transactions of a single mutex operation.
+// See [[MetricsHook.Transactions]].
+type TxnMetrics struct {
+	// Mutex identifies the mutex. See [[ClientSession.Locks]].
+	Mutex string
+
+	// Client is the name of the client which performed the operation.
+	Client string
+
+	// Op names the operation: "Acquire", "TryAcquire", "Release",
+	// "TransferTo", "CommitRelease", "Expire", or "Heartbeat". Expire
+	// operations are the cycles of [[Mutex.AutoRelease]].
+	Op string
+
+	// Transactions is the number of transactions run by the operation.
+	Transactions int64
+
+	// Retries is the number of times the operation's
+	// transactions were retried, for any reason.
+	Retries int64
+
+	// Conflicts is the number of retries caused by the database
+	// rejecting a commit. FDB rejects commits almost exclusively
+	// because of conflicts with concurrent transactions.
+	Conflicts int64
+}
+
+// txnHook returns the transactions hook of the
+// process's metrics hook, which may be nil.
+func txnHook() func(TxnMetrics) {
+	held.mu.Lock()
+	defer held.mu.Unlock()
+	return held.hook.Transactions
+}
+
+// reportTxns passes the counts of the operation's
+// transactions to the metrics hook, if one is set.
+func (x *Mutex) reportTxns(op string, c *attemptCounter) {
+	hook := txnHook()
+	if hook == nil {
+		return
+	}
+	transactions := c.transactions.Load()
+	retries := c.attempts.Load() - transactions
+	hook(TxnMetrics{
+		Mutex:        lockID(x.Subspace),
+		Client:       x.name,
+		Op:           op,
+		Transactions: transactions,
+		Retries:      retries,
+		Conflicts:    c.conflicts.Load(),
+	})
+}
+
+// attemptCounter counts the attempts made by its transactions,
+// including those retried after a conflict or transient error.
+// A transaction whose function succeeded but was attempted
+// again must have had its commit rejected, which is counted
+// as a conflict.
+type attemptCounter struct {
+	fdb.Transactor
+	transactions atomic.Int64
+	attempts     atomic.Int64
+	conflicts    atomic.Int64
+}
+
+func (t *attemptCounter) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	t.transactions.Add(1)
+	var committing bool
+	return t.Transactor.Transact(func(tr fdb.Transaction) (any, error) {
+		t.attempts.Add(1)
+		if committing {
+			t.conflicts.Add(1)
+		}
+		ret, err := f(tr)
+		committing = err == nil
+		return ret, err
+	})
+}
+
+func (t *attemptCounter) ReadTransact(f func(fdb.ReadTransaction) (any, error)) (any, error) {
+	t.transactions.Add(1)
+	return t.Transactor.ReadTransact(func(tr fdb.ReadTransaction) (any, error) {
+		t.attempts.Add(1)
+		return f(tr)
+	})
+}
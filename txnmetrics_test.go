@@ -0,0 +1,72 @@
+package mutex
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+// rejectingTransactor runs each transaction's function twice, as
+// if the database rejected the first commit.
+type rejectingTransactor struct{ fdb.Transactor }
+
+func (t rejectingTransactor) Transact(f func(fdb.Transaction) (any, error)) (any, error) {
+	if _, err := f(fdb.Transaction{}); err != nil {
+		return nil, err
+	}
+	return f(fdb.Transaction{})
+}
+
+func TestAttemptCounter(t *testing.T) {
+	c := &attemptCounter{Transactor: rejectingTransactor{}}
+	_, err := c.Transact(func(fdb.Transaction) (any, error) { return nil, nil })
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1), c.transactions.Load())
+	require.Equal(t, int64(2), c.attempts.Load())
+	require.Equal(t, int64(1), c.conflicts.Load())
+}
+
+func TestTxnMetrics(t *testing.T) {
+	tests := map[string]testFn{
+		"hook": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			var (
+				mu      sync.Mutex
+				metrics []TxnMetrics
+			)
+			SetMetricsHook(MetricsHook{
+				Transactions: func(m TxnMetrics) {
+					mu.Lock()
+					defer mu.Unlock()
+					if m.Mutex == lockID(root) {
+						metrics = append(metrics, m)
+					}
+				},
+			})
+			defer SetMetricsHook(MetricsHook{})
+
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x.Release(db))
+
+			mu.Lock()
+			defer mu.Unlock()
+			var ops []string
+			for _, m := range metrics {
+				require.Equal(t, "client", m.Client)
+				require.Positive(t, m.Transactions)
+				ops = append(ops, m.Op)
+			}
+			require.Equal(t, []string{"TryAcquire", "Release"}, ops)
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,49 @@
+package mutex
+
+import (
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+// Validate returns nil if this client still owns the mutex. Otherwise,
+// [[ErrNotOwner]] is returned. To avoid a read on every call in hot paths,
+// the answer is taken from local state if ownership was confirmed within
+// 'staleness'. Ownership is confirmed by each heartbeat which commits, see
+// [[Mutex.AddHeartbeat]], and by each read made by this method. The window
+// is shortened if the local clock is known to drift. See [[Mutex.ClockDrift]].
+//
+// A cached answer may be stale by up to 'staleness', so it must be shorter
+// than the 'maxAge' given to [[Mutex.AutoRelease]] minus the heartbeat
+// interval. Operations which must not race a change of ownership should use
+// [[Guard.Transact]] instead. A 'staleness' of zero always reads the owner.
+func (x *Mutex) Validate(db fdb.Transactor, staleness time.Duration) (err error) {
+	defer wrapErr(&err)
+
+	// Only a running heartbeat vouches for the hold.
+	// Once it's stopped or lost, the cache is invalid.
+	switch x.HeartbeatState() {
+	case HeartbeatHealthy, HeartbeatDegraded:
+	default:
+		return ErrNotOwner
+	}
+
+	confirmed := max(x.lastBeat.Load(), x.validated.Load())
+	if time.Since(time.Unix(0, confirmed)) < x.clock.margin(staleness) {
+		return nil
+	}
+
+	start := time.Now()
+	owner, err := x.getOwner(x.withBreaker(db))
+	if err != nil {
+		return err
+	}
+	if owner.name != x.name {
+		return ErrNotOwner
+	}
+
+	// The read version was chosen after the read started,
+	// so ownership is known to hold as of 'start'.
+	x.validated.Store(start.UnixNano())
+	return nil
+}
@@ -0,0 +1,46 @@
+package mutex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]testFn{
+		"cached": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			require.ErrorIs(t, x.Validate(db, time.Minute), ErrNotOwner)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x.Validate(db, time.Minute))
+
+			// Steal the mutex. The cached answer is stale
+			// but a read notices the change of ownership.
+			require.NoError(t, x.setOwner(db, "thief"))
+			require.NoError(t, x.Validate(db, time.Minute))
+			require.ErrorIs(t, x.Validate(db, 0), ErrNotOwner)
+		},
+		"released": func(t *testing.T, db fdb.Database, root subspace.Subspace) {
+			x, err := NewMutex(db, root, "client")
+			require.NoError(t, err)
+
+			acquired, err := x.TryAcquire(db)
+			require.NoError(t, err)
+			require.True(t, acquired)
+			require.NoError(t, x.Validate(db, 0))
+
+			require.NoError(t, x.Release(db))
+			require.ErrorIs(t, x.Validate(db, time.Minute), ErrNotOwner)
+		},
+	}
+
+	runTests(t, tests)
+}